@@ -0,0 +1,83 @@
+// Package admin serves the operational endpoints of the service.
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/database"
+)
+
+// Pool is the database connection pool to inspect and tune.
+type Pool interface {
+	Stats() sql.DBStats
+	Limits() database.PoolLimits
+	MaxConns() int
+	SetLimits(limits database.PoolLimits) error
+}
+
+type DBHandler struct {
+	pool Pool
+}
+
+func NewDBHandler(p Pool) *DBHandler {
+	return &DBHandler{
+		pool: p,
+	}
+}
+
+// HandleStats reports the statistics of the connection pool, to tell
+// whether requests wait for connections.
+func (h *DBHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	api.OKResponse(w, h.statsResponse())
+}
+
+// HandleSetPool changes the limits of the connection pool without a
+// redeploy. Limits out of bounds are unprocessable.
+func (h *DBHandler) HandleSetPool(w http.ResponseWriter, r *http.Request) {
+	var req PoolLimitsRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+	if req.MaxOpenConns == nil && req.MaxIdleConns == nil {
+		api.ErrorResponse(w, http.StatusBadRequest, "max_open_conns or max_idle_conns is required")
+		return
+	}
+
+	limits := h.pool.Limits()
+	if req.MaxOpenConns != nil {
+		limits.MaxOpenConns = *req.MaxOpenConns
+	}
+	if req.MaxIdleConns != nil {
+		limits.MaxIdleConns = *req.MaxIdleConns
+	}
+	if err := h.pool.SetLimits(limits); err != nil {
+		var limitsErr *database.PoolLimitsError
+		if errors.As(err, &limitsErr) {
+			api.ErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		api.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.OKResponse(w, h.statsResponse())
+}
+
+func (h *DBHandler) statsResponse() DBStatsResponse {
+	stats := h.pool.Stats()
+	limits := h.pool.Limits()
+	return DBStatsResponse{
+		OpenConnections:   stats.OpenConnections,
+		InUse:             stats.InUse,
+		Idle:              stats.Idle,
+		WaitCount:         stats.WaitCount,
+		WaitDurationMS:    stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:     stats.MaxIdleClosed,
+		MaxLifetimeClosed: stats.MaxLifetimeClosed,
+		Limits:            PoolLimits(limits),
+		Bounds:            PoolLimitBounds{MaxOpenConns: h.pool.MaxConns()},
+	}
+}
@@ -0,0 +1,109 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/mytheresa/go-hiring-challenge/app/database"
+)
+
+// newPool returns a pool over a mocked connection, which has real
+// statistics and limits.
+func newPool(t *testing.T) *database.Pool {
+	t.Helper()
+
+	sqlDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	pool, err := database.NewPool(db, database.PoolLimits{MaxOpenConns: 10, MaxIdleConns: 2}, 20)
+	require.NoError(t, err)
+	return pool
+}
+
+func newTestMux(h *DBHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/db/stats", h.HandleStats)
+	mux.HandleFunc("PUT /admin/db/pool", h.HandleSetPool)
+	return mux
+}
+
+func TestHandleStats(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestMux(NewDBHandler(newPool(t))).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/db/stats", nil))
+
+	// The mocked connection opened by gorm is idle in the pool
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"open_connections":1,"in_use":0,"idle":1,
+		"wait_count":0,"wait_duration_ms":0,
+		"max_idle_closed":0,"max_lifetime_closed":0,
+		"limits":{"max_open_conns":10,"max_idle_conns":2},
+		"bounds":{"max_open_conns":20}
+	}`, rec.Body.String())
+}
+
+func TestHandleSetPool(t *testing.T) {
+	put := func(pool *database.Pool, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/admin/db/pool", strings.NewReader(body))
+		newTestMux(NewDBHandler(pool)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("changes both limits", func(t *testing.T) {
+		pool := newPool(t)
+
+		rec := put(pool, `{"max_open_conns":15,"max_idle_conns":5}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"limits":{"max_open_conns":15,"max_idle_conns":5}`)
+		assert.Equal(t, 15, pool.Stats().MaxOpenConnections)
+		assert.Equal(t, database.PoolLimits{MaxOpenConns: 15, MaxIdleConns: 5}, pool.Limits())
+	})
+
+	t.Run("keeps the limits left out", func(t *testing.T) {
+		pool := newPool(t)
+
+		rec := put(pool, `{"max_idle_conns":0}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, database.PoolLimits{MaxOpenConns: 10, MaxIdleConns: 0}, pool.Limits())
+	})
+
+	t.Run("rejects limits out of bounds", func(t *testing.T) {
+		pool := newPool(t)
+
+		rec := put(pool, `{"max_open_conns":50}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"error":"invalid pool limits: max open connections must be between 1 and 20"}`, rec.Body.String())
+		assert.Equal(t, 10, pool.Stats().MaxOpenConnections)
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"max_open_conns":"many"}`, `{"max_conns":5}`} {
+			pool := newPool(t)
+
+			rec := put(pool, body)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+			assert.Equal(t, database.PoolLimits{MaxOpenConns: 10, MaxIdleConns: 2}, pool.Limits())
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package admin
+
+// DBStatsResponse is a snapshot of the connection pool along with its limits.
+type DBStatsResponse struct {
+	OpenConnections int   `json:"open_connections"`
+	InUse           int   `json:"in_use"`
+	Idle            int   `json:"idle"`
+	WaitCount       int64 `json:"wait_count"`
+	// WaitDurationMS is the total time spent waiting for a connection.
+	WaitDurationMS    int64           `json:"wait_duration_ms"`
+	MaxIdleClosed     int64           `json:"max_idle_closed"`
+	MaxLifetimeClosed int64           `json:"max_lifetime_closed"`
+	Limits            PoolLimits      `json:"limits"`
+	Bounds            PoolLimitBounds `json:"bounds"`
+}
+
+type PoolLimits struct {
+	MaxOpenConns int `json:"max_open_conns"`
+	MaxIdleConns int `json:"max_idle_conns"`
+}
+
+// PoolLimitBounds is how far the limits can be raised at runtime.
+type PoolLimitBounds struct {
+	MaxOpenConns int `json:"max_open_conns"`
+}
+
+// PoolLimitsRequest changes the limits given, leaving the others as they are.
+type PoolLimitsRequest struct {
+	MaxOpenConns *int `json:"max_open_conns"`
+	MaxIdleConns *int `json:"max_idle_conns"`
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+)
+
+// StatusClientClosedRequest is the non standard status, borrowed from nginx,
+// logged for the requests whose client went away before their response.
+const StatusClientClosedRequest = 499
+
+// Abandoned tells whether the client of r went away, in which case handlers
+// return without building nor writing a response nobody reads. Abandoned
+// requests are logged with status 499 so that they can be counted. Requests
+// past their deadline are not abandoned, TimeoutMiddleware answers them.
+func Abandoned(r *http.Request) bool {
+	if !errors.Is(r.Context().Err(), context.Canceled) {
+		return false
+	}
+	logging.FromContext(r.Context()).Info("Request abandoned by the client",
+		"method", r.Method, "path", r.URL.Path, "status", StatusClientClosedRequest)
+	return true
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbandoned(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	// answer writes a response unless the client went away, as handlers do
+	// after their repository calls
+	answer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Abandoned(r) {
+			return
+		}
+		OKResponse(w, map[string]string{"status": "ok"})
+	})
+	serve := func(ctx context.Context) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/catalog", nil).WithContext(ctx)
+		req.Header.Set(RequestIDHeader, "req-42")
+		recorder := httptest.NewRecorder()
+		LoggerMiddleware(logger, TimeoutMiddleware(time.Second, time.Second, answer)).ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("requests still wanted are answered", func(t *testing.T) {
+		logs.Reset()
+
+		recorder := serve(context.Background())
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+		assert.Empty(t, logs.String())
+	})
+
+	t.Run("abandoned requests are logged without a response", func(t *testing.T) {
+		logs.Reset()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		recorder := serve(ctx)
+
+		assert.Empty(t, recorder.Body.String())
+		assert.Empty(t, recorder.Header())
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &entry), logs.String())
+		assert.Equal(t, "Request abandoned by the client", entry["msg"])
+		assert.Equal(t, "req-42", entry["request_id"])
+		assert.Equal(t, "/catalog", entry["path"])
+		assert.EqualValues(t, StatusClientClosedRequest, entry["status"])
+	})
+
+	t.Run("requests past their deadline are not abandoned", func(t *testing.T) {
+		logs.Reset()
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/catalog", nil).WithContext(ctx)
+
+		assert.False(t, Abandoned(req))
+		assert.Empty(t, logs.String())
+	})
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+type actorKey struct{}
+
+// KeyID identifies a key without revealing it: the hex encoded first 8
+// bytes of its SHA-256.
+func KeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// WithActor returns a copy of ctx carrying the actor authenticated for the
+// request.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor authenticated by RequireAPIKey or
+// AdminAuthMiddleware, such as "admin:3f2a9c1b7e4d5a60", empty when the
+// request was not authenticated.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader carries the key required by write endpoints.
+const APIKeyHeader = "X-API-Key"
+
+// RequireAPIKey only lets requests through when their X-API-Key header
+// matches key, with the actor "api-key:" followed by the KeyID of key. An
+// empty key rejects every request, so the protected endpoints stay closed
+// until a key is configured.
+func RequireAPIKey(key string, next http.Handler) http.Handler {
+	actor := "api-key:" + KeyID(key)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get(APIKeyHeader)
+		if key == "" || subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+			ErrorResponse(w, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithActor(r.Context(), actor)))
+	})
+}
+
+// AdminAuthMiddleware only lets requests through when their Authorization
+// header carries token as a bearer token, with the actor "admin:" followed by
+// the KeyID of token. Requests without a bearer token
+// get a 401, those with another token a 403. An empty token rejects every
+// request, so the protected endpoints stay closed until a token is
+// configured.
+func AdminAuthMiddleware(token string, next http.Handler) http.Handler {
+	// The tokens are compared through their hashes so that the time taken
+	// does not depend on their length either
+	want := sha256.Sum256([]byte(token))
+	actor := "admin:" + KeyID(token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			ErrorResponse(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		gotSum := sha256.Sum256([]byte(got))
+		if token == "" || subtle.ConstantTimeCompare(gotSum[:], want[:]) != 1 {
+			ErrorResponse(w, http.StatusForbidden, "invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithActor(r.Context(), actor)))
+	})
+}
+
+// AdminAuthWhen applies AdminAuthMiddleware to the requests for which
+// protected returns true, letting the others through.
+func AdminAuthWhen(token string, protected func(*http.Request) bool, next http.Handler) http.Handler {
+	admin := AdminAuthMiddleware(token, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if protected(r) {
+			admin.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsAdmin tells whether r carries token as a bearer token, for the endpoints
+// showing more to admins rather than rejecting everyone else. An empty token
+// matches no request.
+func IsAdmin(r *http.Request, token string) bool {
+	got, ok := bearerToken(r)
+	if !ok || token == "" {
+		return false
+	}
+	gotSum, want := sha256.Sum256([]byte(got)), sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare(gotSum[:], want[:]) == 1
+}
+
+// bearerToken returns the token of the Authorization header, whose scheme is
+// case-insensitive.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
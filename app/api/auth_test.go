@@ -0,0 +1,197 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		header     string
+		wantStatus int
+	}{
+		{"matching key", "secret", "secret", http.StatusNoContent},
+		{"wrong key", "secret", "guess", http.StatusUnauthorized},
+		{"missing key", "secret", "", http.StatusUnauthorized},
+		{"no key configured", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(APIKeyHeader, tt.header)
+			}
+			recorder := httptest.NewRecorder()
+
+			RequireAPIKey(tt.key, ok).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.JSONEq(t, `{"error":"missing or invalid API key"}`, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("POST /catalog", AdminAuthMiddleware("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})))
+	mux.HandleFunc("GET /catalog", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		wantStatus    int
+		wantErr       string
+	}{
+		{"correct token", http.MethodPost, "Bearer secret", http.StatusCreated, ""},
+		{"case-insensitive scheme", http.MethodPost, "bearer secret", http.StatusCreated, ""},
+		{"no token", http.MethodPost, "", http.StatusUnauthorized, "missing bearer token"},
+		{"empty token", http.MethodPost, "Bearer ", http.StatusUnauthorized, "missing bearer token"},
+		{"other scheme", http.MethodPost, "Basic c2VjcmV0", http.StatusUnauthorized, "missing bearer token"},
+		{"wrong token", http.MethodPost, "Bearer guess", http.StatusForbidden, "invalid bearer token"},
+		{"token prefix", http.MethodPost, "Bearer secre", http.StatusForbidden, "invalid bearer token"},
+		{"read left open", http.MethodGet, "", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/catalog", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+
+			mux.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			if tt.wantErr != "" {
+				assert.JSONEq(t, `{"error":"`+tt.wantErr+`"}`, recorder.Body.String())
+				assert.NotContains(t, recorder.Body.String(), "secret")
+			}
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", recorder.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	t.Run("no token configured", func(t *testing.T) {
+		for _, authorization := range []string{"", "Bearer ", "Bearer anything"} {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("Authorization", authorization)
+			recorder := httptest.NewRecorder()
+
+			AdminAuthMiddleware("", http.NotFoundHandler()).ServeHTTP(recorder, req)
+
+			assert.Contains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, recorder.Code, authorization)
+		}
+	})
+}
+
+func TestAdminAuthWhen(t *testing.T) {
+	handler := AdminAuthWhen("secret", func(r *http.Request) bool {
+		return r.URL.Query().Get("includeUnavailable") == "true"
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		target        string
+		authorization string
+		wantStatus    int
+	}{
+		{"unprotected request", "/catalog", "", http.StatusOK},
+		{"unprotected request with a wrong token", "/catalog?includeUnavailable=false", "Bearer guess", http.StatusOK},
+		{"protected request with the token", "/catalog?includeUnavailable=true", "Bearer secret", http.StatusOK},
+		{"protected request without token", "/catalog?includeUnavailable=true", "", http.StatusUnauthorized},
+		{"protected request with a wrong token", "/catalog?includeUnavailable=true", "Bearer guess", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+		})
+	}
+}
+
+func TestAuthActor(t *testing.T) {
+	var actor string
+	record := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = ActorFromContext(r.Context())
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(APIKeyHeader, "secret")
+
+		RequireAPIKey("secret", record).ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "api-key:"+KeyID("secret"), actor)
+	})
+
+	t.Run("admin token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		AdminAuthMiddleware("secret", record).ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "admin:"+KeyID("secret"), actor)
+	})
+
+	t.Run("key ids", func(t *testing.T) {
+		assert.Equal(t, "2bb80d537b1da3e3", KeyID("secret"))
+		assert.NotEqual(t, KeyID("secret"), KeyID("other"))
+		assert.Empty(t, ActorFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+	})
+}
+
+func TestIsAdmin(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          bool
+	}{
+		{"correct token", "secret", "Bearer secret", true},
+		{"case-insensitive scheme", "secret", "bearer secret", true},
+		{"no token", "secret", "", false},
+		{"wrong token", "secret", "Bearer guess", false},
+		{"other scheme", "secret", "Basic c2VjcmV0", false},
+		{"no token configured", "", "Bearer ", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/catalog", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			assert.Equal(t, tt.want, IsAdmin(req, tt.token))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodyBytes is the request body limit used when none is configured.
+const DefaultMaxBodyBytes = 1 << 20
+
+// BodyLimitMiddleware caps request bodies at limit bytes, reading past it
+// fails with an *http.MaxBytesError.
+func BodyLimitMiddleware(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ValueError is a value of a request body that is valid JSON but cannot be
+// accepted, such as a price with too many decimal places. The UnmarshalJSON
+// methods return it for DecodeJSON to answer 422 rather than 400.
+type ValueError struct {
+	Err error
+}
+
+func (e *ValueError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeJSON decodes the request body, a single JSON object without unknown
+// fields, into dst. When the body cannot be decoded it writes the error
+// response, 413 for bodies over the limit, 422 for a *ValueError and 400
+// otherwise, and returns false.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		// Anything but the end of the body after the object is refused
+		if _, extra := dec.Token(); !errors.Is(extra, io.EOF) {
+			err = errTrailingData
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		ErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit))
+		return false
+	}
+	var valueErr *ValueError
+	if errors.As(err, &valueErr) {
+		ErrorResponse(w, http.StatusUnprocessableEntity, valueErr.Error())
+		return false
+	}
+	ErrorResponse(w, http.StatusBadRequest, decodeErrorMessage(err))
+	return false
+}
+
+// ReadBody reads the whole request body. When it cannot be read it writes
+// the error response, 413 for bodies over the limit and 400 otherwise, and
+// returns false.
+func ReadBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		return body, true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		ErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit))
+		return nil, false
+	}
+	ErrorResponse(w, http.StatusBadRequest, "request body could not be read")
+	return nil, false
+}
+
+var errTrailingData = errors.New("request body must contain a single JSON object")
+
+func decodeErrorMessage(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "request body must not be empty"
+	case errors.Is(err, errTrailingData):
+		return err.Error()
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return "request body has unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Sprintf("request body has an invalid value for %s", typeErr.Field)
+	default:
+		return "invalid request body"
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// size refuses the negative sizes with a *ValueError.
+type size int
+
+func (s *size) UnmarshalJSON(data []byte) error {
+	if strings.HasPrefix(string(data), "-") {
+		return &ValueError{Err: errors.New("size must not be negative")}
+	}
+	*s = size(len(data))
+	return nil
+}
+
+func TestDecodeJSON(t *testing.T) {
+	type request struct {
+		Code  string `json:"code"`
+		Price int    `json:"price"`
+		Size  *size  `json:"size,omitempty"`
+	}
+
+	handler := BodyLimitMiddleware(64, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if !DecodeJSON(w, r, &req) {
+			return
+		}
+		OKResponse(w, req)
+	}))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid", `{"code":"PROD001","price":10}`, http.StatusOK, `{"code":"PROD001","price":10}`},
+		{"trailing whitespace", "{\"code\":\"PROD001\"}\n", http.StatusOK, `{"code":"PROD001","price":0}`},
+		{"over the limit", `{"code":"` + strings.Repeat("A", 100) + `"}`, http.StatusRequestEntityTooLarge, `{"error":"request body must not be larger than 64 bytes"}`},
+		{"unknown field", `{"code":"PROD001","colour":"red"}`, http.StatusBadRequest, `{"error":"request body has unknown field \"colour\""}`},
+		{"trailing garbage", `{"code":"PROD001"}garbage`, http.StatusBadRequest, `{"error":"request body must contain a single JSON object"}`},
+		{"second object", `{"code":"PROD001"}{"code":"PROD002"}`, http.StatusBadRequest, `{"error":"request body must contain a single JSON object"}`},
+		{"empty", ``, http.StatusBadRequest, `{"error":"request body must not be empty"}`},
+		{"malformed", `{"code":`, http.StatusBadRequest, `{"error":"invalid request body"}`},
+		{"wrong type", `{"price":"ten"}`, http.StatusBadRequest, `{"error":"request body has an invalid value for price"}`},
+		{"unacceptable value", `{"size":-1}`, http.StatusUnprocessableEntity, `{"error":"size must not be negative"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			assert.JSONEq(t, tt.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	handler := BodyLimitMiddleware(16, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := ReadBody(w, r)
+		if !ok {
+			return
+		}
+		w.Write(body)
+	}))
+
+	t.Run("within the limit", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("code,price\n")))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "code,price\n", recorder.Body.String())
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("A", 17))))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.JSONEq(t, `{"error":"request body must not be larger than 16 bytes"}`, recorder.Body.String())
+	})
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Default max ages of the cacheable responses.
+const (
+	DefaultListMaxAge   = 60 * time.Second
+	DefaultDetailMaxAge = 300 * time.Second
+)
+
+// NoStore is the Cache-Control of the responses that must never be cached.
+const NoStore = "no-store"
+
+// CachePolicy decides the Cache-Control header of the responses by route
+// pattern, so that new routes get sensible headers without doing anything.
+// Zero max ages fall back to the defaults.
+type CachePolicy struct {
+	// ListMaxAge applies to the GET routes listing resources, such as
+	// GET /catalog.
+	ListMaxAge time.Duration
+	// DetailMaxAge applies to the GET routes of a single resource, those
+	// ending with a wildcard such as GET /catalog/{code}.
+	DetailMaxAge time.Duration
+	// Routes overrides the Cache-Control of the GET routes, keyed by their
+	// mux pattern.
+	Routes map[string]string
+}
+
+func (p CachePolicy) cacheControl(pattern string) string {
+	if v, ok := p.Routes[pattern]; ok {
+		return v
+	}
+	if pattern == "" {
+		return NoStore
+	}
+
+	maxAge := orDefault(p.ListMaxAge, DefaultListMaxAge)
+	if strings.HasSuffix(pattern, "}") {
+		maxAge = orDefault(p.DetailMaxAge, DefaultDetailMaxAge)
+	}
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
+// cacheResponseWriter sets the Cache-Control header once the status is
+// known, as error responses must not be cached whatever the route.
+type cacheResponseWriter struct {
+	http.ResponseWriter
+	cacheControl string
+	wroteHeader  bool
+}
+
+func (w *cacheResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		h := w.Header()
+		switch {
+		case status >= http.StatusBadRequest:
+			h.Set("Cache-Control", NoStore)
+		case h.Get("Cache-Control") != "":
+			// The handler knows better
+		default:
+			h.Set("Cache-Control", w.cacheControl)
+		}
+		if h.Get("Cache-Control") != NoStore {
+			// Content is localized with the Accept-Language header, while
+			// the other parameters such as the currency are in the URL
+			h.Add("Vary", "Accept-Language")
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// CacheMiddleware sets the Cache-Control header of the responses of mux
+// according to the policy of the matched route. Requests other than GET and
+// HEAD, unknown routes and error responses are sent with no-store, and
+// cacheable responses vary on Accept-Language.
+func CacheMiddleware(policy CachePolicy, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cacheControl := NoStore
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			_, pattern := mux.Handler(r)
+			cacheControl = policy.cacheControl(pattern)
+		}
+		mux.ServeHTTP(&cacheResponseWriter{ResponseWriter: w, cacheControl: cacheControl}, r)
+	})
+}
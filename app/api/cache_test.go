@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		OKResponse(w, map[string]string{"status": "ok"})
+	}
+	mux.HandleFunc("GET /catalog", ok)
+	mux.HandleFunc("GET /catalog/{code}", ok)
+	mux.HandleFunc("GET /categories/{code}/price-range", ok)
+	mux.HandleFunc("GET /wishlist/{token}", ok)
+	mux.HandleFunc("POST /catalog", func(w http.ResponseWriter, r *http.Request) {
+		CreatedResponse(w, map[string]string{"status": "created"})
+	})
+	mux.HandleFunc("GET /broken", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		ErrorResponse(w, http.StatusInternalServerError, "boom")
+	})
+	mux.HandleFunc("GET /crashed", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("GET /custom", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=5")
+		w.Write([]byte("ok"))
+	})
+
+	handler := CacheMiddleware(CachePolicy{
+		ListMaxAge: 30 * time.Second,
+		Routes:     map[string]string{"GET /wishlist/{token}": "private, no-cache"},
+	}, mux)
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		status       int
+		cacheControl string
+		vary         string
+	}{
+		{"list", http.MethodGet, "/catalog", http.StatusOK, "public, max-age=30", "Accept-Language"},
+		{"head of a list", http.MethodHead, "/catalog", http.StatusOK, "public, max-age=30", "Accept-Language"},
+		{"detail with the default max age", http.MethodGet, "/catalog/PROD001", http.StatusOK, "public, max-age=300", "Accept-Language"},
+		{"nested list", http.MethodGet, "/categories/shoes/price-range", http.StatusOK, "public, max-age=30", "Accept-Language"},
+		{"route override", http.MethodGet, "/wishlist/abc-123", http.StatusOK, "private, no-cache", "Accept-Language"},
+		{"handler header kept", http.MethodGet, "/custom", http.StatusOK, "public, max-age=5", "Accept-Language"},
+		{"write", http.MethodPost, "/catalog", http.StatusCreated, "no-store", ""},
+		{"unknown route", http.MethodGet, "/unknown", http.StatusNotFound, "no-store", ""},
+		{"method not allowed", http.MethodDelete, "/catalog", http.StatusMethodNotAllowed, "no-store", ""},
+		{"server error", http.MethodGet, "/broken", http.StatusInternalServerError, "no-store", ""},
+		{"server error without a body", http.MethodGet, "/crashed", http.StatusInternalServerError, "no-store", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.target, nil))
+
+			assert.Equal(t, tt.status, recorder.Code)
+			assert.Equal(t, tt.cacheControl, recorder.Header().Get("Cache-Control"))
+			assert.Equal(t, tt.vary, recorder.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestErrorResponse_NoStore(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	ErrorResponse(recorder, http.StatusInternalServerError, "boom")
+
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// DefaultMaxConcurrentRequests is the concurrency limit used when none is
+// configured.
+const DefaultMaxConcurrentRequests = 200
+
+// busyRetryAfter is the number of seconds clients turned away by the
+// concurrency limit are asked to wait.
+const busyRetryAfter = 1
+
+// ConcurrencyLimitMiddleware serves at most limit requests at a time. The
+// requests over it are not queued but answered 503 with a Retry-After header,
+// so that a spike of traffic cannot open an unbounded number of database
+// queries. A limit below 1 serves every request.
+func ConcurrencyLimitMiddleware(limit int, next http.Handler) http.Handler {
+	if limit < 1 {
+		return next
+	}
+	slots := make(chan struct{}, limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			w.Header().Set("Retry-After", strconv.Itoa(busyRetryAfter))
+			ErrorResponse(w, http.StatusServiceUnavailable, "server is busy, retry later")
+			return
+		}
+		// Released even when the handler panics
+		defer func() { <-slots }()
+
+		next.ServeHTTP(w, r)
+	})
+}
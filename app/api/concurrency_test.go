@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	serve := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	t.Run("requests over the limit answer 503", func(t *testing.T) {
+		const limit, requests = 3, 10
+		release := make(chan struct{})
+		handler := ConcurrencyLimitMiddleware(limit, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			OKResponse(w, map[string]string{"status": "ok"})
+		}))
+
+		responses := make(chan *httptest.ResponseRecorder, requests)
+		for range requests {
+			go func() { responses <- serve(handler, "/") }()
+		}
+
+		// The requests holding a slot wait for the release, the others
+		// answer right away
+		for range requests - limit {
+			recorder := <-responses
+			assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+			assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+			assert.JSONEq(t, `{"error":"server is busy, retry later"}`, recorder.Body.String())
+		}
+		close(release)
+		for range limit {
+			assert.Equal(t, http.StatusOK, (<-responses).Code)
+		}
+
+		// The slots are free again
+		assert.Equal(t, http.StatusOK, serve(handler, "/").Code)
+	})
+
+	t.Run("slots are released when the handler panics", func(t *testing.T) {
+		handler := RecoverMiddleware(ConcurrencyLimitMiddleware(1, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/panic" {
+				panic("index out of range")
+			}
+			OKResponse(w, map[string]string{"status": "ok"})
+		})))
+
+		assert.Equal(t, http.StatusInternalServerError, serve(handler, "/panic").Code)
+		assert.Equal(t, http.StatusInternalServerError, serve(handler, "/panic").Code)
+		assert.Equal(t, http.StatusOK, serve(handler, "/").Code)
+	})
+
+	t.Run("no limit below 1", func(t *testing.T) {
+		const requests = 5
+		var entered sync.WaitGroup
+		entered.Add(requests)
+		release := make(chan struct{})
+		handler := ConcurrencyLimitMiddleware(0, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entered.Done()
+			<-release
+			OKResponse(w, map[string]string{"status": "ok"})
+		}))
+
+		responses := make(chan *httptest.ResponseRecorder, requests)
+		for range requests {
+			go func() { responses <- serve(handler, "/") }()
+		}
+		// Every request is served at once
+		entered.Wait()
+		close(release)
+		for range requests {
+			assert.Equal(t, http.StatusOK, (<-responses).Code)
+		}
+	})
+}
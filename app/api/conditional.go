@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetLastModified sets the Last-Modified header to the given time.
+func SetLastModified(w http.ResponseWriter, lastModified time.Time) {
+	w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+}
+
+// NotModifiedSince reports whether the request's If-Modified-Since header is
+// at or after lastModified. Missing or malformed headers are ignored.
+func NotModifiedSince(r *http.Request, lastModified time.Time) bool {
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates have a one second resolution
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// SetVersionETag sets the ETag header to version, as a strong entity tag such
+// as "3", for clients to send it back in If-Match.
+func SetVersionETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// IfMatchVersion reads the version of the If-Match header, as set by
+// SetVersionETag. ok is false without the header; any other entity tag,
+// including weak ones and lists, is an error.
+func IfMatchVersion(r *http.Request) (version int, ok bool, err error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" {
+		return 0, false, nil
+	}
+	if len(v) > 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		if version, err := strconv.Atoi(v[1 : len(v)-1]); err == nil && version > 0 {
+			return version, true, nil
+		}
+	}
+	return 0, false, errors.New(`If-Match must be a single version entity tag such as "1"`)
+}
+
+type preconditionFailedBody struct {
+	Error          string `json:"error"`
+	CurrentVersion int    `json:"current_version"`
+}
+
+// PreconditionFailedResponse refuses with 412 an update made from an outdated
+// version, telling the current one in the body and the ETag header.
+func PreconditionFailedResponse(w http.ResponseWriter, message string, current int) {
+	w.Header().Set("Cache-Control", NoStore)
+	SetVersionETag(w, current)
+	writeJSON(w, http.StatusPreconditionFailed, preconditionFailedBody{Error: message, CurrentVersion: current})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLastModified(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	SetLastModified(recorder, time.Date(2025, 6, 1, 10, 30, 0, 500, time.FixedZone("CEST", 2*60*60)))
+
+	assert.Equal(t, "Sun, 01 Jun 2025 08:30:00 GMT", recorder.Header().Get("Last-Modified"))
+}
+
+func TestNotModifiedSince(t *testing.T) {
+	lastModified := time.Date(2025, 6, 1, 8, 30, 0, 250_000_000, time.UTC)
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"missing header", "", false},
+		{"malformed header", "yesterday", false},
+		{"modified after header", "Sun, 01 Jun 2025 08:29:59 GMT", false},
+		{"same second as header", "Sun, 01 Jun 2025 08:30:00 GMT", true},
+		{"header after last modification", "Mon, 02 Jun 2025 00:00:00 GMT", true},
+		{"obsolete RFC 850 format", "Sunday, 01-Jun-25 08:30:00 GMT", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("If-Modified-Since", tt.header)
+			}
+
+			assert.Equal(t, tt.want, NotModifiedSince(req, lastModified))
+		})
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    int
+		wantOK  bool
+		wantErr bool
+	}{
+		{"missing header", "", 0, false, false},
+		{"version", `"3"`, 3, true, false},
+		{"surrounding spaces", ` "12" `, 12, true, false},
+		{"unquoted", "3", 0, false, true},
+		{"weak", `W/"3"`, 0, false, true},
+		{"list", `"3", "4"`, 0, false, true},
+		{"any", "*", 0, false, true},
+		{"zero", `"0"`, 0, false, true},
+		{"not a number", `"abc"`, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("If-Match", tt.header)
+			}
+
+			version, ok, err := IfMatchVersion(req)
+
+			assert.Equal(t, tt.want, version)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPreconditionFailedResponse(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	PreconditionFailedResponse(recorder, "category was modified meanwhile", 4)
+
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+	assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
+	assert.Equal(t, NoStore, recorder.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"error":"category was modified meanwhile","current_version":4}`, recorder.Body.String())
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SupportedLocales lists the locales content can be translated to.
+var SupportedLocales = []string{"en", "de"}
+
+// RequestLocale picks the supported locale that best matches the request,
+// taken from the locale query parameter or else the Accept-Language header.
+// It returns an empty string when none of the requested locales is supported.
+func RequestLocale(r *http.Request) string {
+	if locale := r.URL.Query().Get("locale"); locale != "" {
+		return matchLocale(locale)
+	}
+	return parseAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// parseAcceptLanguage walks a header such as "de-DE,de;q=0.9,en;q=0.8" by
+// decreasing quality and returns the first supported match.
+func parseAcceptLanguage(header string) string {
+	type tag struct {
+		locale  string
+		quality float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		locale, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if locale == "" || quality <= 0 {
+			continue
+		}
+		tags = append(tags, tag{locale: locale, quality: quality})
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	for _, t := range tags {
+		if locale := matchLocale(t.locale); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// matchLocale maps a language tag to a supported locale, trying the tag
+// itself and then its primary language, so de-DE matches de.
+func matchLocale(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if slices.Contains(SupportedLocales, tag) {
+		return tag
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	if slices.Contains(SupportedLocales, base) {
+		return base
+	}
+	return ""
+}
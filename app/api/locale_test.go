@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   string
+	}{
+		{"no preference", "/", "", ""},
+		{"exact match", "/", "de", "de"},
+		{"region falls back to language", "/", "de-DE", "de"},
+		{"quality values", "/", "de-DE,de;q=0.9,en;q=0.8", "de"},
+		{"highest quality wins", "/", "en;q=0.5,de;q=0.9", "de"},
+		{"unsupported locales are skipped", "/", "fr-FR,fr;q=0.9,en;q=0.8", "en"},
+		{"zero quality is excluded", "/", "de;q=0,en;q=0.1", "en"},
+		{"malformed quality is ignored", "/", "de;q=abc,en", "en"},
+		{"nothing supported", "/", "fr,it;q=0.5", ""},
+		{"wildcard", "/", "*", ""},
+		{"query parameter wins", "/?locale=en", "de", "en"},
+		{"query parameter is case insensitive", "/?locale=DE-at", "", "de"},
+		{"unsupported query parameter", "/?locale=fr", "de", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set("Accept-Language", tt.header)
+			}
+
+			assert.Equal(t, tt.want, RequestLocale(r))
+		})
+	}
+}
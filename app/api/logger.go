@@ -0,0 +1,20 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+)
+
+// LoggerMiddleware makes logger available to the handlers through
+// logging.FromContext, tagged with the id of the request when it has one.
+func LoggerMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := logger
+		if id := r.Header.Get(RequestIDHeader); id != "" {
+			l = l.With("request_id", id)
+		}
+		next.ServeHTTP(w, r.WithContext(logging.NewContext(r.Context(), l)))
+	})
+}
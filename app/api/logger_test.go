@@ -0,0 +1,39 @@
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+)
+
+func TestLoggerMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	handler := LoggerMiddleware(slog.New(slog.NewTextHandler(&logs, nil)), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("handled")
+	}))
+
+	t.Run("tags the entries with the request id", func(t *testing.T) {
+		logs.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/catalog", nil)
+		req.Header.Set(RequestIDHeader, "req-42")
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Contains(t, logs.String(), "msg=handled request_id=req-42")
+	})
+
+	t.Run("without a request id", func(t *testing.T) {
+		logs.Reset()
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/catalog", nil))
+
+		assert.Contains(t, logs.String(), "msg=handled")
+		assert.NotContains(t, logs.String(), "request_id")
+	})
+}
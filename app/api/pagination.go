@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SetPaginationHeaders describes the page of a listing in the X-Total-Count,
+// X-Offset and X-Limit headers, along with a Link header to the next and
+// previous pages at the public URL of the request.
+func SetPaginationHeaders(w http.ResponseWriter, r *http.Request, offset, limit int, total int64) {
+	h := w.Header()
+	h.Set("X-Total-Count", strconv.FormatInt(total, 10))
+	h.Set("X-Offset", strconv.Itoa(offset))
+	h.Set("X-Limit", strconv.Itoa(limit))
+
+	u, err := url.Parse(AbsoluteURL(r, r.URL.RequestURI()))
+	if err != nil {
+		return
+	}
+	if link := PaginationLinks(u, offset, limit, total); link != "" {
+		h.Set("Link", link)
+	}
+}
+
+// PaginationLinks builds an RFC 5988 Link header value pointing to the pages
+// around the one at offset. The URLs keep every other query parameter of u.
+// next is omitted on the last page and prev on the first one.
+func PaginationLinks(u *url.URL, offset, limit int, total int64) string {
+	var links []string
+	if int64(offset+limit) < total {
+		links = append(links, pageLink(u, offset+limit, limit, "next"))
+	}
+	if offset > 0 {
+		links = append(links, pageLink(u, max(offset-limit, 0), limit, "prev"))
+	}
+	return strings.Join(links, ", ")
+}
+
+func pageLink(u *url.URL, offset, limit int, rel string) string {
+	q := u.Query()
+	q.Set("offset", strconv.Itoa(offset))
+	q.Set("limit", strconv.Itoa(limit))
+
+	page := *u
+	page.RawQuery = q.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, page.String(), rel)
+}
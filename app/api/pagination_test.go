@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginationLinks(t *testing.T) {
+	u, err := url.Parse("/catalog?category=shoes&priceLessThan=20&sort=price_asc&offset=10&limit=10")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		offset int
+		limit  int
+		total  int64
+		want   string
+	}{
+		{
+			name: "first page", offset: 0, limit: 10, total: 25,
+			want: `</catalog?category=shoes&limit=10&offset=10&priceLessThan=20&sort=price_asc>; rel="next"`,
+		},
+		{
+			name: "middle page", offset: 10, limit: 10, total: 25,
+			want: `</catalog?category=shoes&limit=10&offset=20&priceLessThan=20&sort=price_asc>; rel="next", ` +
+				`</catalog?category=shoes&limit=10&offset=0&priceLessThan=20&sort=price_asc>; rel="prev"`,
+		},
+		{
+			name: "last page", offset: 20, limit: 10, total: 25,
+			want: `</catalog?category=shoes&limit=10&offset=10&priceLessThan=20&sort=price_asc>; rel="prev"`,
+		},
+		{
+			name: "prev does not go below zero", offset: 5, limit: 10, total: 8,
+			want: `</catalog?category=shoes&limit=10&offset=0&priceLessThan=20&sort=price_asc>; rel="prev"`,
+		},
+		{
+			name: "single page", offset: 0, limit: 10, total: 10,
+			want: "",
+		},
+		{
+			name: "empty listing", offset: 0, limit: 10, total: 0,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PaginationLinks(u, tt.offset, tt.limit, tt.total))
+		})
+	}
+}
+
+func TestSetPaginationHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8484/catalog", nil)
+
+	t.Run("with links", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		SetPaginationHeaders(recorder, req, 0, 10, 12)
+
+		assert.Equal(t, "12", recorder.Header().Get("X-Total-Count"))
+		assert.Equal(t, "0", recorder.Header().Get("X-Offset"))
+		assert.Equal(t, "10", recorder.Header().Get("X-Limit"))
+		assert.Equal(t, `<http://localhost:8484/catalog?limit=10&offset=10>; rel="next"`, recorder.Header().Get("Link"))
+	})
+
+	t.Run("links at the public base URL", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		cfg := PublicURL{BaseURL: "https://api.example.com/catalog-svc"}
+
+		PublicURLMiddleware(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			SetPaginationHeaders(w, r, 0, 10, 12)
+		})).ServeHTTP(recorder, req)
+
+		assert.Equal(t, `<https://api.example.com/catalog-svc/catalog?limit=10&offset=10>; rel="next"`, recorder.Header().Get("Link"))
+	})
+
+	t.Run("no link on a single page", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		SetPaginationHeaders(recorder, req, 0, 10, 3)
+
+		assert.Equal(t, "3", recorder.Header().Get("X-Total-Count"))
+		assert.NotContains(t, recorder.Header(), "Link")
+	})
+}
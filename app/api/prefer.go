@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Return preferences of the Prefer header, see RFC 7240.
+const (
+	ReturnMinimal        = "minimal"
+	ReturnRepresentation = "representation"
+)
+
+// ReturnPreference reads the return preference of the Prefer headers, such as
+// "return=minimal" in "respond-async, return=minimal". It returns an empty
+// string when the request states none, the first one winning otherwise.
+func ReturnPreference(r *http.Request) string {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			token, _, _ := strings.Cut(pref, ";")
+			name, value, ok := strings.Cut(strings.TrimSpace(token), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "return") {
+				continue
+			}
+			return strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`))
+		}
+	}
+	return ""
+}
+
+// CreatedPreferredResponse answers a creation with 201 and data, unless the
+// request prefers return=minimal: the body is then left empty, the Location
+// header set by the handler telling where the created resource is. The
+// preference honoured is echoed in Preference-Applied.
+func CreatedPreferredResponse(w http.ResponseWriter, r *http.Request, data any) {
+	switch ReturnPreference(r) {
+	case ReturnMinimal:
+		w.Header().Set("Preference-Applied", "return="+ReturnMinimal)
+		w.WriteHeader(http.StatusCreated)
+		return
+	case ReturnRepresentation:
+		w.Header().Set("Preference-Applied", "return="+ReturnRepresentation)
+	}
+	CreatedResponse(w, data)
+}
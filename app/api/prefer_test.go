@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReturnPreference(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    string
+	}{
+		{"no header", nil, ""},
+		{"minimal", []string{"return=minimal"}, ReturnMinimal},
+		{"representation", []string{"return=representation"}, ReturnRepresentation},
+		{"among other preferences", []string{"respond-async, wait=10, return=minimal"}, ReturnMinimal},
+		{"in a later header", []string{"respond-async", "return=minimal"}, ReturnMinimal},
+		{"case and quotes", []string{`Return="Minimal"`}, ReturnMinimal},
+		{"with parameters", []string{"return=minimal; foo=bar"}, ReturnMinimal},
+		{"first one wins", []string{"return=representation, return=minimal"}, ReturnRepresentation},
+		{"other preferences only", []string{"respond-async"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			for _, h := range tt.headers {
+				req.Header.Add("Prefer", h)
+			}
+
+			assert.Equal(t, tt.want, ReturnPreference(req))
+		})
+	}
+}
+
+func TestCreatedPreferredResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefer      string
+		wantBody    string
+		wantApplied string
+	}{
+		{"default", "", `{"code":"bags"}`, ""},
+		{"representation", "return=representation", `{"code":"bags"}`, "return=representation"},
+		{"minimal", "return=minimal", "", "return=minimal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/categories", nil)
+			if tt.prefer != "" {
+				req.Header.Set("Prefer", tt.prefer)
+			}
+			recorder := httptest.NewRecorder()
+
+			CreatedPreferredResponse(recorder, req, map[string]string{"code": "bags"})
+
+			assert.Equal(t, http.StatusCreated, recorder.Code)
+			assert.Equal(t, tt.wantApplied, recorder.Header().Get("Preference-Applied"))
+			if tt.wantBody == "" {
+				assert.Empty(t, recorder.Body.String())
+				assert.Empty(t, recorder.Header().Get("Content-Type"))
+			} else {
+				assert.JSONEq(t, tt.wantBody, recorder.Body.String())
+			}
+		})
+	}
+}
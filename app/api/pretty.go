@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+)
+
+// prettyResponseWriter marks responses whose JSON body should be indented.
+type prettyResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w prettyResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// PrettyMiddleware indents the JSON responses of requests carrying the
+// pretty=true query parameter, which is handy when browsing the API.
+// Responses stay compact otherwise.
+func PrettyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pretty") == "true" {
+			w = prettyResponseWriter{w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isPretty(w http.ResponseWriter) bool {
+	for {
+		switch rw := w.(type) {
+		case prettyResponseWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrettyMiddleware(t *testing.T) {
+	handler := PrettyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		OKResponse(w, map[string]any{"products": []string{"PROD001"}})
+	}))
+
+	t.Run("indented when pretty=true", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/catalog?pretty=true", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "\n")
+		assert.Equal(t, "{\n  \"products\": [\n    \"PROD001\"\n  ]\n}", recorder.Body.String())
+	})
+
+	t.Run("compact by default", func(t *testing.T) {
+		for _, target := range []string{"/catalog", "/catalog?pretty=false"} {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+			assert.NotContains(t, recorder.Body.String(), "\n")
+			assert.Equal(t, `{"products":["PROD001"]}`, recorder.Body.String())
+		}
+	})
+
+	t.Run("error responses are indented too", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		PrettyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ErrorResponse(w, http.StatusNotFound, "not found")
+		})).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?pretty=true", nil))
+
+		assert.Equal(t, "{\n  \"error\": \"not found\"\n}", recorder.Body.String())
+	})
+}
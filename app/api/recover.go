@@ -0,0 +1,36 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+)
+
+// RequestIDHeader carries the id the client or a proxy gave to the request.
+const RequestIDHeader = "X-Request-ID"
+
+// RecoverMiddleware answers 500 to requests whose handler panics, logging the
+// panic and its stack trace rather than sending them to the client.
+// http.ErrAbortHandler is re-raised so the server aborts the response as
+// intended.
+func RecoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			logging.FromContext(r.Context()).Error("Panic serving request",
+				"method", r.Method, "path", r.URL.Path, "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+			ErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	handler := LoggerMiddleware(logger, RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("index out of range")
+		}
+		OKResponse(w, map[string]string{"status": "ok"})
+	})))
+
+	t.Run("panics answer 500", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+		req.Header.Set(RequestIDHeader, "req-42")
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.JSONEq(t, `{"error":"internal server error"}`, recorder.Body.String())
+		assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &entry), logs.String())
+		assert.Equal(t, "ERROR", entry["level"])
+		assert.Equal(t, "req-42", entry["request_id"])
+		assert.Equal(t, "/panic", entry["path"])
+		assert.Equal(t, "index out of range", entry["panic"])
+		assert.Contains(t, entry["stack"], "runtime/debug.Stack")
+	})
+
+	t.Run("later requests are served", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+	})
+
+	t.Run("aborted handlers are not recovered", func(t *testing.T) {
+		aborting := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			aborting.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+}
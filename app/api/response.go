@@ -1,11 +1,96 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
 )
 
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// FieldError is the problem with one field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type validationErrorBody struct {
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors"`
+}
+
 func OKResponse(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusOK, data)
+}
+
+func CreatedResponse(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusCreated, data)
+}
+
+func AcceptedResponse(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusAccepted, data)
 }
 
+// ErrorResponse responds with the message as JSON. Errors are never cached,
+// including those written outside of CacheMiddleware such as on panics.
 func ErrorResponse(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Cache-Control", NoStore)
+	writeJSON(w, status, errorBody{Error: message})
+}
+
+// ValidationErrorResponse responds with 400 and every field error at once.
+// The error message joins them all, for clients reading only that one.
+func ValidationErrorResponse(w http.ResponseWriter, fields []FieldError) {
+	messages := make([]string, len(fields))
+	for i, f := range fields {
+		messages[i] = f.Message
+	}
+	w.Header().Set("Cache-Control", NoStore)
+	writeJSON(w, http.StatusBadRequest, validationErrorBody{
+		Error:  strings.Join(messages, "; "),
+		Errors: fields,
+	})
+}
+
+// RepositoryErrorResponse responds with the status matching the kind of a
+// repository error.
+func RepositoryErrorResponse(w http.ResponseWriter, err error) {
+	ErrorResponse(w, repositoryErrorStatus(err), err.Error())
+}
+
+func repositoryErrorStatus(err error) int {
+	switch errs.KindOf(err) {
+	case errs.NotFound:
+		return http.StatusNotFound
+	case errs.Conflict:
+		return http.StatusConflict
+	case errs.Invalid:
+		return http.StatusBadRequest
+	case errs.Precondition:
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	var body []byte
+	var err error
+	if isPretty(w) {
+		body, err = json.MarshalIndent(data, "", "  ")
+	} else {
+		body, err = json.Marshal(data)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
 }
@@ -1,11 +1,15 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
 )
 
 func TestOKResponse(t *testing.T) {
@@ -40,3 +44,47 @@ func TestErrorResponse(t *testing.T) {
 		assert.JSONEq(t, expected, recorder.Body.String(), "Response body does not match expected")
 	})
 }
+
+func TestValidationErrorResponse(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ValidationErrorResponse(recorder, []FieldError{
+		{Field: "code", Message: "code is required"},
+		{Field: "name", Message: "name must be at most 256 characters"},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, NoStore, recorder.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{
+		"error":"code is required; name must be at most 256 characters",
+		"errors":[
+			{"field":"code","message":"code is required"},
+			{"field":"name","message":"name must be at most 256 characters"}
+		]
+	}`, recorder.Body.String())
+}
+
+func TestRepositoryErrorResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"not found", errs.New(errs.NotFound, "product not found"), http.StatusNotFound},
+		{"conflict", errs.New(errs.Conflict, "product already exists"), http.StatusConflict},
+		{"invalid", errs.New(errs.Invalid, "invalid product id"), http.StatusBadRequest},
+		{"precondition", errs.New(errs.Precondition, "category was modified meanwhile"), http.StatusPreconditionFailed},
+		{"internal", errs.New(errs.Internal, "connection reset"), http.StatusInternalServerError},
+		{"unclassified", errors.New("connection reset"), http.StatusInternalServerError},
+		{"wrapped", fmt.Errorf("loading: %w", errs.New(errs.NotFound, "product not found")), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			RepositoryErrorResponse(recorder, tt.err)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			assert.JSONEq(t, `{"error":"`+tt.err.Error()+`"}`, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+)
+
+// RoutesMiddleware serves the requests routed by mux to one of patterns, such
+// as "POST /catalog/import", with matched, and the other requests with next.
+// It lets a few routes opt out of the middlewares wrapping the others.
+func RoutesMiddleware(mux *http.ServeMux, patterns []string, matched, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); slices.Contains(patterns, pattern) {
+			matched.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutesMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /catalog/import", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /catalog", func(w http.ResponseWriter, r *http.Request) {})
+	matched := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) })
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	handler := RoutesMiddleware(mux, []string{"POST /catalog/import"}, matched, next)
+
+	for target, want := range map[string]int{
+		"/catalog/import": http.StatusAccepted,
+		"/catalog":        http.StatusNoContent,
+		"/unknown":        http.StatusNoContent,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+		assert.Equal(t, want, rec.Code, target)
+	}
+}
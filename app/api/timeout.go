@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+)
+
+// TimeoutHeader lets clients ask for a request timeout in milliseconds.
+const TimeoutHeader = "X-Timeout-Ms"
+
+// timeoutResponseWriter holds the response of a handler racing its deadline.
+// The handler gets headers of its own, sent once it writes the status, so
+// that the timeout response can be written concurrently. Whatever the
+// handler writes after the timeout response is dropped.
+type timeoutResponseWriter struct {
+	http.ResponseWriter
+	ctx context.Context
+
+	mu          sync.Mutex
+	header      http.Header
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader turns the server errors of requests whose deadline expired
+// into timeout responses.
+func (w *timeoutResponseWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+	if status >= http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timeOut()
+		return
+	}
+	w.writeHeader(status)
+}
+
+func (w *timeoutResponseWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !w.wroteHeader {
+		w.writeHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the response written so far, for the handlers streaming it.
+func (w *timeoutResponseWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	if !w.wroteHeader {
+		w.writeHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *timeoutResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writeHeader sends the headers of the handler. w.mu must be held.
+func (w *timeoutResponseWriter) writeHeader(status int) {
+	w.wroteHeader = true
+	dst := w.ResponseWriter.Header()
+	clear(dst)
+	maps.Copy(dst, w.header)
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// timeOut answers with the timeout response unless a response was already
+// started. w.mu must be held.
+func (w *timeoutResponseWriter) timeOut() {
+	if w.wroteHeader || w.timedOut {
+		return
+	}
+	w.timedOut = true
+	ErrorResponse(w.ResponseWriter, http.StatusServiceUnavailable, "request timed out")
+}
+
+// TimeoutMiddleware bounds every request by the timeout asked for in the
+// X-Timeout-Ms header, clamped to max, or by def when there is none.
+// Malformed headers are rejected with 400. Requests still unanswered at their
+// deadline get a 503, whether or not their handler gave up; responses already
+// started are left to complete. Timeouts are logged with the request logger.
+func TimeoutMiddleware(def, max time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := def
+		if v := r.Header.Get(TimeoutHeader); v != "" {
+			ms, err := strconv.Atoi(v)
+			if err != nil || ms <= 0 {
+				ErrorResponse(w, http.StatusBadRequest, TimeoutHeader+" must be a positive number of milliseconds")
+				return
+			}
+			timeout = min(time.Duration(ms)*time.Millisecond, max)
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		tw := &timeoutResponseWriter{ResponseWriter: w, ctx: ctx, header: w.Header().Clone()}
+		// The handler runs on its own goroutine so that the timeout response
+		// does not wait for it. Its panics are raised again here, for the
+		// middlewares around to recover them.
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return
+		case p := <-panicked:
+			panic(p)
+		case <-ctx.Done():
+		}
+
+		tw.mu.Lock()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			tw.timeOut()
+		}
+		timedOut := tw.timedOut
+		tw.mu.Unlock()
+		if timedOut {
+			logging.FromContext(r.Context()).Warn("Request timed out",
+				"method", r.Method, "path", r.URL.Path, "timeout", timeout)
+			return
+		}
+
+		// The response is under way, or the client went away
+		select {
+		case <-done:
+		case p := <-panicked:
+			panic(p)
+		}
+	})
+}
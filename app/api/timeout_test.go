@@ -0,0 +1,150 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	// deadline reports how long the handler was given
+	deadline := func(t *testing.T, header string) (time.Duration, *httptest.ResponseRecorder) {
+		var got time.Duration
+		handler := TimeoutMiddleware(2*time.Second, 10*time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, ok := r.Context().Deadline()
+			require.True(t, ok)
+			got = time.Until(d)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/catalog", nil)
+		if header != "" {
+			req.Header.Set(TimeoutHeader, header)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return got, recorder
+	}
+
+	t.Run("default timeout", func(t *testing.T) {
+		got, recorder := deadline(t, "")
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.InDelta(t, 2*time.Second, got, float64(100*time.Millisecond))
+	})
+
+	t.Run("header shortens the deadline", func(t *testing.T) {
+		got, recorder := deadline(t, "500")
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.InDelta(t, 500*time.Millisecond, got, float64(100*time.Millisecond))
+	})
+
+	t.Run("header above the max is clamped", func(t *testing.T) {
+		got, recorder := deadline(t, "60000")
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.InDelta(t, 10*time.Second, got, float64(100*time.Millisecond))
+	})
+
+	t.Run("malformed header", func(t *testing.T) {
+		for _, header := range []string{"abc", "1.5", "0", "-100"} {
+			_, recorder := deadline(t, header)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, header)
+			assert.JSONEq(t, `{"error":"X-Timeout-Ms must be a positive number of milliseconds"}`, recorder.Body.String())
+		}
+	})
+
+	t.Run("exceeded deadline answers 503", func(t *testing.T) {
+		handler := TimeoutMiddleware(time.Second, time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			ErrorResponse(w, http.StatusInternalServerError, r.Context().Err().Error())
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/catalog", nil)
+		req.Header.Set(TimeoutHeader, "10")
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.JSONEq(t, `{"error":"request timed out"}`, recorder.Body.String())
+	})
+
+	t.Run("slow handlers ignoring the deadline are cut off", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		handler := TimeoutMiddleware(20*time.Millisecond, time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.Header().Set("X-Late", "true")
+			w.WriteHeader(http.StatusOK)
+		}))
+		recorder := httptest.NewRecorder()
+
+		start := time.Now()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.JSONEq(t, `{"error":"request timed out"}`, recorder.Body.String())
+		assert.Empty(t, recorder.Header().Get("X-Late"))
+	})
+
+	t.Run("fast handlers pass through", func(t *testing.T) {
+		handler := TimeoutMiddleware(time.Second, time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Fast", "true")
+			OKResponse(w, map[string]string{"status": "ok"})
+		}))
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "true", recorder.Header().Get("X-Fast"))
+		assert.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+	})
+
+	t.Run("responses started before the deadline are completed", func(t *testing.T) {
+		handler := TimeoutMiddleware(10*time.Millisecond, time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("first "))
+			<-r.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			w.Write([]byte("last"))
+		}))
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/catalog/export", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "first last", recorder.Body.String())
+	})
+
+	t.Run("panics reach the recovering middleware", func(t *testing.T) {
+		handler := RecoverMiddleware(TimeoutMiddleware(time.Second, time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})))
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+
+	t.Run("timeouts keep pretty printing", func(t *testing.T) {
+		handler := PrettyMiddleware(TimeoutMiddleware(time.Millisecond, time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			ErrorResponse(w, http.StatusInternalServerError, "boom")
+		})))
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/catalog?pretty=true", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal(t, "{\n  \"error\": \"request timed out\"\n}", recorder.Body.String())
+	})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PublicURL configures how the URLs pointing back to the service are built.
+type PublicURL struct {
+	// BaseURL, e.g. https://api.example.com/catalog-svc, prefixes every
+	// generated URL when set.
+	BaseURL string
+	// TrustProxy takes the scheme and host from the X-Forwarded-Proto and
+	// X-Forwarded-Host headers. Only enable it behind a proxy setting them.
+	TrustProxy bool
+}
+
+type publicURLKey struct{}
+
+// ValidateBaseURL accepts absolute http and https URLs without a trailing
+// slash, query or fragment, so paths can be appended to them as is.
+func ValidateBaseURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("base URL must be an absolute http or https URL")
+	}
+	if strings.HasSuffix(s, "/") {
+		return errors.New("base URL must not end with a slash")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("base URL must not have a query or fragment")
+	}
+	return nil
+}
+
+// PublicURLMiddleware makes the configuration available to AbsoluteURL.
+func PublicURLMiddleware(cfg PublicURL, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), publicURLKey{}, cfg)))
+	})
+}
+
+// AbsoluteURL returns the URL clients reach path at, path being absolute and
+// possibly carrying a query. The configured base URL wins, then the forwarded
+// headers of trusted proxies, then the host the request was sent to.
+func AbsoluteURL(r *http.Request, path string) string {
+	cfg, _ := r.Context().Value(publicURLKey{}).(PublicURL)
+	if cfg.BaseURL != "" {
+		return cfg.BaseURL + path
+	}
+
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if cfg.TrustProxy {
+		if v := forwarded(r, "X-Forwarded-Proto"); v == "http" || v == "https" {
+			scheme = v
+		}
+		if v := forwarded(r, "X-Forwarded-Host"); v != "" {
+			host = v
+		}
+	}
+	return scheme + "://" + host + path
+}
+
+// forwarded returns the value set by the proxy closest to the client when
+// several proxies appended to the header.
+func forwarded(r *http.Request, header string) string {
+	v, _, _ := strings.Cut(r.Header.Get(header), ",")
+	return strings.TrimSpace(v)
+}
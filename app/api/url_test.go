@@ -0,0 +1,105 @@
+package api
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbsoluteURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *PublicURL
+		tls     bool
+		headers map[string]string
+		want    string
+	}{
+		{
+			name: "direct request",
+			want: "http://localhost:8484/catalog/PROD001?allImages=true",
+		},
+		{
+			name: "direct tls request",
+			tls:  true,
+			want: "https://localhost:8484/catalog/PROD001?allImages=true",
+		},
+		{
+			name:    "forwarded headers ignored by default",
+			cfg:     &PublicURL{},
+			headers: map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"},
+			want:    "http://localhost:8484/catalog/PROD001?allImages=true",
+		},
+		{
+			name:    "trusted proxy",
+			cfg:     &PublicURL{TrustProxy: true},
+			headers: map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"},
+			want:    "https://api.example.com/catalog/PROD001?allImages=true",
+		},
+		{
+			name:    "trusted proxy chain",
+			cfg:     &PublicURL{TrustProxy: true},
+			headers: map[string]string{"X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "api.example.com, internal:8080"},
+			want:    "https://api.example.com/catalog/PROD001?allImages=true",
+		},
+		{
+			name:    "trusted proxy with an unknown scheme",
+			cfg:     &PublicURL{TrustProxy: true},
+			headers: map[string]string{"X-Forwarded-Proto": "gopher"},
+			want:    "http://localhost:8484/catalog/PROD001?allImages=true",
+		},
+		{
+			name:    "configured base URL",
+			cfg:     &PublicURL{BaseURL: "https://api.example.com/catalog-svc", TrustProxy: true},
+			headers: map[string]string{"X-Forwarded-Proto": "http", "X-Forwarded-Host": "internal:8080"},
+			want:    "https://api.example.com/catalog-svc/catalog/PROD001?allImages=true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:8484/catalog/PROD001?allImages=true", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			var got string
+			handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = AbsoluteURL(r, r.URL.RequestURI())
+			}))
+			if tt.cfg != nil {
+				handler = PublicURLMiddleware(*tt.cfg, handler)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateBaseURL(t *testing.T) {
+	for _, valid := range []string{
+		"https://api.example.com",
+		"https://api.example.com/catalog-svc",
+		"http://localhost:8484",
+	} {
+		assert.NoError(t, ValidateBaseURL(valid), valid)
+	}
+
+	for _, invalid := range []string{
+		"https://api.example.com/",
+		"https://api.example.com/catalog-svc/",
+		"api.example.com/catalog-svc",
+		"//api.example.com",
+		"ftp://api.example.com",
+		"https://api.example.com?env=prod",
+		"https://api.example.com#top",
+	} {
+		assert.Error(t, ValidateBaseURL(invalid), invalid)
+	}
+}
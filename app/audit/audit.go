@@ -0,0 +1,88 @@
+// Package audit records who changed what through the write endpoints, and
+// serves the audit log to the admins.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+	auditrepo "github.com/mytheresa/go-hiring-challenge/app/repos/audit"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// Actions of the changes.
+const (
+	Create       = "create"
+	Update       = "update"
+	Delete       = "delete"
+	AdjustPrices = "adjust_prices"
+	Import       = "import"
+)
+
+// Types of the entities changed.
+const (
+	Category = "category"
+	Product  = "product"
+	Catalog  = "catalog"
+)
+
+// Change is a successful change, with the state of the entity before and
+// after it, nil when there is none.
+type Change struct {
+	Action     string
+	EntityType string
+	EntityCode string
+	Before     any
+	After      any
+}
+
+// Recorder records the changes made through the write endpoints, after
+// their repository call succeeded.
+type Recorder interface {
+	Record(ctx context.Context, c Change)
+}
+
+// Log is the Recorder storing the changes in the audit log, on behalf of the
+// actor of the request. Recording is best effort: the change is made
+// already, so failures are logged as errors rather than failing the request.
+type Log struct {
+	repo auditrepo.Repository
+}
+
+func NewLog(repo auditrepo.Repository) *Log {
+	return &Log{
+		repo: repo,
+	}
+}
+
+func (l *Log) Record(ctx context.Context, c Change) {
+	entry := models.AuditEntry{
+		Actor:      api.ActorFromContext(ctx),
+		Action:     c.Action,
+		EntityType: c.EntityType,
+		EntityCode: c.EntityCode,
+	}
+	before, err := snapshot(c.Before)
+	if err == nil {
+		entry.Before = before
+		entry.After, err = snapshot(c.After)
+	}
+	if err == nil {
+		// Recorded even when the client went away after the change
+		err = l.repo.Record(context.WithoutCancel(ctx), &entry)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to record a change in the audit log",
+			"actor", entry.Actor, "action", c.Action, "entity_type", c.EntityType, "entity_code", c.EntityCode, "error", err)
+	}
+}
+
+// snapshot encodes the state of an entity, nil for none.
+func snapshot(state any) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+	return json.Marshal(state)
+}
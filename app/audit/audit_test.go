@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestLog_Record(t *testing.T) {
+	type category struct {
+		Name string `json:"name"`
+	}
+
+	t.Run("both versions on behalf of the actor", func(t *testing.T) {
+		repo := new(mockRepo)
+		var recorded *models.AuditEntry
+		repo.On("Record", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			recorded = args.Get(1).(*models.AuditEntry)
+		}).Return(nil)
+		ctx := api.WithActor(context.Background(), "api-key:2bb80d537b1da3e3")
+
+		NewLog(repo).Record(ctx, Change{
+			Action: Update, EntityType: Category, EntityCode: "shoes",
+			Before: category{Name: "Shoes"}, After: category{Name: "Footwear"},
+		})
+
+		require.NotNil(t, recorded)
+		assert.Equal(t, "api-key:2bb80d537b1da3e3", recorded.Actor)
+		assert.Equal(t, "update", recorded.Action)
+		assert.Equal(t, "category", recorded.EntityType)
+		assert.Equal(t, "shoes", recorded.EntityCode)
+		assert.JSONEq(t, `{"name":"Shoes"}`, string(recorded.Before))
+		assert.JSONEq(t, `{"name":"Footwear"}`, string(recorded.After))
+	})
+
+	t.Run("no version before a creation", func(t *testing.T) {
+		repo := new(mockRepo)
+		var recorded *models.AuditEntry
+		repo.On("Record", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			recorded = args.Get(1).(*models.AuditEntry)
+		}).Return(nil)
+
+		NewLog(repo).Record(context.Background(), Change{
+			Action: Create, EntityType: Category, EntityCode: "shoes", After: category{Name: "Shoes"},
+		})
+
+		require.NotNil(t, recorded)
+		assert.Nil(t, recorded.Before)
+		assert.JSONEq(t, `{"name":"Shoes"}`, string(recorded.After))
+	})
+
+	t.Run("recorded after the client went away", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Record", mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil }), mock.Anything).Return(nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		NewLog(repo).Record(ctx, Change{Action: Delete, EntityType: Product, EntityCode: "PROD001"})
+
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("failures are logged", func(t *testing.T) {
+		var logs bytes.Buffer
+		ctx := logging.NewContext(context.Background(), slog.New(slog.NewJSONHandler(&logs, nil)))
+		ctx = api.WithActor(ctx, "admin:2bb80d537b1da3e3")
+		repo := new(mockRepo)
+		repo.On("Record", mock.Anything, mock.Anything).Return(errors.New("connection refused"))
+
+		NewLog(repo).Record(ctx, Change{Action: Delete, EntityType: Product, EntityCode: "PROD001"})
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+		assert.Equal(t, "ERROR", entry["level"])
+		assert.Equal(t, "Failed to record a change in the audit log", entry["msg"])
+		assert.Equal(t, "admin:2bb80d537b1da3e3", entry["actor"])
+		assert.Equal(t, "delete", entry["action"])
+		assert.Equal(t, "PROD001", entry["entity_code"])
+		assert.Equal(t, "connection refused", entry["error"])
+	})
+
+	t.Run("versions that cannot be encoded are logged", func(t *testing.T) {
+		var logs bytes.Buffer
+		ctx := logging.NewContext(context.Background(), slog.New(slog.NewJSONHandler(&logs, nil)))
+		repo := new(mockRepo)
+
+		NewLog(repo).Record(ctx, Change{Action: Update, EntityType: Product, EntityCode: "PROD001", After: func() {}})
+
+		assert.Contains(t, logs.String(), "Failed to record a change in the audit log")
+		repo.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+}
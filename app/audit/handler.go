@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	auditrepo "github.com/mytheresa/go-hiring-challenge/app/repos/audit"
+)
+
+// Bounds of a page of the audit log.
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// null is the JSON of the missing snapshots.
+var null = json.RawMessage("null")
+
+type Handler struct {
+	repo auditrepo.Repository
+}
+
+func NewHandler(r auditrepo.Repository) *Handler {
+	return &Handler{
+		repo: r,
+	}
+}
+
+// HandleList returns a page of the audit log, oldest first. entity keeps the
+// changes of a type of entity, such as product, or of a single one, such as
+// product:PROD001. since keeps the changes made from an RFC 3339 time on.
+func (h *Handler) HandleList(w http.ResponseWriter, r *http.Request) {
+	filters, err := validateListQuery(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, total, err := h.repo.List(r.Context(), filters)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	response := EntriesResponse{Entries: make([]Entry, len(res)), Total: total}
+	for i, e := range res {
+		response.Entries[i] = Entry{
+			ID:         e.ID,
+			Actor:      e.Actor,
+			Action:     e.Action,
+			EntityType: e.EntityType,
+			EntityCode: e.EntityCode,
+			Before:     orNull(e.Before),
+			After:      orNull(e.After),
+			CreatedAt:  e.CreatedAt.UTC(),
+		}
+	}
+	api.SetPaginationHeaders(w, r, filters.Offset, filters.Limit, total)
+	api.OKResponse(w, response)
+}
+
+func orNull(snapshot json.RawMessage) json.RawMessage {
+	if len(snapshot) == 0 {
+		return null
+	}
+	return snapshot
+}
+
+// validateListQuery reads the entity and since filters and the page, the
+// limit being clamped.
+func validateListQuery(r *http.Request) (auditrepo.Filters, error) {
+	q := r.URL.Query()
+	filters := auditrepo.Filters{Limit: defaultLimit}
+
+	if v := q.Get("entity"); v != "" {
+		typ, code, _ := strings.Cut(v, ":")
+		if typ == "" {
+			return filters, errors.New("entity must be a type of entity, optionally followed by a colon and a code")
+		}
+		filters.EntityType, filters.EntityCode = typ, code
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filters, errors.New("since must be an RFC 3339 timestamp, such as 2025-06-01T08:30:00Z")
+		}
+		filters.Since = since
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return filters, errors.New("offset must be a non-negative integer")
+		}
+		filters.Offset = offset
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return filters, errors.New("limit must be an integer")
+		}
+		filters.Limit = min(max(limit, 1), maxLimit)
+	}
+	return filters, nil
+}
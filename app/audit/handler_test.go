@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	auditrepo "github.com/mytheresa/go-hiring-challenge/app/repos/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func get(repo *mockRepo, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	NewHandler(repo).HandleList(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestHandleList(t *testing.T) {
+	createdAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("changes of an entity", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, auditrepo.Filters{EntityType: "category", EntityCode: "shoes", Offset: 1, Limit: 1}).Return([]models.AuditEntry{
+			{ID: 7, Actor: "api-key:2bb80d537b1da3e3", Action: "update", EntityType: "category", EntityCode: "shoes",
+				Before: []byte(`{"name":"Shoes"}`), After: []byte(`{"name":"Footwear"}`), CreatedAt: createdAt},
+		}, int64(3), nil)
+
+		rec := get(repo, "/admin/audit?entity=category:shoes&offset=1&limit=1")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"entries":[
+			{"id":7,"actor":"api-key:2bb80d537b1da3e3","action":"update","entity_type":"category","entity_code":"shoes",
+			 "before":{"name":"Shoes"},"after":{"name":"Footwear"},"created_at":"2026-10-01T12:00:00Z"}
+		],"total":3}`, rec.Body.String())
+		assert.Equal(t, "3", rec.Header().Get("X-Total-Count"))
+		assert.Contains(t, rec.Header().Get("Link"), `rel="next"`)
+	})
+
+	t.Run("changes of a type of entity since a time", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, auditrepo.Filters{EntityType: "product", Since: createdAt, Limit: defaultLimit}).Return([]models.AuditEntry{
+			{ID: 8, Actor: "admin:2bb80d537b1da3e3", Action: "create", EntityType: "product", EntityCode: "PROD009",
+				After: []byte(`{"code":"PROD009"}`), CreatedAt: createdAt},
+			{ID: 9, Actor: "admin:2bb80d537b1da3e3", Action: "import", EntityType: "catalog", CreatedAt: createdAt},
+		}, int64(2), nil)
+
+		rec := get(repo, "/admin/audit?entity=product&since=2026-10-01T12:00:00Z")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"entries":[
+			{"id":8,"actor":"admin:2bb80d537b1da3e3","action":"create","entity_type":"product","entity_code":"PROD009",
+			 "before":null,"after":{"code":"PROD009"},"created_at":"2026-10-01T12:00:00Z"},
+			{"id":9,"actor":"admin:2bb80d537b1da3e3","action":"import","entity_type":"catalog",
+			 "before":null,"after":null,"created_at":"2026-10-01T12:00:00Z"}
+		],"total":2}`, rec.Body.String())
+	})
+
+	t.Run("empty log", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, auditrepo.Filters{Limit: defaultLimit}).Return([]models.AuditEntry{}, int64(0), nil)
+
+		rec := get(repo, "/admin/audit")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"entries":[],"total":0}`, rec.Body.String())
+		testsupport.AssertJSONArrays(t, rec.Body.String(), "entries")
+	})
+
+	t.Run("limit is clamped", func(t *testing.T) {
+		for target, limit := range map[string]int{"/admin/audit?limit=5000": maxLimit, "/admin/audit?limit=0": 1} {
+			repo := new(mockRepo)
+			repo.On("List", mock.Anything, auditrepo.Filters{Limit: limit}).Return([]models.AuditEntry{}, int64(0), nil)
+
+			rec := get(repo, target)
+
+			assert.Equal(t, http.StatusOK, rec.Code, target)
+			repo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for target, msg := range map[string]string{
+			"/admin/audit?entity=:shoes":    "entity must be a type of entity, optionally followed by a colon and a code",
+			"/admin/audit?since=yesterday":  "since must be an RFC 3339 timestamp, such as 2025-06-01T08:30:00Z",
+			"/admin/audit?since=2026-10-01": "since must be an RFC 3339 timestamp, such as 2025-06-01T08:30:00Z",
+			"/admin/audit?offset=-1":        "offset must be a non-negative integer",
+			"/admin/audit?limit=ten":        "limit must be an integer",
+		} {
+			repo := new(mockRepo)
+
+			rec := get(repo, target)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+			assert.JSONEq(t, `{"error":"`+msg+`"}`, rec.Body.String(), target)
+			repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, mock.Anything).Return(nil, int64(0), errors.New("connection refused"))
+
+		rec := get(repo, "/admin/audit")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
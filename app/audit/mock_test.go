@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	auditrepo "github.com/mytheresa/go-hiring-challenge/app/repos/audit"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type mockRepo struct {
+	mock.Mock
+}
+
+func (m *mockRepo) Record(ctx context.Context, entry *models.AuditEntry) error {
+	return m.Called(ctx, entry).Error(0)
+}
+
+func (m *mockRepo) List(ctx context.Context, filters auditrepo.Filters) ([]models.AuditEntry, int64, error) {
+	args := m.Called(ctx, filters)
+	entries, _ := args.Get(0).([]models.AuditEntry)
+	return entries, args.Get(1).(int64), args.Error(2)
+}
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EntriesResponse is a page of the audit log, along with the number of
+// entries matching the filters.
+type EntriesResponse struct {
+	Entries []Entry `json:"entries"`
+	Total   int64   `json:"total"`
+}
+
+type Entry struct {
+	ID         uint64 `json:"id"`
+	Actor      string `json:"actor"`
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	// EntityCode is omitted for the changes spanning the catalog.
+	EntityCode string          `json:"entity_code,omitempty"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
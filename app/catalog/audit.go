@@ -0,0 +1,61 @@
+package catalog
+
+import (
+	"context"
+
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/dto"
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+)
+
+// record records a successful change in the audit log, when enabled.
+func (h *CatalogHandler) record(ctx context.Context, c audit.Change) {
+	if h.audit != nil {
+		h.audit.Record(ctx, c)
+	}
+}
+
+// productState is the state of the product recorded in the audit log, in
+// the base currency. It is nil when the audit log is disabled, and when the
+// product cannot be read, the change being recorded regardless.
+func (h *CatalogHandler) productState(ctx context.Context, code string) any {
+	if h.audit == nil {
+		return nil
+	}
+	p, err := h.reader.GetByCodeIncludingUnavailable(ctx, code)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to read a product for the audit log", "code", code, "error", err)
+		return nil
+	}
+	return dto.ToProductDetailsResponse(p, h.baseConversion(), "")
+}
+
+// categoryPriceState is the state of the prices of the category recorded in
+// the audit log, as productState.
+func (h *CatalogHandler) categoryPriceState(ctx context.Context, code string) any {
+	if h.audit == nil {
+		return nil
+	}
+	stats, err := h.reader.PriceStats(ctx, code)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to read the category prices for the audit log", "category", code, "error", err)
+		return nil
+	}
+	return priceStatsResponse(code, stats, h.baseConversion())
+}
+
+// recordProductUpdate records the update of the product from its state
+// before.
+func (h *CatalogHandler) recordProductUpdate(ctx context.Context, code string, before any) {
+	h.record(ctx, audit.Change{
+		Action: audit.Update, EntityType: audit.Product, EntityCode: code,
+		Before: before, After: h.productState(ctx, code),
+	})
+}
+
+// recordImport records the products created by an import, if any.
+func (h *CatalogHandler) recordImport(ctx context.Context, res ImportResult) {
+	if res.Created > 0 {
+		h.record(ctx, audit.Change{Action: audit.Import, EntityType: audit.Catalog, After: res})
+	}
+}
@@ -0,0 +1,152 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// recordingAuditor keeps the changes recorded, encoded as the audit log does.
+type recordingAuditor struct {
+	changes []audit.Change
+}
+
+func (a *recordingAuditor) Record(_ context.Context, c audit.Change) {
+	a.changes = append(a.changes, c)
+}
+
+// encoded returns the JSON of a version of a change, null for none.
+func encoded(t *testing.T, state any) string {
+	t.Helper()
+	b, err := json.Marshal(state)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestAudit(t *testing.T) {
+	product := models.Product{
+		Code:  "PROD001",
+		Price: decimal.RequireFromString("10.99"),
+		Variants: []models.Variant{
+			{Name: "Variant A", SKU: "SKU001A", Price: decimal.RequireFromString("11.99")},
+		},
+	}
+	serve := func(repo *mockRepo, auditor *recordingAuditor, method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		newTestMux(newHandler(t, repo, Options{Audit: auditor})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("an update captures both versions", func(t *testing.T) {
+		repriced := product
+		repriced.Variants = []models.Variant{
+			{Name: "Variant A", SKU: "SKU001A", Price: decimal.RequireFromString("12.49")},
+		}
+		repo := new(mockRepo)
+		repo.On("GetByCodeIncludingUnavailable", mock.Anything, "PROD001").Return(product, nil).Once()
+		repo.On("UpdateVariantPrices", mock.Anything, "PROD001", mock.Anything).Return([]string{}, nil)
+		repo.On("GetByCodeIncludingUnavailable", mock.Anything, "PROD001").Return(repriced, nil).Once()
+		auditor := new(recordingAuditor)
+
+		rec := serve(repo, auditor, http.MethodPatch, "/catalog/PROD001/variants/prices", `{"prices":{"SKU001A":"12.49"}}`)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Len(t, auditor.changes, 1)
+		c := auditor.changes[0]
+		assert.Equal(t, audit.Update, c.Action)
+		assert.Equal(t, audit.Product, c.EntityType)
+		assert.Equal(t, "PROD001", c.EntityCode)
+		assert.Contains(t, encoded(t, c.Before), `{"name":"Variant A","sku":"SKU001A","price":"11.99"}`)
+		assert.Contains(t, encoded(t, c.After), `{"name":"Variant A","sku":"SKU001A","price":"12.49"}`)
+	})
+
+	t.Run("a creation has no version before", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		auditor := new(recordingAuditor)
+
+		rec := serve(repo, auditor, http.MethodPost, "/catalog", `{"code":"PROD009","price":"10.99"}`)
+
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Len(t, auditor.changes, 1)
+		assert.Equal(t, audit.Create, auditor.changes[0].Action)
+		assert.Nil(t, auditor.changes[0].Before)
+		assert.Contains(t, encoded(t, auditor.changes[0].After), `"code":"PROD009"`)
+	})
+
+	t.Run("price adjustments capture the category prices", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceStats", mock.Anything, "shoes").Return(products.PriceStats{
+			Min: decimal.NewNullDecimal(decimal.NewFromInt(10)), Max: decimal.NewNullDecimal(decimal.NewFromInt(20)), Avg: decimal.NewNullDecimal(decimal.NewFromInt(15)), Count: 2,
+		}, nil).Once()
+		repo.On("AdjustPrices", mock.Anything, "shoes", mock.Anything).Return(int64(2), nil)
+		repo.On("PriceStats", mock.Anything, "shoes").Return(products.PriceStats{
+			Min: decimal.NewNullDecimal(decimal.NewFromInt(9)), Max: decimal.NewNullDecimal(decimal.NewFromInt(18)), Avg: decimal.NewNullDecimal(decimal.RequireFromString("13.5")), Count: 2,
+		}, nil).Once()
+		auditor := new(recordingAuditor)
+
+		rec := serve(repo, auditor, http.MethodPost, "/categories/shoes/adjust-prices", `{"factor":"0.9"}`)
+
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Len(t, auditor.changes, 1)
+		assert.Equal(t, audit.AdjustPrices, auditor.changes[0].Action)
+		assert.JSONEq(t, `{"category":"shoes","min":"10.00","max":"20.00","avg":"15.00","count":2}`, encoded(t, auditor.changes[0].Before))
+		assert.JSONEq(t, `{"category":"shoes","min":"9.00","max":"18.00","avg":"13.50","count":2}`, encoded(t, auditor.changes[0].After))
+	})
+
+	t.Run("failed writes are not recorded", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCodeIncludingUnavailable", mock.Anything, "PROD001").Return(product, nil)
+		repo.On("DeleteVariant", mock.Anything, "PROD001", "SKU001Z").Return(products.ErrVariantNotFound)
+		auditor := new(recordingAuditor)
+
+		rec := serve(repo, auditor, http.MethodDelete, "/catalog/PROD001/variants/SKU001Z", "")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Empty(t, auditor.changes)
+	})
+
+	t.Run("reads are not recorded", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, mock.Anything).Return([]models.Product{product}, int64(1), nil)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+		repo.On("Exists", mock.Anything, "PROD001").Return(true, nil)
+		repo.On("PriceStats", mock.Anything, "shoes").Return(products.PriceStats{}, nil)
+		repo.On("ListVariants", mock.Anything, mock.Anything).Return([]products.ResolvedVariant{}, int64(0), nil)
+		auditor := new(recordingAuditor)
+
+		for _, target := range []string{
+			"/catalog", "/catalog/PROD001", "/catalog/PROD001/exists",
+			"/categories/shoes/products", "/categories/shoes/price-stats", "/variants",
+		} {
+			rec := serve(repo, auditor, http.MethodGet, target, "")
+			assert.Equal(t, http.StatusOK, rec.Code, target)
+		}
+		assert.Empty(t, auditor.changes)
+	})
+
+	t.Run("disabled without a recorder", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteImage", mock.Anything, "PROD001", uint(3)).Return(nil)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/catalog/PROD001/images/3", nil))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		// The versions are not read for nothing
+		repo.AssertNotCalled(t, "GetByCodeIncludingUnavailable", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,138 @@
+package catalog
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/dto"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+)
+
+// Bounds of a page of changes.
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+)
+
+// Types of the changes of the change feed. Products are never deleted, so
+// there are no deletions to report.
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+)
+
+// errMalformedCursor is reported for cursors not returned by HandleChanges.
+var errMalformedCursor = errors.New("cursor is malformed")
+
+// HandleChanges returns the products changed since the since timestamp, or
+// after the position of the cursor returned by the previous page, oldest
+// change first. Each change carries the current state of the product, so
+// that consumers replaying the feed in order end up with the catalog as it
+// is. Products outside of their availability window come marked as
+// unavailable rather than left out, for consumers to remove them; the window
+// of each product tells when it closes without any change to report. The
+// next cursor is returned even when there are no changes, to poll for later
+// ones.
+func (h *CatalogHandler) HandleChanges(w http.ResponseWriter, r *http.Request) {
+	after, limit, err := validateChangesQuery(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.reader.ListChangedSince(r.Context(), after, limit)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	locale := api.RequestLocale(r)
+	now := time.Now()
+	response := ChangesResponse{Changes: make([]Change, len(res))}
+	for i, p := range res {
+		changeType := ChangeUpdated
+		if p.CreatedAt.Equal(p.UpdatedAt) {
+			changeType = ChangeCreated
+		}
+		response.Changes[i] = Change{
+			Code:      p.Code,
+			Type:      changeType,
+			ChangedAt: p.UpdatedAt.UTC(),
+			Available: p.AvailableAt(now),
+			Product:   dto.ToProductDetailsResponse(p, h.baseConversion(), locale),
+		}
+		after = products.ChangePosition{UpdatedAt: p.UpdatedAt, ID: p.ID}
+	}
+	response.NextCursor = encodeChangeCursor(after)
+	api.OKResponse(w, response)
+}
+
+// validateChangesQuery reads the position to list the changes from, out of
+// either the since or the cursor parameter, and the clamped limit.
+func validateChangesQuery(r *http.Request) (products.ChangePosition, int, error) {
+	q := r.URL.Query()
+	var after products.ChangePosition
+	switch since, cursor := q.Get("since"), q.Get("cursor"); {
+	case since != "" && cursor != "":
+		return after, 0, errors.New("since and cursor cannot be combined")
+	case since != "":
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return after, 0, errors.New("since must be an RFC 3339 timestamp")
+		}
+		after.UpdatedAt = t
+	case cursor != "":
+		var err error
+		if after, err = decodeChangeCursor(cursor); err != nil {
+			return after, 0, err
+		}
+	default:
+		return after, 0, errors.New("since or cursor is required")
+	}
+
+	limit := defaultChangesLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return after, 0, errors.New("limit must be an integer")
+		}
+		limit = min(max(n, 1), maxChangesLimit)
+	}
+	return after, limit, nil
+}
+
+// encodeChangeCursor makes an opaque cursor of the position, keeping the
+// sub-second part of the time so that no change is skipped.
+func encodeChangeCursor(p products.ChangePosition) string {
+	s := fmt.Sprintf("%s,%d", p.UpdatedAt.UTC().Format(time.RFC3339Nano), p.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeChangeCursor(cursor string) (products.ChangePosition, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return products.ChangePosition{}, errMalformedCursor
+	}
+	at, id, ok := strings.Cut(string(b), ",")
+	if !ok {
+		return products.ChangePosition{}, errMalformedCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return products.ChangePosition{}, errMalformedCursor
+	}
+	n, err := strconv.ParseUint(id, 10, 0)
+	if err != nil {
+		return products.ChangePosition{}, errMalformedCursor
+	}
+	return products.ChangePosition{UpdatedAt: t, ID: uint(n)}, nil
+}
@@ -0,0 +1,143 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestHandleChanges(t *testing.T) {
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	updated := created.Add(90*time.Minute + 123456*time.Microsecond)
+	res := []models.Product{
+		{ID: 7, Code: "PROD007", Price: decimal.RequireFromString("10"), CreatedAt: created, UpdatedAt: created},
+		{ID: 3, Code: "PROD003", Price: decimal.RequireFromString("12.5"), CreatedAt: created, UpdatedAt: updated},
+	}
+
+	get := func(repo *mockRepo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("lists the changes since a time", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListChangedSince", mock.Anything, products.ChangePosition{UpdatedAt: created}, 100).Return(res, nil)
+
+		rec := get(repo, "/catalog/changes?since=2026-03-01T10:00:00Z")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"changes": [
+				{"code":"PROD007","type":"created","changed_at":"2026-03-01T10:00:00Z","available":true,"product":{"code":"PROD007","price":"10.00","variants":[]}},
+				{"code":"PROD003","type":"updated","changed_at":"2026-03-01T11:30:00.123456Z","available":true,"product":{"code":"PROD003","price":"12.50","variants":[]}}
+			],
+			"next_cursor": "`+encodeChangeCursor(products.ChangePosition{UpdatedAt: updated, ID: 3})+`"
+		}`, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("marks the products outside of their window", func(t *testing.T) {
+		closed, opening := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+		repo := new(mockRepo)
+		repo.On("ListChangedSince", mock.Anything, mock.Anything, 100).Return([]models.Product{
+			{ID: 7, Code: "PROD007", Price: decimal.RequireFromString("10"), AvailableTo: &closed},
+			{ID: 8, Code: "PROD008", Price: decimal.RequireFromString("10"), AvailableFrom: &opening},
+			{ID: 9, Code: "PROD009", Price: decimal.RequireFromString("10"), AvailableFrom: &closed, AvailableTo: &opening},
+		}, nil)
+
+		rec := get(repo, "/catalog/changes?since=2026-03-01T10:00:00Z")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body ChangesResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Len(t, body.Changes, 3)
+		assert.False(t, body.Changes[0].Available)
+		assert.False(t, body.Changes[1].Available)
+		assert.True(t, body.Changes[2].Available)
+	})
+
+	t.Run("continues after the cursor", func(t *testing.T) {
+		repo := new(mockRepo)
+		after := products.ChangePosition{UpdatedAt: updated, ID: 3}
+		repo.On("ListChangedSince", mock.Anything, mock.MatchedBy(func(p products.ChangePosition) bool {
+			return p.UpdatedAt.Equal(after.UpdatedAt) && p.ID == after.ID
+		}), 2).Return([]models.Product{}, nil)
+
+		rec := get(repo, "/catalog/changes?limit=2&cursor="+encodeChangeCursor(after))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var body ChangesResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Empty(t, body.Changes)
+		assert.Equal(t, encodeChangeCursor(after), body.NextCursor, "the cursor stays put to poll again")
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("clamps the limit", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListChangedSince", mock.Anything, mock.Anything, 1000).Return([]models.Product{}, nil)
+
+		rec := get(repo, "/catalog/changes?since=2026-03-01T10:00:00Z&limit=5000")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListChangedSince", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("boom"))
+
+		rec := get(repo, "/catalog/changes?since=2026-03-01T10:00:00Z")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("rejects malformed queries", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  string
+		}{
+			{"", "since or cursor is required"},
+			{"since=yesterday", "since must be an RFC 3339 timestamp"},
+			{"since=2026-03-01T10:00:00Z&cursor=abc", "since and cursor cannot be combined"},
+			{"cursor=!!!", "cursor is malformed"},
+			{"cursor=" + encodeChangeCursor(products.ChangePosition{})[:4], "cursor is malformed"},
+			{"since=2026-03-01T10:00:00Z&limit=ten", "limit must be an integer"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := get(repo, "/catalog/changes?"+tt.query)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.JSONEq(t, fmt.Sprintf(`{"error":%q}`, tt.want), rec.Body.String())
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+}
+
+func TestChangeCursor(t *testing.T) {
+	p := products.ChangePosition{UpdatedAt: time.Date(2026, 3, 1, 10, 0, 0, 123456000, time.UTC), ID: 42}
+
+	got, err := decodeChangeCursor(encodeChangeCursor(p))
+
+	require.NoError(t, err)
+	assert.True(t, got.UpdatedAt.Equal(p.UpdatedAt))
+	assert.Equal(t, uint(42), got.ID)
+}
@@ -0,0 +1,153 @@
+package catalog
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/app/dto"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// Bounds of the products compared at once.
+const (
+	minCompareCodes = 2
+	maxCompareCodes = 5
+)
+
+// HandleCompare returns the products of the comma separated codes parameter
+// side by side. Malformed and repeated codes are left out, and unknown codes
+// answer 404.
+func (h *CatalogHandler) HandleCompare(w http.ResponseWriter, r *http.Request) {
+	codes, err := h.compareCodes(r.URL.Query().Get("codes"))
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conv, ok := h.requestedConversion(w, r)
+	if !ok {
+		return
+	}
+	format, err := h.requestedPriceFormat(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.reader.GetByCodes(r.Context(), codes)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	byCode := make(map[string]models.Product, len(res))
+	for _, p := range res {
+		byCode[p.Code] = p
+	}
+	ordered := make([]models.Product, 0, len(codes))
+	var missing []string
+	for _, code := range codes {
+		p, ok := byCode[code]
+		if !ok {
+			missing = append(missing, code)
+			continue
+		}
+		ordered = append(ordered, p)
+	}
+	if len(missing) > 0 {
+		api.ErrorResponse(w, http.StatusNotFound, "products not found: "+strings.Join(missing, ", "))
+		return
+	}
+
+	response := compareProducts(ordered, conv, api.RequestLocale(r))
+	for i := range response.Products {
+		format.render(&response.Products[i].Product)
+		format.Apply(&response.Products[i].PriceDifference)
+	}
+	api.OKResponse(w, response)
+}
+
+// compareCodes keeps the valid codes of the codes parameter, dropping
+// repeated ones.
+func (h *CatalogHandler) compareCodes(param string) ([]string, error) {
+	var codes []string
+	for _, code := range strings.Split(param, ",") {
+		if h.codes.validate(code) == nil && !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) < minCompareCodes || len(codes) > maxCompareCodes {
+		return nil, fmt.Errorf("codes must list between %d and %d valid product codes", minCompareCodes, maxCompareCodes)
+	}
+	return codes, nil
+}
+
+// compareProducts lines the products up: each one is priced relative to the
+// cheapest, and its variant names are set against those of the others.
+func compareProducts(res []models.Product, conv currency.Conversion, locale string) ComparisonResponse {
+	response := ComparisonResponse{
+		Products:           make([]ComparedProduct, len(res)),
+		VariantNames:       []string{},
+		CommonVariantNames: []string{},
+	}
+	if len(res) == 0 {
+		return response
+	}
+
+	// Differences are computed on the converted prices, so they add up with
+	// the prices shown
+	prices := make([]decimal.Decimal, len(res))
+	for i, p := range res {
+		prices[i] = conv.Convert(p.Price)
+	}
+	cheapest := decimal.Min(prices[0], prices[1:]...)
+
+	names := make([][]string, len(res))
+	for i, p := range res {
+		names[i] = variantNames(p)
+		response.VariantNames = append(response.VariantNames, names[i]...)
+	}
+	slices.Sort(response.VariantNames)
+	response.VariantNames = slices.Compact(response.VariantNames)
+
+	for _, name := range response.VariantNames {
+		if !slices.ContainsFunc(names, func(n []string) bool { return !slices.Contains(n, name) }) {
+			response.CommonVariantNames = append(response.CommonVariantNames, name)
+		}
+	}
+
+	for i, p := range res {
+		compared := ComparedProduct{
+			Product:             dto.ToProductResponse(p, conv, locale),
+			PriceDifference:     currency.NewMoney(prices[i].Sub(cheapest), conv.Rounding),
+			VariantNames:        names[i],
+			MissingVariantNames: []string{},
+		}
+		for _, name := range response.VariantNames {
+			if !slices.Contains(names[i], name) {
+				compared.MissingVariantNames = append(compared.MissingVariantNames, name)
+			}
+		}
+		response.Products[i] = compared
+	}
+	return response
+}
+
+// variantNames returns the distinct variant names of the product, sorted.
+func variantNames(p models.Product) []string {
+	names := make([]string, len(p.Variants))
+	for i, v := range p.Variants {
+		names[i] = v.Name
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
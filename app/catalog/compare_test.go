@@ -0,0 +1,143 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestCompareProducts(t *testing.T) {
+	variants := func(names ...string) []models.Variant {
+		res := make([]models.Variant, len(names))
+		for i, name := range names {
+			res[i] = models.Variant{Name: name, SKU: "SKU-" + name}
+		}
+		return res
+	}
+	res := []models.Product{
+		{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Variants: variants("M", "S", "L")},
+		{Code: "PROD002", Price: decimal.RequireFromString("7.50"), Variants: variants("M", "XL")},
+		{Code: "PROD003", Price: decimal.RequireFromString("12.49"), Variants: variants("M", "S", "M")},
+	}
+
+	t.Run("prices relative to the cheapest", func(t *testing.T) {
+		got := compareProducts(res, currency.Conversion{Rate: decimal.NewFromInt(1)}, "")
+
+		var differences []string
+		for _, p := range got.Products {
+			differences = append(differences, p.PriceDifference.StringFixed(2))
+		}
+		assert.Equal(t, []string{"3.49", "0.00", "4.99"}, differences)
+	})
+
+	t.Run("differences of the converted prices", func(t *testing.T) {
+		got := compareProducts(res, currency.Conversion{Rate: decimal.RequireFromString("0.85")}, "")
+
+		// 10.99 and 7.50 convert to 9.34 and 6.38
+		assert.Equal(t, "2.96", got.Products[0].PriceDifference.StringFixed(2))
+		assert.Equal(t, "9.34", got.Products[0].Price.StringFixed(2))
+	})
+
+	t.Run("variant names aligned", func(t *testing.T) {
+		got := compareProducts(res, currency.Conversion{Rate: decimal.NewFromInt(1)}, "")
+
+		assert.Equal(t, []string{"L", "M", "S", "XL"}, got.VariantNames)
+		assert.Equal(t, []string{"M"}, got.CommonVariantNames)
+		assert.Equal(t, []string{"L", "M", "S"}, got.Products[0].VariantNames)
+		assert.Equal(t, []string{"XL"}, got.Products[0].MissingVariantNames)
+		assert.Equal(t, []string{"M", "XL"}, got.Products[1].VariantNames)
+		assert.Equal(t, []string{"L", "S"}, got.Products[1].MissingVariantNames)
+		assert.Equal(t, []string{"M", "S"}, got.Products[2].VariantNames)
+		assert.Equal(t, []string{"L", "XL"}, got.Products[2].MissingVariantNames)
+	})
+
+	t.Run("products without variants", func(t *testing.T) {
+		got := compareProducts([]models.Product{
+			{Code: "PROD001", Price: decimal.RequireFromString("10.99")},
+			{Code: "PROD002", Price: decimal.RequireFromString("10.99"), Variants: variants("S")},
+		}, currency.Conversion{Rate: decimal.NewFromInt(1)}, "")
+
+		assert.Equal(t, []string{"S"}, got.VariantNames)
+		assert.Empty(t, got.CommonVariantNames)
+		assert.Equal(t, []string{"S"}, got.Products[0].MissingVariantNames)
+		assert.Equal(t, "0.00", got.Products[1].PriceDifference.StringFixed(2))
+	})
+}
+
+func TestHandleCompare(t *testing.T) {
+	compare := func(repo *mockRepo, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/compare?"+query, nil))
+		return rec
+	}
+
+	t.Run("compares in the order of the codes", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCodes", mock.Anything, []string{"PROD002", "PROD001"}).Return([]models.Product{
+			{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Category: &models.Category{Code: "clothing", Name: "Clothing"},
+				Variants: []models.Variant{{Name: "S", SKU: "SKU001A"}}},
+			{Code: "PROD002", Price: decimal.RequireFromString("12.49"),
+				Variants: []models.Variant{{Name: "S", SKU: "SKU002A"}, {Name: "M", SKU: "SKU002B"}}},
+		}, nil)
+
+		rec := compare(repo, "codes=PROD002,PROD001,PROD002,bad")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"products":[
+				{"code":"PROD002","price":"12.49","price_difference":"1.50","variant_names":["M","S"],"missing_variant_names":[]},
+				{"code":"PROD001","price":"10.99","category":{"code":"clothing","name":"Clothing"},"price_difference":"0.00",
+					"variant_names":["S"],"missing_variant_names":["M"]}
+			],
+			"variant_names":["M","S"],
+			"common_variant_names":["S"]
+		}`, rec.Body.String())
+	})
+
+	t.Run("unknown codes", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCodes", mock.Anything, []string{"PROD001", "PROD998", "PROD999"}).Return([]models.Product{
+			{Code: "PROD001", Price: decimal.RequireFromString("10.99")},
+		}, nil)
+
+		rec := compare(repo, "codes=PROD001,PROD998,PROD999")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"products not found: PROD998, PROD999"}`, rec.Body.String())
+	})
+
+	t.Run("rejects the wrong number of codes", func(t *testing.T) {
+		for _, query := range []string{
+			"",
+			"codes=PROD001",
+			"codes=PROD001,PROD001",
+			"codes=PROD001,bad,prod-2",
+			"codes=PROD001,PROD002,PROD003,PROD004,PROD005,PROD006",
+		} {
+			repo := new(mockRepo)
+
+			rec := compare(repo, query)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+			assert.JSONEq(t, `{"error":"codes must list between 2 and 5 valid product codes"}`, rec.Body.String())
+			assert.Empty(t, repo.Calls)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCodes", mock.Anything, mock.Anything).Return(nil, errors.New("boom"))
+
+		rec := compare(repo, "codes=PROD001,PROD002")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
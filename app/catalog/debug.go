@@ -0,0 +1,91 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+)
+
+// Debug explains how a listing was served, to find out why a product did not
+// show up.
+type Debug struct {
+	// Filters are those the repository was queried with, once defaulted and
+	// clamped.
+	Filters DebugFilters `json:"filters"`
+	// Total is the number of products matching the filters before
+	// pagination.
+	Total int64 `json:"total"`
+	// RepositoryTimeMS is the time spent querying the repository.
+	RepositoryTimeMS float64 `json:"repository_time_ms"`
+}
+
+// DebugFilters echoes products.SearchFilters.
+type DebugFilters struct {
+	Offset               int              `json:"offset"`
+	Limit                int              `json:"limit"`
+	Category             string           `json:"category,omitempty"`
+	Query                string           `json:"q,omitempty"`
+	PriceLessThan        *decimal.Decimal `json:"price_less_than,omitempty"`
+	PriceEquals          *decimal.Decimal `json:"price_equals,omitempty"`
+	VariantPrices        bool             `json:"variant_prices,omitempty"`
+	Variant              string           `json:"variant,omitempty"`
+	SKUPrefix            string           `json:"sku_prefix,omitempty"`
+	HasVariants          *bool            `json:"has_variants,omitempty"`
+	ModifiedSince        *time.Time       `json:"modified_since,omitempty"`
+	Sort                 string           `json:"sort,omitempty"`
+	IncludeUnavailable   bool             `json:"include_unavailable,omitempty"`
+	AllowMissingVariants bool             `json:"allow_missing_variants,omitempty"`
+}
+
+// diagnostics records a listing for its Debug object.
+type diagnostics struct {
+	filters products.SearchFilters
+	total   int64
+	elapsed time.Duration
+}
+
+// requestedDiagnostics tells whether r asks for the debug object with
+// debug=true. Only admins get it: for anyone else the parameter is ignored
+// and nil is returned.
+func (h *CatalogHandler) requestedDiagnostics(r *http.Request) (*diagnostics, error) {
+	switch r.URL.Query().Get("debug") {
+	case "", "false":
+		return nil, nil
+	case "true":
+	default:
+		return nil, errors.New("debug must be true or false")
+	}
+	if !api.IsAdmin(r, h.adminToken) {
+		return nil, nil
+	}
+	return &diagnostics{}, nil
+}
+
+func (d *diagnostics) debug() *Debug {
+	f := d.filters
+	return &Debug{
+		Filters: DebugFilters{
+			Offset:               f.Offset,
+			Limit:                f.Limit,
+			Category:             f.Category,
+			Query:                f.Query,
+			PriceLessThan:        f.PriceLessThan,
+			PriceEquals:          f.PriceEquals,
+			VariantPrices:        f.VariantPrices,
+			Variant:              f.Variant,
+			SKUPrefix:            f.SKUPrefix,
+			HasVariants:          f.HasVariants,
+			ModifiedSince:        f.ModifiedSince,
+			Sort:                 f.Sort,
+			IncludeUnavailable:   f.IncludeUnavailable,
+			AllowMissingVariants: f.AllowMissingVariants,
+		},
+		Total:            d.total,
+		RepositoryTimeMS: float64(d.elapsed) / float64(time.Millisecond),
+	}
+}
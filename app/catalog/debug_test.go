@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestHandleGet_Debug(t *testing.T) {
+	res := []models.Product{{Code: "PROD001", Price: decimal.RequireFromString("10.99")}}
+	// The listing is asked with a clamped limit, the debug object echoes the
+	// limit actually used
+	filters := products.SearchFilters{Offset: 2, Limit: 100, Category: "clothing"}
+
+	get := func(repo *mockRepo, target, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{AdminToken: "secret"})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("admins get the filters, total and time", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, filters).Return(res, int64(3), nil)
+
+		rec := get(repo, "/catalog?debug=true&offset=2&limit=1000&category=clothing", "Bearer secret")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+		var body struct {
+			Debug *Debug `json:"debug"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.NotNil(t, body.Debug)
+		assert.Equal(t, DebugFilters{Offset: 2, Limit: 100, Category: "clothing"}, body.Debug.Filters)
+		assert.Equal(t, int64(3), body.Debug.Total)
+		assert.GreaterOrEqual(t, body.Debug.RepositoryTimeMS, 0.0)
+		assert.Contains(t, rec.Body.String(), `"filters":{"offset":2,"limit":100,"category":"clothing"}`)
+	})
+
+	t.Run("ignored without the admin token", func(t *testing.T) {
+		for _, authorization := range []string{"", "Bearer guess", "Basic c2VjcmV0"} {
+			repo := new(mockRepo)
+			repo.On("List", mock.Anything, filters).Return(res, int64(3), nil)
+
+			rec := get(repo, "/catalog?debug=true&offset=2&limit=1000&category=clothing", authorization)
+
+			assert.Equal(t, http.StatusOK, rec.Code, authorization)
+			assert.NotContains(t, rec.Body.String(), "debug", authorization)
+			assert.Empty(t, rec.Header().Get("Cache-Control"), authorization)
+		}
+	})
+
+	t.Run("not asked for", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, filters).Return(res, int64(3), nil)
+
+		rec := get(repo, "/catalog?debug=false&offset=2&limit=1000&category=clothing", "Bearer secret")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "debug")
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := get(repo, "/catalog?debug=yes", "Bearer secret")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"debug must be true or false"}`, rec.Body.String())
+		repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not available without an admin token configured", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, filters).Return(res, int64(3), nil)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/catalog?debug=true&offset=2&limit=1000&category=clothing", nil)
+		req.Header.Set("Authorization", "Bearer ")
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "debug")
+	})
+}
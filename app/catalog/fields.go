@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mytheresa/go-hiring-challenge/app/dto"
+)
+
+// fieldExclusion lists the product fields left out of the responses, by
+// their JSON name.
+type fieldExclusion []string
+
+// requestedFieldExclusion reads the comma separated fieldsExclude parameter,
+// which cannot be combined with a selection of fields. The code identifies
+// the products and is always kept, and the unknown fields are ignored.
+func requestedFieldExclusion(r *http.Request) (fieldExclusion, error) {
+	q := r.URL.Query()
+	v := q.Get("fieldsExclude")
+	if v == "" {
+		return nil, nil
+	}
+	if q.Has("fields") {
+		return nil, errors.New("fields and fieldsExclude cannot be combined")
+	}
+
+	var excluded fieldExclusion
+	for name := range strings.SplitSeq(v, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && name != "code" && !slices.Contains(excluded, name) {
+			excluded = append(excluded, name)
+		}
+	}
+	return excluded, nil
+}
+
+// product returns the product as the map of its JSON fields, without the
+// excluded ones.
+func (e fieldExclusion) product(p dto.Product) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for _, name := range e {
+		delete(fields, name)
+	}
+	return fields, nil
+}
+
+// listing returns the listing as the map of its JSON fields, its products
+// without the excluded fields.
+func (e fieldExclusion) listing(res Response) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+
+	products := make([]map[string]json.RawMessage, len(res.Products))
+	for i, p := range res.Products {
+		if products[i], err = e.product(p); err != nil {
+			return nil, err
+		}
+	}
+	if fields["products"], err = json.Marshal(products); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,86 @@
+package catalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestFieldsExclude(t *testing.T) {
+	product := models.Product{
+		Code:     "PROD001",
+		Price:    decimal.RequireFromString("10.99"),
+		Category: &models.Category{Code: "clothing", Name: "Clothing"},
+		Variants: []models.Variant{{Name: "Variant A", SKU: "SKU001A", Price: decimal.RequireFromString("11.99")}},
+	}
+	get := func(repo *mockRepo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("listing without the category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, products.SearchFilters{Limit: 10}).Return([]models.Product{product}, int64(1), nil)
+
+		rec := get(repo, "/catalog?fieldsExclude=category")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"products":[{"code":"PROD001","price":"10.99"}],
+			"products_available":1,
+			"availability":{"page_variant_count":1,"total_products":1}
+		}`, rec.Body.String())
+		assert.Equal(t, "1", rec.Header().Get("X-Total-Count"))
+	})
+
+	t.Run("details without the category and variants", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001?fieldsExclude=category,%20variants")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"code":"PROD001","price":"10.99"}`, rec.Body.String())
+	})
+
+	t.Run("code is always kept", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001?fieldsExclude=code,price,category,variants")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"code":"PROD001"}`, rec.Body.String())
+	})
+
+	t.Run("unknown fields are ignored", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001?fieldsExclude=colour,,variants")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"code":"PROD001","price":"10.99","category":{"code":"clothing","name":"Clothing"}}`, rec.Body.String())
+	})
+
+	t.Run("cannot be combined with fields", func(t *testing.T) {
+		for _, target := range []string{"/catalog?fields=code,price&fieldsExclude=category", "/catalog/PROD001?fields=&fieldsExclude=variants"} {
+			repo := new(mockRepo)
+
+			rec := get(repo, target)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+			assert.JSONEq(t, `{"error":"fields and fieldsExclude cannot be combined"}`, rec.Body.String(), target)
+			repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+			repo.AssertNotCalled(t, "GetByCode", mock.Anything, mock.Anything)
+		}
+	})
+}
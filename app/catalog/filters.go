@@ -0,0 +1,199 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/pricefmt"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+)
+
+const (
+	defaultLimit = 10
+	minLimit     = 1
+	maxLimit     = 100
+
+	maxVariantNameLength = 64
+	maxQueryLength       = 64
+)
+
+// singleValued lists the parameters that cannot be repeated with different
+// values, as only one of them would be used.
+var singleValued = []string{"offset", "limit", "priceLessThan", "priceAppliesTo", "category"}
+
+// offsetLimit bounds how deep clients can paginate, as skipping rows gets
+// slower the further the page is.
+type offsetLimit struct {
+	max int
+	// clamp serves the last allowed page for offsets over max instead of
+	// rejecting them.
+	clamp bool
+}
+
+// page reads the offset and limit parameters, clamping the limit to the
+// allowed range and the offset to max when asked to.
+func (o offsetLimit) page(q url.Values) (offset, limit int, err error) {
+	limit = defaultLimit
+	if v := q.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		if offset > o.max {
+			if !o.clamp {
+				return 0, 0, fmt.Errorf("offset must be at most %d, narrow down the results with filters to go further", o.max)
+			}
+			offset = o.max
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, errors.New("limit must be an integer")
+		}
+		limit = min(max(limit, minLimit), maxLimit)
+	}
+	return offset, limit, nil
+}
+
+// validateVariantFilters builds the filters of the variants listing from the
+// query string, paginated as the product listing.
+func validateVariantFilters(r *http.Request, offsets offsetLimit) (products.VariantFilters, error) {
+	q := r.URL.Query()
+	for _, name := range []string{"offset", "limit", "priceLessThan", "category"} {
+		if values := q[name]; slices.ContainsFunc(values, func(v string) bool { return v != values[0] }) {
+			return products.VariantFilters{}, fmt.Errorf("%s must not be repeated with different values", name)
+		}
+	}
+
+	filters := products.VariantFilters{Category: q.Get("category")}
+	var err error
+	if filters.Offset, filters.Limit, err = offsets.page(q); err != nil {
+		return filters, err
+	}
+	if v := q.Get("priceLessThan"); v != "" {
+		price, err := pricefmt.Parse(v)
+		if err != nil {
+			return filters, errors.New("priceLessThan must be a price " + pricefmt.Accepted)
+		}
+		filters.PriceLessThan = &price
+	}
+	return filters, nil
+}
+
+// validateProductFilters builds the search filters from the query string.
+// Limits outside of the allowed range are clamped, while malformed values
+// are reported as errors.
+func validateProductFilters(r *http.Request, offsets offsetLimit) (products.SearchFilters, error) {
+	q := r.URL.Query()
+	for _, name := range singleValued {
+		if values := q[name]; slices.ContainsFunc(values, func(v string) bool { return v != values[0] }) {
+			return products.SearchFilters{}, fmt.Errorf("%s must not be repeated with different values", name)
+		}
+	}
+
+	filters := products.SearchFilters{
+		Category: q.Get("category"),
+	}
+	var err error
+	if filters.Offset, filters.Limit, err = offsets.page(q); err != nil {
+		return filters, err
+	}
+
+	if v := q.Get("q"); v != "" {
+		if len(v) > maxQueryLength {
+			return filters, fmt.Errorf("q must be at most %d characters", maxQueryLength)
+		}
+		filters.Query = v
+	}
+
+	// Prices formatted for display, such as €1,299.50, are understood too
+	if v := q.Get("priceLessThan"); v != "" {
+		price, err := pricefmt.Parse(v)
+		if err != nil {
+			return filters, errors.New("priceLessThan must be a price " + pricefmt.Accepted)
+		}
+		filters.PriceLessThan = &price
+	}
+
+	if v := q.Get("priceEquals"); v != "" {
+		if filters.PriceLessThan != nil {
+			return filters, errors.New("priceEquals cannot be combined with priceLessThan")
+		}
+		price, err := pricefmt.Parse(v)
+		if err != nil || price.IsNegative() {
+			return filters, errors.New("priceEquals must be a non-negative price " + pricefmt.Accepted)
+		}
+		filters.PriceEquals = &price
+	}
+
+	// Variant prices count instead of the product one with priceAppliesTo=variant
+	switch q.Get("priceAppliesTo") {
+	case "", "product":
+	case "variant":
+		filters.VariantPrices = true
+	default:
+		return filters, errors.New("priceAppliesTo must be product or variant")
+	}
+
+	if v := q.Get("variant"); v != "" {
+		if len(v) > maxVariantNameLength || strings.Contains(v, "%") {
+			return filters, errors.New("variant must be at most 64 characters and must not contain '%'")
+		}
+		filters.Variant = v
+	}
+
+	if v := q.Get("skuPrefix"); v != "" {
+		if !skuPattern.MatchString(v) {
+			return filters, errors.New("skuPrefix must be up to 32 letters, digits or dashes")
+		}
+		filters.SKUPrefix = v
+	}
+
+	// Only the literal true and false are accepted, unlike strconv.ParseBool
+	if v := q.Get("hasVariants"); v != "" {
+		if v != "true" && v != "false" {
+			return filters, errors.New("hasVariants must be true or false")
+		}
+		hasVariants := v == "true"
+		filters.HasVariants = &hasVariants
+	}
+
+	// Products outside of their availability window are listed too with
+	// includeUnavailable=true, which the server restricts to admins
+	if v := q.Get("includeUnavailable"); v != "" {
+		if v != "true" && v != "false" {
+			return filters, errors.New("includeUnavailable must be true or false")
+		}
+		filters.IncludeUnavailable = v == "true"
+	}
+
+	if v := q.Get("modifiedSince"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filters, errors.New("modifiedSince must be an RFC 3339 timestamp")
+		}
+		filters.ModifiedSince = &since
+	}
+
+	if v := q.Get("sort"); v != "" {
+		switch {
+		case v == products.SortRelevance:
+			if filters.Query == "" {
+				return filters, errors.New("sort=relevance requires q")
+			}
+		case !products.IsValidSort(v):
+			return filters, errors.New("sort must be one of featured, newest, price_asc, price_desc or relevance")
+		}
+		filters.Sort = v
+	}
+
+	return filters, nil
+}
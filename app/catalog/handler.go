@@ -1,56 +1,1295 @@
 package catalog
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/app/dto"
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/imports"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
 	"github.com/mytheresa/go-hiring-challenge/models"
 )
 
-type Response struct {
-	Products []Product `json:"products"`
-}
+// maxLookupCodes caps the codes of a single lookup.
+const maxLookupCodes = 100
+
+// maxValidateProducts caps the products of a single validation.
+const maxValidateProducts = 1000
+
+// Bounds of the related products.
+const (
+	defaultRelatedLimit = 4
+	maxRelatedLimit     = 20
+)
+
+// Bounds of the grouped listing.
+const (
+	maxGroupedCategories = 10
+	defaultPerCategory   = 3
+	maxPerCategory       = 20
+)
 
-type Product struct {
-	Code  string  `json:"code"`
-	Price float64 `json:"price"`
+// DefaultMaxOffset is how deep the catalog listing can be paginated.
+const DefaultMaxOffset = 10000
+
+// Counts of Availability the deprecated products_available field can alias.
+const (
+	AliasTotalProducts    = "total_products"
+	AliasPageVariantCount = "page_variant_count"
+)
+
+// DefaultMaxVariantsPerProduct caps the variants accepted when creating a product.
+const DefaultMaxVariantsPerProduct = 50
+
+// Options configures the catalog handler. Zero values fall back to the defaults.
+type Options struct {
+	// MaxVariantsPerProduct caps the variants accepted when creating a product.
+	MaxVariantsPerProduct int
+	// ProductCodePattern is the regular expression product codes must match.
+	ProductCodePattern string
+	// Rates converts prices to the currency requested by the clients.
+	Rates currency.RatesProvider
+	// Events receives the product.* events of successful mutations.
+	Events events.Publisher
+	// MaxOffset is the deepest offset of the catalog listing.
+	MaxOffset int
+	// ClampOffset serves offsets over MaxOffset as MaxOffset rather than
+	// rejecting them with 400.
+	ClampOffset bool
+	// MaxPrice is the highest price of the products and variants written.
+	MaxPrice decimal.Decimal
+	// ImportJobs stores the asynchronous imports, which are unavailable
+	// when it or ImportQueue is nil.
+	ImportJobs imports.Repository
+	// ImportQueue runs the asynchronous imports with RunImportJob.
+	ImportQueue ImportQueue
+	// Writer stores the products written by the write endpoints, which
+	// answer 503 when it is nil.
+	Writer products.ProductWriter
+	// Transactor runs the atomic imports, which are unavailable when it is
+	// nil.
+	Transactor Transactor
+	// MinorUnits tells the minor unit of each currency, for the prices
+	// rendered in minor units.
+	MinorUnits currency.MinorUnits
+	// Rounding rounds the prices shown, HalfEven by default.
+	Rounding pricing.Mode
+	// DegradeOnVariantError serves the catalog listing without variants,
+	// flagged as degraded, when they cannot be read rather than failing it.
+	DegradeOnVariantError bool
+	// AdminToken is the bearer token of the admins, who can ask the catalog
+	// listing for its debug object.
+	AdminToken string
+	// ProductsAvailableAlias is the count of Availability the deprecated
+	// products_available field equals, AliasTotalProducts by default.
+	ProductsAvailableAlias string
+	// Audit records the changes of the write endpoints, which are not
+	// recorded when it is nil.
+	Audit audit.Recorder
+	// ConcurrentQueries runs the page and total queries of the catalog
+	// listing concurrently rather than one after the other.
+	ConcurrentQueries bool
+	// QueryConcurrency is the number of queries of a listing run at a time
+	// when ConcurrentQueries is set, all of them when it is 0.
+	QueryConcurrency int
 }
 
 type CatalogHandler struct {
-	repo *models.ProductsRepository
+	reader      products.ProductReader
+	writer      products.ProductWriter
+	maxVariants int
+	codes       codeValidator
+	prices      priceValidator
+	rates       currency.RatesProvider
+	minorUnits  currency.MinorUnits
+	rounding    pricing.Mode
+	events      events.Publisher
+	offsets     offsetLimit
+	importJobs  imports.Repository
+	importQueue ImportQueue
+	transactor  Transactor
+	degrade     bool
+	adminToken  string
+	alias       string
+	audit       audit.Recorder
+	concurrent  bool
+	queries     queryGroup
 }
 
-func NewCatalogHandler(r *models.ProductsRepository) *CatalogHandler {
+// NewCatalogHandler reads the products from r and writes them through
+// opts.Writer. It fails when the configured product code pattern is not a
+// valid regular expression, the products_available alias is unknown or a
+// limit is negative.
+func NewCatalogHandler(r products.ProductReader, opts Options) (*CatalogHandler, error) {
+	if opts.MaxVariantsPerProduct == 0 {
+		opts.MaxVariantsPerProduct = DefaultMaxVariantsPerProduct
+	}
+	if opts.MaxVariantsPerProduct < 0 {
+		return nil, errors.New("max variants per product must not be negative")
+	}
+	if opts.ProductCodePattern == "" {
+		opts.ProductCodePattern = DefaultProductCodePattern
+	}
+	if opts.Rates == nil {
+		opts.Rates = currency.StaticRates{}
+	}
+	if opts.Events == nil {
+		opts.Events = events.NopPublisher{}
+	}
+	if opts.MaxOffset == 0 {
+		opts.MaxOffset = DefaultMaxOffset
+	}
+	if opts.MaxOffset < 0 {
+		return nil, errors.New("max offset must not be negative")
+	}
+	if opts.QueryConcurrency < 0 {
+		return nil, errors.New("query concurrency must not be negative")
+	}
+
+	if opts.MaxPrice.IsZero() {
+		opts.MaxPrice = DefaultMaxPrice
+	}
+	switch opts.ProductsAvailableAlias {
+	case "":
+		opts.ProductsAvailableAlias = AliasTotalProducts
+	case AliasTotalProducts, AliasPageVariantCount:
+	default:
+		return nil, fmt.Errorf("products_available alias must be %s or %s", AliasTotalProducts, AliasPageVariantCount)
+	}
+
+	codes, err := newCodeValidator(opts.ProductCodePattern)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := newPriceValidator(opts.MaxPrice)
+	if err != nil {
+		return nil, err
+	}
+
 	return &CatalogHandler{
-		repo: r,
+		reader:      r,
+		writer:      opts.Writer,
+		maxVariants: opts.MaxVariantsPerProduct,
+		codes:       codes,
+		prices:      prices,
+		rates:       opts.Rates,
+		minorUnits:  opts.MinorUnits,
+		rounding:    opts.Rounding,
+		events:      opts.Events,
+		offsets:     offsetLimit{max: opts.MaxOffset, clamp: opts.ClampOffset},
+		importJobs:  opts.ImportJobs,
+		importQueue: opts.ImportQueue,
+		transactor:  opts.Transactor,
+		degrade:     opts.DegradeOnVariantError,
+		adminToken:  opts.AdminToken,
+		alias:       opts.ProductsAvailableAlias,
+		audit:       opts.Audit,
+		concurrent:  opts.ConcurrentQueries,
+		queries:     queryGroup{limit: opts.QueryConcurrency},
+	}, nil
+}
+
+// writable responds with 503 when the handler has no writer.
+func (h *CatalogHandler) writable(w http.ResponseWriter) bool {
+	if h.writer == nil {
+		api.ErrorResponse(w, http.StatusServiceUnavailable, "catalog writes are not available")
+		return false
 	}
+	return true
 }
 
 func (h *CatalogHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
-	res, err := h.repo.GetAllProducts()
+	h.serveListing(w, r, "")
+}
+
+// HandleCategoryProducts lists the products of the category of the path with
+// the filters of the catalog listing, answering as /catalog?category= does.
+func (h *CatalogHandler) HandleCategoryProducts(w http.ResponseWriter, r *http.Request) {
+	h.serveListing(w, r, r.PathValue("code"))
+}
+
+// serveListing answers with a page of the products matching the filters of
+// the query string, restricted to category unless it is empty.
+func (h *CatalogHandler) serveListing(w http.ResponseWriter, r *http.Request, category string) {
+	filters, err := validateProductFilters(r, h.offsets)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if category != "" {
+		if filters.Category != "" && filters.Category != category {
+			api.ErrorResponse(w, http.StatusBadRequest, "category must match the category of the path")
+			return
+		}
+		filters.Category = category
+	}
 
-	// Map response
-	products := make([]Product, len(res))
+	conv, ok := h.requestedConversion(w, r)
+	if !ok {
+		return
+	}
+	format, err := h.requestedPriceFormat(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	diag, err := h.requestedDiagnostics(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	excluded, err := requestedFieldExclusion(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filters.AllowMissingVariants = h.degrade
+	start := time.Now()
+	res, total, err := h.list(r.Context(), filters)
+	elapsed := time.Since(start)
+	if api.Abandoned(r) {
+		return
+	}
+	degraded := errors.Is(err, products.ErrVariantsUnavailable)
+	if err != nil && !degraded {
+		api.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := h.prepareResponse(res, total, conv, api.RequestLocale(r))
+	if degraded {
+		response.Degraded = true
+		w.Header().Set("X-Degraded", "true")
+	}
+	for i := range response.Products {
+		format.render(&response.Products[i])
+	}
+	if diag != nil {
+		diag.filters, diag.total, diag.elapsed = filters, total, elapsed
+		response.Debug = diag.debug()
+	}
+	if filters.IncludeUnavailable || diag != nil {
+		// Admin listings must not end up in shared caches
+		w.Header().Set("Cache-Control", api.NoStore)
+	}
+	setDeprecation(w)
+	api.SetPaginationHeaders(w, r, filters.Offset, filters.Limit, total)
+	if len(excluded) == 0 {
+		api.OKResponse(w, response)
+		return
+	}
+	trimmed, err := excluded.listing(response)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.OKResponse(w, trimmed)
+}
+
+// list reads the page of the catalog listing and its total, with a single
+// List call or, when the queries are concurrent, with Count and ListPage run
+// together. Like List, it comes with ErrVariantsUnavailable when the page was
+// read without variants.
+func (h *CatalogHandler) list(ctx context.Context, filters products.SearchFilters) ([]models.Product, int64, error) {
+	if !h.concurrent {
+		return h.reader.List(ctx, filters)
+	}
+
+	var (
+		res      []models.Product
+		total    int64
+		degraded error
+	)
+	err := h.queries.run(ctx,
+		func(ctx context.Context) (err error) {
+			total, err = h.reader.Count(ctx, filters)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			res, err = h.reader.ListPage(ctx, filters)
+			// A page without variants does not fail the listing
+			if errors.Is(err, products.ErrVariantsUnavailable) {
+				degraded, err = err, nil
+			}
+			return err
+		},
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res, total, degraded
+}
+
+// HandleGrouped returns the first perCategory products of each category of
+// the comma separated categories parameter, along with the number of
+// products of each. Unknown categories come back empty.
+func (h *CatalogHandler) HandleGrouped(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	categories, err := groupedCategories(q.Get("categories"))
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	perCategory := defaultPerCategory
+	if v := q.Get("perCategory"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPerCategory {
+			api.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("perCategory must be an integer between 1 and %d", maxPerCategory))
+			return
+		}
+		perCategory = n
+	}
+
+	conv, ok := h.requestedConversion(w, r)
+	if !ok {
+		return
+	}
+	format, err := h.requestedPriceFormat(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pages, err := h.reader.ListByCategories(r.Context(), categories, perCategory)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	locale := api.RequestLocale(r)
+	response := GroupedResponse{Categories: make(map[string]Response, len(categories))}
+	for _, code := range categories {
+		page := pages[code]
+		listing := h.prepareResponse(page.Products, page.Total, conv, locale)
+		for i := range listing.Products {
+			format.render(&listing.Products[i])
+		}
+		response.Categories[code] = listing
+	}
+	setDeprecation(w)
+	api.OKResponse(w, response)
+}
+
+// groupedCategories splits the categories parameter, dropping repeated codes.
+func groupedCategories(param string) ([]string, error) {
+	if param == "" {
+		return nil, errors.New("categories is required")
+	}
+
+	var categories []string
+	for _, code := range strings.Split(param, ",") {
+		if code == "" {
+			return nil, errors.New("categories must not contain empty codes")
+		}
+		if !slices.Contains(categories, code) {
+			categories = append(categories, code)
+		}
+	}
+	if len(categories) > maxGroupedCategories {
+		return nil, fmt.Errorf("categories must list at most %d codes", maxGroupedCategories)
+	}
+	return categories, nil
+}
+
+// HandleLookup returns the products of the comma separated codes parameter.
+// Products come in the order of the codes, which is skipped with
+// preserveOrder=false, and the unknown codes are listed as missing.
+func (h *CatalogHandler) HandleLookup(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	codes, err := h.lookupCodes(q.Get("codes"))
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	preserveOrder := true
+	switch q.Get("preserveOrder") {
+	case "", "true":
+	case "false":
+		preserveOrder = false
+	default:
+		api.ErrorResponse(w, http.StatusBadRequest, "preserveOrder must be true or false")
+		return
+	}
+
+	conv, ok := h.requestedConversion(w, r)
+	if !ok {
+		return
+	}
+	format, err := h.requestedPriceFormat(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.reader.GetByCodes(r.Context(), codes)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	byCode := make(map[string]models.Product, len(res))
+	for _, p := range res {
+		byCode[p.Code] = p
+	}
+	if preserveOrder {
+		ordered := make([]models.Product, 0, len(res))
+		for _, code := range codes {
+			if p, ok := byCode[code]; ok {
+				ordered = append(ordered, p)
+			}
+		}
+		res = ordered
+	}
+
+	locale := api.RequestLocale(r)
+	response := LookupResponse{
+		Products: make([]dto.Product, len(res)),
+		Missing:  []string{},
+	}
+	for i, p := range res {
+		response.Products[i] = dto.ToProductResponse(p, conv, locale)
+		format.render(&response.Products[i])
+	}
+	for _, code := range codes {
+		if _, ok := byCode[code]; !ok {
+			response.Missing = append(response.Missing, code)
+		}
+	}
+	api.OKResponse(w, response)
+}
+
+// lookupCodes splits the codes parameter, dropping repeated codes.
+func (h *CatalogHandler) lookupCodes(param string) ([]string, error) {
+	if param == "" {
+		return nil, errors.New("codes is required")
+	}
+
+	var codes []string
+	seen := map[string]bool{}
+	for _, code := range strings.Split(param, ",") {
+		if err := h.codes.validate(code); err != nil {
+			return nil, fmt.Errorf("%w %q", err, code)
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) > maxLookupCodes {
+		return nil, fmt.Errorf("codes must list at most %d products", maxLookupCodes)
+	}
+	return codes, nil
+}
+
+// HandleGetSpecific returns the details of a product. The code is normalized
+// before validation, so lowercase or padded codes find the product too.
+func (h *CatalogHandler) HandleGetSpecific(w http.ResponseWriter, r *http.Request) {
+	code := normalizeProductCode(r.PathValue("code"))
+	if err := h.codes.validate(code); err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conv, ok := h.requestedConversion(w, r)
+	if !ok {
+		return
+	}
+
+	var allImages bool
+	switch v := r.URL.Query().Get("allImages"); v {
+	case "", "false":
+	case "true":
+		allImages = true
+	default:
+		api.ErrorResponse(w, http.StatusBadRequest, "allImages must be true or false")
+		return
+	}
+	format, err := h.requestedPriceFormat(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	excluded, err := requestedFieldExclusion(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.reader.GetByCode(r.Context(), code)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	lastModified := lastModifiedAt(res)
+	api.SetLastModified(w, lastModified)
+	if api.NotModifiedSince(r, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	product := dto.ToProductDetailsResponse(res, conv, api.RequestLocale(r))
+	if !allImages {
+		product.Images = dto.FirstImage(product.Images)
+	}
+	format.render(&product)
+	if len(excluded) == 0 {
+		api.OKResponse(w, product)
+		return
+	}
+	trimmed, err := excluded.product(product)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.OKResponse(w, trimmed)
+}
+
+// HandleRelated returns other products of the category of the product, by
+// id. The limit parameter is clamped like the listing limit.
+func (h *CatalogHandler) HandleRelated(w http.ResponseWriter, r *http.Request) {
+	code := normalizeProductCode(r.PathValue("code"))
+	if err := h.codes.validate(code); err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := defaultRelatedLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			api.ErrorResponse(w, http.StatusBadRequest, "limit must be an integer")
+			return
+		}
+		limit = min(max(n, 1), maxRelatedLimit)
+	}
+
+	conv, ok := h.requestedConversion(w, r)
+	if !ok {
+		return
+	}
+	format, err := h.requestedPriceFormat(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.reader.GetRelated(r.Context(), code, limit)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	locale := api.RequestLocale(r)
+	response := RelatedResponse{Products: make([]dto.Product, len(res))}
 	for i, p := range res {
-		products[i] = Product{
-			Code:  p.Code,
-			Price: p.Price.InexactFloat64(),
+		response.Products[i] = dto.ToProductResponse(p, conv, locale)
+		format.render(&response.Products[i])
+	}
+	api.OKResponse(w, response)
+}
+
+func (h *CatalogHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	var req CreateProductRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.validateCreateProduct(req); err != nil {
+		api.ErrorResponse(w, validationStatus(err), err.Error())
+		return
+	}
+
+	product := newProductModel(req)
+	if err := h.writer.Create(r.Context(), &product); err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	created := dto.ToProductDetailsResponse(product, h.baseConversion(), "")
+	h.events.Publish(events.New(events.ProductCreated, created))
+	h.record(r.Context(), audit.Change{Action: audit.Create, EntityType: audit.Product, EntityCode: product.Code, After: created})
+	w.Header().Set("Location", api.AbsoluteURL(r, "/catalog/"+product.Code))
+	api.CreatedPreferredResponse(w, r, created)
+}
+
+// newProductModel maps a validated creation request to the product to store.
+func newProductModel(req CreateProductRequest) models.Product {
+	product := models.Product{
+		Code:     req.Code,
+		Price:    req.Price.Decimal,
+		Variants: make([]models.Variant, len(req.Variants)),
+	}
+	if req.Category != "" {
+		product.Category = &models.Category{Code: req.Category}
+	}
+	for i, v := range req.Variants {
+		product.Variants[i] = models.Variant{
+			Name:  v.Name,
+			SKU:   v.SKU,
+			Price: v.Price.Decimal,
+		}
+	}
+	return product
+}
+
+// HandleValidate runs the checks of HandleCreate on a product or an array of
+// products without writing anything, so imports can be checked beforehand.
+// Existing codes, SKUs and categories are looked up with plain reads.
+func (h *CatalogHandler) HandleValidate(w http.ResponseWriter, r *http.Request) {
+	var payload ProductsPayload
+	if !api.DecodeJSON(w, r, &payload) {
+		return
+	}
+	if len(payload) == 0 {
+		api.ErrorResponse(w, http.StatusBadRequest, "at least one product is required")
+		return
+	}
+	if len(payload) > maxValidateProducts {
+		api.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("at most %d products can be validated at once", maxValidateProducts))
+		return
+	}
+
+	existing, err := h.reader.FindExisting(r.Context(), payloadKeys(payload))
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	api.OKResponse(w, h.validationReport(payload, existing))
+}
+
+// payloadKeys collects the distinct product codes, variant SKUs and category
+// codes of the payload, sorted.
+func payloadKeys(payload ProductsPayload) products.ExistingKeys {
+	codes, skus, categories := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, p := range payload {
+		codes[p.Code] = true
+		if p.Category != "" {
+			categories[p.Category] = true
+		}
+		for _, v := range p.Variants {
+			skus[v.SKU] = true
+		}
+	}
+	return products.ExistingKeys{
+		Codes:      slices.Sorted(maps.Keys(codes)),
+		SKUs:       slices.Sorted(maps.Keys(skus)),
+		Categories: slices.Sorted(maps.Keys(categories)),
+	}
+}
+
+// validationReport validates the products in order. Only the products that
+// would be created count when looking for conflicts within the payload.
+func (h *CatalogHandler) validationReport(payload ProductsPayload, existing products.ExistingKeys) ValidationReport {
+	existingCodes := keySet(existing.Codes)
+	existingSKUs := keySet(existing.SKUs)
+	categories := keySet(existing.Categories)
+	createdCodes := map[string]int{}
+	createdSKUs := map[string]int{}
+
+	report := ValidationReport{Products: make([]ProductValidation, len(payload))}
+	for i, req := range payload {
+		item := ProductValidation{
+			Index:    i,
+			Code:     req.Code,
+			Errors:   []string{},
+			Warnings: []string{},
+		}
+
+		for _, err := range h.createProductErrors(req) {
+			item.Errors = append(item.Errors, err.Error())
+		}
+		if req.Category == "" {
+			item.Warnings = append(item.Warnings, "product has no category")
+		} else if !categories[req.Category] {
+			item.Errors = append(item.Errors, fmt.Sprintf("category %q not found", req.Category))
+		}
+
+		var conflicts []string
+		if existingCodes[req.Code] {
+			conflicts = append(conflicts, fmt.Sprintf("product code %s already exists", req.Code))
+		} else if j, ok := createdCodes[req.Code]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("product code %s is already used by product %d of the payload", req.Code, j))
+		}
+		for _, v := range req.Variants {
+			if existingSKUs[v.SKU] {
+				conflicts = append(conflicts, fmt.Sprintf("variant sku %s already exists", v.SKU))
+			} else if j, ok := createdSKUs[v.SKU]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("variant sku %s is already used by product %d of the payload", v.SKU, j))
+			}
+		}
+
+		switch {
+		case len(item.Errors) > 0:
+			item.Outcome = OutcomeInvalid
+		case len(conflicts) > 0:
+			item.Outcome = OutcomeWouldConflict
+		default:
+			item.Outcome = OutcomeWouldCreate
+			createdCodes[req.Code] = i
+			for _, v := range req.Variants {
+				createdSKUs[v.SKU] = i
+			}
+		}
+		item.Errors = append(item.Errors, conflicts...)
+		report.Products[i] = item
+	}
+	return report
+}
+
+func keySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return set
+}
+
+// HandleExport streams every product with its variants as JSON lines,
+// optionally limited to the category parameter. The response is flushed after
+// each batch, so memory stays bounded and clients can start reading right away.
+func (h *CatalogHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	conv := h.baseConversion()
+	locale := api.RequestLocale(r)
+
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	err := h.reader.ListAllFunc(r.Context(), r.URL.Query().Get("category"), func(batch []models.Product) error {
+		if !started {
+			start()
+		}
+		for _, p := range batch {
+			if err := enc.Encode(dto.ToProductDetailsResponse(p, conv, locale)); err != nil {
+				return err
+			}
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	})
+	switch {
+	case err != nil && api.Abandoned(r):
+		// Nobody is left to read the error
+	case err != nil && !started:
+		api.RepositoryErrorResponse(w, err)
+	case err != nil:
+		// Too late for an error status, the client sees a truncated stream
+		logging.FromContext(r.Context()).Error("Catalog export interrupted", "error", err)
+	case !started:
+		start()
+	}
+}
+
+func (h *CatalogHandler) HandleDeleteVariant(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	code := normalizeProductCode(r.PathValue("code"))
+	if err := h.codes.validate(code); err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sku := r.PathValue("sku")
+	if !skuPattern.MatchString(sku) {
+		api.ErrorResponse(w, http.StatusBadRequest, "invalid variant sku")
+		return
+	}
+
+	before := h.productState(r.Context(), code)
+	if err := h.writer.DeleteVariant(r.Context(), code, sku); err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	h.events.Publish(events.New(events.ProductUpdated, ProductChanged{Code: code}))
+	h.recordProductUpdate(r.Context(), code, before)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestedConversion resolves the exchange rate of the currency query
+// parameter, defaulting to the base currency. It writes the error response
+// itself and reports whether the handler can carry on.
+func (h *CatalogHandler) requestedConversion(w http.ResponseWriter, r *http.Request) (currency.Conversion, bool) {
+	code := requestedCurrency(r)
+	rate, err := h.rates.Rate(r.Context(), code)
+	if errors.Is(err, currency.ErrUnsupportedCurrency) {
+		api.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("unsupported currency %q, supported currencies are %s", code, strings.Join(h.rates.Supported(), ", ")))
+		return currency.Conversion{}, false
+	}
+	if err != nil {
+		api.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return currency.Conversion{}, false
+	}
+	return currency.Conversion{Rate: rate, Rounding: h.rounding}, true
+}
+
+// baseConversion keeps the prices in the base currency, as stored.
+func (h *CatalogHandler) baseConversion() currency.Conversion {
+	return currency.Conversion{Rate: decimal.NewFromInt(1), Rounding: h.rounding}
+}
+
+// requestedCurrency is the currency query parameter, defaulting to the base
+// currency.
+func requestedCurrency(r *http.Request) string {
+	if code := strings.ToUpper(r.URL.Query().Get("currency")); code != "" {
+		return code
+	}
+	return currency.Base
+}
+
+// priceFormat renders the prices of a response in the currency.Format
+// requested.
+type priceFormat struct {
+	currency.Format
+}
+
+// requestedPriceFormat reads the priceFormat=string|number and
+// amountFormat=major|minor query parameters. Minor units are counted in the
+// requested currency.
+func (h *CatalogHandler) requestedPriceFormat(r *http.Request) (priceFormat, error) {
+	q := r.URL.Query()
+	format, err := currency.ParseFormat(q.Get("priceFormat"), q.Get("amountFormat"), requestedCurrency(r), h.minorUnits)
+	return priceFormat{format}, err
+}
+
+// render applies the format to the prices of the product and its variants.
+// Products in minor units name their currency, which the prices of their
+// variants share.
+func (f priceFormat) render(p *dto.Product) {
+	f.Apply(&p.Price)
+	for i := range p.Variants {
+		f.Apply(&p.Variants[i].Price)
+	}
+	p.Currency = f.Currency
+}
+
+// HandleAddImage adds an image to the product, shifting the images from its
+// position on.
+func (h *CatalogHandler) HandleAddImage(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	code := r.PathValue("code")
+	if err := h.codes.validate(code); err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req AddImageRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+	if err := validateAddImage(req); err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	image := models.Image{
+		URL:      req.URL,
+		Position: req.Position,
+		AltText:  req.AltText,
+	}
+	before := h.productState(r.Context(), code)
+	if err := h.writer.AddImage(r.Context(), code, &image); err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	h.events.Publish(events.New(events.ProductUpdated, ProductChanged{Code: code}))
+	h.recordProductUpdate(r.Context(), code, before)
+	api.CreatedResponse(w, dto.ToImageResponse(image))
+}
+
+// HandleDeleteImage removes an image of the product, moving the following
+// images up.
+func (h *CatalogHandler) HandleDeleteImage(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	code := r.PathValue("code")
+	if err := h.codes.validate(code); err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || id == 0 {
+		api.ErrorResponse(w, http.StatusBadRequest, "invalid image id")
+		return
+	}
+
+	before := h.productState(r.Context(), code)
+	if err := h.writer.DeleteImage(r.Context(), code, uint(id)); err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	h.events.Publish(events.New(events.ProductUpdated, ProductChanged{Code: code}))
+	h.recordProductUpdate(r.Context(), code, before)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleUpdateVariantPrices sets the prices of several variants of a product
+// at once. The SKUs matching none of its variants are reported, the others
+// are updated.
+func (h *CatalogHandler) HandleUpdateVariantPrices(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	code := r.PathValue("code")
+	if err := h.codes.validate(code); err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req VariantPricesRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+	if err := h.validateVariantPrices(req); err != nil {
+		api.ErrorResponse(w, validationStatus(err), err.Error())
+		return
+	}
+
+	before := h.productState(r.Context(), code)
+	unmatched, err := h.writer.UpdateVariantPrices(r.Context(), code, req.decimalPrices())
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	updated := len(req.Prices) - len(unmatched)
+	if updated > 0 {
+		h.events.Publish(events.New(events.ProductUpdated, ProductChanged{Code: code}))
+		h.recordProductUpdate(r.Context(), code, before)
+	}
+	api.OKResponse(w, VariantPricesResponse{Updated: updated, UnmatchedSKUs: unmatched})
+}
+
+// validateVariantPrices checks the prices as those of the variants of a new
+// product, in SKU order.
+func (h *CatalogHandler) validateVariantPrices(req VariantPricesRequest) error {
+	if len(req.Prices) == 0 {
+		return errors.New("prices must not be empty")
+	}
+	if len(req.Prices) > h.maxVariants {
+		return fmt.Errorf("prices can list at most %d variants", h.maxVariants)
+	}
+	for _, sku := range slices.Sorted(maps.Keys(req.Prices)) {
+		if !skuPattern.MatchString(sku) {
+			return fmt.Errorf("invalid variant sku %q", sku)
+		}
+		price := req.Prices[sku]
+		if price.IsNegative() {
+			return fmt.Errorf("variant %s price must not be negative", sku)
+		}
+		if err := h.prices.validateVariant("variant "+sku+" price", price.Decimal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleAdjustPrices applies a percentage, absolute or factor price
+// adjustment to every product of a category. Adjustments that would make any
+// price negative or too large are refused with 422 and nothing is changed.
+func (h *CatalogHandler) HandleAdjustPrices(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	var req PriceAdjustmentRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+	if req.Category == "" {
+		api.ErrorResponse(w, http.StatusBadRequest, "category is required")
+		return
+	}
+	switch req.Type {
+	case products.AdjustPercentage, products.AdjustAbsolute:
+		if req.Value.IsZero() {
+			api.ErrorResponse(w, http.StatusBadRequest, "value must not be zero")
+			return
+		}
+	case products.AdjustFactor:
+		if !req.Value.IsPositive() {
+			api.ErrorResponse(w, http.StatusBadRequest, "value must be positive")
+			return
 		}
+	default:
+		api.ErrorResponse(w, http.StatusBadRequest, "type must be percentage, absolute or factor")
+		return
+	}
+
+	updated, ok := h.adjustPrices(w, r, req.Category, products.Adjustment{Type: req.Type, Value: req.Value})
+	if !ok {
+		return
+	}
+	api.OKResponse(w, PriceAdjustmentResponse{Updated: updated})
+}
+
+// HandleAdjustCategoryPrices applies a promotion factor to the price of every
+// product in the category, as a factor adjustment of HandleAdjustPrices.
+func (h *CatalogHandler) HandleAdjustCategoryPrices(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	var req AdjustPricesRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+	if !req.Factor.IsPositive() {
+		api.ErrorResponse(w, http.StatusBadRequest, "factor must be positive")
+		return
+	}
+
+	if _, ok := h.adjustPrices(w, r, r.PathValue("code"), products.Adjustment{Type: products.AdjustFactor, Value: req.Factor}); !ok {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adjustPrices applies adj to the category, publishes and records the
+// change. When it fails it writes the error response and returns false.
+func (h *CatalogHandler) adjustPrices(w http.ResponseWriter, r *http.Request, category string, adj products.Adjustment) (int64, bool) {
+	before := h.categoryPriceState(r.Context(), category)
+	updated, err := h.writer.AdjustPrices(r.Context(), category, adj)
+	var negErr *products.NegativePriceError
+	var overflowErr *products.PriceOverflowError
+	switch {
+	case errors.As(err, &negErr), errors.As(err, &overflowErr):
+		api.ErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+		return 0, false
+	case err != nil:
+		api.RepositoryErrorResponse(w, err)
+		return 0, false
+	}
+
+	h.events.Publish(events.New(events.CategoryPricesAdjusted, CategoryPricesChanged{Code: category, Updated: updated}))
+	h.record(r.Context(), audit.Change{
+		Action: audit.AdjustPrices, EntityType: audit.Category, EntityCode: category,
+		Before: before, After: h.categoryPriceState(r.Context(), category),
+	})
+	return updated, true
+}
+
+// HandleExists tells whether a product exists without loading it, for
+// clients such as the inventory sync that need nothing else.
+func (h *CatalogHandler) HandleExists(w http.ResponseWriter, r *http.Request) {
+	code := normalizeProductCode(r.PathValue("code"))
+	if err := h.codes.validate(code); err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	exists, err := h.reader.Exists(r.Context(), code)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
 	}
 
-	// Return the products as a JSON response
-	w.Header().Set("Content-Type", "application/json")
+	api.OKResponse(w, ExistsResponse{Exists: exists})
+}
 
-	response := Response{
-		Products: products,
+// HandlePriceStats returns the price aggregates of the category, in the
+// currency and the format requested as the product prices.
+func (h *CatalogHandler) HandlePriceStats(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	conv, ok := h.requestedConversion(w, r)
+	if !ok {
+		return
+	}
+	format, err := h.requestedPriceFormat(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	stats, err := h.reader.PriceStats(r.Context(), code)
+	if api.Abandoned(r) {
 		return
 	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	response := priceStatsResponse(code, stats, conv)
+	format.Apply(&response.Min)
+	format.Apply(&response.Max)
+	format.Apply(&response.Avg)
+	response.Currency = format.Currency
+	api.OKResponse(w, response)
+}
+
+// priceStatsResponse maps the price aggregates of the category, converted
+// with conv.
+func priceStatsResponse(code string, stats products.PriceStats, conv currency.Conversion) PriceStatsResponse {
+	return PriceStatsResponse{
+		Category: code,
+		Min:      conv.Money(stats.Min.Decimal),
+		Max:      conv.Money(stats.Max.Decimal),
+		Avg:      conv.Money(stats.Avg.Decimal),
+		Count:    stats.Count,
+	}
+}
+
+// HandleDeleteOrphanVariants deletes the variants left behind by deleted
+// products on demand, as the nightly cleanup does.
+func (h *CatalogHandler) HandleDeleteOrphanVariants(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	deleted, err := h.writer.DeleteOrphanVariants(r.Context())
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	res := OrphanVariantsResponse{Deleted: deleted}
+	if deleted > 0 {
+		h.record(r.Context(), audit.Change{Action: audit.Delete, EntityType: audit.Catalog, After: res})
+	}
+	api.OKResponse(w, res)
+}
+
+func (h *CatalogHandler) validateCreateProduct(req CreateProductRequest) error {
+	if problems := h.createProductErrors(req); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// createProductErrors lists every problem of the product to create, in the
+// order they are checked.
+func (h *CatalogHandler) createProductErrors(req CreateProductRequest) []error {
+	var problems []error
+	if err := h.codes.validate(req.Code); err != nil {
+		problems = append(problems, err)
+	}
+	if !req.Price.IsPositive() {
+		problems = append(problems, errors.New("price must be positive"))
+	} else if err := h.prices.validate("price", req.Price.Decimal); err != nil {
+		problems = append(problems, err)
+	}
+	if len(req.Variants) > h.maxVariants {
+		problems = append(problems, fmt.Errorf("a product can have at most %d variants", h.maxVariants))
+	}
+
+	skus := make(map[string]struct{}, len(req.Variants))
+	for _, v := range req.Variants {
+		if v.Name == "" {
+			problems = append(problems, errors.New("variant name is required"))
+		}
+		if !skuPattern.MatchString(v.SKU) {
+			problems = append(problems, fmt.Errorf("invalid variant sku %q", v.SKU))
+			continue
+		}
+		if v.Price.IsNegative() {
+			problems = append(problems, fmt.Errorf("variant %s price must not be negative", v.SKU))
+		} else if err := h.prices.validateVariant("variant "+v.SKU+" price", v.Price.Decimal); err != nil {
+			problems = append(problems, err)
+		}
+		if _, ok := skus[v.SKU]; ok {
+			problems = append(problems, fmt.Errorf("duplicate variant sku %s", v.SKU))
+		}
+		skus[v.SKU] = struct{}{}
+	}
+	return problems
+}
+
+// lastModifiedAt is the most recent update of the product or any of its variants.
+func lastModifiedAt(p models.Product) time.Time {
+	lastModified := p.UpdatedAt
+	for _, v := range p.Variants {
+		if v.UpdatedAt.After(lastModified) {
+			lastModified = v.UpdatedAt
+		}
+	}
+	return lastModified
+}
+
+// prepareResponse maps the products to the response, converting prices from
+// the base currency with conv and naming categories in locale. The variants
+// are counted on the products loaded, those of a degraded listing counting
+// none.
+func (h *CatalogHandler) prepareResponse(res []models.Product, total int64, conv currency.Conversion, locale string) Response {
+	// Map response
+	products := dto.ToProductsResponse(res, conv, locale)
+	availability := Availability{TotalProducts: total}
+	for i := range res {
+		availability.PageVariantCount += len(res[i].Variants)
+	}
+
+	alias := availability.TotalProducts
+	if h.alias == AliasPageVariantCount {
+		alias = int64(availability.PageVariantCount)
+	}
+	return Response{
+		Products:          products,
+		ProductsAvailable: alias,
+		Availability:      availability,
+	}
+}
+
+// setDeprecation flags the responses carrying the deprecated
+// products_available field, which every listing still does.
+func setDeprecation(w http.ResponseWriter) {
+	w.Header().Set("Deprecation", "true")
 }
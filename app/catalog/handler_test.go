@@ -0,0 +1,2428 @@
+package catalog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/app/dto"
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+var testRates = currency.StaticRates{"GBP": decimal.RequireFromString("0.5")}
+
+func newHandler(t testing.TB, repo products.Repository, opts Options) *CatalogHandler {
+	t.Helper()
+	if opts.Rates == nil {
+		opts.Rates = testRates
+	}
+	if opts.Writer == nil {
+		opts.Writer = repo
+	}
+
+	h, err := NewCatalogHandler(repo, opts)
+	require.NoError(t, err)
+	return h
+}
+
+func newTestMux(h *CatalogHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /catalog", h.HandleGet)
+	mux.HandleFunc("GET /catalog/schema", h.HandleSchema)
+	mux.HandleFunc("GET /catalog/lookup", h.HandleLookup)
+	mux.HandleFunc("GET /catalog/compare", h.HandleCompare)
+	mux.HandleFunc("GET /catalog/grouped", h.HandleGrouped)
+	mux.HandleFunc("GET /catalog/export", h.HandleExport)
+	mux.HandleFunc("GET /catalog/changes", h.HandleChanges)
+	mux.HandleFunc("GET /catalog/{code}", h.HandleGetSpecific)
+	mux.HandleFunc("GET /catalog/{code}/related", h.HandleRelated)
+	mux.HandleFunc("GET /catalog/{code}/exists", h.HandleExists)
+	mux.HandleFunc("GET /variants", h.HandleVariants)
+	mux.HandleFunc("POST /catalog", h.HandleCreate)
+	mux.HandleFunc("POST /catalog/validate", h.HandleValidate)
+	mux.HandleFunc("POST /catalog/import", h.HandleImport)
+	mux.HandleFunc("GET /catalog/import/jobs/{id}", h.HandleImportJob)
+	mux.HandleFunc("GET /categories/{code}/products", h.HandleCategoryProducts)
+	mux.HandleFunc("GET /categories/{code}/price-stats", h.HandlePriceStats)
+	mux.HandleFunc("POST /categories/{code}/adjust-prices", h.HandleAdjustCategoryPrices)
+	mux.HandleFunc("POST /catalog/price-adjustments", h.HandleAdjustPrices)
+	mux.HandleFunc("POST /admin/import", h.HandleSnapshotImport)
+	mux.HandleFunc("POST /admin/maintenance/orphan-variants", h.HandleDeleteOrphanVariants)
+	mux.HandleFunc("DELETE /catalog/{code}/variants/{sku}", h.HandleDeleteVariant)
+	mux.HandleFunc("PATCH /catalog/{code}/variants/prices", h.HandleUpdateVariantPrices)
+	mux.HandleFunc("POST /catalog/{code}/images", h.HandleAddImage)
+	mux.HandleFunc("DELETE /catalog/{code}/images/{id}", h.HandleDeleteImage)
+	return mux
+}
+
+func TestCatalogHandler_ReadOnly(t *testing.T) {
+	repo := new(mockRepo)
+	repo.On("Exists", mock.Anything, "PROD001").Return(true, nil)
+	h, err := NewCatalogHandler(repo, Options{Rates: testRates})
+	require.NoError(t, err)
+	mux := newTestMux(h)
+
+	writes := []struct{ method, target string }{
+		{http.MethodPost, "/catalog"},
+		{http.MethodPost, "/catalog/import"},
+		{http.MethodPost, "/categories/shoes/adjust-prices"},
+		{http.MethodPost, "/catalog/price-adjustments"},
+		{http.MethodPost, "/admin/import"},
+		{http.MethodPost, "/admin/maintenance/orphan-variants"},
+		{http.MethodDelete, "/catalog/PROD001/variants/SKU001A"},
+		{http.MethodPatch, "/catalog/PROD001/variants/prices"},
+		{http.MethodPost, "/catalog/PROD001/images"},
+		{http.MethodDelete, "/catalog/PROD001/images/1"},
+	}
+	for _, tt := range writes {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(`{}`)))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code, tt.method+" "+tt.target)
+		assert.JSONEq(t, `{"error":"catalog writes are not available"}`, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/PROD001/exists", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHandleGet(t *testing.T) {
+	clothing := &models.Category{Code: "clothing", Name: "Clothing"}
+
+	t.Run("lists products with category and total", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, products.SearchFilters{Limit: 10}).Return([]models.Product{
+			{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Category: clothing},
+		}, int64(8), nil)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[{"code":"PROD001","price":"10.99","category":{"code":"clothing","name":"Clothing"}}],"products_available":8,"availability":{"page_variant_count":0,"total_products":8}}`, rec.Body.String())
+	})
+
+	t.Run("localizes category names", func(t *testing.T) {
+		shoes := &models.Category{Code: "shoes", Name: "Shoes", Translations: []models.CategoryTranslation{
+			{Locale: "de", Name: "Schuhe"},
+		}}
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, products.SearchFilters{Limit: 10}).Return([]models.Product{
+			{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Category: clothing},
+			{Code: "PROD002", Price: decimal.RequireFromString("12.49"), Category: shoes},
+		}, int64(2), nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/catalog", nil)
+		req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[
+			{"code":"PROD001","price":"10.99","category":{"code":"clothing","name":"Clothing"}},
+			{"code":"PROD002","price":"12.49","category":{"code":"shoes","name":"Schuhe"}}
+		],"products_available":2,"availability":{"page_variant_count":0,"total_products":2}}`, rec.Body.String())
+	})
+
+	t.Run("passes filters to the repository", func(t *testing.T) {
+		price := decimal.RequireFromString("20")
+		yes, no := true, false
+		since := time.Date(2025, 6, 1, 8, 30, 0, 0, time.UTC)
+		sinceWithOffset := time.Date(2025, 6, 1, 10, 30, 0, 0, time.FixedZone("", 2*60*60))
+		tests := []struct {
+			name    string
+			query   string
+			filters products.SearchFilters
+		}{
+			{"pagination", "offset=5&limit=3", products.SearchFilters{Offset: 5, Limit: 3}},
+			{"limit clamped to max", "limit=1000", products.SearchFilters{Limit: 100}},
+			{"limit clamped to min", "limit=0", products.SearchFilters{Limit: 1}},
+			{"price less than", "priceLessThan=20", products.SearchFilters{Limit: 10, PriceLessThan: &price}},
+			{"price equals", "priceEquals=20", products.SearchFilters{Limit: 10, PriceEquals: &price}},
+			{"price applies to products", "priceLessThan=20&priceAppliesTo=product", products.SearchFilters{Limit: 10, PriceLessThan: &price}},
+			{"price applies to variants", "priceLessThan=20&priceAppliesTo=variant", products.SearchFilters{Limit: 10, PriceLessThan: &price, VariantPrices: true}},
+			{"variant name", "variant=Medium", products.SearchFilters{Limit: 10, Variant: "Medium"}},
+			{"sku prefix", "skuPrefix=SKU00", products.SearchFilters{Limit: 10, SKUPrefix: "SKU00"}},
+			{"variant with category", "variant=variant%20a&category=shoes", products.SearchFilters{Limit: 10, Category: "shoes", Variant: "variant a"}},
+			{"has variants", "hasVariants=true", products.SearchFilters{Limit: 10, HasVariants: &yes}},
+			{"has no variants", "hasVariants=false", products.SearchFilters{Limit: 10, HasVariants: &no}},
+			{"has variants absent", "hasVariants=", products.SearchFilters{Limit: 10}},
+			{"include unavailable", "includeUnavailable=true", products.SearchFilters{Limit: 10, IncludeUnavailable: true}},
+			{"exclude unavailable", "includeUnavailable=false", products.SearchFilters{Limit: 10}},
+			{"sort", "sort=price_desc", products.SearchFilters{Limit: 10, Sort: products.SortPriceDesc}},
+			{"query", "q=prod00", products.SearchFilters{Limit: 10, Query: "prod00"}},
+			{"identical duplicates", "limit=5&category=shoes&limit=5&category=shoes", products.SearchFilters{Limit: 5, Category: "shoes"}},
+			{"other parameters repeated", "variant=Small&variant=Large&pretty=false&pretty=true", products.SearchFilters{Limit: 10, Variant: "Small"}},
+			{"query by relevance", "q=shoes&sort=relevance", products.SearchFilters{Limit: 10, Query: "shoes", Sort: products.SortRelevance}},
+			{"modified since", "modifiedSince=2025-06-01T08:30:00Z", products.SearchFilters{Limit: 10, ModifiedSince: &since}},
+			{"modified since with offset", "modifiedSince=2025-06-01T10:30:00%2B02:00", products.SearchFilters{Limit: 10, ModifiedSince: &sinceWithOffset}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("List", mock.Anything, tt.filters).Return([]models.Product{}, int64(0), nil)
+
+				rec := httptest.NewRecorder()
+				newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?"+tt.query, nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.JSONEq(t, `{"products":[],"products_available":0,"availability":{"page_variant_count":0,"total_products":0}}`, rec.Body.String())
+				repo.AssertExpectations(t)
+			})
+		}
+	})
+
+	t.Run("rejects invalid filters", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			query string
+		}{
+			{"negative offset", "offset=-1"},
+			{"non numeric limit", "limit=ten"},
+			{"malformed price", "priceLessThan=cheap"},
+			{"malformed exact price", "priceEquals=cheap"},
+			{"negative exact price", "priceEquals=-1"},
+			{"exact price within a range", "priceEquals=20&priceLessThan=30"},
+			{"price applies to something else", "priceLessThan=20&priceAppliesTo=category"},
+			{"repeated price target", "priceAppliesTo=product&priceAppliesTo=variant"},
+			{"variant with wildcard", "variant=Med%25"},
+			{"variant too long", "variant=" + strings.Repeat("a", 65)},
+			{"sku prefix with wildcard", "skuPrefix=SKU%25"},
+			{"sku prefix too long", "skuPrefix=" + strings.Repeat("A", 33)},
+			{"has variants not a boolean", "hasVariants=maybe"},
+			{"has variants as a number", "hasVariants=1"},
+			{"has variants abbreviated", "hasVariants=t"},
+			{"has variants upper case", "hasVariants=TRUE"},
+			{"include unavailable not a boolean", "includeUnavailable=yes"},
+			{"unknown sort", "sort=popularity"},
+			{"relevance without query", "sort=relevance"},
+			{"repeated offset", "offset=0&offset=10"},
+			{"repeated limit", "limit=5&limit=50"},
+			{"repeated price", "priceLessThan=10&priceLessThan=20"},
+			{"repeated category", "category=shoes&category=bags"},
+			{"query too long", "q=" + strings.Repeat("a", 65)},
+			{"modified since not a timestamp", "modifiedSince=yesterday"},
+			{"modified since without time zone", "modifiedSince=2025-06-01T08:30:00"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := httptest.NewRecorder()
+				newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?"+tt.query, nil))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("understands formatted prices", func(t *testing.T) {
+		for _, query := range []string{"priceLessThan=%E2%82%AC1%2C299.50", "priceLessThan=1.299%2C50", "priceLessThan=1299%2C50"} {
+			repo := new(mockRepo)
+			repo.On("List", mock.Anything, mock.MatchedBy(func(f products.SearchFilters) bool {
+				return f.PriceLessThan != nil && f.PriceLessThan.Equal(decimal.RequireFromString("1299.50"))
+			})).Return([]models.Product{}, int64(0), nil)
+
+			rec := httptest.NewRecorder()
+			newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?"+query, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code, query)
+			repo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("explains the accepted price formats", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?priceLessThan=1%2C29.50", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"priceLessThan must be a price such as 1299.50, 1,299.50, 1.299,50 or €1299"}`, rec.Body.String())
+	})
+
+	t.Run("names the repeated parameter", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?limit=5&limit=50", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"limit must not be repeated with different values"}`, rec.Body.String())
+	})
+
+	t.Run("listings including unavailable products are not cached", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, mock.Anything).Return([]models.Product{}, int64(0), nil)
+		handler := newTestMux(newHandler(t, repo, Options{}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?includeUnavailable=true", nil))
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+		assert.Empty(t, rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, mock.Anything).Return(nil, int64(0), errors.New("boom"))
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("degrades without variants when allowed", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, products.SearchFilters{Limit: 10, AllowMissingVariants: true}).Return([]models.Product{
+			{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Category: clothing},
+		}, int64(8), products.ErrVariantsUnavailable)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{DegradeOnVariantError: true})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "true", rec.Header().Get("X-Degraded"))
+		assert.JSONEq(t, `{"products":[{"code":"PROD001","price":"10.99","category":{"code":"clothing","name":"Clothing"}}],"products_available":8,"availability":{"page_variant_count":0,"total_products":8},"degraded":true}`, rec.Body.String())
+	})
+
+	t.Run("still fails on other errors when degrading is allowed", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, mock.Anything).Return(nil, int64(0), errors.New("boom"))
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{DegradeOnVariantError: true})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-Degraded"))
+	})
+
+	t.Run("product details never degrade", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(models.Product{}, errors.New(`relation "product_variants" does not exist`))
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{DegradeOnVariantError: true})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/PROD001", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-Degraded"))
+	})
+}
+
+func TestHandleCategoryProducts(t *testing.T) {
+	res := []models.Product{
+		{Code: "PROD002", Price: decimal.RequireFromString("12.49"), Category: &models.Category{Code: "shoes", Name: "Shoes"}},
+	}
+	price := decimal.RequireFromString("20")
+
+	t.Run("answers as the catalog filtered by category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, products.SearchFilters{Offset: 1, Limit: 1, Category: "shoes", PriceLessThan: &price}).
+			Return(res, int64(3), nil).Twice()
+		mux := newTestMux(newHandler(t, repo, Options{}))
+
+		byPath := httptest.NewRecorder()
+		mux.ServeHTTP(byPath, httptest.NewRequest(http.MethodGet, "/categories/shoes/products?offset=1&limit=1&priceLessThan=20", nil))
+		byQuery := httptest.NewRecorder()
+		mux.ServeHTTP(byQuery, httptest.NewRequest(http.MethodGet, "/catalog?category=shoes&offset=1&limit=1&priceLessThan=20", nil))
+
+		assert.Equal(t, http.StatusOK, byPath.Code)
+		assert.Equal(t, byQuery.Code, byPath.Code)
+		assert.JSONEq(t, byQuery.Body.String(), byPath.Body.String())
+		for _, header := range []string{"X-Total-Count", "X-Offset", "X-Limit"} {
+			assert.Equal(t, byQuery.Header().Get(header), byPath.Header().Get(header), header)
+		}
+		assert.Contains(t, byPath.Header().Get("Link"), "/categories/shoes/products?")
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("pages through the category by following the links", func(t *testing.T) {
+		const total, limit = 60, 25
+		page := func(offset int) []models.Product {
+			res := make([]models.Product, min(limit, total-offset))
+			for i := range res {
+				res[i] = models.Product{Code: fmt.Sprintf("PROD%03d", offset+i), Price: decimal.RequireFromString("10")}
+			}
+			return res
+		}
+		repo := new(mockRepo)
+		for offset := 0; offset < total; offset += limit {
+			repo.On("List", mock.Anything, products.SearchFilters{Offset: offset, Limit: limit, Category: "shoes"}).
+				Return(page(offset), int64(total), nil).Once()
+		}
+		mux := newTestMux(newHandler(t, repo, Options{}))
+		next := regexp.MustCompile(`<http://example.com([^>]*)>; rel="next"`)
+
+		seen := map[string]bool{}
+		target := "/categories/shoes/products?limit=25"
+		for target != "" {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			require.Equal(t, http.StatusOK, rec.Code, target)
+
+			var body Response
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			for _, p := range body.Products {
+				assert.False(t, seen[p.Code], "%s listed twice", p.Code)
+				seen[p.Code] = true
+			}
+			target = ""
+			if m := next.FindStringSubmatch(rec.Header().Get("Link")); m != nil {
+				target = m[1]
+			}
+		}
+
+		assert.Len(t, seen, total)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("same category in the query", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, products.SearchFilters{Limit: 10, Category: "shoes"}).Return(res, int64(1), nil)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/categories/shoes/products?category=shoes", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects another category in the query", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/categories/shoes/products?category=bags", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"category must match the category of the path"}`, rec.Body.String())
+		repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects invalid filters", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/categories/shoes/products?priceLessThan=cheap", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+}
+
+func TestPaginationHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		offset int
+		total  int64
+		link   string
+	}{
+		{
+			name: "first page", query: "category=shoes&limit=10", offset: 0, total: 25,
+			link: `<http://example.com/catalog?category=shoes&limit=10&offset=10>; rel="next"`,
+		},
+		{
+			name: "middle page", query: "category=shoes&offset=10&limit=10&sort=price_asc", offset: 10, total: 25,
+			link: `<http://example.com/catalog?category=shoes&limit=10&offset=20&sort=price_asc>; rel="next", ` +
+				`<http://example.com/catalog?category=shoes&limit=10&offset=0&sort=price_asc>; rel="prev"`,
+		},
+		{
+			name: "last page", query: "priceLessThan=20&offset=20&limit=10", offset: 20, total: 25,
+			link: `<http://example.com/catalog?limit=10&offset=10&priceLessThan=20>; rel="prev"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockRepo)
+			repo.On("List", mock.Anything, mock.Anything).Return([]models.Product{}, tt.total, nil)
+
+			rec := httptest.NewRecorder()
+			newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?"+tt.query, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, strconv.FormatInt(tt.total, 10), rec.Header().Get("X-Total-Count"))
+			assert.Equal(t, strconv.Itoa(tt.offset), rec.Header().Get("X-Offset"))
+			assert.Equal(t, "10", rec.Header().Get("X-Limit"))
+			assert.Equal(t, tt.link, rec.Header().Get("Link"))
+		})
+	}
+
+	t.Run("limit is reported once clamped", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, mock.Anything).Return([]models.Product{}, int64(250), nil)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?limit=1000", nil))
+
+		assert.Equal(t, "100", rec.Header().Get("X-Limit"))
+		assert.Equal(t, `<http://example.com/catalog?limit=100&offset=100>; rel="next"`, rec.Header().Get("Link"))
+	})
+}
+
+func TestMaxOffset(t *testing.T) {
+	tests := []struct {
+		name   string
+		clamp  bool
+		offset string
+		status int
+		want   int
+	}{
+		{"strict under the max", false, "99", http.StatusOK, 99},
+		{"strict at the max", false, "100", http.StatusOK, 100},
+		{"strict over the max", false, "101", http.StatusBadRequest, 0},
+		{"lenient under the max", true, "99", http.StatusOK, 99},
+		{"lenient at the max", true, "100", http.StatusOK, 100},
+		{"lenient over the max", true, "999999", http.StatusOK, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockRepo)
+			repo.On("List", mock.Anything, products.SearchFilters{Offset: tt.want, Limit: 10}).Return([]models.Product{}, int64(0), nil)
+			h := newHandler(t, repo, Options{MaxOffset: 100, ClampOffset: tt.clamp})
+
+			rec := httptest.NewRecorder()
+			newTestMux(h).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?offset="+tt.offset, nil))
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, strconv.Itoa(tt.want), rec.Header().Get("X-Offset"))
+				repo.AssertExpectations(t)
+			} else {
+				assert.JSONEq(t, `{"error":"offset must be at most 100, narrow down the results with filters to go further"}`, rec.Body.String())
+				assert.Empty(t, repo.Calls)
+			}
+		})
+	}
+
+	t.Run("defaults to DefaultMaxOffset", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?offset=10001", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("negative max fails construction", func(t *testing.T) {
+		_, err := NewCatalogHandler(new(mockRepo), Options{MaxOffset: -1})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestHandleGet_Availability(t *testing.T) {
+	page := []models.Product{
+		{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Variants: []models.Variant{{SKU: "SKU001A"}, {SKU: "SKU001B"}}},
+		{Code: "PROD002", Price: decimal.RequireFromString("12.49"), Variants: []models.Variant{{SKU: "SKU002A"}}},
+		{Code: "PROD003", Price: decimal.RequireFromString("5.00")},
+	}
+	get := func(t *testing.T, opts Options) *httptest.ResponseRecorder {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, products.SearchFilters{Limit: 10}).Return(page, int64(8), nil)
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, opts)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+		return rec
+	}
+	var body struct {
+		ProductsAvailable int64        `json:"products_available"`
+		Availability      Availability `json:"availability"`
+	}
+
+	t.Run("products_available aliases the total by default", func(t *testing.T) {
+		rec := get(t, Options{})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, Availability{PageVariantCount: 3, TotalProducts: 8}, body.Availability)
+		assert.Equal(t, int64(8), body.ProductsAvailable)
+	})
+
+	t.Run("products_available aliases the page variant count when configured", func(t *testing.T) {
+		rec := get(t, Options{ProductsAvailableAlias: AliasPageVariantCount})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, Availability{PageVariantCount: 3, TotalProducts: 8}, body.Availability)
+		assert.Equal(t, int64(3), body.ProductsAvailable)
+	})
+
+	t.Run("unknown alias fails construction", func(t *testing.T) {
+		_, err := NewCatalogHandler(new(mockRepo), Options{ProductsAvailableAlias: "in_stock"})
+
+		assert.EqualError(t, err, "products_available alias must be total_products or page_variant_count")
+	})
+}
+
+func TestHandleGrouped(t *testing.T) {
+	get := func(repo *mockRepo, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/grouped?"+query, nil))
+		return rec
+	}
+
+	t.Run("groups the products by category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListByCategories", mock.Anything, []string{"shoes", "clothing", "bags"}, 2).Return(map[string]products.CategoryProducts{
+			"shoes": {Products: []models.Product{
+				{Code: "PROD002", Price: decimal.RequireFromString("12.49"), Variants: []models.Variant{{SKU: "SKU002A"}}},
+			}, Total: 1},
+			"clothing": {Products: []models.Product{
+				{Code: "PROD001", Price: decimal.RequireFromString("10.99")},
+				{Code: "PROD004", Price: decimal.RequireFromString("15")},
+			}, Total: 3},
+			"bags": {},
+		}, nil)
+
+		rec := get(repo, "categories=shoes,clothing,bags,shoes&perCategory=2")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+		assert.JSONEq(t, `{"categories":{
+			"shoes":{"products":[{"code":"PROD002","price":"12.49"}],"products_available":1,"availability":{"page_variant_count":1,"total_products":1}},
+			"clothing":{"products":[{"code":"PROD001","price":"10.99"},{"code":"PROD004","price":"15.00"}],"products_available":3,"availability":{"page_variant_count":0,"total_products":3}},
+			"bags":{"products":[],"products_available":0,"availability":{"page_variant_count":0,"total_products":0}}
+		}}`, rec.Body.String())
+	})
+
+	t.Run("three products per category by default", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListByCategories", mock.Anything, []string{"shoes"}, 3).Return(map[string]products.CategoryProducts{}, nil)
+
+		rec := get(repo, "categories=shoes")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"categories":{"shoes":{"products":[],"products_available":0,"availability":{"page_variant_count":0,"total_products":0}}}}`, rec.Body.String())
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			query string
+		}{
+			{"missing categories", ""},
+			{"empty category", "categories=shoes,,bags"},
+			{"too many categories", "categories=a,b,c,d,e,f,g,h,i,j,k"},
+			{"perCategory not a number", "categories=shoes&perCategory=few"},
+			{"perCategory zero", "categories=shoes&perCategory=0"},
+			{"perCategory over the max", "categories=shoes&perCategory=21"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := get(repo, tt.query)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+}
+
+func TestHandleExport(t *testing.T) {
+	export := func(repo *mockRepo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	batches := [][]models.Product{
+		{
+			{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Variants: []models.Variant{{Name: "Small", SKU: "SKU001A"}}},
+			{Code: "PROD002", Price: decimal.RequireFromString("12.49")},
+		},
+		{
+			{Code: "PROD003", Price: decimal.RequireFromString("5")},
+		},
+	}
+	walk := func(batches ...[]models.Product) func(mock.Arguments) {
+		return func(args mock.Arguments) {
+			fn := args.Get(2).(func([]models.Product) error)
+			for _, b := range batches {
+				if err := fn(b); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	t.Run("streams a product per line", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllFunc", mock.Anything, "", mock.Anything).Run(walk(batches...)).Return(nil)
+
+		rec := export(repo, "/catalog/export")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+		assert.True(t, rec.Flushed)
+
+		body := rec.Body.String()
+		assert.True(t, strings.HasPrefix(body, `{"code":"PROD001","price":"10.99","variants":[{"name":"Small","sku":"SKU001A","price":"10.99"}]}`+"\n"))
+
+		var codes []string
+		scanner := bufio.NewScanner(strings.NewReader(body))
+		for scanner.Scan() {
+			var p dto.Product
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &p), scanner.Text())
+			codes = append(codes, p.Code)
+		}
+		assert.Equal(t, []string{"PROD001", "PROD002", "PROD003"}, codes)
+	})
+
+	t.Run("limited to a category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllFunc", mock.Anything, "shoes", mock.Anything).Run(walk(batches[1])).Return(nil)
+
+		rec := export(repo, "/catalog/export?category=shoes")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, strings.Count(rec.Body.String(), "\n"))
+	})
+
+	t.Run("empty catalog", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllFunc", mock.Anything, "", mock.Anything).Return(nil)
+
+		rec := export(repo, "/catalog/export")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("repository error before streaming", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllFunc", mock.Anything, "", mock.Anything).Return(errors.New("boom"))
+
+		rec := export(repo, "/catalog/export")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.JSONEq(t, `{"error":"boom"}`, rec.Body.String())
+	})
+
+	t.Run("repository error while streaming", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+		t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+		repo := new(mockRepo)
+		repo.On("ListAllFunc", mock.Anything, "", mock.Anything).Run(walk(batches[0])).Return(errors.New("boom"))
+
+		rec := export(repo, "/catalog/export")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 2, strings.Count(rec.Body.String(), "\n"))
+	})
+}
+
+func TestHandleLookup(t *testing.T) {
+	product := func(code string) models.Product {
+		return models.Product{Code: code, Price: decimal.RequireFromString("10")}
+	}
+	// The repository returns the products in an order unrelated to the request
+	shuffled := []models.Product{product("PROD005"), product("PROD001"), product("PROD003")}
+
+	get := func(repo *mockRepo, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/lookup?"+query, nil))
+		return rec
+	}
+
+	t.Run("preserves the requested order", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCodes", mock.Anything, []string{"PROD003", "PROD009", "PROD001", "PROD005"}).Return(shuffled, nil)
+
+		rec := get(repo, "codes=PROD003,PROD009,PROD001,PROD005,PROD003")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"products":[
+				{"code":"PROD003","price":"10.00"},
+				{"code":"PROD001","price":"10.00"},
+				{"code":"PROD005","price":"10.00"}
+			],
+			"missing":["PROD009"]
+		}`, rec.Body.String())
+	})
+
+	t.Run("repository order on request", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCodes", mock.Anything, []string{"PROD003", "PROD001", "PROD005"}).Return(shuffled, nil)
+
+		rec := get(repo, "codes=PROD003,PROD001,PROD005&preserveOrder=false")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"products":[
+				{"code":"PROD005","price":"10.00"},
+				{"code":"PROD001","price":"10.00"},
+				{"code":"PROD003","price":"10.00"}
+			],
+			"missing":[]
+		}`, rec.Body.String())
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		tooMany := make([]string, 101)
+		for i := range tooMany {
+			tooMany[i] = fmt.Sprintf("PROD%03d", i)
+		}
+		tests := []struct {
+			name  string
+			query string
+		}{
+			{"missing codes", ""},
+			{"invalid code", "codes=PROD001,prod-2"},
+			{"empty code", "codes=PROD001,"},
+			{"too many codes", "codes=" + strings.Join(tooMany, ",")},
+			{"invalid preserveOrder", "codes=PROD001&preserveOrder=maybe"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := get(repo, tt.query)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCodes", mock.Anything, []string{"PROD001"}).Return(nil, errors.New("boom"))
+
+		rec := get(repo, "codes=PROD001")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestHandleDeleteVariant(t *testing.T) {
+	t.Run("deletes the variant", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteVariant", mock.Anything, "PROD001", "SKU001A").Return(nil)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/catalog/PROD001/variants/SKU001A", nil))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("unknown variant", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteVariant", mock.Anything, "PROD001", "SKU999Z").Return(products.ErrVariantNotFound)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/catalog/PROD001/variants/SKU999Z", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"variant not found"}`, rec.Body.String())
+	})
+
+	t.Run("a variant of another product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteVariant", mock.Anything, "PROD001", "SKU002A").Return(products.ErrVariantNotFound)
+		publisher := &recordingPublisher{}
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{Events: publisher})).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/catalog/PROD001/variants/SKU002A", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"variant not found"}`, rec.Body.String())
+		assert.Empty(t, publisher.events)
+	})
+
+	t.Run("code typed by hand", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteVariant", mock.Anything, "PROD001", "SKU001A").Return(nil)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/catalog/prod001/variants/SKU001A", nil))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		repo.AssertExpectations(t)
+	})
+
+	for _, target := range []string{"/catalog/PROD001/variants/SKU%25001", "/catalog/prod-1/variants/SKU001A"} {
+		t.Run("invalid "+target, func(t *testing.T) {
+			repo := new(mockRepo)
+
+			rec := httptest.NewRecorder()
+			newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, target, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			repo.AssertNotCalled(t, "DeleteVariant", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandleGetSpecific(t *testing.T) {
+	updatedAt := time.Date(2025, 6, 1, 8, 30, 0, 0, time.UTC)
+	product := models.Product{
+		Code:      "PROD001",
+		Price:     decimal.RequireFromString("10.99"),
+		Category:  &models.Category{Code: "clothing", Name: "Clothing"},
+		UpdatedAt: updatedAt,
+		Variants: []models.Variant{
+			{Name: "Variant A", SKU: "SKU001A", Price: decimal.RequireFromString("11.99"), UpdatedAt: updatedAt},
+			{Name: "Variant B", SKU: "SKU001B", UpdatedAt: updatedAt.Add(time.Hour)},
+		},
+	}
+
+	get := func(repo *mockRepo, path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("returns details with inherited variant prices", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001", nil)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Sun, 01 Jun 2025 09:30:00 GMT", rec.Header().Get("Last-Modified"))
+		assert.JSONEq(t, `{
+			"code":"PROD001","price":"10.99",
+			"category":{"code":"clothing","name":"Clothing"},
+			"variants":[
+				{"name":"Variant A","sku":"SKU001A","price":"11.99"},
+				{"name":"Variant B","sku":"SKU001B","price":"10.99"}
+			]
+		}`, rec.Body.String())
+	})
+
+	t.Run("inherited variant prices follow the product price", func(t *testing.T) {
+		repriced := product
+		repriced.Price = decimal.RequireFromString("8.49")
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil).Once()
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(repriced, nil).Once()
+
+		before, after := get(repo, "/catalog/PROD001", nil), get(repo, "/catalog/PROD001", nil)
+
+		assert.Contains(t, before.Body.String(), `{"name":"Variant B","sku":"SKU001B","price":"10.99"}`)
+		assert.Contains(t, after.Body.String(), `{"name":"Variant A","sku":"SKU001A","price":"11.99"}`)
+		assert.Contains(t, after.Body.String(), `{"name":"Variant B","sku":"SKU001B","price":"8.49"}`)
+	})
+
+	t.Run("variants expose only their public fields", func(t *testing.T) {
+		withIDs := product
+		withIDs.Variants = []models.Variant{
+			{ID: 3, ProductID: 1, Name: "Variant A", SKU: "SKU001A", Price: decimal.RequireFromString("11.99"), UpdatedAt: updatedAt},
+		}
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(withIDs, nil)
+
+		rec := get(repo, "/catalog/PROD001", nil)
+
+		var body struct {
+			Variants []map[string]any `json:"variants"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Len(t, body.Variants, 1)
+		assert.ElementsMatch(t, []string{"name", "sku", "price"}, slices.Collect(maps.Keys(body.Variants[0])))
+	})
+
+	t.Run("modified since the header", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001", http.Header{"If-Modified-Since": {"Sun, 01 Jun 2025 09:00:00 GMT"}})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotEmpty(t, rec.Body.String())
+	})
+
+	t.Run("not modified since the header", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001", http.Header{"If-Modified-Since": {"Sun, 01 Jun 2025 09:30:00 GMT"}})
+
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Equal(t, "Sun, 01 Jun 2025 09:30:00 GMT", rec.Header().Get("Last-Modified"))
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("malformed header is ignored", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001", http.Header{"If-Modified-Since": {"not a date"}})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("images", func(t *testing.T) {
+		withImages := product
+		withImages.Variants = nil
+		withImages.Images = []models.Image{
+			{ID: 4, URL: "https://cdn.example.com/front.jpg", Position: 1, AltText: "Front"},
+			{ID: 7, URL: "https://cdn.example.com/back.jpg", Position: 2},
+		}
+		tests := []struct {
+			name   string
+			target string
+			want   string
+		}{
+			{"first image by default", "/catalog/PROD001",
+				`[{"id":4,"url":"https://cdn.example.com/front.jpg","position":1,"alt_text":"Front"}]`},
+			{"all images on request", "/catalog/PROD001?allImages=true",
+				`[{"id":4,"url":"https://cdn.example.com/front.jpg","position":1,"alt_text":"Front"},{"id":7,"url":"https://cdn.example.com/back.jpg","position":2}]`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("GetByCode", mock.Anything, "PROD001").Return(withImages, nil)
+
+				rec := get(repo, tt.target, nil)
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.JSONEq(t, `{
+					"code":"PROD001","price":"10.99",
+					"category":{"code":"clothing","name":"Clothing"},
+					"variants":[],
+					"images":`+tt.want+`
+				}`, rec.Body.String())
+			})
+		}
+	})
+
+	t.Run("invalid allImages", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := get(repo, "/catalog/PROD001?allImages=maybe", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"allImages must be true or false"}`, rec.Body.String())
+		assert.Empty(t, repo.Calls)
+	})
+
+	t.Run("normalizes the product code", func(t *testing.T) {
+		for _, target := range []string{"/catalog/PROD001", "/catalog/prod001", "/catalog/%20PROD001%20", "/catalog/%09pRoD001"} {
+			t.Run(target, func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+				rec := get(repo, target, nil)
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Contains(t, rec.Body.String(), `"code":"PROD001"`)
+			})
+		}
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD999").Return(models.Product{}, products.ErrProductNotFound)
+
+		rec := get(repo, "/catalog/PROD999", nil)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"product not found"}`, rec.Body.String())
+	})
+
+	t.Run("invalid product code", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := get(repo, "/catalog/prod-1", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		repo.AssertNotCalled(t, "GetByCode", mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandleExists(t *testing.T) {
+	exists := func(repo *mockRepo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("existing product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Exists", mock.Anything, "PROD001").Return(true, nil)
+
+		rec := exists(repo, "/catalog/prod001/exists")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"exists":true}`, rec.Body.String())
+		repo.AssertNotCalled(t, "GetByCode", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Exists", mock.Anything, "PROD999").Return(false, nil)
+
+		rec := exists(repo, "/catalog/PROD999/exists")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"exists":false}`, rec.Body.String())
+	})
+
+	t.Run("malformed code", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := exists(repo, "/catalog/prod-1/exists")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"invalid product code"}`, rec.Body.String())
+		assert.Empty(t, repo.Calls)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Exists", mock.Anything, "PROD001").Return(false, errors.New("connection refused"))
+
+		rec := exists(repo, "/catalog/PROD001/exists")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestHandleRelated(t *testing.T) {
+	related := func(repo *mockRepo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("products of the same category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetRelated", mock.Anything, "PROD001", 4).Return([]models.Product{
+			{Code: "PROD004", Price: decimal.RequireFromString("15"), Category: &models.Category{Code: "clothing", Name: "Clothing"}},
+			{Code: "PROD007", Price: decimal.RequireFromString("21"), Category: &models.Category{Code: "clothing", Name: "Clothing"}},
+		}, nil)
+
+		rec := related(repo, "/catalog/prod001/related")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[
+			{"code":"PROD004","price":"15.00","category":{"code":"clothing","name":"Clothing"}},
+			{"code":"PROD007","price":"21.00","category":{"code":"clothing","name":"Clothing"}}
+		]}`, rec.Body.String())
+	})
+
+	t.Run("no siblings", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetRelated", mock.Anything, "PROD006", 4).Return([]models.Product{}, nil)
+
+		rec := related(repo, "/catalog/PROD006/related")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[]}`, rec.Body.String())
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetRelated", mock.Anything, "PROD999", 4).Return(nil, products.ErrProductNotFound)
+
+		rec := related(repo, "/catalog/PROD999/related")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"product not found"}`, rec.Body.String())
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		tests := []struct {
+			query string
+			limit int
+		}{
+			{"limit=2", 2},
+			{"limit=0", 1},
+			{"limit=1000", 20},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("GetRelated", mock.Anything, "PROD001", tt.limit).Return([]models.Product{}, nil)
+
+				rec := related(repo, "/catalog/PROD001/related?"+tt.query)
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				repo.AssertExpectations(t)
+			})
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		for _, target := range []string{"/catalog/PROD001/related?limit=four", "/catalog/prod-1/related"} {
+			repo := new(mockRepo)
+
+			rec := related(repo, target)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+			assert.Empty(t, repo.Calls)
+		}
+	})
+}
+
+func TestHandleCreate(t *testing.T) {
+	post := func(h *CatalogHandler, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(h).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/catalog", strings.NewReader(body)))
+		return rec
+	}
+
+	variants := func(skus ...string) string {
+		parts := make([]string, len(skus))
+		for i, sku := range skus {
+			parts[i] = fmt.Sprintf(`{"name":"Variant %d","sku":%q}`, i, sku)
+		}
+		return `{"code":"PROD009","price":"19.99","category":"shoes","variants":[` + strings.Join(parts, ",") + `]}`
+	}
+
+	t.Run("exactly at the variant limit", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+			return p.Code == "PROD009" && p.Category.Code == "shoes" && len(p.Variants) == 3
+		})).Run(func(args mock.Arguments) {
+			p := args.Get(1).(*models.Product)
+			p.Category.Name = "Shoes"
+		}).Return(nil)
+
+		rec := post(newHandler(t, repo, Options{MaxVariantsPerProduct: 3}), variants("SKU009A", "SKU009B", "SKU009C"))
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "http://example.com/catalog/PROD009", rec.Header().Get("Location"))
+		assert.JSONEq(t, `{
+			"code":"PROD009","price":"19.99",
+			"category":{"code":"shoes","name":"Shoes"},
+			"variants":[
+				{"name":"Variant 0","sku":"SKU009A","price":"19.99"},
+				{"name":"Variant 1","sku":"SKU009B","price":"19.99"},
+				{"name":"Variant 2","sku":"SKU009C","price":"19.99"}
+			]
+		}`, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("without the representation with return=minimal", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		req := httptest.NewRequest(http.MethodPost, "/catalog", strings.NewReader(variants("SKU009A")))
+		req.Header.Set("Prefer", "return=minimal")
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "http://example.com/catalog/PROD009", rec.Header().Get("Location"))
+		assert.Equal(t, "return=minimal", rec.Header().Get("Preference-Applied"))
+		assert.Empty(t, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("over the variant limit", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := post(newHandler(t, repo, Options{MaxVariantsPerProduct: 3}), variants("SKU009A", "SKU009B", "SKU009C", "SKU009D"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"a product can have at most 3 variants"}`, rec.Body.String())
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("duplicate sku within the batch", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := post(newHandler(t, repo, Options{MaxVariantsPerProduct: 3}), variants("SKU009A", "SKU009B", "SKU009A"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"duplicate variant sku SKU009A"}`, rec.Body.String())
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects invalid payloads", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{"malformed json", `{"code":`},
+			{"invalid code", `{"code":"P1","price":"1"}`},
+			{"non positive price", `{"code":"PROD009","price":"0"}`},
+			{"variant without name", `{"code":"PROD009","price":"1","variants":[{"sku":"SKU009A"}]}`},
+			{"negative variant price", `{"code":"PROD009","price":"1","variants":[{"name":"A","sku":"SKU009A","price":"-1"}]}`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := post(newHandler(t, repo, Options{MaxVariantsPerProduct: 3}), tt.body)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("rejects prices breaking the price constraints with 422", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+			want string
+		}{
+			{"price scale", `{"code":"PROD009","price":"19.999"}`, "price must have at most 2 decimal places"},
+			{"price over the max", `{"code":"PROD009","price":"1000"}`, "price must be at most 500"},
+			{"variant price scale", `{"code":"PROD009","price":"1","variants":[{"name":"A","sku":"SKU009A","price":"0.001"}]}`, "variant SKU009A price must have at most 2 decimal places"},
+			{"number price scale", `{"code":"PROD009","price":19.999}`, "price must have at most 2 decimal places"},
+			{"scientific notation", `{"code":"PROD009","price":1e2}`, "price must not use scientific notation"},
+			{"variant price in scientific notation", `{"code":"PROD009","price":"1","variants":[{"name":"A","sku":"SKU009A","price":"5E-1"}]}`, "price must not use scientific notation"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := post(newHandler(t, repo, Options{MaxPrice: decimal.NewFromInt(500)}), tt.body)
+
+				assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+				assert.JSONEq(t, fmt.Sprintf(`{"error":%q}`, tt.want), rec.Body.String())
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("inherited variant price", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		rec := post(newHandler(t, repo, Options{}), `{"code":"PROD009","price":"19.99","variants":[{"name":"A","sku":"SKU009A","price":"0"}]}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+
+	t.Run("maps repository errors", func(t *testing.T) {
+		tests := []struct {
+			err    error
+			status int
+		}{
+			// The repository classifies the missing category of a new product
+			// as invalid
+			{errs.WithKind(errs.Invalid, products.ErrCategoryNotFound), http.StatusBadRequest},
+			{products.ErrProductExists, http.StatusConflict},
+			{errs.New(errs.NotFound, "gone"), http.StatusNotFound},
+			{errs.New(errs.Internal, "broken"), http.StatusInternalServerError},
+			{errors.New("boom"), http.StatusInternalServerError},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.err.Error(), func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("Create", mock.Anything, mock.Anything).Return(tt.err)
+
+				rec := post(newHandler(t, repo, Options{MaxVariantsPerProduct: 3}), variants("SKU009A"))
+
+				assert.Equal(t, tt.status, rec.Code)
+			})
+		}
+	})
+
+	t.Run("negative max fails construction", func(t *testing.T) {
+		_, err := NewCatalogHandler(new(mockRepo), Options{MaxVariantsPerProduct: -1})
+
+		assert.EqualError(t, err, "max variants per product must not be negative")
+	})
+}
+
+func TestHandleValidate(t *testing.T) {
+	validate := func(repo *mockRepo, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/catalog/validate", strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("reports each product of a mixed payload", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("FindExisting", mock.Anything, products.ExistingKeys{
+			Codes:      []string{"PROD001", "PROD009", "PROD010", "PROD011", "bad"},
+			SKUs:       []string{"SKU001A", "SKU009A", "SKU010A"},
+			Categories: []string{"bags", "shoes"},
+		}).Return(products.ExistingKeys{
+			Codes:      []string{"PROD001"},
+			SKUs:       []string{"SKU001A"},
+			Categories: []string{"shoes"},
+		}, nil)
+
+		rec := validate(repo, `[
+			{"code":"PROD009","price":"19.99","category":"shoes","variants":[{"name":"Small","sku":"SKU009A"}]},
+			{"code":"PROD001","price":"10.99","category":"shoes"},
+			{"code":"PROD009","price":"20.00","category":"shoes"},
+			{"code":"PROD010","price":"5.99","variants":[{"name":"Small","sku":"SKU009A"},{"name":"Large","sku":"SKU010A"}]},
+			{"code":"bad","price":"-1","category":"bags","variants":[{"name":"","sku":"SKU001A"}]},
+			{"code":"PROD011","price":"5.999","category":"shoes"}
+		]`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[
+			{"index":0,"code":"PROD009","outcome":"would_create","errors":[],"warnings":[]},
+			{"index":1,"code":"PROD001","outcome":"would_conflict","errors":["product code PROD001 already exists"],"warnings":[]},
+			{"index":2,"code":"PROD009","outcome":"would_conflict","errors":["product code PROD009 is already used by product 0 of the payload"],"warnings":[]},
+			{"index":3,"code":"PROD010","outcome":"would_conflict",
+				"errors":["variant sku SKU009A is already used by product 0 of the payload"],
+				"warnings":["product has no category"]},
+			{"index":4,"code":"bad","outcome":"invalid",
+				"errors":["invalid product code","price must be positive","variant name is required","category \"bags\" not found","variant sku SKU001A already exists"],
+				"warnings":[]},
+			{"index":5,"code":"PROD011","outcome":"invalid","errors":["price must have at most 2 decimal places"],"warnings":[]}
+		]}`, rec.Body.String())
+	})
+
+	t.Run("accepts a single product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("FindExisting", mock.Anything, products.ExistingKeys{
+			Codes:      []string{"PROD009"},
+			Categories: []string{"shoes"},
+		}).Return(products.ExistingKeys{Categories: []string{"shoes"}}, nil)
+
+		rec := validate(repo, `{"code":"PROD009","price":"19.99","category":"shoes"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[{"index":0,"code":"PROD009","outcome":"would_create","errors":[],"warnings":[]}]}`, rec.Body.String())
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects invalid payloads", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{"empty array", `[]`},
+			{"unknown field", `[{"code":"PROD009","price":"19.99","colour":"red"}]`},
+			{"not a product", `"PROD009"`},
+			{"too many products", "[" + strings.TrimSuffix(strings.Repeat(`{"code":"PROD009"},`, maxValidateProducts+1), ",") + "]"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := validate(repo, tt.body)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("FindExisting", mock.Anything, mock.Anything).Return(products.ExistingKeys{}, errors.New("boom"))
+
+		rec := validate(repo, `{"code":"PROD009","price":"19.99"}`)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestCurrencyConversion(t *testing.T) {
+	t.Run("list prices in the requested currency", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, products.SearchFilters{Limit: 10}).Return([]models.Product{
+			{Code: "PROD001", Price: decimal.RequireFromString("20.01")},
+			{Code: "PROD002", Price: decimal.RequireFromString("10.99")},
+		}, int64(2), nil)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?currency=gbp", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[{"code":"PROD001","price":"10.00"},{"code":"PROD002","price":"5.50"}],"products_available":2,"availability":{"page_variant_count":0,"total_products":2}}`, rec.Body.String())
+	})
+
+	t.Run("rounded with the configured mode", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, products.SearchFilters{Limit: 10}).Return([]models.Product{
+			{Code: "PROD001", Price: decimal.RequireFromString("20.01")},
+		}, int64(1), nil)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{Rounding: pricing.HalfUp})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog?currency=gbp", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[{"code":"PROD001","price":"10.01"}],"products_available":1,"availability":{"page_variant_count":0,"total_products":1}}`, rec.Body.String())
+	})
+
+	t.Run("inherited variant prices are converted after inheritance", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(models.Product{
+			Code:  "PROD001",
+			Price: decimal.RequireFromString("20.03"),
+			Variants: []models.Variant{
+				{Name: "Variant A", SKU: "SKU001A", Price: decimal.RequireFromString("21.00")},
+				{Name: "Variant B", SKU: "SKU001B"},
+			},
+		}, nil)
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/PROD001?currency=GBP", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"code":"PROD001","price":"10.02",
+			"variants":[
+				{"name":"Variant A","sku":"SKU001A","price":"10.50"},
+				{"name":"Variant B","sku":"SKU001B","price":"10.02"}
+			]
+		}`, rec.Body.String())
+	})
+
+	t.Run("unsupported currency", func(t *testing.T) {
+		for _, path := range []string{"/catalog?currency=USD", "/catalog/PROD001?currency=USD"} {
+			repo := new(mockRepo)
+
+			rec := httptest.NewRecorder()
+			newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, `{"error":"unsupported currency \"USD\", supported currencies are EUR, GBP"}`, rec.Body.String())
+			assert.Empty(t, repo.Calls)
+		}
+	})
+}
+
+func TestPriceFormat(t *testing.T) {
+	// 0.1 + 0.2 is 0.30000000000000004 as a float
+	price := decimal.RequireFromString("0.1").Add(decimal.RequireFromString("0.2"))
+	product := models.Product{
+		Code:     "PROD001",
+		Price:    price,
+		Variants: []models.Variant{{Name: "Variant A", SKU: "SKU001A"}},
+	}
+
+	get := func(repo *mockRepo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("strings by default", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"code":"PROD001","price":"0.30","variants":[{"name":"Variant A","sku":"SKU001A","price":"0.30"}]}`, rec.Body.String())
+	})
+
+	t.Run("numbers on request", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001?priceFormat=number")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"code":"PROD001","price":0.30,"variants":[{"name":"Variant A","sku":"SKU001A","price":0.30}]}`, rec.Body.String())
+	})
+
+	t.Run("numbers in the listing", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, mock.Anything).Return([]models.Product{product}, int64(1), nil)
+
+		rec := get(repo, "/catalog?priceFormat=number")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"products":[{"code":"PROD001","price":0.30}],"products_available":1,"availability":{"page_variant_count":1,"total_products":1}}`, rec.Body.String())
+	})
+
+	t.Run("numbers in the grouped listing", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListByCategories", mock.Anything, []string{"clothing"}, 3).Return(map[string]products.CategoryProducts{
+			"clothing": {Products: []models.Product{product}, Total: 1},
+		}, nil)
+
+		rec := get(repo, "/catalog/grouped?categories=clothing&priceFormat=number")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"categories":{"clothing":{"products":[{"code":"PROD001","price":0.30}],"products_available":1,"availability":{"page_variant_count":1,"total_products":1}}}}`, rec.Body.String())
+	})
+
+	t.Run("numbers in the related products", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetRelated", mock.Anything, "PROD004", 4).Return([]models.Product{product}, nil)
+
+		rec := get(repo, "/catalog/PROD004/related?priceFormat=number")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"products":[{"code":"PROD001","price":0.30}]}`, rec.Body.String())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := get(repo, "/catalog/PROD001?priceFormat=float")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"priceFormat must be string or number"}`, rec.Body.String())
+		assert.Empty(t, repo.Calls)
+	})
+}
+
+func TestAmountFormat(t *testing.T) {
+	product := models.Product{
+		Code:  "PROD001",
+		Price: decimal.RequireFromString("10.99"),
+		Variants: []models.Variant{
+			{Name: "Variant A", SKU: "SKU001A"},
+			{Name: "Variant B", SKU: "SKU001B", Price: decimal.RequireFromString("12.50")},
+		},
+	}
+	opts := Options{
+		Rates:      currency.StaticRates{"GBP": decimal.RequireFromString("0.85"), "JPY": decimal.RequireFromString("160")},
+		MinorUnits: currency.MinorUnits{"JPY": 0},
+	}
+
+	get := func(repo *mockRepo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, opts)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("major units by default", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+		rec := get(repo, "/catalog/PROD001?amountFormat=major")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"code":"PROD001","price":"10.99","variants":[{"name":"Variant A","sku":"SKU001A","price":"10.99"},{"name":"Variant B","sku":"SKU001B","price":"12.50"}]}`, rec.Body.String())
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{
+			"cents of the base currency",
+			"/catalog/PROD001?amountFormat=minor",
+			`{"code":"PROD001","price":1099,"currency":"EUR","variants":[{"name":"Variant A","sku":"SKU001A","price":1099},{"name":"Variant B","sku":"SKU001B","price":1250}]}`,
+		},
+		{
+			// 10.625 rounds to the even cent
+			"cents of the requested currency",
+			"/catalog/PROD001?amountFormat=minor&currency=gbp",
+			`{"code":"PROD001","price":934,"currency":"GBP","variants":[{"name":"Variant A","sku":"SKU001A","price":934},{"name":"Variant B","sku":"SKU001B","price":1062}]}`,
+		},
+		{
+			"currency without minor unit",
+			"/catalog/PROD001?amountFormat=minor&currency=JPY",
+			`{"code":"PROD001","price":1758,"currency":"JPY","variants":[{"name":"Variant A","sku":"SKU001A","price":1758},{"name":"Variant B","sku":"SKU001B","price":2000}]}`,
+		},
+		{
+			"prices as numbers too",
+			"/catalog/PROD001?amountFormat=minor&priceFormat=number",
+			`{"code":"PROD001","price":1099,"currency":"EUR","variants":[{"name":"Variant A","sku":"SKU001A","price":1099},{"name":"Variant B","sku":"SKU001B","price":1250}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockRepo)
+			repo.On("GetByCode", mock.Anything, "PROD001").Return(product, nil)
+
+			rec := get(repo, tt.target)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.want, rec.Body.String())
+		})
+	}
+
+	t.Run("minor units in the listing", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, mock.Anything).Return([]models.Product{product}, int64(1), nil)
+
+		rec := get(repo, "/catalog?amountFormat=minor")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"products":[{"code":"PROD001","price":1099,"currency":"EUR"}],"products_available":1,"availability":{"page_variant_count":2,"total_products":1}}`, rec.Body.String())
+	})
+
+	t.Run("minor units in the grouped listing", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListByCategories", mock.Anything, []string{"clothing"}, 3).Return(map[string]products.CategoryProducts{
+			"clothing": {Products: []models.Product{product}, Total: 1},
+		}, nil)
+
+		rec := get(repo, "/catalog/grouped?categories=clothing&amountFormat=minor&currency=GBP")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"categories":{"clothing":{"products":[{"code":"PROD001","price":934,"currency":"GBP"}],"products_available":1,"availability":{"page_variant_count":2,"total_products":1}}}}`, rec.Body.String())
+	})
+
+	t.Run("minor units in the related products", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetRelated", mock.Anything, "PROD004", 4).Return([]models.Product{product}, nil)
+
+		rec := get(repo, "/catalog/PROD004/related?amountFormat=minor")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"products":[{"code":"PROD001","price":1099,"currency":"EUR"}]}`, rec.Body.String())
+	})
+
+	t.Run("unknown format in the grouped listing and the related products", func(t *testing.T) {
+		for _, target := range []string{"/catalog/grouped?categories=clothing&amountFormat=cents", "/catalog/PROD004/related?amountFormat=cents"} {
+			repo := new(mockRepo)
+
+			rec := get(repo, target)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+			assert.Empty(t, repo.Calls)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := get(repo, "/catalog/PROD001?amountFormat=cents")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"amountFormat must be major or minor"}`, rec.Body.String())
+		assert.Empty(t, repo.Calls)
+	})
+}
+
+func TestHandleAdjustCategoryPrices(t *testing.T) {
+	var publisher *recordingPublisher
+	adjust := func(repo *mockRepo, code, body string) *httptest.ResponseRecorder {
+		publisher = new(recordingPublisher)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/categories/"+code+"/adjust-prices", strings.NewReader(body))
+		newTestMux(newHandler(t, repo, Options{Events: publisher})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("applies the factor", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AdjustPrices", mock.Anything, "shoes", mock.MatchedBy(func(adj products.Adjustment) bool {
+			return adj.Type == products.AdjustFactor && adj.Value.Equal(decimal.RequireFromString("0.9"))
+		})).Return(int64(2), nil)
+
+		rec := adjust(repo, "shoes", `{"factor":"0.9"}`)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		repo.AssertExpectations(t)
+		require.Len(t, publisher.events, 1)
+		assert.Equal(t, events.CategoryPricesAdjusted, publisher.events[0].Type)
+		assert.Equal(t, CategoryPricesChanged{Code: "shoes", Updated: 2}, publisher.events[0].Data)
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AdjustPrices", mock.Anything, "bags", mock.Anything).Return(int64(0), products.ErrCategoryNotFound)
+
+		rec := adjust(repo, "bags", `{"factor":"1.1"}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"category not found"}`, rec.Body.String())
+	})
+
+	t.Run("prices over the column precision are unprocessable", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AdjustPrices", mock.Anything, "shoes", mock.Anything).Return(int64(0), &products.PriceOverflowError{Count: 1})
+
+		rec := adjust(repo, "shoes", `{"factor":"1000000"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"error":"adjustment would raise the price of 1 products over 99999999.99"}`, rec.Body.String())
+		assert.Empty(t, publisher.events)
+	})
+
+	t.Run("rejects non positive factors", func(t *testing.T) {
+		for _, body := range []string{`{"factor":"0"}`, `{"factor":"-0.5"}`, `{}`, `{"factor":"abc"}`} {
+			repo := new(mockRepo)
+
+			rec := adjust(repo, "shoes", body)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+			assert.Empty(t, repo.Calls)
+		}
+	})
+}
+
+func TestHandlePriceStats(t *testing.T) {
+	clothing := products.PriceStats{
+		Min:   decimal.NewNullDecimal(decimal.RequireFromString("10.99")),
+		Max:   decimal.NewNullDecimal(decimal.RequireFromString("18.2")),
+		Avg:   decimal.NewNullDecimal(decimal.RequireFromString("14.73")),
+		Count: 3,
+	}
+	get := func(repo *mockRepo, code, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/categories/"+code+"/price-stats?"+query, nil)
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("populated category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceStats", mock.Anything, "clothing").Return(clothing, nil)
+
+		rec := get(repo, "clothing", "")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"category":"clothing","min":"10.99","max":"18.20","avg":"14.73","count":3}`, rec.Body.String())
+	})
+
+	t.Run("in the requested currency and format", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceStats", mock.Anything, "clothing").Return(clothing, nil)
+
+		rec := get(repo, "clothing", "currency=GBP&amountFormat=minor")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"category":"clothing","min":550,"max":910,"avg":736,"currency":"GBP","count":3}`, rec.Body.String())
+	})
+
+	t.Run("empty category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceStats", mock.Anything, "bags").Return(products.PriceStats{}, nil)
+
+		rec := get(repo, "bags", "")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"category":"bags","min":"0.00","max":"0.00","avg":"0.00","count":0}`, rec.Body.String())
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		for _, query := range []string{"currency=XYZ", "priceFormat=float", "amountFormat=cents"} {
+			repo := new(mockRepo)
+
+			rec := get(repo, "clothing", query)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+			assert.Empty(t, repo.Calls)
+		}
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceStats", mock.Anything, "unknown").Return(products.PriceStats{}, products.ErrCategoryNotFound)
+
+		rec := get(repo, "unknown", "")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"category not found"}`, rec.Body.String())
+	})
+}
+
+func TestHandleDeleteOrphanVariants(t *testing.T) {
+	run := func(repo *mockRepo) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/maintenance/orphan-variants", nil)
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("reports the deleted variants", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteOrphanVariants", mock.Anything).Return(int64(3), nil)
+
+		rec := run(repo)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"deleted":3}`, rec.Body.String())
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteOrphanVariants", mock.Anything).Return(int64(0), errors.New("lock timeout"))
+
+		rec := run(repo)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestHandleAdjustPrices(t *testing.T) {
+	var publisher *recordingPublisher
+	adjust := func(repo *mockRepo, body string) *httptest.ResponseRecorder {
+		publisher = new(recordingPublisher)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/catalog/price-adjustments", strings.NewReader(body))
+		newTestMux(newHandler(t, repo, Options{Events: publisher})).ServeHTTP(rec, req)
+		return rec
+	}
+	adjustment := func(typ, value string) any {
+		return mock.MatchedBy(func(adj products.Adjustment) bool {
+			return adj.Type == typ && adj.Value.Equal(decimal.RequireFromString(value))
+		})
+	}
+
+	t.Run("reports the updated products", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AdjustPrices", mock.Anything, "shoes", adjustment(products.AdjustPercentage, "-10")).Return(int64(3), nil)
+
+		rec := adjust(repo, `{"category":"shoes","type":"percentage","value":-10}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"updated":3}`, rec.Body.String())
+		repo.AssertExpectations(t)
+		require.Len(t, publisher.events, 1)
+		assert.Equal(t, events.CategoryPricesAdjusted, publisher.events[0].Type)
+		assert.Equal(t, CategoryPricesChanged{Code: "shoes", Updated: 3}, publisher.events[0].Data)
+	})
+
+	t.Run("absolute adjustment", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AdjustPrices", mock.Anything, "shoes", adjustment(products.AdjustAbsolute, "2.5")).Return(int64(2), nil)
+
+		rec := adjust(repo, `{"category":"shoes","type":"absolute","value":"2.50"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"updated":2}`, rec.Body.String())
+	})
+
+	t.Run("factor adjustment", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AdjustPrices", mock.Anything, "shoes", adjustment(products.AdjustFactor, "0.9")).Return(int64(3), nil)
+
+		rec := adjust(repo, `{"category":"shoes","type":"factor","value":"0.9"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"updated":3}`, rec.Body.String())
+	})
+
+	t.Run("prices over the column precision are unprocessable", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AdjustPrices", mock.Anything, "shoes", mock.Anything).Return(int64(0), &products.PriceOverflowError{Count: 2})
+
+		rec := adjust(repo, `{"category":"shoes","type":"percentage","value":100000000}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"error":"adjustment would raise the price of 2 products over 99999999.99"}`, rec.Body.String())
+	})
+
+	t.Run("negative prices are unprocessable", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AdjustPrices", mock.Anything, "shoes", mock.Anything).Return(int64(0), &products.NegativePriceError{Count: 4})
+
+		rec := adjust(repo, `{"category":"shoes","type":"absolute","value":-50}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"error":"adjustment would make the price of 4 products negative"}`, rec.Body.String())
+		assert.Empty(t, publisher.events)
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AdjustPrices", mock.Anything, "bags", mock.Anything).Return(int64(0), products.ErrCategoryNotFound)
+
+		rec := adjust(repo, `{"category":"bags","type":"percentage","value":5}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"category not found"}`, rec.Body.String())
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		tests := []struct {
+			body    string
+			wantErr string
+		}{
+			{`{"category":`, "invalid request body"},
+			{`{"type":"percentage","value":5}`, "category is required"},
+			{`{"category":"shoes","type":"ratio","value":5}`, "type must be percentage, absolute or factor"},
+			{`{"category":"shoes","type":"absolute"}`, "value must not be zero"},
+			{`{"category":"shoes","type":"factor","value":-0.5}`, "value must be positive"},
+		}
+
+		for _, tt := range tests {
+			repo := new(mockRepo)
+
+			rec := adjust(repo, tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, tt.body)
+			assert.JSONEq(t, `{"error":"`+tt.wantErr+`"}`, rec.Body.String())
+			assert.Empty(t, repo.Calls)
+		}
+	})
+}
+
+func TestHandleAddImage(t *testing.T) {
+	post := func(repo *mockRepo, publisher events.Publisher, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/catalog/PROD001/images", strings.NewReader(body))
+		newTestMux(newHandler(t, repo, Options{Events: publisher})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("adds the image", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AddImage", mock.Anything, "PROD001", &models.Image{
+			URL: "https://cdn.example.com/side.jpg", Position: 2, AltText: "Side",
+		}).Run(func(args mock.Arguments) {
+			args.Get(2).(*models.Image).ID = 9
+		}).Return(nil)
+		publisher := &recordingPublisher{}
+
+		rec := post(repo, publisher, `{"url":"https://cdn.example.com/side.jpg","position":2,"alt_text":"Side"}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.JSONEq(t, `{"id":9,"url":"https://cdn.example.com/side.jpg","position":2,"alt_text":"Side"}`, rec.Body.String())
+		require.Len(t, publisher.events, 1)
+		assert.Equal(t, events.ProductUpdated, publisher.events[0].Type)
+		assert.Equal(t, ProductChanged{Code: "PROD001"}, publisher.events[0].Data)
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("AddImage", mock.Anything, "PROD001", mock.Anything).Return(products.ErrProductNotFound)
+		publisher := &recordingPublisher{}
+
+		rec := post(repo, publisher, `{"url":"https://cdn.example.com/side.jpg"}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Empty(t, publisher.events)
+	})
+
+	t.Run("rejects invalid payloads", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{"missing url", `{"position":1}`},
+			{"relative url", `{"url":"/images/side.jpg"}`},
+			{"unsupported scheme", `{"url":"ftp://cdn.example.com/side.jpg"}`},
+			{"url too long", `{"url":"https://cdn.example.com/` + strings.Repeat("a", 2048) + `"}`},
+			{"alt text too long", `{"url":"https://cdn.example.com/side.jpg","alt_text":"` + strings.Repeat("a", 257) + `"}`},
+			{"negative position", `{"url":"https://cdn.example.com/side.jpg","position":-1}`},
+			{"unknown field", `{"url":"https://cdn.example.com/side.jpg","caption":"Side"}`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := post(repo, nil, tt.body)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+}
+
+func TestHandleDeleteImage(t *testing.T) {
+	del := func(repo *mockRepo, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+		return rec
+	}
+
+	t.Run("deletes the image", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteImage", mock.Anything, "PROD001", uint(4)).Return(nil)
+
+		rec := del(repo, "/catalog/PROD001/images/4")
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("unknown image", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteImage", mock.Anything, "PROD001", uint(99)).Return(products.ErrImageNotFound)
+
+		rec := del(repo, "/catalog/PROD001/images/99")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("invalid image id", func(t *testing.T) {
+		for _, id := range []string{"abc", "0", "-1"} {
+			repo := new(mockRepo)
+
+			rec := del(repo, "/catalog/PROD001/images/"+id)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, id)
+			assert.Empty(t, repo.Calls)
+		}
+	})
+}
+
+func TestHandleUpdateVariantPrices(t *testing.T) {
+	patch := func(repo *mockRepo, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body)))
+		return rec
+	}
+	prices := map[string]decimal.Decimal{
+		"SKU001A": decimal.RequireFromString("8.99"),
+		"SKU001B": decimal.RequireFromString("9.5"),
+	}
+	body := `{"prices":{"SKU001A":"8.99","SKU001B":9.5}}`
+
+	t.Run("all variants matched", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("UpdateVariantPrices", mock.Anything, "PROD001", prices).Return([]string{}, nil)
+
+		rec := patch(repo, "/catalog/PROD001/variants/prices", body)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"updated":2,"unmatched_skus":[]}`, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("some SKUs unmatched", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("UpdateVariantPrices", mock.Anything, "PROD001", prices).Return([]string{"SKU001B"}, nil)
+
+		rec := patch(repo, "/catalog/PROD001/variants/prices", body)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"updated":1,"unmatched_skus":["SKU001B"]}`, rec.Body.String())
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("UpdateVariantPrices", mock.Anything, "PROD999", prices).Return(nil, products.ErrProductNotFound)
+
+		rec := patch(repo, "/catalog/PROD999/variants/prices", body)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("rejects invalid prices", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			body       string
+			wantStatus int
+			wantErr    string
+		}{
+			{"no prices", `{"prices":{}}`, http.StatusBadRequest, "prices must not be empty"},
+			{"negative price", `{"prices":{"SKU001A":"-1"}}`, http.StatusBadRequest, "variant SKU001A price must not be negative"},
+			{"invalid sku", `{"prices":{"SKU 1":"1"}}`, http.StatusBadRequest, `invalid variant sku \"SKU 1\"`},
+			{"too precise", `{"prices":{"SKU001A":"1.999"}}`, http.StatusUnprocessableEntity, "variant SKU001A price must have at most 2 decimal places"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := patch(repo, "/catalog/PROD001/variants/prices", tt.body)
+
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				assert.JSONEq(t, `{"error":"`+tt.wantErr+`"}`, rec.Body.String())
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+
+	t.Run("invalid product code", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := patch(repo, "/catalog/prod-1/variants/prices", body)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, repo.Calls)
+	})
+}
+
+func TestEvents(t *testing.T) {
+	t.Run("product created", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		publisher := &recordingPublisher{}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/catalog", strings.NewReader(`{"code":"PROD009","price":"19.99"}`))
+		newTestMux(newHandler(t, repo, Options{Events: publisher})).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		require.Len(t, publisher.events, 1)
+		assert.Equal(t, events.ProductCreated, publisher.events[0].Type)
+		assert.Equal(t, dto.Product{Code: "PROD009", Price: currency.NewMoney(decimal.RequireFromString("19.99"), pricing.HalfEven), Variants: []dto.Variant{}}, publisher.events[0].Data)
+	})
+
+	t.Run("variant deleted updates the product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("DeleteVariant", mock.Anything, "PROD001", "SKU001A").Return(nil)
+		publisher := &recordingPublisher{}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/catalog/PROD001/variants/SKU001A", nil)
+		newTestMux(newHandler(t, repo, Options{Events: publisher})).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		require.Len(t, publisher.events, 1)
+		assert.Equal(t, events.ProductUpdated, publisher.events[0].Type)
+		assert.Equal(t, ProductChanged{Code: "PROD001"}, publisher.events[0].Data)
+	})
+
+	t.Run("failed mutations publish nothing", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(products.ErrProductExists)
+		repo.On("DeleteVariant", mock.Anything, "PROD001", "SKU999Z").Return(products.ErrVariantNotFound)
+		publisher := &recordingPublisher{}
+		mux := newTestMux(newHandler(t, repo, Options{Events: publisher}))
+
+		for _, req := range []*http.Request{
+			httptest.NewRequest(http.MethodPost, "/catalog", strings.NewReader(`{"code":"PROD001","price":"10.99"}`)),
+			httptest.NewRequest(http.MethodPost, "/catalog", strings.NewReader(`{"code":"bad"}`)),
+			httptest.NewRequest(http.MethodDelete, "/catalog/PROD001/variants/SKU999Z", nil),
+		} {
+			mux.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		assert.Empty(t, publisher.events)
+	})
+}
+
+func TestProductCodePattern(t *testing.T) {
+	t.Run("invalid pattern fails construction", func(t *testing.T) {
+		_, err := NewCatalogHandler(new(mockRepo), Options{ProductCodePattern: `^MT-(\d+$`})
+
+		assert.ErrorContains(t, err, "invalid product code pattern")
+	})
+
+	t.Run("default pattern when none is configured", func(t *testing.T) {
+		h := newHandler(t, new(mockRepo), Options{})
+
+		assert.Equal(t, DefaultProductCodePattern, h.codes.pattern.String())
+		assert.NoError(t, h.codes.validate("PROD001"))
+		for _, code := range []string{"PROD01", "PROD0001", "MT-123456", "prod001"} {
+			assert.Error(t, h.codes.validate(code), code)
+		}
+	})
+
+	opts := Options{ProductCodePattern: `^MT-\d{6}$`}
+
+	t.Run("custom pattern on lookup", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("GetByCode", mock.Anything, "MT-123456").Return(models.Product{Code: "MT-123456", Price: decimal.RequireFromString("99")}, nil)
+		mux := newTestMux(newHandler(t, repo, opts))
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/MT-123456", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/PROD001", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("custom pattern on creation", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mux := newTestMux(newHandler(t, repo, opts))
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/catalog", strings.NewReader(`{"code":"MT-123456","price":"99"}`)))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/catalog", strings.NewReader(`{"code":"PROD009","price":"99"}`)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		repo.AssertNumberOfCalls(t, "Create", 1)
+	})
+}
+
+// Clients iterate over the collections without null checks, so they are
+// empty arrays even when the repository returns nil slices.
+func TestHandlers_EmptyCollections(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		setup  func(repo *mockRepo)
+		paths  []string
+	}{
+		{"listing", "/catalog", func(repo *mockRepo) {
+			repo.On("List", mock.Anything, mock.Anything).Return([]models.Product(nil), int64(0), nil)
+		}, []string{"products"}},
+		{"product without variants", "/catalog/PROD001", func(repo *mockRepo) {
+			repo.On("GetByCode", mock.Anything, "PROD001").Return(models.Product{Code: "PROD001", Price: decimal.RequireFromString("10")}, nil)
+		}, []string{"variants"}},
+		{"lookup", "/catalog/lookup?codes=PROD001", func(repo *mockRepo) {
+			repo.On("GetByCodes", mock.Anything, []string{"PROD001"}).Return([]models.Product(nil), nil)
+		}, []string{"products", "missing"}},
+		{"related", "/catalog/PROD001/related", func(repo *mockRepo) {
+			repo.On("GetRelated", mock.Anything, "PROD001", 4).Return([]models.Product(nil), nil)
+		}, []string{"products"}},
+		{"grouped", "/catalog/grouped?categories=shoes", func(repo *mockRepo) {
+			repo.On("ListByCategories", mock.Anything, []string{"shoes"}, 3).Return(map[string]products.CategoryProducts(nil), nil)
+		}, []string{"categories.shoes.products"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockRepo)
+			tt.setup(repo)
+			rec := httptest.NewRecorder()
+
+			newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			testsupport.AssertJSONArrays(t, rec.Body.String(), tt.paths...)
+		})
+	}
+}
+
+// Requests whose client went away get no response, whether the repository
+// call completed or failed with the cancellation.
+func TestHandlers_Abandoned(t *testing.T) {
+	res := []models.Product{{Code: "PROD001", Price: decimal.RequireFromString("10.99")}}
+	serve := func(ctx context.Context, repo *mockRepo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx)
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		setup  func(repo *mockRepo, cancel context.CancelFunc)
+	}{
+		{"listing completed", "/catalog", func(repo *mockRepo, cancel context.CancelFunc) {
+			repo.On("List", mock.Anything, mock.Anything).Run(func(mock.Arguments) { cancel() }).Return(res, int64(1), nil)
+		}},
+		{"listing cancelled", "/catalog", func(repo *mockRepo, cancel context.CancelFunc) {
+			repo.On("List", mock.Anything, mock.Anything).Run(func(mock.Arguments) { cancel() }).Return(nil, int64(0), context.Canceled)
+		}},
+		{"details cancelled", "/catalog/PROD001", func(repo *mockRepo, cancel context.CancelFunc) {
+			repo.On("GetByCode", mock.Anything, "PROD001").Run(func(mock.Arguments) { cancel() }).Return(models.Product{}, fmt.Errorf("getting product: %w", context.Canceled))
+		}},
+		{"export cancelled", "/catalog/export", func(repo *mockRepo, cancel context.CancelFunc) {
+			repo.On("ListAllFunc", mock.Anything, "", mock.Anything).Run(func(mock.Arguments) { cancel() }).Return(context.Canceled)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			repo := new(mockRepo)
+			tt.setup(repo, cancel)
+
+			rec := serve(ctx, repo, tt.target)
+
+			assert.Empty(t, rec.Body.String())
+			assert.Empty(t, rec.Header())
+			repo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("cancelled while answering", func(t *testing.T) {
+		// Whichever of the cancellation and the response comes first, the
+		// response is either complete or missing, never an error
+		for range 50 {
+			ctx, cancel := context.WithCancel(context.Background())
+			repo := new(mockRepo)
+			repo.On("List", mock.Anything, mock.Anything).Return(res, int64(1), nil)
+			go cancel()
+
+			rec := serve(ctx, repo, "/catalog")
+
+			if rec.Body.Len() > 0 {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Contains(t, rec.Body.String(), `"code":"PROD001"`)
+			}
+			cancel()
+		}
+	})
+}
+
+// largePage is a listing page of 100 products of 3 categories, with 10
+// variants and 3 images each.
+func largePage() []models.Product {
+	categories := []*models.Category{
+		{ID: 1, Code: "clothing", Name: "Clothing", Translations: []models.CategoryTranslation{{Locale: "de", Name: "Kleidung"}, {Locale: "fr", Name: "Vêtements"}}},
+		{ID: 2, Code: "shoes", Name: "Shoes", Translations: []models.CategoryTranslation{{Locale: "de", Name: "Schuhe"}, {Locale: "fr", Name: "Chaussures"}}},
+		{ID: 3, Code: "accessories", Name: "Accessories", Translations: []models.CategoryTranslation{{Locale: "de", Name: "Accessoires"}, {Locale: "fr", Name: "Accessoires"}}},
+	}
+	page := make([]models.Product, 100)
+	for i := range page {
+		p := models.Product{
+			ID:       uint(i + 1),
+			Code:     fmt.Sprintf("PROD%03d", i+1),
+			Price:    decimal.New(int64(1000+i), -2),
+			Category: categories[i%len(categories)],
+			Variants: make([]models.Variant, 10),
+			Images:   make([]models.Image, 3),
+		}
+		for j := range p.Variants {
+			p.Variants[j] = models.Variant{Name: fmt.Sprintf("Variant %d", j), SKU: fmt.Sprintf("SKU%03d%c", i+1, 'A'+j), Price: decimal.New(int64(1100+j), -2)}
+		}
+		for j := range p.Images {
+			p.Images[j] = models.Image{ID: uint(3*i + j + 1), URL: fmt.Sprintf("https://cdn.example.com/%s-%d.jpg", p.Code, j), Position: j + 1}
+		}
+		page[i] = p
+	}
+	return page
+}
+
+func BenchmarkPrepareResponse(b *testing.B) {
+	h := newHandler(b, new(mockRepo), Options{})
+	page := largePage()
+	conv := h.baseConversion()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		h.prepareResponse(page, 1000, conv, "de")
+	}
+}
+
+func BenchmarkHandleGet(b *testing.B) {
+	repo := new(mockRepo)
+	repo.On("List", mock.Anything, mock.Anything).Return(largePage(), int64(1000), nil)
+	mux := newTestMux(newHandler(b, repo, Options{}))
+	req := httptest.NewRequest(http.MethodGet, "/catalog?limit=100&locale=de", nil)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			b.Fatal(rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/dto"
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+	"github.com/mytheresa/go-hiring-challenge/app/repos"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// importProgressInterval is how many rows are imported between two saves of
+// the progress of a job.
+const importProgressInterval = 100
+
+// importColumns are the columns of an import CSV, all but category required.
+var importColumns = []string{"code", "price", "category"}
+
+// ImportQueue runs the import jobs in the background.
+type ImportQueue interface {
+	Enqueue(id uint) error
+}
+
+// Transactor runs fn in a transaction, rolled back when fn fails.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(repos.TxRepos) error) error
+}
+
+// errImportRolledBack rolls an atomic import back once some rows failed.
+var errImportRolledBack = errors.New("import rolled back")
+
+// importRow is a product of an import CSV, or why it could not be read.
+type importRow struct {
+	req CreateProductRequest
+	err error
+}
+
+// HandleImport creates the products of a CSV upload having a header row and
+// code, price and optionally category columns. Rows failing validation or
+// conflicting with existing products are reported while the others are
+// created. With async=true the upload is stored and imported in the
+// background instead, answering 202 with the job to poll. With atomic=true
+// either every row is created or, when any fails, none is.
+func (h *CatalogHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	async := false
+	if v := r.URL.Query().Get("async"); v != "" {
+		if v != "true" && v != "false" {
+			api.ErrorResponse(w, http.StatusBadRequest, "async must be true or false")
+			return
+		}
+		async = v == "true"
+	}
+	atomic := false
+	if v := r.URL.Query().Get("atomic"); v != "" {
+		if v != "true" && v != "false" {
+			api.ErrorResponse(w, http.StatusBadRequest, "atomic must be true or false")
+			return
+		}
+		atomic = v == "true"
+	}
+	if async && atomic {
+		api.ErrorResponse(w, http.StatusBadRequest, "async and atomic imports cannot be combined")
+		return
+	}
+	if atomic && h.transactor == nil {
+		api.ErrorResponse(w, http.StatusServiceUnavailable, "atomic imports are not available")
+		return
+	}
+
+	body, ok := api.ReadBody(w, r)
+	if !ok {
+		return
+	}
+	rows, err := parseImport(bytes.NewReader(body))
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		api.ErrorResponse(w, http.StatusBadRequest, "CSV must contain at least one product")
+		return
+	}
+
+	if async {
+		h.enqueueImport(w, r, body, len(rows))
+		return
+	}
+
+	if atomic {
+		h.importAtomically(w, r, rows)
+		return
+	}
+
+	res, err := h.importProducts(r.Context(), h.writer, h.events, rows, nil)
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+	h.recordImport(r.Context(), res)
+	api.OKResponse(w, res)
+}
+
+// importAtomically imports the rows in a single transaction, rolled back
+// when any row fails. The events are only published once committed.
+func (h *CatalogHandler) importAtomically(w http.ResponseWriter, r *http.Request, rows []importRow) {
+	var (
+		res     ImportResult
+		pending events.Buffer
+	)
+	err := h.transactor.WithTransaction(r.Context(), func(tx repos.TxRepos) error {
+		var err error
+		res, err = h.importProducts(r.Context(), tx.Products, &pending, rows, nil)
+		if err == nil && res.Failed > 0 {
+			err = errImportRolledBack
+		}
+		return err
+	})
+	if errors.Is(err, errImportRolledBack) {
+		res.Created, res.RolledBack = 0, true
+		api.OKResponse(w, res)
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+	pending.Flush(h.events)
+	h.recordImport(r.Context(), res)
+	api.OKResponse(w, res)
+}
+
+func (h *CatalogHandler) enqueueImport(w http.ResponseWriter, r *http.Request, body []byte, total int) {
+	if h.importJobs == nil || h.importQueue == nil {
+		api.ErrorResponse(w, http.StatusServiceUnavailable, "asynchronous imports are not available")
+		return
+	}
+
+	job := models.ImportJob{Status: models.ImportQueued, Data: body, Total: total}
+	if err := h.importJobs.Create(r.Context(), &job); err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+	if err := h.importQueue.Enqueue(job.ID); err != nil {
+		// The job would otherwise stay queued forever
+		job.Status, job.Error = models.ImportFailed, err.Error()
+		if err := h.importJobs.Update(r.Context(), &job); err != nil {
+			logging.FromContext(r.Context()).Error("Failed to save import job", "job", job.ID, "error", err)
+		}
+		api.ErrorResponse(w, http.StatusServiceUnavailable, "too many imports in progress, retry later")
+		return
+	}
+
+	// The job runs without the actor of the request, the upload is recorded
+	// instead
+	queued := newImportJobResponse(job)
+	h.record(r.Context(), audit.Change{Action: audit.Import, EntityType: audit.Catalog, After: queued})
+	w.Header().Set("Location", api.AbsoluteURL(r, fmt.Sprintf("/catalog/import/jobs/%d", job.ID)))
+	api.AcceptedResponse(w, queued)
+}
+
+// HandleImportJob reports the status and progress of an asynchronous import.
+func (h *CatalogHandler) HandleImportJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 0)
+	if err != nil || id == 0 {
+		api.ErrorResponse(w, http.StatusBadRequest, "job id must be a positive integer")
+		return
+	}
+	if h.importJobs == nil {
+		api.ErrorResponse(w, http.StatusServiceUnavailable, "asynchronous imports are not available")
+		return
+	}
+
+	job, err := h.importJobs.Get(r.Context(), uint(id))
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+	api.OKResponse(w, newImportJobResponse(job))
+}
+
+// RunImportJob imports the upload of a queued job, saving its progress along
+// the way. It is the runner of the import queue.
+func (h *CatalogHandler) RunImportJob(ctx context.Context, id uint) {
+	logger := logging.FromContext(ctx).With("job", id)
+	job, err := h.importJobs.Claim(ctx, id)
+	if err != nil {
+		logger.Error("Failed to start import job", "error", err)
+		return
+	}
+
+	rows, err := parseImport(bytes.NewReader(job.Data))
+	if err == nil {
+		job.Total = len(rows)
+		_, err = h.importProducts(ctx, h.writer, h.events, rows, func(res ImportResult) error {
+			job.Processed, job.Created, job.Failed, job.Errors = res.Processed, res.Created, res.Failed, res.Errors
+			if res.Processed%importProgressInterval != 0 {
+				return nil
+			}
+			return h.importJobs.Update(ctx, &job)
+		})
+	}
+
+	job.Status = models.ImportDone
+	switch {
+	case ctx.Err() != nil:
+		job.Status, job.Error = models.ImportFailed, "interrupted by a server shutdown"
+	case err != nil:
+		job.Status, job.Error = models.ImportFailed, err.Error()
+	}
+	// Saved even when the queue is stopping, so the job does not look running
+	if err := h.importJobs.Update(context.WithoutCancel(ctx), &job); err != nil {
+		logger.Error("Failed to save import job", "error", err)
+	}
+}
+
+// importProducts creates the products one at a time through writer, so that
+// a row failing does not prevent the others from being imported, and
+// publishes their events to publisher. progress, when not nil,
+// is called after every row. The import stops when ctx is done, on storage
+// errors other than conflicts and missing categories, and when progress
+// fails.
+func (h *CatalogHandler) importProducts(ctx context.Context, writer products.ProductWriter, publisher events.Publisher, rows []importRow, progress func(ImportResult) error) (ImportResult, error) {
+	res := ImportResult{Total: len(rows), Errors: []models.ImportError{}}
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		err := h.importProduct(ctx, writer, publisher, row)
+		if err != nil && errs.KindOf(err) == errs.Internal {
+			return res, err
+		}
+		res.Processed++
+		if err != nil {
+			res.Failed++
+			res.Errors = append(res.Errors, models.ImportError{Row: i + 1, Code: row.req.Code, Error: err.Error()})
+		} else {
+			res.Created++
+		}
+
+		if progress != nil {
+			if err := progress(res); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// importProduct creates the product of the row as HandleCreate does. Rows
+// that cannot be created as they are fail with an Invalid error.
+func (h *CatalogHandler) importProduct(ctx context.Context, writer products.ProductWriter, publisher events.Publisher, row importRow) error {
+	err := row.err
+	if err == nil {
+		err = h.validateCreateProduct(row.req)
+	}
+	if err != nil {
+		return errs.WithKind(errs.Invalid, err)
+	}
+
+	product := newProductModel(row.req)
+	if err := writer.Create(ctx, &product); err != nil {
+		return err
+	}
+	publisher.Publish(events.New(events.ProductCreated, dto.ToProductDetailsResponse(product, h.baseConversion(), "")))
+	return nil
+}
+
+// parseImport reads the products of an import CSV, whose columns may come in
+// any order. Malformed CSV fails the whole import, while malformed values are
+// reported on their row.
+func parseImport(r io.Reader) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("CSV must start with a header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(importColumns, name) {
+			return nil, fmt.Errorf("unknown CSV column %q, expected code, price and optionally category", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("CSV column %q is repeated", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"code", "price"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV column %q is required", name)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		rows = append(rows, parseImportRow(record, columns))
+	}
+}
+
+func parseImportRow(record []string, columns map[string]int) importRow {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := importRow{req: CreateProductRequest{Code: field("code"), Category: field("category")}}
+	price, err := parsePrice(field("price"))
+	if err != nil {
+		row.err = err
+		return row
+	}
+	row.req.Price = PriceField{price}
+	return row
+}
@@ -0,0 +1,303 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/jobs"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func postImport(h *CatalogHandler, query, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	newTestMux(h).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/catalog/import"+query, strings.NewReader(body)))
+	return rec
+}
+
+func getImportJob(h *CatalogHandler, id string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	newTestMux(h).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/import/jobs/"+id, nil))
+	return rec
+}
+
+// mixedImport has a valid row, a malformed price, an invalid code, a code
+// that already exists and a price with too many decimals.
+const mixedImport = "code,price,category\n" +
+	"PROD010,19.99,shoes\n" +
+	"PROD011,abc,shoes\n" +
+	"P1,10,\n" +
+	"PROD001,10,\n" +
+	"PROD012,10.999,\n"
+
+func expectMixedImport(repo *mockRepo) {
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.Code == "PROD010" && p.Category.Code == "shoes"
+	})).Return(nil)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.Code == "PROD001"
+	})).Return(products.ErrProductExists)
+}
+
+const mixedImportErrors = `[
+	{"row":2,"code":"PROD011","error":"price must be a decimal number"},
+	{"row":3,"code":"P1","error":"invalid product code"},
+	{"row":4,"code":"PROD001","error":"product code or variant sku already exists"},
+	{"row":5,"code":"PROD012","error":"price must have at most 2 decimal places"}
+]`
+
+func TestHandleImport(t *testing.T) {
+	t.Run("imports the valid rows and reports the others", func(t *testing.T) {
+		repo := new(mockRepo)
+		expectMixedImport(repo)
+		publisher := &recordingPublisher{}
+
+		rec := postImport(newHandler(t, repo, Options{Events: publisher}), "", mixedImport)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"total":5,"processed":5,"created":1,"failed":4,"errors":`+mixedImportErrors+`}`, rec.Body.String())
+		assert.Len(t, publisher.events, 1)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("prices in scientific notation", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := postImport(newHandler(t, repo, Options{}), "", "code,price\nPROD013,1e3\n")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"total":1,"processed":1,"created":0,"failed":1,"errors":[
+			{"row":1,"code":"PROD013","error":"price must not use scientific notation"}
+		]}`, rec.Body.String())
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("columns in any order", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+			return p.Code == "PROD010" && p.Price.String() == "5" && p.Category == nil
+		})).Return(nil)
+
+		rec := postImport(newHandler(t, repo, Options{}), "", " Price , CODE\n5, PROD010\n")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"total":1,"processed":1,"created":1,"failed":0,"errors":[]}`, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("stops on storage errors", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(errors.New("boom")).Once()
+
+		rec := postImport(newHandler(t, repo, Options{}), "", "code,price\nPROD010,1\nPROD011,1\n")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		repo.AssertNumberOfCalls(t, "Create", 1)
+	})
+
+	t.Run("rejects malformed uploads", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			query string
+			body  string
+			want  string
+		}{
+			{"empty", "", "", "CSV must start with a header row"},
+			{"header only", "", "code,price\n", "CSV must contain at least one product"},
+			{"unknown column", "", "code,price,colour\n", `unknown CSV column "colour", expected code, price and optionally category`},
+			{"repeated column", "", "code,price,code\n", `CSV column "code" is repeated`},
+			{"missing column", "", "code,category\n", `CSV column "price" is required`},
+			{"wrong number of fields", "", "code,price\nPROD010\n", "invalid CSV: record on line 2: wrong number of fields"},
+			{"invalid async", "?async=yes", "code,price\nPROD010,1\n", "async must be true or false"},
+			{"invalid atomic", "?atomic=yes", "code,price\nPROD010,1\n", "atomic must be true or false"},
+			{"async and atomic", "?async=true&atomic=true", "code,price\nPROD010,1\n", "async and atomic imports cannot be combined"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := postImport(newHandler(t, repo, Options{}), tt.query, tt.body)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.JSONEq(t, fmt.Sprintf(`{"error":%q}`, tt.want), rec.Body.String())
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			})
+		}
+	})
+}
+
+func TestHandleImport_Atomic(t *testing.T) {
+	t.Run("creates every row in a transaction", func(t *testing.T) {
+		repo, txRepo := new(mockRepo), new(mockRepo)
+		txRepo.On("Create", mock.Anything, mock.Anything).Return(nil).Twice()
+		tx := &fakeTransactor{repo: txRepo}
+		publisher := &recordingPublisher{}
+
+		rec := postImport(newHandler(t, repo, Options{Transactor: tx, Events: publisher}), "?atomic=true", "code,price\nPROD010,1\nPROD011,2\n")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"total":2,"processed":2,"created":2,"failed":0,"errors":[]}`, rec.Body.String())
+		assert.True(t, tx.committed)
+		assert.Len(t, publisher.events, 2)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		txRepo.AssertExpectations(t)
+	})
+
+	t.Run("rolls back every row when one fails", func(t *testing.T) {
+		repo, txRepo := new(mockRepo), new(mockRepo)
+		expectMixedImport(txRepo)
+		tx := &fakeTransactor{repo: txRepo}
+		publisher := &recordingPublisher{}
+
+		rec := postImport(newHandler(t, repo, Options{Transactor: tx, Events: publisher}), "?atomic=true", mixedImport)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"total":5,"processed":5,"created":0,"failed":4,"rolled_back":true,"errors":`+mixedImportErrors+`}`, rec.Body.String())
+		assert.True(t, tx.rolledBack)
+		assert.Empty(t, publisher.events, "nothing was created")
+		txRepo.AssertExpectations(t)
+	})
+
+	t.Run("rolls back on storage errors", func(t *testing.T) {
+		repo, txRepo := new(mockRepo), new(mockRepo)
+		txRepo.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+		txRepo.On("Create", mock.Anything, mock.Anything).Return(errors.New("boom")).Once()
+		tx := &fakeTransactor{repo: txRepo}
+		publisher := &recordingPublisher{}
+
+		rec := postImport(newHandler(t, repo, Options{Transactor: tx, Events: publisher}), "?atomic=true", "code,price\nPROD010,1\nPROD011,2\n")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.True(t, tx.rolledBack)
+		assert.Empty(t, publisher.events)
+	})
+
+	t.Run("unavailable without a transactor", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := postImport(newHandler(t, repo, Options{}), "?atomic=true", "code,price\nPROD010,1\n")
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.JSONEq(t, `{"error":"atomic imports are not available"}`, rec.Body.String())
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandleImport_Async(t *testing.T) {
+	newAsyncHandler := func(t *testing.T, repo *mockRepo) (*CatalogHandler, *memoryImportJobs, *drainQueue) {
+		store, queue := newMemoryImportJobs(), &drainQueue{}
+		return newHandler(t, repo, Options{ImportJobs: store, ImportQueue: queue}), store, queue
+	}
+
+	t.Run("runs the import in the background", func(t *testing.T) {
+		repo := new(mockRepo)
+		expectMixedImport(repo)
+		h, store, queue := newAsyncHandler(t, repo)
+
+		rec := postImport(h, "?async=true", mixedImport)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, "http://example.com/catalog/import/jobs/1", rec.Header().Get("Location"))
+		assert.JSONEq(t, `{"id":1,"status":"queued","total":5,"processed":0,"created":0,"failed":0}`, rec.Body.String())
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+		rec = getImportJob(h, "1")
+		assert.JSONEq(t, `{"id":1,"status":"queued","total":5,"processed":0,"created":0,"failed":0}`, rec.Body.String())
+
+		queue.drain(context.Background(), h.RunImportJob)
+
+		rec = getImportJob(h, "1")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"id":1,"status":"done","total":5,"processed":5,"created":1,"failed":4,"errors":`+mixedImportErrors+`}`, rec.Body.String())
+		assert.Equal(t, []string{models.ImportQueued, models.ImportRunning, models.ImportDone}, store.statuses[1])
+		assert.Nil(t, store.jobs[1].Data, "the upload is dropped once the job is finished")
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("saves the progress along the way", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		h, store, queue := newAsyncHandler(t, repo)
+		var csv strings.Builder
+		csv.WriteString("code,price\n")
+		for i := range 250 {
+			fmt.Fprintf(&csv, "PROD%03d,1\n", i)
+		}
+
+		postImport(h, "?async=true", csv.String())
+		queue.drain(context.Background(), h.RunImportJob)
+
+		assert.Equal(t, []string{models.ImportQueued, models.ImportRunning, models.ImportRunning, models.ImportRunning, models.ImportDone}, store.statuses[1])
+		assert.Equal(t, 250, store.jobs[1].Created)
+	})
+
+	t.Run("fails the job on storage errors", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(errors.New("boom"))
+		h, store, queue := newAsyncHandler(t, repo)
+
+		postImport(h, "?async=true", "code,price\nPROD010,1\n")
+		queue.drain(context.Background(), h.RunImportJob)
+
+		rec := getImportJob(h, "1")
+		assert.JSONEq(t, `{"id":1,"status":"failed","total":1,"processed":0,"created":0,"failed":0,"error":"boom"}`, rec.Body.String())
+		assert.Equal(t, []string{models.ImportQueued, models.ImportRunning, models.ImportFailed}, store.statuses[1])
+	})
+
+	t.Run("fails the job interrupted by a shutdown", func(t *testing.T) {
+		repo := new(mockRepo)
+		h, _, queue := newAsyncHandler(t, repo)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		postImport(h, "?async=true", "code,price\nPROD010,1\n")
+		queue.drain(ctx, h.RunImportJob)
+
+		rec := getImportJob(h, "1")
+		assert.JSONEq(t, `{"id":1,"status":"failed","total":1,"processed":0,"created":0,"failed":0,"error":"interrupted by a server shutdown"}`, rec.Body.String())
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		store := newMemoryImportJobs()
+		h := newHandler(t, new(mockRepo), Options{ImportJobs: store, ImportQueue: &drainQueue{err: jobs.ErrQueueFull}})
+
+		rec := postImport(h, "?async=true", "code,price\nPROD010,1\n")
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, models.ImportFailed, store.jobs[1].Status)
+	})
+
+	t.Run("unavailable without a queue", func(t *testing.T) {
+		rec := postImport(newHandler(t, new(mockRepo), Options{}), "?async=true", "code,price\nPROD010,1\n")
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
+
+func TestHandleImportJob(t *testing.T) {
+	h := newHandler(t, new(mockRepo), Options{ImportJobs: newMemoryImportJobs(), ImportQueue: &drainQueue{}})
+
+	t.Run("unknown job", func(t *testing.T) {
+		rec := getImportJob(h, "9")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"import job not found"}`, rec.Body.String())
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		rec := getImportJob(h, "abc")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"job id must be a positive integer"}`, rec.Body.String())
+	})
+}
@@ -0,0 +1,260 @@
+package catalog
+
+import (
+	"context"
+	"slices"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/repos"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/imports"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type mockRepo struct {
+	mock.Mock
+}
+
+func (m *mockRepo) ListAll(ctx context.Context) ([]models.Product, error) {
+	args := m.Called(ctx)
+	products, _ := args.Get(0).([]models.Product)
+	return products, args.Error(1)
+}
+
+func (m *mockRepo) UpsertSnapshot(ctx context.Context, snapshot products.Snapshot) (products.SnapshotSummary, error) {
+	args := m.Called(ctx, snapshot)
+	return args.Get(0).(products.SnapshotSummary), args.Error(1)
+}
+
+func (m *mockRepo) FindOrphanVariants(ctx context.Context) ([]models.Variant, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Variant), args.Error(1)
+}
+
+func (m *mockRepo) DeleteOrphanVariants(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRepo) DeleteVariant(ctx context.Context, code, sku string) error {
+	args := m.Called(ctx, code, sku)
+	return args.Error(0)
+}
+
+func (m *mockRepo) List(ctx context.Context, filters products.SearchFilters) ([]models.Product, int64, error) {
+	args := m.Called(ctx, filters)
+	res, _ := args.Get(0).([]models.Product)
+	return res, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *mockRepo) Count(ctx context.Context, filters products.SearchFilters) (int64, error) {
+	args := m.Called(ctx, filters)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRepo) ListPage(ctx context.Context, filters products.SearchFilters) ([]models.Product, error) {
+	args := m.Called(ctx, filters)
+	res, _ := args.Get(0).([]models.Product)
+	return res, args.Error(1)
+}
+
+func (m *mockRepo) Exists(ctx context.Context, code string) (bool, error) {
+	args := m.Called(ctx, code)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRepo) GetByCode(ctx context.Context, code string) (models.Product, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *mockRepo) ListByCategories(ctx context.Context, categories []string, perCategory int) (map[string]products.CategoryProducts, error) {
+	args := m.Called(ctx, categories, perCategory)
+	res, _ := args.Get(0).(map[string]products.CategoryProducts)
+	return res, args.Error(1)
+}
+
+func (m *mockRepo) GetByCodeIncludingUnavailable(ctx context.Context, code string) (models.Product, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *mockRepo) GetByCodes(ctx context.Context, codes []string) ([]models.Product, error) {
+	args := m.Called(ctx, codes)
+	res, _ := args.Get(0).([]models.Product)
+	return res, args.Error(1)
+}
+
+func (m *mockRepo) GetByID(ctx context.Context, id string) (models.Product, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *mockRepo) Create(ctx context.Context, product *models.Product) error {
+	args := m.Called(ctx, product)
+	return args.Error(0)
+}
+
+func (m *mockRepo) ListAllFunc(ctx context.Context, category string, fn func([]models.Product) error) error {
+	args := m.Called(ctx, category, fn)
+	return args.Error(0)
+}
+
+func (m *mockRepo) PriceStats(ctx context.Context, categoryCode string) (products.PriceStats, error) {
+	args := m.Called(ctx, categoryCode)
+	return args.Get(0).(products.PriceStats), args.Error(1)
+}
+
+func (m *mockRepo) AdjustPrices(ctx context.Context, categoryCode string, adj products.Adjustment) (int64, error) {
+	args := m.Called(ctx, categoryCode, adj)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRepo) UpdateVariantPrices(ctx context.Context, code string, prices map[string]decimal.Decimal) ([]string, error) {
+	args := m.Called(ctx, code, prices)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *mockRepo) ListChangedSince(ctx context.Context, after products.ChangePosition, limit int) ([]models.Product, error) {
+	args := m.Called(ctx, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Product), args.Error(1)
+}
+
+func (m *mockRepo) ListVariants(ctx context.Context, filters products.VariantFilters) ([]products.ResolvedVariant, int64, error) {
+	args := m.Called(ctx, filters)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]products.ResolvedVariant), args.Get(1).(int64), args.Error(2)
+}
+
+// recordingPublisher keeps the published events for assertions.
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(e events.Event) {
+	p.events = append(p.events, e)
+}
+
+func (m *mockRepo) FindExisting(ctx context.Context, keys products.ExistingKeys) (products.ExistingKeys, error) {
+	args := m.Called(ctx, keys)
+	return args.Get(0).(products.ExistingKeys), args.Error(1)
+}
+
+func (m *mockRepo) GetRelated(ctx context.Context, code string, limit int) ([]models.Product, error) {
+	args := m.Called(ctx, code, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Product), args.Error(1)
+}
+
+func (m *mockRepo) AddImage(ctx context.Context, code string, image *models.Image) error {
+	args := m.Called(ctx, code, image)
+	return args.Error(0)
+}
+
+func (m *mockRepo) DeleteImage(ctx context.Context, code string, id uint) error {
+	args := m.Called(ctx, code, id)
+	return args.Error(0)
+}
+
+// memoryImportJobs keeps the import jobs in memory, recording the statuses
+// each job was saved with.
+type memoryImportJobs struct {
+	jobs     map[uint]models.ImportJob
+	statuses map[uint][]string
+}
+
+func newMemoryImportJobs() *memoryImportJobs {
+	return &memoryImportJobs{jobs: map[uint]models.ImportJob{}, statuses: map[uint][]string{}}
+}
+
+func (m *memoryImportJobs) save(job models.ImportJob) {
+	m.jobs[job.ID] = job
+	m.statuses[job.ID] = append(m.statuses[job.ID], job.Status)
+}
+
+func (m *memoryImportJobs) Create(ctx context.Context, job *models.ImportJob) error {
+	job.ID = uint(len(m.jobs) + 1)
+	m.save(*job)
+	return nil
+}
+
+func (m *memoryImportJobs) Get(ctx context.Context, id uint) (models.ImportJob, error) {
+	job, ok := m.jobs[id]
+	if !ok {
+		return job, imports.ErrJobNotFound
+	}
+	job.Data = nil
+	return job, nil
+}
+
+func (m *memoryImportJobs) Claim(ctx context.Context, id uint) (models.ImportJob, error) {
+	job, ok := m.jobs[id]
+	if !ok || job.Status != models.ImportQueued {
+		return job, imports.ErrJobNotQueued
+	}
+	job.Status = models.ImportRunning
+	m.save(job)
+	return job, nil
+}
+
+func (m *memoryImportJobs) Update(ctx context.Context, job *models.ImportJob) error {
+	if job.Finished() {
+		job.Data = nil
+	}
+	saved := *job
+	saved.Errors = slices.Clone(job.Errors)
+	m.save(saved)
+	return nil
+}
+
+func (m *memoryImportJobs) FailInterrupted(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// drainQueue is an ImportQueue whose jobs run synchronously once drained.
+type drainQueue struct {
+	ids []uint
+	err error
+}
+
+func (q *drainQueue) Enqueue(id uint) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.ids = append(q.ids, id)
+	return nil
+}
+
+func (q *drainQueue) drain(ctx context.Context, run func(context.Context, uint)) {
+	for len(q.ids) > 0 {
+		id := q.ids[0]
+		q.ids = q.ids[1:]
+		run(ctx, id)
+	}
+}
+
+// fakeTransactor runs the transactions on repo, recording how they ended.
+type fakeTransactor struct {
+	repo       *mockRepo
+	committed  bool
+	rolledBack bool
+}
+
+func (t *fakeTransactor) WithTransaction(ctx context.Context, fn func(repos.TxRepos) error) error {
+	err := fn(repos.TxRepos{Products: t.repo})
+	t.committed, t.rolledBack = err == nil, err != nil
+	return err
+}
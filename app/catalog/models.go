@@ -0,0 +1,297 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/app/dto"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type Response struct {
+	Products []dto.Product `json:"products"`
+	// ProductsAvailable is a deprecated alias of one of the counts of
+	// Availability, chosen by the ProductsAvailableAlias option.
+	ProductsAvailable int64        `json:"products_available"`
+	Availability      Availability `json:"availability"`
+	// Degraded is set when the products were listed without their variants.
+	Degraded bool `json:"degraded,omitempty"`
+	// Debug is only set for the admins asking for it with debug=true.
+	Debug *Debug `json:"debug,omitempty"`
+}
+
+// Availability tells apart the counts products_available was read as.
+type Availability struct {
+	// PageVariantCount is the number of variants of the products of the
+	// page.
+	PageVariantCount int `json:"page_variant_count"`
+	// TotalProducts is the number of products matching the filters,
+	// regardless of pagination.
+	TotalProducts int64 `json:"total_products"`
+}
+
+// GroupedResponse holds the first products of each requested category,
+// keyed by category code.
+type GroupedResponse struct {
+	Categories map[string]Response `json:"categories"`
+}
+
+// LookupResponse lists the products found for the requested codes, in the
+// order of the request unless preserveOrder=false, and the codes not found.
+type LookupResponse struct {
+	Products []dto.Product `json:"products"`
+	Missing  []string      `json:"missing"`
+}
+
+// Change is a product of the change feed, as it is now. Available is false
+// for the products outside of their availability window, which the catalog
+// does not list.
+type Change struct {
+	Code      string      `json:"code"`
+	Type      string      `json:"type"`
+	ChangedAt time.Time   `json:"changed_at"`
+	Available bool        `json:"available"`
+	Product   dto.Product `json:"product"`
+}
+
+// ChangesResponse is a page of the change feed. NextCursor lists the
+// changes following the page.
+type ChangesResponse struct {
+	Changes    []Change `json:"changes"`
+	NextCursor string   `json:"next_cursor"`
+}
+
+// RelatedResponse lists other products of the category of a product.
+type RelatedResponse struct {
+	Products []dto.Product `json:"products"`
+}
+
+// ImportResult counts the rows of an import, numbered from 1 with the header
+// excluded, and lists those that could not be imported. RolledBack tells an
+// atomic import created nothing as some rows failed.
+type ImportResult struct {
+	Total      int                  `json:"total"`
+	Processed  int                  `json:"processed"`
+	Created    int                  `json:"created"`
+	Failed     int                  `json:"failed"`
+	Errors     []models.ImportError `json:"errors"`
+	RolledBack bool                 `json:"rolled_back,omitempty"`
+}
+
+// ImportJobResponse is the status of an asynchronous import. Errors lists the
+// rows that could not be imported once the job is finished, and Error why
+// the job failed as a whole.
+type ImportJobResponse struct {
+	ID        uint                 `json:"id"`
+	Status    string               `json:"status"`
+	Total     int                  `json:"total"`
+	Processed int                  `json:"processed"`
+	Created   int                  `json:"created"`
+	Failed    int                  `json:"failed"`
+	Errors    []models.ImportError `json:"errors,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+func newImportJobResponse(job models.ImportJob) ImportJobResponse {
+	res := ImportJobResponse{
+		ID:        job.ID,
+		Status:    job.Status,
+		Total:     job.Total,
+		Processed: job.Processed,
+		Created:   job.Created,
+		Failed:    job.Failed,
+		Error:     job.Error,
+	}
+	if job.Finished() {
+		res.Errors = job.Errors
+	}
+	return res
+}
+
+// ComparisonResponse holds the compared products in the order of the request.
+type ComparisonResponse struct {
+	Products []ComparedProduct `json:"products"`
+	// VariantNames are the variant names of any of the products, sorted.
+	VariantNames []string `json:"variant_names"`
+	// CommonVariantNames are the variant names all the products have.
+	CommonVariantNames []string `json:"common_variant_names"`
+}
+
+type ComparedProduct struct {
+	dto.Product
+	// PriceDifference is how much more the product costs than the cheapest
+	// of the comparison.
+	PriceDifference currency.Money `json:"price_difference"`
+	VariantNames    []string       `json:"variant_names"`
+	// MissingVariantNames are the variant names other products have but
+	// this one does not.
+	MissingVariantNames []string `json:"missing_variant_names"`
+}
+
+type CreateProductRequest struct {
+	Code     string                 `json:"code"`
+	Price    PriceField             `json:"price"`
+	Category string                 `json:"category"`
+	Variants []CreateVariantRequest `json:"variants"`
+}
+
+// ProductsPayload is the body of a validation: a single product, as for
+// creation, or an array of them.
+type ProductsPayload []CreateProductRequest
+
+func (p *ProductsPayload) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		return dec.Decode((*[]CreateProductRequest)(p))
+	}
+
+	var product CreateProductRequest
+	if err := dec.Decode(&product); err != nil {
+		return err
+	}
+	*p = ProductsPayload{product}
+	return nil
+}
+
+// Outcomes of a product in a validation report.
+const (
+	OutcomeWouldCreate   = "would_create"
+	OutcomeWouldConflict = "would_conflict"
+	OutcomeInvalid       = "invalid"
+)
+
+// ValidationReport holds the validation of each product of the payload, in
+// the order of the payload.
+type ValidationReport struct {
+	Products []ProductValidation `json:"products"`
+}
+
+// ProductValidation tells whether creating the product would succeed.
+// Conflicts with existing codes or SKUs, or with earlier products of the
+// payload, are listed among the errors.
+type ProductValidation struct {
+	Index    int      `json:"index"`
+	Code     string   `json:"code"`
+	Outcome  string   `json:"outcome"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+type CreateVariantRequest struct {
+	Name  string     `json:"name"`
+	SKU   string     `json:"sku"`
+	Price PriceField `json:"price"`
+}
+
+type AdjustPricesRequest struct {
+	Factor decimal.Decimal `json:"factor"`
+}
+
+type PriceAdjustmentRequest struct {
+	Category string          `json:"category"`
+	Type     string          `json:"type"`
+	Value    decimal.Decimal `json:"value"`
+}
+
+type PriceAdjustmentResponse struct {
+	Updated int64 `json:"updated"`
+}
+
+type ExistsResponse struct {
+	Exists bool `json:"exists"`
+}
+
+// PriceStatsResponse holds the price aggregates of a category, all zero when
+// the category has no products. Currency is set when the prices are in minor
+// units.
+type PriceStatsResponse struct {
+	Category string         `json:"category"`
+	Min      currency.Money `json:"min"`
+	Max      currency.Money `json:"max"`
+	Avg      currency.Money `json:"avg"`
+	Currency string         `json:"currency,omitempty"`
+	Count    int64          `json:"count"`
+}
+
+// SnapshotRequest is a catalog snapshot to upsert. Products refer to their
+// category by code, either one of the snapshot or an existing one.
+type SnapshotRequest struct {
+	Categories []SnapshotCategory     `json:"categories"`
+	Products   []CreateProductRequest `json:"products"`
+}
+
+type SnapshotCategory struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// SnapshotResponse counts the upserted rows by kind.
+type SnapshotResponse struct {
+	Categories UpsertCounts `json:"categories"`
+	Products   UpsertCounts `json:"products"`
+	Variants   UpsertCounts `json:"variants"`
+}
+
+// UpsertCounts tells how many rows were created, and how many existing ones
+// were written over, whether or not their values changed.
+type UpsertCounts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+type OrphanVariantsResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// ProductChanged is the data of product.updated events.
+type ProductChanged struct {
+	Code string `json:"code"`
+}
+
+// CategoryPricesChanged is the data of category.prices_adjusted events.
+type CategoryPricesChanged struct {
+	Code    string `json:"code"`
+	Updated int64  `json:"updated"`
+}
+
+// VariantPricesRequest sets the prices of variants of a product, keyed by
+// SKU. A zero price makes the variant inherit the price of the product.
+type VariantPricesRequest struct {
+	Prices map[string]PriceField `json:"prices"`
+}
+
+// VariantPricesResponse tells how many variants were updated and which SKUs
+// matched none of the variants of the product.
+type VariantPricesResponse struct {
+	Updated       int      `json:"updated"`
+	UnmatchedSKUs []string `json:"unmatched_skus"`
+}
+
+// AddImageRequest adds an image at Position, or last when it is zero.
+type AddImageRequest struct {
+	URL      string `json:"url"`
+	Position int    `json:"position"`
+	AltText  string `json:"alt_text"`
+}
+
+// VariantsResponse is a page of the variants of the catalog, along with the
+// number of variants matching the filters.
+type VariantsResponse struct {
+	Variants []ListedVariant `json:"variants"`
+	Total    int64           `json:"total"`
+}
+
+// ListedVariant is a variant with its resolved price, the price of its
+// product when it has none of its own.
+type ListedVariant struct {
+	SKU         string         `json:"sku"`
+	Name        string         `json:"name"`
+	Price       currency.Money `json:"price"`
+	ProductCode string         `json:"product_code"`
+	Category    string         `json:"category,omitempty"`
+}
@@ -0,0 +1,143 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/category"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// The tests below run the handler against the seeded database of the sql
+// directory.
+
+func TestPostgres_HandleGetSpecific_VariantDeleted(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	// The product and its variants were last updated long before the
+	// deletion, not within the second of precision of Last-Modified
+	require.NoError(t, db.Exec(`UPDATE products SET updated_at = now() - interval '1 hour'`).Error)
+	require.NoError(t, db.Exec(`UPDATE product_variants SET updated_at = now() - interval '1 hour'`).Error)
+	mux := newTestMux(newHandler(t, products.NewGormRepo(db), Options{}))
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/catalog/PROD001", nil)
+		req.Header = header
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get(http.Header{})
+	require.Equal(t, http.StatusOK, first.Code)
+	lastModified := first.Header().Get("Last-Modified")
+	require.NotEmpty(t, lastModified)
+	require.Equal(t, http.StatusNotModified, get(http.Header{"If-Modified-Since": {lastModified}}).Code)
+
+	require.NoError(t, products.NewGormRepo(db).DeleteVariant(context.Background(), "PROD001", "SKU001C"))
+
+	rec := get(http.Header{"If-Modified-Since": {lastModified}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "SKU001C")
+	assert.NotEqual(t, lastModified, rec.Header().Get("Last-Modified"))
+}
+
+// The inherited variant prices depend on the markup of the category, whose
+// changes move the Last-Modified of its products, unlike its renames.
+func TestPostgres_HandleGetSpecific_MarkupChanged(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	require.NoError(t, db.Exec(`UPDATE products SET updated_at = now() - interval '1 hour'`).Error)
+	require.NoError(t, db.Exec(`UPDATE product_variants SET updated_at = now() - interval '1 hour'`).Error)
+	mux := newTestMux(newHandler(t, products.NewGormRepo(db), Options{}))
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/catalog/PROD002", nil)
+		req.Header = header
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	categories := category.NewGormRepo(db)
+	ctx := context.Background()
+
+	first := get(http.Header{})
+	require.Equal(t, http.StatusOK, first.Code)
+	lastModified := first.Header().Get("Last-Modified")
+	require.NotEmpty(t, lastModified)
+
+	require.NoError(t, categories.Update(ctx, &models.Category{Code: "shoes", Name: "Footwear", Version: 1}))
+	require.Equal(t, http.StatusNotModified, get(http.Header{"If-Modified-Since": {lastModified}}).Code)
+
+	markup := decimal.NullDecimal{Decimal: decimal.NewFromInt(10), Valid: true}
+	require.NoError(t, categories.Update(ctx, &models.Category{Code: "shoes", Name: "Footwear", MarkupPercent: markup, Version: 2}))
+
+	rec := get(http.Header{"If-Modified-Since": {lastModified}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `{"name":"Variant A","sku":"SKU002A","price":"13.74"}`)
+	assert.NotEqual(t, lastModified, rec.Header().Get("Last-Modified"))
+}
+
+func TestPostgres_UnavailableProducts(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	// PROD001 opens tomorrow and PROD003's window closed yesterday, both are
+	// left out of every public endpoint but the changes feed
+	past, future := time.Now().Add(-24*time.Hour), time.Now().Add(24*time.Hour)
+	require.NoError(t, db.Exec(`UPDATE products SET available_from = ? WHERE code = 'PROD001'`, future).Error)
+	require.NoError(t, db.Exec(`UPDATE products SET available_from = ?, available_to = ? WHERE code = 'PROD003'`, past.Add(-24*time.Hour), past).Error)
+	mux := newTestMux(newHandler(t, products.NewGormRepo(db), Options{}))
+
+	tests := []struct {
+		name     string
+		target   string
+		wantCode int
+		want     string
+	}{
+		{"details", "/catalog/PROD001", http.StatusNotFound, "product not found"},
+		{"exists", "/catalog/PROD003/exists", http.StatusOK, `{"exists":false}`},
+		{"related", "/catalog/PROD004/related", http.StatusOK, "PROD007"},
+		{"lookup", "/catalog/lookup?codes=PROD001,PROD003,PROD004", http.StatusOK, "PROD004"},
+		{"compare", "/catalog/compare?codes=PROD001,PROD004", http.StatusNotFound, "products not found: PROD001"},
+		{"export", "/catalog/export", http.StatusOK, "PROD004"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			require.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+			assert.Contains(t, rec.Body.String(), tt.want)
+			if tt.wantCode == http.StatusOK {
+				assert.NotContains(t, rec.Body.String(), "PROD001")
+			}
+			assert.NotContains(t, rec.Body.String(), "PROD003")
+		})
+	}
+
+	t.Run("changes", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/changes?since=2000-01-01T00:00:00Z&limit=100", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var body ChangesResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		available := map[string]bool{}
+		for _, c := range body.Changes {
+			available[c.Code] = c.Available
+		}
+		assert.Len(t, available, 8)
+		assert.False(t, available["PROD001"])
+		assert.False(t, available["PROD003"])
+		assert.True(t, available["PROD004"])
+	})
+}
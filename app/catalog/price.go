@@ -0,0 +1,70 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+)
+
+// plainDecimal matches the prices written in plain decimal notation.
+var plainDecimal = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+var errMalformedPrice = errors.New("price must be a decimal number")
+
+// PriceField is a price of a request body, given as a JSON number or a
+// string. It is read digit by digit, never through a float64, so that a price
+// more precise than stored reaches priceValidator as sent rather than
+// rounded. null leaves it zero.
+type PriceField struct {
+	decimal.Decimal
+}
+
+// UnmarshalJSON refuses the prices in scientific notation with an
+// *api.ValueError, answered with 422, and those that are no number at all as
+// an invalid body.
+func (p *PriceField) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	switch {
+	case text == "null":
+		return nil
+	case strings.HasPrefix(text, `"`):
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+
+	price, err := parsePrice(text)
+	var priceErr *PriceError
+	if errors.As(err, &priceErr) {
+		return &api.ValueError{Err: err}
+	}
+	p.Decimal = price
+	return err
+}
+
+// parsePrice reads a price in plain decimal notation, refusing scientific
+// notation with a *PriceError. Its scale is left to priceValidator, which
+// names the price in its errors.
+func parsePrice(text string) (decimal.Decimal, error) {
+	if !plainDecimal.MatchString(text) {
+		if _, err := decimal.NewFromString(text); err == nil && strings.ContainsAny(text, "eE") {
+			return decimal.Decimal{}, &PriceError{Field: "price", Constraint: "not use scientific notation"}
+		}
+		return decimal.Decimal{}, errMalformedPrice
+	}
+	return decimal.NewFromString(text)
+}
+
+// decimalPrices returns the prices of the request keyed by SKU.
+func (r VariantPricesRequest) decimalPrices() map[string]decimal.Decimal {
+	prices := make(map[string]decimal.Decimal, len(r.Prices))
+	for sku, price := range r.Prices {
+		prices[sku] = price.Decimal
+	}
+	return prices
+}
@@ -0,0 +1,75 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+)
+
+func TestPriceField_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+		// wantErr is the message of the *api.ValueError, "invalid" for the
+		// values that are no price at all
+		wantErr string
+	}{
+		{"number", `19.99`, "19.99", ""},
+		{"string", `"19.99"`, "19.99", ""},
+		{"integer", `20`, "20", ""},
+		{"negative", `-5.5`, "-5.5", ""},
+		{"zero", `0`, "0", ""},
+		{"trailing zeros", `19.990`, "19.99", ""},
+		// Left as sent for priceValidator to refuse, not rounded as a
+		// float64 would
+		{"more than 2 decimal places", `19.999`, "19.999", ""},
+		{"more precise than a float64", `0.1000000000000000055511151231257827`, "0.1000000000000000055511151231257827", ""},
+		{"null", `null`, "0", ""},
+		{"scientific notation", `1e3`, "", "price must not use scientific notation"},
+		{"negative exponent", `1.5E-2`, "", "price must not use scientific notation"},
+		{"scientific notation string", `"1e3"`, "", "price must not use scientific notation"},
+		{"empty string", `""`, "", "invalid"},
+		{"text", `"ten"`, "", "invalid"},
+		{"padded string", `" 19.99"`, "", "invalid"},
+		{"explicit sign", `"+19.99"`, "", "invalid"},
+		{"boolean", `true`, "", "invalid"},
+		{"object", `{"amount":"19.99"}`, "", "invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req struct {
+				Price PriceField `json:"price"`
+			}
+			err := json.Unmarshal([]byte(`{"price":`+tt.json+`}`), &req)
+
+			switch tt.wantErr {
+			case "":
+				require.NoError(t, err)
+				assert.True(t, req.Price.Equal(decimal.RequireFromString(tt.want)), req.Price.String())
+			case "invalid":
+				var valueErr *api.ValueError
+				require.Error(t, err)
+				assert.False(t, errors.As(err, &valueErr))
+			default:
+				var valueErr *api.ValueError
+				require.ErrorAs(t, err, &valueErr)
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPriceField_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal(CreateVariantRequest{Name: "A", SKU: "SKU009A", Price: PriceField{decimal.RequireFromString("19.99")}})
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"A","sku":"SKU009A","price":"19.99"}`, string(b))
+}
@@ -0,0 +1,25 @@
+package catalog
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// queryGroup runs the independent queries of a request concurrently, at most
+// limit at a time when limit is positive. The first query failing cancels
+// the context of the others, and its error is the one returned.
+type queryGroup struct {
+	limit int
+}
+
+func (g queryGroup) run(ctx context.Context, queries ...func(context.Context) error) error {
+	group, ctx := errgroup.WithContext(ctx)
+	if g.limit > 0 {
+		group.SetLimit(g.limit)
+	}
+	for _, query := range queries {
+		group.Go(func() error { return query(ctx) })
+	}
+	return group.Wait()
+}
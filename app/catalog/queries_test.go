@@ -0,0 +1,97 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestHandleGet_ConcurrentQueries(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	res := []models.Product{{Code: "PROD001", Price: decimal.RequireFromString("10.99")}}
+	filters := products.SearchFilters{Limit: 10}
+
+	get := func(repo *mockRepo, opts Options) (*httptest.ResponseRecorder, time.Duration) {
+		opts.ConcurrentQueries = true
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		newTestMux(newHandler(t, repo, opts)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+		return rec, time.Since(start)
+	}
+
+	t.Run("the page and total are queried together", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Count", mock.Anything, filters).After(delay).Return(int64(8), nil)
+		repo.On("ListPage", mock.Anything, filters).After(delay).Return(res, nil)
+
+		rec, elapsed := get(repo, Options{})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[{"code":"PROD001","price":"10.99"}],"products_available":8,"availability":{"page_variant_count":0,"total_products":8}}`, rec.Body.String())
+		// The slowest query, not the sum of them
+		assert.Less(t, elapsed, 2*delay)
+		repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+
+	t.Run("one at a time", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Count", mock.Anything, filters).After(delay).Return(int64(8), nil)
+		repo.On("ListPage", mock.Anything, filters).After(delay).Return(res, nil)
+
+		rec, elapsed := get(repo, Options{QueryConcurrency: 1})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.GreaterOrEqual(t, elapsed, 2*delay)
+	})
+
+	t.Run("a failure cancels the other queries", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Count", mock.Anything, filters).Return(int64(0), errors.New("boom"))
+		// The page query only ends when it is canceled
+		var pageErr error
+		repo.On("ListPage", mock.Anything, filters).Run(func(args mock.Arguments) {
+			ctx := args.Get(0).(context.Context)
+			select {
+			case <-ctx.Done():
+				pageErr = ctx.Err()
+			case <-time.After(10 * delay):
+			}
+		}).Return(nil, context.Canceled)
+
+		rec, elapsed := get(repo, Options{})
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.JSONEq(t, `{"error":"boom"}`, rec.Body.String())
+		assert.ErrorIs(t, pageErr, context.Canceled)
+		assert.Less(t, elapsed, 10*delay)
+	})
+
+	t.Run("a page without variants degrades the listing", func(t *testing.T) {
+		degraded := products.SearchFilters{Limit: 10, AllowMissingVariants: true}
+		repo := new(mockRepo)
+		repo.On("Count", mock.Anything, degraded).Return(int64(8), nil)
+		repo.On("ListPage", mock.Anything, degraded).Return(res, products.ErrVariantsUnavailable)
+
+		rec, _ := get(repo, Options{DegradeOnVariantError: true})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "true", rec.Header().Get("X-Degraded"))
+		assert.Contains(t, rec.Body.String(), `"total_products":8`)
+	})
+}
+
+func TestNewCatalogHandler_QueryConcurrency(t *testing.T) {
+	_, err := NewCatalogHandler(new(mockRepo), Options{QueryConcurrency: -1})
+
+	assert.EqualError(t, err, "query concurrency must not be negative")
+}
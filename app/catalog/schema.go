@@ -0,0 +1,17 @@
+package catalog
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// responseSchema is the JSON Schema of Response, kept in sync by the tests.
+//
+//go:embed schema.json
+var responseSchema []byte
+
+// HandleSchema serves the JSON Schema of the catalog listing response.
+func (h *CatalogHandler) HandleSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(responseSchema)
+}
@@ -0,0 +1,163 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// servedSchemas compiles the schema served by GET /catalog/schema, for the
+// listings, and its product definition, for the product details.
+func servedSchemas(t *testing.T) (listing, product *jsonschema.Schema) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	newTestMux(newHandler(t, new(mockRepo), Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/schema", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/schema+json", rec.Header().Get("Content-Type"))
+
+	doc, err := jsonschema.UnmarshalJSON(rec.Body)
+	require.NoError(t, err)
+	c := jsonschema.NewCompiler()
+	require.NoError(t, c.AddResource("catalog-response.json", doc))
+	listing, err = c.Compile("catalog-response.json")
+	require.NoError(t, err)
+	product, err = c.Compile("catalog-response.json#/$defs/product")
+	require.NoError(t, err)
+	return listing, product
+}
+
+func validate(t *testing.T, schema *jsonschema.Schema, body []byte) error {
+	t.Helper()
+
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	require.NoError(t, err)
+	return schema.Validate(inst)
+}
+
+// schemaProducts are stored products filling every field of the responses.
+func schemaProducts() []models.Product {
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 3, 0)
+	return []models.Product{
+		{Code: "PROD001", Price: decimal.RequireFromString("10.99")},
+		{
+			Code:          "PROD002",
+			Price:         decimal.RequireFromString("12.49"),
+			Category:      &models.Category{Code: "shoes", Name: "Shoes"},
+			Variants:      []models.Variant{{Name: "Variant A", SKU: "SKU002A", Price: decimal.RequireFromString("13")}, {Name: "Variant B", SKU: "SKU002B"}},
+			Images:        []models.Image{{ID: 3, URL: "https://cdn.example.com/prod002.jpg", Position: 1, AltText: "Front"}, {ID: 4, URL: "https://cdn.example.com/back.jpg", Position: 2}},
+			AvailableFrom: &from,
+			AvailableTo:   &to,
+		},
+	}
+}
+
+func TestResponseSchema(t *testing.T) {
+	listing, product := servedSchemas(t)
+	stored := schemaProducts()
+
+	t.Run("listings are valid", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			target string
+			opts   Options
+			res    []models.Product
+			err    error
+		}{
+			{"empty", "/catalog", Options{}, nil, nil},
+			{"every field", "/catalog", Options{}, stored, nil},
+			{"number prices", "/catalog?priceFormat=number", Options{}, stored, nil},
+			{"minor units", "/catalog?amountFormat=minor&currency=GBP", Options{}, stored, nil},
+			{"degraded", "/catalog", Options{DegradeOnVariantError: true}, stored, products.ErrVariantsUnavailable},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("List", mock.Anything, mock.Anything).Return(tt.res, int64(len(tt.res)), tt.err)
+
+				rec := httptest.NewRecorder()
+				newTestMux(newHandler(t, repo, tt.opts)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+				require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+				assert.NoError(t, validate(t, listing, rec.Body.Bytes()), rec.Body.String())
+			})
+		}
+	})
+
+	t.Run("debug listings are valid", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, mock.Anything).Return(stored, int64(len(stored)), nil)
+		req := httptest.NewRequest(http.MethodGet, "/catalog?debug=true&category=shoes&q=shoe&priceLessThan=20&priceAppliesTo=variant"+
+			"&variant=Variant&skuPrefix=SKU&hasVariants=true&modifiedSince=2025-06-01T00:00:00Z&sort=price_asc&includeUnavailable=true", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{AdminToken: "secret", DegradeOnVariantError: true})).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.Contains(t, rec.Body.String(), `"debug"`)
+		assert.NoError(t, validate(t, listing, rec.Body.Bytes()), rec.Body.String())
+	})
+
+	t.Run("product details are valid", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			target string
+		}{
+			{"default", "/catalog/PROD002"},
+			{"all images", "/catalog/PROD002?allImages=true"},
+			{"number prices", "/catalog/PROD002?priceFormat=number"},
+			{"minor units", "/catalog/PROD002?amountFormat=minor"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("GetByCode", mock.Anything, "PROD002").Return(stored[1], nil)
+
+				rec := httptest.NewRecorder()
+				newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+				require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+				assert.NoError(t, validate(t, product, rec.Body.Bytes()), rec.Body.String())
+			})
+		}
+	})
+
+	t.Run("unexpected documents are invalid", func(t *testing.T) {
+		availability := map[string]any{"page_variant_count": 0, "total_products": 1}
+		samples := []any{
+			map[string]any{"products": []any{}, "availability": availability},
+			map[string]any{"products": []any{}, "products_available": 1},
+			map[string]any{"products": []any{map[string]any{"code": "PROD001"}}, "products_available": 1, "availability": availability},
+			map[string]any{"products": []any{}, "products_available": 1, "availability": availability, "total": 1},
+			map[string]any{"products": []any{}, "products_available": 1, "availability": map[string]any{"total_products": 1}},
+			map[string]any{"products": []any{map[string]any{"code": "PROD001", "price": 10.999}}, "products_available": 1, "availability": availability},
+			map[string]any{"products": []any{map[string]any{"code": "PROD001", "price": -10.99}}, "products_available": 1, "availability": availability},
+			map[string]any{"products": []any{map[string]any{"code": "PROD001", "price": "10.9"}}, "products_available": 1, "availability": availability},
+			map[string]any{"products": []any{map[string]any{"code": "PROD001", "price": "10.99", "available_from": 1}}, "products_available": 1, "availability": availability},
+			map[string]any{"products": []any{map[string]any{"code": "PROD001", "price": 1099, "currency": "eur"}}, "products_available": 1, "availability": availability},
+			map[string]any{"products": []any{}, "products_available": 1, "availability": availability, "degraded": false},
+		}
+
+		for _, sample := range samples {
+			body, err := json.Marshal(sample)
+			require.NoError(t, err)
+			assert.Error(t, validate(t, listing, body), string(body))
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// categoryCodePattern matches the category codes accepted by POST /categories.
+var categoryCodePattern = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+// HandleSnapshotImport upserts a catalog snapshot of categories and products,
+// keyed on their codes, so that loading the same snapshot again changes
+// nothing. The snapshot is validated as a whole before anything is written,
+// and written all or nothing.
+func (h *CatalogHandler) HandleSnapshotImport(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	var req SnapshotRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+	if err := h.validateSnapshot(req); err != nil {
+		api.ErrorResponse(w, validationStatus(err), err.Error())
+		return
+	}
+
+	snapshot := products.Snapshot{
+		Categories: make([]models.Category, len(req.Categories)),
+		Products:   make([]models.Product, len(req.Products)),
+	}
+	for i, c := range req.Categories {
+		snapshot.Categories[i] = models.Category{Code: c.Code, Name: c.Name}
+	}
+	for i, p := range req.Products {
+		snapshot.Products[i] = newProductModel(p)
+	}
+
+	summary, err := h.writer.UpsertSnapshot(r.Context(), snapshot)
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	res := SnapshotResponse{
+		Categories: UpsertCounts(summary.Categories),
+		Products:   UpsertCounts(summary.Products),
+		Variants:   UpsertCounts(summary.Variants),
+	}
+	h.record(r.Context(), audit.Change{Action: audit.Import, EntityType: audit.Catalog, After: res})
+	api.OKResponse(w, res)
+}
+
+// validateSnapshot checks the products as HandleCreate does, and that no
+// category code, product code or variant SKU appears twice.
+func (h *CatalogHandler) validateSnapshot(req SnapshotRequest) error {
+	if len(req.Categories) == 0 && len(req.Products) == 0 {
+		return errors.New("at least one category or product is required")
+	}
+
+	categories := make(map[string]struct{}, len(req.Categories))
+	for _, c := range req.Categories {
+		if !categoryCodePattern.MatchString(c.Code) {
+			return fmt.Errorf("invalid category code %q", c.Code)
+		}
+		if c.Name == "" {
+			return fmt.Errorf("category %s: name is required", c.Code)
+		}
+		if _, ok := categories[c.Code]; ok {
+			return fmt.Errorf("duplicate category code %s", c.Code)
+		}
+		categories[c.Code] = struct{}{}
+	}
+
+	codes := make(map[string]struct{}, len(req.Products))
+	skus := make(map[string]struct{})
+	for _, p := range req.Products {
+		if err := h.validateCreateProduct(p); err != nil {
+			if p.Code == "" {
+				return err
+			}
+			return fmt.Errorf("product %s: %w", p.Code, err)
+		}
+		if _, ok := codes[p.Code]; ok {
+			return fmt.Errorf("duplicate product code %s", p.Code)
+		}
+		codes[p.Code] = struct{}{}
+		for _, v := range p.Variants {
+			if _, ok := skus[v.SKU]; ok {
+				return fmt.Errorf("duplicate variant sku %s", v.SKU)
+			}
+			skus[v.SKU] = struct{}{}
+		}
+	}
+	return nil
+}
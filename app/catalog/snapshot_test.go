@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestHandleSnapshotImport(t *testing.T) {
+	upsert := func(repo *mockRepo, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/import", strings.NewReader(body))
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, req)
+		return rec
+	}
+	const snapshot = `{
+		"categories": [{"code":"bags","name":"Bags"}],
+		"products": [
+			{"code":"PROD009","price":"49.90","category":"bags","variants":[{"name":"Small","sku":"SKU009A"}]},
+			{"code":"PROD010","price":"5.99"}
+		]
+	}`
+
+	t.Run("first import creates everything", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("UpsertSnapshot", mock.Anything, products.Snapshot{
+			Categories: []models.Category{{Code: "bags", Name: "Bags"}},
+			Products: []models.Product{
+				{
+					Code:     "PROD009",
+					Price:    decimal.RequireFromString("49.90"),
+					Category: &models.Category{Code: "bags"},
+					Variants: []models.Variant{{Name: "Small", SKU: "SKU009A"}},
+				},
+				{Code: "PROD010", Price: decimal.RequireFromString("5.99"), Variants: []models.Variant{}},
+			},
+		}).Return(products.SnapshotSummary{
+			Categories: products.UpsertCounts{Created: 1},
+			Products:   products.UpsertCounts{Created: 2},
+			Variants:   products.UpsertCounts{Created: 1},
+		}, nil)
+
+		rec := upsert(repo, snapshot)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"categories":{"created":1,"updated":0},
+			"products":{"created":2,"updated":0},
+			"variants":{"created":1,"updated":0}
+		}`, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("re-import updates everything", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("UpsertSnapshot", mock.Anything, mock.Anything).Return(products.SnapshotSummary{
+			Categories: products.UpsertCounts{Updated: 1},
+			Products:   products.UpsertCounts{Updated: 2},
+			Variants:   products.UpsertCounts{Updated: 1},
+		}, nil)
+
+		rec := upsert(repo, snapshot)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"categories":{"created":0,"updated":1},
+			"products":{"created":0,"updated":2},
+			"variants":{"created":0,"updated":1}
+		}`, rec.Body.String())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("UpsertSnapshot", mock.Anything, mock.Anything).
+			Return(products.SnapshotSummary{}, errs.WithKind(errs.Invalid, errors.New("category not found: bags")))
+
+		rec := upsert(repo, `{"products":[{"code":"PROD009","price":"49.90","category":"bags"}]}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"category not found: bags"}`, rec.Body.String())
+	})
+
+	t.Run("rejects invalid snapshots", func(t *testing.T) {
+		tests := []struct {
+			body       string
+			wantStatus int
+			wantErr    string
+		}{
+			{`{}`, http.StatusBadRequest, "at least one category or product is required"},
+			{`{"categories":[{"code":"Bags","name":"Bags"}]}`, http.StatusBadRequest, `invalid category code "Bags"`},
+			{`{"categories":[{"code":"bags"}]}`, http.StatusBadRequest, "category bags: name is required"},
+			{`{"categories":[{"code":"bags","name":"Bags"},{"code":"bags","name":"Other bags"}]}`, http.StatusBadRequest, "duplicate category code bags"},
+			{`{"products":[{"code":"PROD009","price":"0"}]}`, http.StatusBadRequest, "product PROD009: price must be positive"},
+			{`{"products":[{"code":"PROD009","price":"4.999"}]}`, http.StatusUnprocessableEntity, "product PROD009: price must have at most 2 decimal places"},
+			{`{"products":[{"price":"4.99"}]}`, http.StatusBadRequest, "invalid product code"},
+			{`{"products":[{"code":"PROD009","price":"4.99"},{"code":"PROD009","price":"5.99"}]}`, http.StatusBadRequest, "duplicate product code PROD009"},
+			{`{"products":[
+				{"code":"PROD009","price":"4.99","variants":[{"name":"Small","sku":"SKU009A"}]},
+				{"code":"PROD010","price":"5.99","variants":[{"name":"Small","sku":"SKU009A"}]}
+			]}`, http.StatusBadRequest, "duplicate variant sku SKU009A"},
+		}
+
+		for _, tt := range tests {
+			repo := new(mockRepo)
+
+			rec := upsert(repo, tt.body)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, tt.body)
+			assert.JSONEq(t, `{"error":`+strconv.Quote(tt.wantErr)+`}`, rec.Body.String(), tt.body)
+			assert.Empty(t, repo.Calls)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultProductCodePattern is the product code format used unless configured otherwise.
+const DefaultProductCodePattern = `^PROD\d{3}$`
+
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,32}$`)
+
+const (
+	maxImageURLLength = 2048
+	maxAltTextLength  = 256
+)
+
+// codeValidator checks product codes against the configured pattern. The
+// same validator is shared by every endpoint receiving a product code so they
+// cannot drift apart.
+type codeValidator struct {
+	pattern *regexp.Regexp
+}
+
+func newCodeValidator(pattern string) (codeValidator, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return codeValidator{}, fmt.Errorf("invalid product code pattern: %w", err)
+	}
+	return codeValidator{pattern: re}, nil
+}
+
+func (v codeValidator) validate(code string) error {
+	if !v.pattern.MatchString(code) {
+		return errors.New("invalid product code")
+	}
+	return nil
+}
+
+// DefaultMaxPrice is the highest price accepted unless configured otherwise.
+var DefaultMaxPrice = decimal.NewFromInt(1_000_000)
+
+// maxPriceScale is the number of decimals prices are stored with.
+const maxPriceScale = 2
+
+// PriceError is a price breaking one of the constraints of priceValidator.
+// Handlers answer it with 422 rather than 400.
+type PriceError struct {
+	Field      string
+	Constraint string
+}
+
+func (e *PriceError) Error() string {
+	return fmt.Sprintf("%s must %s", e.Field, e.Constraint)
+}
+
+// priceValidator checks the scale and the upper bound of the prices written
+// by clients. Like codeValidator, it is shared by every endpoint writing
+// prices so they cannot drift apart.
+type priceValidator struct {
+	max decimal.Decimal
+}
+
+func newPriceValidator(max decimal.Decimal) (priceValidator, error) {
+	if !max.IsPositive() {
+		return priceValidator{}, errors.New("max price must be positive")
+	}
+	return priceValidator{max: max}, nil
+}
+
+// validate checks the price of field, returning a *PriceError. Whether the
+// price may be zero or negative is up to the callers.
+func (v priceValidator) validate(field string, price decimal.Decimal) error {
+	// Trailing zeros, as in 19.990, do not make a price more precise
+	if !price.Equal(price.Truncate(maxPriceScale)) {
+		return &PriceError{Field: field, Constraint: fmt.Sprintf("have at most %d decimal places", maxPriceScale)}
+	}
+	if price.GreaterThan(v.max) {
+		return &PriceError{Field: field, Constraint: "be at most " + v.max.String()}
+	}
+	return nil
+}
+
+// validateVariant is validate for variant prices, zero meaning the variant
+// inherits the price of its product.
+func (v priceValidator) validateVariant(field string, price decimal.Decimal) error {
+	if price.IsZero() {
+		return nil
+	}
+	return v.validate(field, price)
+}
+
+// validationStatus is the status of the response to a validation error.
+func validationStatus(err error) int {
+	var priceErr *PriceError
+	if errors.As(err, &priceErr) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// normalizeProductCode trims and uppercases a product code typed by hand, so
+// that prod001 finds PROD001. The result still has to pass the validator.
+func normalizeProductCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validateAddImage accepts absolute http and https image URLs only.
+func validateAddImage(req AddImageRequest) error {
+	if len(req.URL) > maxImageURLLength {
+		return fmt.Errorf("image url must be at most %d characters", maxImageURLLength)
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("image url must be an absolute http or https URL")
+	}
+	if len(req.AltText) > maxAltTextLength {
+		return fmt.Errorf("alt text must be at most %d characters", maxAltTextLength)
+	}
+	if req.Position < 0 {
+		return errors.New("position must not be negative")
+	}
+	return nil
+}
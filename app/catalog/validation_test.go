@@ -0,0 +1,70 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceValidator(t *testing.T) {
+	v, err := newPriceValidator(DefaultMaxPrice)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		price   string
+		variant bool
+		want    string
+	}{
+		{"scale 0", "10", false, ""},
+		{"scale 1", "10.5", false, ""},
+		{"scale 2", "10.55", false, ""},
+		{"scale 3", "10.555", false, "price must have at most 2 decimal places"},
+		{"scale 4", "10.5555", false, "price must have at most 2 decimal places"},
+		{"trailing zeros", "10.5500", false, ""},
+		{"a cent under the max", "999999.99", false, ""},
+		{"the max", "1000000", false, ""},
+		{"the max with decimals", "1000000.00", false, ""},
+		{"a cent over the max", "1000000.01", false, "price must be at most 1000000"},
+		{"over the max", "1000001", false, "price must be at most 1000000"},
+		{"inherited variant price", "0", true, ""},
+		{"variant scale 3", "0.001", true, "price must have at most 2 decimal places"},
+		{"variant over the max", "1000000.01", true, "price must be at most 1000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validate := v.validate
+			if tt.variant {
+				validate = v.validateVariant
+			}
+
+			err := validate("price", decimal.RequireFromString(tt.price))
+
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var priceErr *PriceError
+			require.ErrorAs(t, err, &priceErr)
+			assert.Equal(t, "price", priceErr.Field)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+
+	t.Run("configured max", func(t *testing.T) {
+		v, err := newPriceValidator(decimal.NewFromInt(500))
+		require.NoError(t, err)
+
+		assert.NoError(t, v.validate("price", decimal.RequireFromString("500")))
+		assert.EqualError(t, v.validate("price", decimal.RequireFromString("500.01")), "price must be at most 500")
+	})
+
+	t.Run("non positive max", func(t *testing.T) {
+		_, err := newPriceValidator(decimal.NewFromInt(-1))
+
+		assert.EqualError(t, err, "max price must be positive")
+	})
+}
@@ -0,0 +1,51 @@
+package catalog
+
+import (
+	"net/http"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+)
+
+// HandleVariants lists the variants of the available products one row per
+// variant, filtered on the category and on the resolved price, so that the
+// variants inheriting the price of their product are priced as they sell.
+func (h *CatalogHandler) HandleVariants(w http.ResponseWriter, r *http.Request) {
+	filters, err := validateVariantFilters(r, h.offsets)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conv, ok := h.requestedConversion(w, r)
+	if !ok {
+		return
+	}
+	format, err := h.requestedPriceFormat(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, total, err := h.reader.ListVariants(r.Context(), filters)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	response := VariantsResponse{Variants: make([]ListedVariant, len(res)), Total: total}
+	for i, v := range res {
+		response.Variants[i] = ListedVariant{
+			SKU:         v.SKU,
+			Name:        v.Name,
+			Price:       conv.Money(v.Price),
+			ProductCode: v.ProductCode,
+			Category:    v.CategoryCode,
+		}
+		format.Apply(&response.Variants[i].Price)
+	}
+	api.SetPaginationHeaders(w, r, filters.Offset, filters.Limit, total)
+	api.OKResponse(w, response)
+}
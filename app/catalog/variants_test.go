@@ -0,0 +1,83 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+)
+
+func TestHandleVariants(t *testing.T) {
+	get := func(repo *mockRepo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestMux(newHandler(t, repo, Options{})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	res := []products.ResolvedVariant{
+		{SKU: "SKU001A", Name: "Variant A", Price: decimal.RequireFromString("11.99"), ProductCode: "PROD001", CategoryCode: "clothing"},
+		{SKU: "SKU009A", Name: "Variant A", Price: decimal.RequireFromString("5.00"), ProductCode: "PROD009"},
+	}
+
+	t.Run("variants with their resolved prices", func(t *testing.T) {
+		price := decimal.RequireFromString("15")
+		repo := new(mockRepo)
+		repo.On("ListVariants", mock.Anything, products.VariantFilters{Offset: 2, Limit: 100, Category: "clothing", PriceLessThan: &price}).
+			Return(res, int64(4), nil)
+
+		rec := get(repo, "/variants?offset=2&limit=1000&category=clothing&priceLessThan=15")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"variants":[
+			{"sku":"SKU001A","name":"Variant A","price":"11.99","product_code":"PROD001","category":"clothing"},
+			{"sku":"SKU009A","name":"Variant A","price":"5.00","product_code":"PROD009"}
+		],"total":4}`, rec.Body.String())
+		assert.Equal(t, "4", rec.Header().Get("X-Total-Count"))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("prices in the requested currency", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListVariants", mock.Anything, products.VariantFilters{Limit: 10}).Return(res[:1], int64(1), nil)
+
+		rec := get(repo, "/variants?currency=gbp")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"price":"6.00"`)
+	})
+
+	t.Run("no variants", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListVariants", mock.Anything, products.VariantFilters{Limit: 10}).Return([]products.ResolvedVariant{}, int64(0), nil)
+
+		rec := get(repo, "/variants")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"variants":[],"total":0}`, rec.Body.String())
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"offset=-1", "limit=ten", "priceLessThan=cheap", "category=shoes&category=bags"} {
+			repo := new(mockRepo)
+
+			rec := get(repo, "/variants?"+query)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+			repo.AssertNotCalled(t, "ListVariants", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListVariants", mock.Anything, products.VariantFilters{Limit: 10}).Return(nil, int64(0), errors.New("connection refused"))
+
+		rec := get(repo, "/variants")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
@@ -0,0 +1,147 @@
+package category
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/category"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// recordingAuditor keeps the changes recorded.
+type recordingAuditor struct {
+	changes []audit.Change
+}
+
+func (a *recordingAuditor) Record(_ context.Context, c audit.Change) {
+	a.changes = append(a.changes, c)
+}
+
+func TestAudit(t *testing.T) {
+	stored := models.Category{
+		ID: 3, Code: "accessories", Name: "Accessories", MarkupPercent: valid("10"), Version: 2,
+		Translations: []models.CategoryTranslation{{CategoryID: 3, Locale: "de", Name: "Accessoires"}},
+	}
+	encoded := func(state any) string {
+		b, err := json.Marshal(state)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	t.Run("an update captures both versions", func(t *testing.T) {
+		renamed := stored
+		renamed.Name, renamed.Version = "Small Accessories", 3
+		repo := new(mockRepo)
+		repo.On("Get", mock.Anything, "accessories").Return(stored, nil).Once()
+		repo.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*models.Category).Version++
+		}).Return(nil)
+		repo.On("Get", mock.Anything, "accessories").Return(renamed, nil).Once()
+		auditor := new(recordingAuditor)
+
+		req := httptest.NewRequest(http.MethodPut, "/categories/accessories", strings.NewReader(`{"name":"Small Accessories","markup_percent":"10"}`))
+		req.Header.Set("If-Match", `"2"`)
+		rec := serveHandler(NewCategoryHandler(repo, repo, nil, auditor), req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Len(t, auditor.changes, 1)
+		c := auditor.changes[0]
+		assert.Equal(t, audit.Update, c.Action)
+		assert.Equal(t, audit.Category, c.EntityType)
+		assert.Equal(t, "accessories", c.EntityCode)
+		assert.JSONEq(t, `{"code":"accessories","name":"Accessories","markup_percent":"10","version":2,"translations":{"de":"Accessoires"}}`, encoded(c.Before))
+		assert.JSONEq(t, `{"code":"accessories","name":"Small Accessories","markup_percent":"10","version":3,"translations":{"de":"Accessoires"}}`, encoded(c.After))
+	})
+
+	t.Run("a creation has no version before", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, mock.Anything).Return(models.Category{Code: "bags", Name: "Bags", Version: 1}, nil)
+		auditor := new(recordingAuditor)
+
+		rec := serveHandler(NewCategoryHandler(repo, repo, nil, auditor),
+			httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(`{"code":"bags","name":"Bags"}`)))
+
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Len(t, auditor.changes, 1)
+		assert.Equal(t, audit.Create, auditor.changes[0].Action)
+		assert.Nil(t, auditor.changes[0].Before)
+		assert.JSONEq(t, `{"code":"bags","name":"Bags","markup_percent":null,"version":1}`, encoded(auditor.changes[0].After))
+	})
+
+	t.Run("an upsert of a stored code is an update", func(t *testing.T) {
+		renamed := stored
+		renamed.Name, renamed.Version = "Small Accessories", 3
+		repo := new(mockRepo)
+		repo.On("Get", mock.Anything, "accessories").Return(stored, nil).Once()
+		repo.On("Upsert", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(1).(*models.Category) = renamed
+		}).Return(nil)
+		repo.On("Get", mock.Anything, "accessories").Return(renamed, nil).Once()
+		auditor := new(recordingAuditor)
+
+		rec := serveHandler(NewCategoryHandler(repo, repo, nil, auditor),
+			httptest.NewRequest(http.MethodPost, "/categories?upsert=true", strings.NewReader(`{"code":"accessories","name":"Small Accessories"}`)))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Len(t, auditor.changes, 1)
+		assert.Equal(t, audit.Update, auditor.changes[0].Action)
+		assert.JSONEq(t, `{"code":"accessories","name":"Accessories","markup_percent":"10","version":2,"translations":{"de":"Accessoires"}}`, encoded(auditor.changes[0].Before))
+		assert.JSONEq(t, `{"code":"accessories","name":"Small Accessories","markup_percent":"10","version":3,"translations":{"de":"Accessoires"}}`, encoded(auditor.changes[0].After))
+	})
+
+	t.Run("an upsert of a new code is a creation", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Get", mock.Anything, "bags").Return(models.Category{}, category.ErrCategoryNotFound)
+		repo.On("Upsert", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*models.Category).Version = 1
+		}).Return(nil)
+		auditor := new(recordingAuditor)
+
+		rec := serveHandler(NewCategoryHandler(repo, repo, nil, auditor),
+			httptest.NewRequest(http.MethodPost, "/categories?upsert=true", strings.NewReader(`{"code":"bags","name":"Bags"}`)))
+
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Len(t, auditor.changes, 1)
+		assert.Equal(t, audit.Create, auditor.changes[0].Action)
+		assert.Nil(t, auditor.changes[0].Before)
+		assert.JSONEq(t, `{"code":"bags","name":"Bags","markup_percent":null,"version":1}`, encoded(auditor.changes[0].After))
+	})
+
+	t.Run("conflicting updates are not recorded", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Get", mock.Anything, "accessories").Return(stored, nil)
+		repo.On("Update", mock.Anything, mock.Anything).Return(&category.VersionMismatchError{Current: 4})
+		auditor := new(recordingAuditor)
+
+		req := httptest.NewRequest(http.MethodPatch, "/categories/accessories", strings.NewReader(`{"name":"Small Accessories"}`))
+		req.Header.Set("If-Match", `"2"`)
+		rec := serveHandler(NewCategoryHandler(repo, repo, nil, auditor), req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Empty(t, auditor.changes)
+	})
+
+	t.Run("reads are not recorded", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllWithCounts", mock.Anything).Return([]category.CategoryCount{{Category: stored, ProductCount: 2}}, nil)
+		repo.On("PriceRange", mock.Anything, "accessories", false).Return(category.PriceRange{}, nil)
+		repo.On("CountProductsInCategory", mock.Anything, "accessories").Return(2, nil)
+		auditor := new(recordingAuditor)
+		h := NewCategoryHandler(repo, repo, nil, auditor)
+
+		for _, target := range []string{"/categories", "/categories/accessories/price-range", "/categories/accessories/products/count"} {
+			rec := serveHandler(h, httptest.NewRequest(http.MethodGet, target, nil))
+			assert.Equal(t, http.StatusOK, rec.Code, target)
+		}
+		assert.Empty(t, auditor.changes)
+	})
+}
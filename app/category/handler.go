@@ -0,0 +1,522 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/category"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+var codePattern = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+const maxNameLength = 256
+
+var maxMarkupPercent = decimal.NewFromInt(100)
+
+type CategoryHandler struct {
+	reader     category.CategoryReader
+	writer     category.CategoryWriter
+	notifier   Notifier
+	audit      audit.Recorder
+	rounding   pricing.Mode
+	minorUnits currency.MinorUnits
+}
+
+// NewCategoryHandler reads the categories from r, writes them through w,
+// notifies n of created categories and records the changes with a. A nil w
+// makes the write endpoints answer 503, a nil n disables notifications and a
+// nil a the audit log.
+func NewCategoryHandler(r category.CategoryReader, w category.CategoryWriter, n Notifier, a audit.Recorder) *CategoryHandler {
+	if n == nil {
+		n = NopNotifier{}
+	}
+	return &CategoryHandler{
+		reader:   r,
+		writer:   w,
+		notifier: n,
+		audit:    a,
+	}
+}
+
+// SetPricing changes how the prices are rendered: rounded with rounding,
+// half-even until set, and in the minor units of units when requested.
+func (h *CategoryHandler) SetPricing(rounding pricing.Mode, units currency.MinorUnits) {
+	h.rounding, h.minorUnits = rounding, units
+}
+
+// writable responds with 503 when the handler has no writer.
+func (h *CategoryHandler) writable(w http.ResponseWriter) bool {
+	if h.writer == nil {
+		api.ErrorResponse(w, http.StatusServiceUnavailable, "category writes are not available")
+		return false
+	}
+	return true
+}
+
+// HandleGet lists the categories with their number of products, unless
+// withCounts=false asks for the cheaper listing without them. nonEmpty=true
+// leaves out the categories without products, for the navigation: the
+// counts tell them apart when they are listed, ListNonEmpty otherwise.
+// nameSearch keeps the categories whose name, in any locale, contains it
+// regardless of case and accents. The categories are few, so they are
+// filtered once loaded rather than relying on database extensions such as
+// unaccent.
+func (h *CategoryHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	withCounts, ok := boolParam(w, r, "withCounts", true)
+	if !ok {
+		return
+	}
+	nonEmpty, ok := boolParam(w, r, "nonEmpty", false)
+	if !ok {
+		return
+	}
+	nameSearch := foldName(strings.TrimSpace(r.URL.Query().Get("nameSearch")))
+
+	var res []category.CategoryCount
+	var err error
+	if withCounts {
+		res, err = h.reader.ListAllWithCounts(r.Context())
+	} else {
+		var list []models.Category
+		if nonEmpty {
+			list, err = h.reader.ListNonEmpty(r.Context())
+		} else {
+			list, err = h.reader.ListAll(r.Context())
+		}
+		for _, c := range list {
+			res = append(res, category.CategoryCount{Category: c})
+		}
+	}
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	locale := api.RequestLocale(r)
+	categories := make([]Category, 0, len(res))
+	for _, c := range res {
+		if nonEmpty && withCounts && c.ProductCount == 0 {
+			continue
+		}
+		if nameSearch != "" && !matchesName(c.Category, nameSearch) {
+			continue
+		}
+		item := Category{
+			Code:    c.Code,
+			Name:    c.LocalizedName(locale),
+			Version: c.Version,
+		}
+		if withCounts {
+			item.ProductCount = &c.ProductCount
+		}
+		categories = append(categories, item)
+	}
+
+	api.OKResponse(w, Response{
+		Categories: categories,
+	})
+}
+
+// boolParam reads the true or false query parameter name, def when absent.
+// Other values are answered with 400.
+func boolParam(w http.ResponseWriter, r *http.Request, name string, def bool) (bool, bool) {
+	switch v := r.URL.Query().Get(name); v {
+	case "":
+		return def, true
+	case "true", "false":
+		return v == "true", true
+	default:
+		api.ErrorResponse(w, http.StatusBadRequest, name+" must be true or false")
+		return false, false
+	}
+}
+
+// HandlePost creates the category of the request. With upsert=true, a
+// category whose code is already stored is renamed instead, answered with
+// 200 rather than 409; its markup and translations are left alone.
+func (h *CategoryHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+	upsert, ok := boolParam(w, r, "upsert", false)
+	if !ok {
+		return
+	}
+
+	var req CreateCategoryRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	translations, fields := validateCreateCategory(req)
+	if len(fields) > 0 {
+		api.ValidationErrorResponse(w, fields)
+		return
+	}
+
+	requested := models.Category{
+		Code:          req.Code,
+		Name:          req.Name,
+		MarkupPercent: nullableMarkup(req.MarkupPercent),
+		Translations:  translations,
+	}
+	if upsert {
+		h.upsert(w, r, requested)
+		return
+	}
+	newCategory, err := h.writer.Create(r.Context(), requested)
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+	h.created(w, r, newCategory)
+}
+
+// upsert creates the category or renames the stored one with its code,
+// answering as a creation or as an update accordingly.
+func (h *CategoryHandler) upsert(w http.ResponseWriter, r *http.Request, c models.Category) {
+	before := h.storedState(r.Context(), c.Code)
+	if err := h.writer.Upsert(r.Context(), &c); err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+	if c.Version == 1 {
+		h.created(w, r, c)
+		return
+	}
+
+	if h.audit != nil {
+		h.audit.Record(r.Context(), audit.Change{
+			Action: audit.Update, EntityType: audit.Category, EntityCode: c.Code,
+			Before: before, After: h.storedState(r.Context(), c.Code),
+		})
+	}
+	api.SetVersionETag(w, c.Version)
+	api.OKResponse(w, newCategoryResponse(c))
+}
+
+// created notifies and records the creation of newCategory and answers
+// with it.
+func (h *CategoryHandler) created(w http.ResponseWriter, r *http.Request, newCategory models.Category) {
+	// Notification failures must not fail the creation itself
+	if err := h.notifier.CategoryCreated(r.Context(), newCategory); err != nil {
+		logging.FromContext(r.Context()).Warn("Failed to notify category creation", "category", newCategory.Code, "error", err)
+	}
+	if h.audit != nil {
+		h.audit.Record(r.Context(), audit.Change{
+			Action: audit.Create, EntityType: audit.Category, EntityCode: newCategory.Code,
+			After: newCategoryResponse(newCategory),
+		})
+	}
+
+	w.Header().Set("Location", api.AbsoluteURL(r, "/categories/"+newCategory.Code))
+	api.SetVersionETag(w, newCategory.Version)
+	api.CreatedPreferredResponse(w, r, newCategoryResponse(newCategory))
+}
+
+// HandlePut replaces the name and the markup of the category, leaving its
+// translations alone. The update must tell the version it was made from, see
+// requestedVersion.
+func (h *CategoryHandler) HandlePut(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	var fields []api.FieldError
+	if msg := validateName(req.Name); msg != "" {
+		fields = append(fields, api.FieldError{Field: "name", Message: msg})
+	}
+	if msg := validateMarkup(req.MarkupPercent); msg != "" {
+		fields = append(fields, api.FieldError{Field: "markup_percent", Message: msg})
+	}
+	if len(fields) > 0 {
+		api.ValidationErrorResponse(w, fields)
+		return
+	}
+
+	version, ok := requestedVersion(w, r, req.Version)
+	if !ok {
+		return
+	}
+
+	h.update(w, r, models.Category{
+		Code:          r.PathValue("code"),
+		Name:          req.Name,
+		MarkupPercent: nullableMarkup(req.MarkupPercent),
+		Version:       version,
+	})
+}
+
+// HandlePatch changes the name or the markup of the category, whichever the
+// request carries, from the version it tells as HandlePut does.
+func (h *CategoryHandler) HandlePatch(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+
+	var req PatchCategoryRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	var fields []api.FieldError
+	if req.Name != nil {
+		if msg := validateName(*req.Name); msg != "" {
+			fields = append(fields, api.FieldError{Field: "name", Message: msg})
+		}
+	}
+	if msg := validateMarkup(req.MarkupPercent.Value); msg != "" {
+		fields = append(fields, api.FieldError{Field: "markup_percent", Message: msg})
+	}
+	if len(fields) > 0 {
+		api.ValidationErrorResponse(w, fields)
+		return
+	}
+
+	version, ok := requestedVersion(w, r, req.Version)
+	if !ok {
+		return
+	}
+
+	// The fields left out are those of the category as stored. Had it
+	// changed since the requested version, the update fails anyway.
+	updated, err := h.reader.Get(r.Context(), r.PathValue("code"))
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+	if req.Name != nil {
+		updated.Name = *req.Name
+	}
+	if req.MarkupPercent.Set {
+		updated.MarkupPercent = nullableMarkup(req.MarkupPercent.Value)
+	}
+	updated.Version = version
+	h.update(w, r, updated)
+}
+
+// requestedVersion reads the version an update was made from, in the
+// If-Match header or the version field of the body, which must agree when
+// both are given. Without either, 428 is answered.
+func requestedVersion(w http.ResponseWriter, r *http.Request, bodyVersion *int) (int, bool) {
+	version, ok, err := api.IfMatchVersion(r)
+	switch {
+	case err != nil:
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return 0, false
+	case bodyVersion != nil && *bodyVersion < 1:
+		api.ErrorResponse(w, http.StatusBadRequest, "version must be a positive integer")
+		return 0, false
+	case bodyVersion != nil && ok && *bodyVersion != version:
+		api.ErrorResponse(w, http.StatusBadRequest, "version and If-Match must match")
+		return 0, false
+	case bodyVersion != nil:
+		return *bodyVersion, true
+	case !ok:
+		api.ErrorResponse(w, http.StatusPreconditionRequired, "If-Match header or version is required")
+		return 0, false
+	}
+	return version, true
+}
+
+// update writes the category, answering with it and its new version, or with
+// 412 and the current version when it was updated meanwhile.
+func (h *CategoryHandler) update(w http.ResponseWriter, r *http.Request, updated models.Category) {
+	before := h.storedState(r.Context(), updated.Code)
+	if err := h.writer.Update(r.Context(), &updated); err != nil {
+		var mismatch *category.VersionMismatchError
+		if errors.As(err, &mismatch) {
+			api.PreconditionFailedResponse(w, err.Error(), mismatch.Current)
+			return
+		}
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	if h.audit != nil {
+		h.audit.Record(r.Context(), audit.Change{
+			Action: audit.Update, EntityType: audit.Category, EntityCode: updated.Code,
+			Before: before, After: h.storedState(r.Context(), updated.Code),
+		})
+	}
+	api.SetVersionETag(w, updated.Version)
+	api.OKResponse(w, newCategoryResponse(updated))
+}
+
+// storedState is the category as stored, with its translations, recorded in
+// the audit log. It is nil when the audit log is disabled, when the category
+// is not stored yet, and when it cannot be read, the change being recorded
+// regardless.
+func (h *CategoryHandler) storedState(ctx context.Context, code string) any {
+	if h.audit == nil {
+		return nil
+	}
+	c, err := h.reader.Get(ctx, code)
+	if errors.Is(err, category.ErrCategoryNotFound) {
+		return nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to read a category for the audit log", "category", code, "error", err)
+		return nil
+	}
+	return newCategoryResponse(c)
+}
+
+// HandleProductCount counts the products of the category without loading
+// them, for clients to size a category page beforehand.
+func (h *CategoryHandler) HandleProductCount(w http.ResponseWriter, r *http.Request) {
+	count, err := h.reader.CountProductsInCategory(r.Context(), r.PathValue("code"))
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	api.OKResponse(w, ProductCountResponse{Count: count})
+}
+
+// HandlePriceRange returns the price aggregates of the category, in the
+// base currency and in the priceFormat and amountFormat requested as the
+// catalog prices.
+func (h *CategoryHandler) HandlePriceRange(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	includeVariants, ok := boolParam(w, r, "includeVariants", false)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	format, err := currency.ParseFormat(q.Get("priceFormat"), q.Get("amountFormat"), currency.Base, h.minorUnits)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.reader.PriceRange(r.Context(), code, includeVariants)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	api.OKResponse(w, PriceRangeResponse{
+		Category: code,
+		Min:      h.price(res.Min, format),
+		Max:      h.price(res.Max, format),
+		Avg:      h.price(res.Avg, format),
+		Currency: format.Currency,
+		Empty:    res.Count == 0,
+	})
+}
+
+// validateCreateCategory checks every field of the request, returning all
+// the problems found rather than the first one. The translations are returned
+// ordered by locale.
+func validateCreateCategory(req CreateCategoryRequest) ([]models.CategoryTranslation, []api.FieldError) {
+	var fields []api.FieldError
+	switch {
+	case req.Code == "":
+		fields = append(fields, api.FieldError{Field: "code", Message: "code is required"})
+	case !codePattern.MatchString(req.Code):
+		fields = append(fields, api.FieldError{Field: "code", Message: "code must be up to 32 lowercase letters, digits or dashes"})
+	}
+	if msg := validateName(req.Name); msg != "" {
+		fields = append(fields, api.FieldError{Field: "name", Message: msg})
+	}
+	if msg := validateMarkup(req.MarkupPercent); msg != "" {
+		fields = append(fields, api.FieldError{Field: "markup_percent", Message: msg})
+	}
+	translations, translationFields := validateTranslations(req.Translations)
+	return translations, append(fields, translationFields...)
+}
+
+// validateName returns the problem with a category name, if any.
+func validateName(name string) string {
+	switch {
+	case name == "":
+		return "name is required"
+	case len(name) > maxNameLength:
+		return "name must be at most 256 characters"
+	}
+	return ""
+}
+
+// validateTranslations checks the requested translations, reporting each
+// invalid one under translations.<locale>, and returns them ordered by locale.
+func validateTranslations(names map[string]string) ([]models.CategoryTranslation, []api.FieldError) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	translations := make([]models.CategoryTranslation, 0, len(names))
+	var fields []api.FieldError
+	for _, locale := range slices.Sorted(maps.Keys(names)) {
+		name := names[locale]
+		switch {
+		case !slices.Contains(api.SupportedLocales, locale):
+			fields = append(fields, api.FieldError{Field: "translations." + locale, Message: fmt.Sprintf("unsupported locale %q", locale)})
+		case name == "" || len(name) > maxNameLength:
+			fields = append(fields, api.FieldError{Field: "translations." + locale, Message: fmt.Sprintf("translated name for %s must be between 1 and 256 characters", locale)})
+		default:
+			translations = append(translations, models.CategoryTranslation{Locale: locale, Name: name})
+		}
+	}
+	return translations, fields
+}
+
+// validateMarkup accepts no markup or a percentage between 0 and 100 with at
+// most 2 decimal places, as stored, returning the problem otherwise.
+func validateMarkup(markup *decimal.Decimal) string {
+	switch {
+	case markup == nil:
+		return ""
+	case markup.IsNegative() || markup.GreaterThan(maxMarkupPercent):
+		return "markup_percent must be between 0 and 100"
+	case !markup.Equal(markup.Round(2)):
+		return "markup_percent must have at most 2 decimal places"
+	}
+	return ""
+}
+
+func nullableMarkup(markup *decimal.Decimal) decimal.NullDecimal {
+	if markup == nil {
+		return decimal.NullDecimal{}
+	}
+	return decimal.NullDecimal{Decimal: *markup, Valid: true}
+}
+
+// price renders an aggregate price in format, nil when there is none.
+func (h *CategoryHandler) price(d decimal.NullDecimal, format currency.Format) *currency.Money {
+	if !d.Valid {
+		return nil
+	}
+	price := currency.NewMoney(d.Decimal, h.rounding)
+	format.Apply(&price)
+	return &price
+}
@@ -0,0 +1,939 @@
+package category
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/category"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func serve(repo *mockRepo, req *http.Request) *httptest.ResponseRecorder {
+	return serveWithNotifier(repo, nil, req)
+}
+
+func serveWithNotifier(repo *mockRepo, n Notifier, req *http.Request) *httptest.ResponseRecorder {
+	return serveHandler(NewCategoryHandler(repo, repo, n, nil), req)
+}
+
+func serveHandler(h *CategoryHandler, req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /categories", h.HandleGet)
+	mux.HandleFunc("POST /categories", h.HandlePost)
+	mux.HandleFunc("PUT /categories/{code}", h.HandlePut)
+	mux.HandleFunc("PATCH /categories/{code}", h.HandlePatch)
+	mux.HandleFunc("GET /categories/{code}/price-range", h.HandlePriceRange)
+	mux.HandleFunc("GET /categories/{code}/products/count", h.HandleProductCount)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func valid(s string) decimal.NullDecimal {
+	return decimal.NullDecimal{Decimal: decimal.RequireFromString(s), Valid: true}
+}
+
+func TestHandlePriceRange(t *testing.T) {
+	t.Run("product prices", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceRange", mock.Anything, "shoes", false).Return(category.PriceRange{
+			Min: valid("5.5"), Max: valid("12.49"), Avg: valid("9"), Count: 2,
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/shoes/price-range", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"category":"shoes","min":"5.50","max":"12.49","avg":"9.00","empty":false}`, rec.Body.String())
+	})
+
+	t.Run("including variant prices", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceRange", mock.Anything, "clothing", true).Return(category.PriceRange{
+			Min: valid("10.99"), Max: valid("18.75"), Avg: valid("15.28"), Count: 7,
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/clothing/price-range?includeVariants=true", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"category":"clothing","min":"10.99","max":"18.75","avg":"15.28","empty":false}`, rec.Body.String())
+	})
+
+	t.Run("prices as numbers", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceRange", mock.Anything, "shoes", false).Return(category.PriceRange{
+			Min: valid("5.5"), Max: valid("12.49"), Avg: valid("9"), Count: 2,
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/shoes/price-range?priceFormat=number", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"category":"shoes","min":5.50,"max":12.49,"avg":9.00,"empty":false}`, rec.Body.String())
+	})
+
+	t.Run("prices in minor units", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceRange", mock.Anything, "shoes", false).Return(category.PriceRange{
+			Min: valid("5.5"), Max: valid("12.49"), Avg: valid("9"), Count: 2,
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/shoes/price-range?amountFormat=minor", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"category":"shoes","min":550,"max":1249,"avg":900,"currency":"EUR","empty":false}`, rec.Body.String())
+	})
+
+	t.Run("category without products", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceRange", mock.Anything, "bags", false).Return(category.PriceRange{}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/bags/price-range", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"category":"bags","min":null,"max":null,"avg":null,"empty":true}`, rec.Body.String())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceRange", mock.Anything, "unknown", false).Return(category.PriceRange{}, category.ErrCategoryNotFound)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/unknown/price-range", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	for _, query := range []string{"includeVariants=sure", "includeVariants=1", "includeVariants=TRUE", "priceFormat=float", "amountFormat=cents"} {
+		t.Run("invalid "+query, func(t *testing.T) {
+			repo := new(mockRepo)
+
+			rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/shoes/price-range?"+query, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Empty(t, repo.Calls)
+		})
+	}
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("PriceRange", mock.Anything, "shoes", false).Return(category.PriceRange{}, errors.New("boom"))
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/shoes/price-range", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestHandleProductCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		count  int
+		err    error
+		status int
+		want   string
+	}{
+		{"populated category", "clothing", 3, nil, http.StatusOK, `{"count":3}`},
+		{"empty category", "bags", 0, nil, http.StatusOK, `{"count":0}`},
+		{"unknown category", "unknown", 0, category.ErrCategoryNotFound, http.StatusNotFound, `{"error":"category not found"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockRepo)
+			repo.On("CountProductsInCategory", mock.Anything, tt.code).Return(tt.count, tt.err)
+
+			rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/"+tt.code+"/products/count", nil))
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.JSONEq(t, tt.want, rec.Body.String())
+		})
+	}
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("CountProductsInCategory", mock.Anything, "shoes").Return(0, errors.New("boom"))
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories/shoes/products/count", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestHandleGet(t *testing.T) {
+	t.Run("lists categories with their product counts", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllWithCounts", mock.Anything).Return([]category.CategoryCount{
+			{Category: models.Category{ID: 3, Code: "bags", Name: "Bags", Version: 1}, ProductCount: 0},
+			{Category: models.Category{ID: 1, Code: "clothing", Name: "Clothing", Version: 4}, ProductCount: 3},
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"categories":[{"code":"bags","name":"Bags","version":1,"product_count":0},{"code":"clothing","name":"Clothing","version":4,"product_count":3}]}`, rec.Body.String())
+	})
+
+	t.Run("without counts", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAll", mock.Anything).Return([]models.Category{
+			{ID: 1, Code: "clothing", Name: "Clothing", Version: 1},
+			{ID: 2, Code: "shoes", Name: "Shoes", Version: 1},
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories?withCounts=false", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"categories":[{"code":"clothing","name":"Clothing","version":1},{"code":"shoes","name":"Shoes","version":1}]}`, rec.Body.String())
+		repo.AssertNotCalled(t, "ListAllWithCounts", mock.Anything)
+	})
+
+	t.Run("searches names regardless of case and accents", func(t *testing.T) {
+		listing := []models.Category{
+			{ID: 1, Code: "cafe", Name: "Café", Version: 1},
+			{ID: 2, Code: "resort", Name: "Resort", Version: 1, Translations: []models.CategoryTranslation{{Locale: "fr", Name: "Crème de la CRÈME"}}},
+			{ID: 3, Code: "shoes", Name: "Shoes", Version: 1},
+		}
+		tests := []struct {
+			name   string
+			search string
+			want   string
+		}{
+			{"unaccented query", "cafe", `[{"code":"cafe","name":"Café","version":1}]`},
+			{"accented query", "CAFÉ", `[{"code":"cafe","name":"Café","version":1}]`},
+			{"decomposed query", "cafe\u0301", `[{"code":"cafe","name":"Café","version":1}]`},
+			{"part of a translation", "creme", `[{"code":"resort","name":"Resort","version":1}]`},
+			{"mixed case", "sHoE", `[{"code":"shoes","name":"Shoes","version":1}]`},
+			{"no match", "bags", `[]`},
+			{"blank", " ", `[{"code":"cafe","name":"Café","version":1},{"code":"resort","name":"Resort","version":1},{"code":"shoes","name":"Shoes","version":1}]`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("ListAll", mock.Anything).Return(listing, nil)
+				target := "/categories?withCounts=false&nameSearch=" + url.QueryEscape(tt.search)
+
+				rec := serve(repo, httptest.NewRequest(http.MethodGet, target, nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.JSONEq(t, `{"categories":`+tt.want+`}`, rec.Body.String())
+			})
+		}
+	})
+
+	t.Run("searches names with counts", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllWithCounts", mock.Anything).Return([]category.CategoryCount{
+			{Category: models.Category{ID: 1, Code: "cafe", Name: "Café", Version: 1}, ProductCount: 2},
+			{Category: models.Category{ID: 3, Code: "shoes", Name: "Shoes", Version: 1}, ProductCount: 5},
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories?nameSearch=Cafe", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"categories":[{"code":"cafe","name":"Café","version":1,"product_count":2}]}`, rec.Body.String())
+	})
+
+	t.Run("only the categories with products", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllWithCounts", mock.Anything).Return([]category.CategoryCount{
+			{Category: models.Category{ID: 3, Code: "bags", Name: "Bags", Version: 1}, ProductCount: 0},
+			{Category: models.Category{ID: 1, Code: "clothing", Name: "Clothing", Version: 1}, ProductCount: 3},
+			{Category: models.Category{ID: 4, Code: "outlet", Name: "Outlet", Version: 1}, ProductCount: 0},
+			{Category: models.Category{ID: 2, Code: "shoes", Name: "Shoes", Version: 1}, ProductCount: 2},
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories?nonEmpty=true", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"categories":[{"code":"clothing","name":"Clothing","version":1,"product_count":3},{"code":"shoes","name":"Shoes","version":1,"product_count":2}]}`, rec.Body.String())
+	})
+
+	t.Run("only the categories with products, without counts", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListNonEmpty", mock.Anything).Return([]models.Category{
+			{ID: 1, Code: "clothing", Name: "Clothing", Version: 1},
+			{ID: 2, Code: "shoes", Name: "Shoes", Version: 1},
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories?nonEmpty=true&withCounts=false", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"categories":[{"code":"clothing","name":"Clothing","version":1},{"code":"shoes","name":"Shoes","version":1}]}`, rec.Body.String())
+		repo.AssertNotCalled(t, "ListAll", mock.Anything)
+	})
+
+	t.Run("empty categories kept with nonEmpty=false", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAll", mock.Anything).Return([]models.Category{
+			{ID: 3, Code: "bags", Name: "Bags", Version: 1},
+			{ID: 1, Code: "clothing", Name: "Clothing", Version: 1},
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories?nonEmpty=false&withCounts=false", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"categories":[{"code":"bags","name":"Bags","version":1},{"code":"clothing","name":"Clothing","version":1}]}`, rec.Body.String())
+		repo.AssertNotCalled(t, "ListNonEmpty", mock.Anything)
+	})
+
+	t.Run("invalid nonEmpty", func(t *testing.T) {
+		rec := serve(new(mockRepo), httptest.NewRequest(http.MethodGet, "/categories?nonEmpty=1", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"nonEmpty must be true or false"}`, rec.Body.String())
+	})
+
+	t.Run("invalid withCounts", func(t *testing.T) {
+		rec := serve(new(mockRepo), httptest.NewRequest(http.MethodGet, "/categories?withCounts=yes", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"withCounts must be true or false"}`, rec.Body.String())
+	})
+
+	t.Run("no categories", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllWithCounts", mock.Anything).Return(nil, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		testsupport.AssertJSONArrays(t, rec.Body.String(), "categories")
+	})
+
+	t.Run("localizes names", func(t *testing.T) {
+		categories := []category.CategoryCount{
+			{Category: models.Category{ID: 1, Code: "clothing", Name: "Clothing", Version: 1, Translations: []models.CategoryTranslation{
+				{CategoryID: 1, Locale: "de", Name: "Kleidung"},
+			}}, ProductCount: 3},
+			{Category: models.Category{ID: 2, Code: "bags", Name: "Bags", Version: 1}},
+		}
+		tests := []struct {
+			name   string
+			target string
+			header string
+			want   string
+		}{
+			{"exact match", "/categories", "de", `{"categories":[{"code":"clothing","name":"Kleidung","version":1,"product_count":3},{"code":"bags","name":"Bags","version":1,"product_count":0}]}`},
+			{"quality values", "/categories", "fr;q=0.9,de-CH;q=0.8", `{"categories":[{"code":"clothing","name":"Kleidung","version":1,"product_count":3},{"code":"bags","name":"Bags","version":1,"product_count":0}]}`},
+			{"locale parameter", "/categories?locale=de", "en", `{"categories":[{"code":"clothing","name":"Kleidung","version":1,"product_count":3},{"code":"bags","name":"Bags","version":1,"product_count":0}]}`},
+			{"falls back to the default name", "/categories", "en", `{"categories":[{"code":"clothing","name":"Clothing","version":1,"product_count":3},{"code":"bags","name":"Bags","version":1,"product_count":0}]}`},
+			{"unsupported locale", "/categories", "fr", `{"categories":[{"code":"clothing","name":"Clothing","version":1,"product_count":3},{"code":"bags","name":"Bags","version":1,"product_count":0}]}`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("ListAllWithCounts", mock.Anything).Return(categories, nil)
+				req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+				req.Header.Set("Accept-Language", tt.header)
+
+				rec := serve(repo, req)
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.JSONEq(t, tt.want, rec.Body.String())
+			})
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("ListAllWithCounts", mock.Anything).Return(nil, errors.New("boom"))
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/categories", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestHandlePost(t *testing.T) {
+	bags := models.Category{Code: "bags", Name: "Bags"}
+	persisted := models.Category{ID: 4, Code: "bags", Name: "Bags", Version: 1}
+
+	post := func(repo *mockRepo, n Notifier, body string) *httptest.ResponseRecorder {
+		return serveWithNotifier(repo, n, httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(body)))
+	}
+
+	t.Run("creates the category and notifies", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, bags).Return(persisted, nil)
+		notifier := new(mockNotifier)
+		notifier.On("CategoryCreated", mock.Anything, persisted).Return(nil)
+
+		rec := post(repo, notifier, `{"code":"bags","name":"Bags"}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "http://example.com/categories/bags", rec.Header().Get("Location"))
+		assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"code":"bags","name":"Bags","markup_percent":null,"version":1}`, rec.Body.String())
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("return preferences", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			prefer      string
+			wantBody    string
+			wantApplied string
+		}{
+			{"default", "", `{"code":"bags","name":"Bags","markup_percent":null,"version":1}`, ""},
+			{"representation", "return=representation", `{"code":"bags","name":"Bags","markup_percent":null,"version":1}`, "return=representation"},
+			{"minimal", "return=minimal", "", "return=minimal"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("Create", mock.Anything, bags).Return(persisted, nil)
+				req := httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(`{"code":"bags","name":"Bags"}`))
+				if tt.prefer != "" {
+					req.Header.Set("Prefer", tt.prefer)
+				}
+
+				rec := serve(repo, req)
+
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				assert.Equal(t, "http://example.com/categories/bags", rec.Header().Get("Location"))
+				assert.Equal(t, tt.wantApplied, rec.Header().Get("Preference-Applied"))
+				if tt.wantBody == "" {
+					assert.Empty(t, rec.Body.String())
+				} else {
+					assert.JSONEq(t, tt.wantBody, rec.Body.String())
+				}
+				repo.AssertExpectations(t)
+			})
+		}
+	})
+
+	t.Run("creates the category with translations", func(t *testing.T) {
+		repo := new(mockRepo)
+		withTranslations := models.Category{
+			Code: "bags",
+			Name: "Bags",
+			Translations: []models.CategoryTranslation{
+				{Locale: "de", Name: "Taschen"},
+				{Locale: "en", Name: "Bags & Purses"},
+			},
+		}
+		created := withTranslations
+		created.ID = 4
+		created.Version = 1
+		repo.On("Create", mock.Anything, withTranslations).Return(created, nil)
+
+		rec := post(repo, nil, `{"code":"bags","name":"Bags","translations":{"en":"Bags & Purses","de":"Taschen"}}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.JSONEq(t, `{"code":"bags","name":"Bags","markup_percent":null,"version":1,"translations":{"de":"Taschen","en":"Bags & Purses"}}`, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("creates the category with a markup", func(t *testing.T) {
+		repo := new(mockRepo)
+		withMarkup := models.Category{
+			Code:          "bags",
+			Name:          "Bags",
+			MarkupPercent: valid("12.5"),
+		}
+		created := withMarkup
+		created.ID = 4
+		created.Version = 1
+		repo.On("Create", mock.Anything, withMarkup).Return(created, nil)
+
+		rec := post(repo, nil, `{"code":"bags","name":"Bags","markup_percent":12.5}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.JSONEq(t, `{"code":"bags","name":"Bags","markup_percent":"12.5","version":1}`, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("notification failures do not fail the request", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, bags).Return(persisted, nil)
+		notifier := new(mockNotifier)
+		notifier.On("CategoryCreated", mock.Anything, persisted).Return(errors.New("webhook down"))
+
+		rec := post(repo, notifier, `{"code":"bags","name":"Bags"}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("existing code is not notified", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, bags).Return(models.Category{}, category.ErrCategoryExists)
+		notifier := new(mockNotifier)
+
+		rec := post(repo, notifier, `{"code":"bags","name":"Bags"}`)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		notifier.AssertNotCalled(t, "CategoryCreated", mock.Anything, mock.Anything)
+	})
+
+	t.Run("repository error is not notified", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, bags).Return(models.Category{}, errors.New("boom"))
+		notifier := new(mockNotifier)
+
+		rec := post(repo, notifier, `{"code":"bags","name":"Bags"}`)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		notifier.AssertNotCalled(t, "CategoryCreated", mock.Anything, mock.Anything)
+	})
+
+	t.Run("maps repository error kinds", func(t *testing.T) {
+		tests := []struct {
+			err    error
+			status int
+		}{
+			{errs.New(errs.NotFound, "gone"), http.StatusNotFound},
+			{errs.New(errs.Conflict, "taken"), http.StatusConflict},
+			{errs.New(errs.Invalid, "bad"), http.StatusBadRequest},
+			{errs.New(errs.Internal, "broken"), http.StatusInternalServerError},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.err.Error(), func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("Create", mock.Anything, bags).Return(models.Category{}, tt.err)
+
+				rec := post(repo, nil, `{"code":"bags","name":"Bags"}`)
+
+				assert.Equal(t, tt.status, rec.Code)
+			})
+		}
+	})
+
+	t.Run("rejects bodies over the limit", func(t *testing.T) {
+		repo := new(mockRepo)
+		h := NewCategoryHandler(repo, repo, nil, nil)
+		body := `{"code":"bags","name":"` + strings.Repeat("a", 2048) + `"}`
+
+		rec := httptest.NewRecorder()
+		api.BodyLimitMiddleware(1024, http.HandlerFunc(h.HandlePost)).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.JSONEq(t, `{"error":"request body must not be larger than 1024 bytes"}`, rec.Body.String())
+		assert.Empty(t, repo.Calls)
+	})
+
+	t.Run("rejects invalid payloads", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{"malformed json", `{"code":`},
+			{"unknown field", `{"code":"bags","name":"Bags","colour":"brown"}`},
+			{"trailing garbage", `{"code":"bags","name":"Bags"}garbage`},
+			{"missing name", `{"code":"bags"}`},
+			{"missing code", `{"name":"Bags"}`},
+			{"invalid code", `{"code":"Bags!","name":"Bags"}`},
+			{"name too long", `{"code":"bags","name":"` + strings.Repeat("a", 257) + `"}`},
+			{"unsupported locale", `{"code":"bags","name":"Bags","translations":{"fr":"Sacs"}}`},
+			{"empty translation", `{"code":"bags","name":"Bags","translations":{"de":""}}`},
+			{"negative markup", `{"code":"bags","name":"Bags","markup_percent":-1}`},
+			{"markup over 100", `{"code":"bags","name":"Bags","markup_percent":100.01}`},
+			{"markup with 3 decimals", `{"code":"bags","name":"Bags","markup_percent":12.345}`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := post(repo, nil, tt.body)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+
+	t.Run("reports every invalid field", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+			want string
+		}{
+			{"code and name too long", `{"code":"` + strings.Repeat("a", 33) + `","name":"` + strings.Repeat("a", 257) + `"}`, `{
+				"error":"code must be up to 32 lowercase letters, digits or dashes; name must be at most 256 characters",
+				"errors":[
+					{"field":"code","message":"code must be up to 32 lowercase letters, digits or dashes"},
+					{"field":"name","message":"name must be at most 256 characters"}
+				]
+			}`},
+			{"missing code and name", `{}`, `{
+				"error":"code is required; name is required",
+				"errors":[
+					{"field":"code","message":"code is required"},
+					{"field":"name","message":"name is required"}
+				]
+			}`},
+			{"markup and translations", `{"code":"bags","name":"Bags","markup_percent":-5,"translations":{"fr":"Sacs","de":""}}`, `{
+				"error":"markup_percent must be between 0 and 100; translated name for de must be between 1 and 256 characters; unsupported locale \"fr\"",
+				"errors":[
+					{"field":"markup_percent","message":"markup_percent must be between 0 and 100"},
+					{"field":"translations.de","message":"translated name for de must be between 1 and 256 characters"},
+					{"field":"translations.fr","message":"unsupported locale \"fr\""}
+				]
+			}`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := post(repo, nil, tt.body)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.JSONEq(t, tt.want, rec.Body.String())
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+}
+
+func TestHandlePost_Upsert(t *testing.T) {
+	// persist has the mock store c as it was upserted
+	persist := func(c models.Category) func(mock.Arguments) {
+		return func(args mock.Arguments) { *args.Get(1).(*models.Category) = c }
+	}
+	post := func(repo *mockRepo, n Notifier, target, body string) *httptest.ResponseRecorder {
+		return serveWithNotifier(repo, n, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+	}
+
+	t.Run("creates a new code", func(t *testing.T) {
+		created := models.Category{ID: 4, Code: "bags", Name: "Bags", Version: 1}
+		repo := new(mockRepo)
+		repo.On("Upsert", mock.Anything, &models.Category{Code: "bags", Name: "Bags"}).Run(persist(created)).Return(nil)
+		notifier := new(mockNotifier)
+		notifier.On("CategoryCreated", mock.Anything, created).Return(nil)
+
+		rec := post(repo, notifier, "/categories?upsert=true", `{"code":"bags","name":"Bags"}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "http://example.com/categories/bags", rec.Header().Get("Location"))
+		assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"code":"bags","name":"Bags","markup_percent":null,"version":1}`, rec.Body.String())
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("renames an existing code", func(t *testing.T) {
+		renamed := models.Category{ID: 2, Code: "shoes", Name: "Footwear", MarkupPercent: valid("10"), Version: 3}
+		repo := new(mockRepo)
+		repo.On("Upsert", mock.Anything, &models.Category{Code: "shoes", Name: "Footwear"}).Run(persist(renamed)).Return(nil)
+		notifier := new(mockNotifier)
+
+		rec := post(repo, notifier, "/categories?upsert=true", `{"code":"shoes","name":"Footwear"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Location"))
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"code":"shoes","name":"Footwear","markup_percent":"10","version":3}`, rec.Body.String())
+		notifier.AssertNotCalled(t, "CategoryCreated", mock.Anything, mock.Anything)
+	})
+
+	t.Run("an existing code still conflicts without upsert", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Create", mock.Anything, models.Category{Code: "shoes", Name: "Footwear"}).Return(models.Category{}, category.ErrCategoryExists)
+
+		rec := post(repo, nil, "/categories?upsert=false", `{"code":"shoes","name":"Footwear"}`)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		repo.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := post(repo, nil, "/categories?upsert=yes", `{"code":"bags","name":"Bags"}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"upsert must be true or false"}`, rec.Body.String())
+		assert.Empty(t, repo.Calls)
+	})
+}
+
+func TestHandlePut(t *testing.T) {
+	put := func(repo *mockRepo, code, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/categories/"+code, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return serve(repo, req)
+	}
+	// updated mimics the repository, which increments the version
+	updated := func(args mock.Arguments) {
+		args.Get(1).(*models.Category).Version++
+	}
+
+	t.Run("sets the markup", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Update", mock.Anything, &models.Category{Code: "accessories", Name: "Accessories", MarkupPercent: valid("100"), Version: 2}).
+			Run(updated).Return(nil)
+
+		rec := put(repo, "accessories", `"2"`, `{"name":"Accessories","markup_percent":"100"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"code":"accessories","name":"Accessories","markup_percent":"100","version":3}`, rec.Body.String())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("removes the markup", func(t *testing.T) {
+		for _, body := range []string{`{"name":"Accessories"}`, `{"name":"Accessories","markup_percent":null}`} {
+			repo := new(mockRepo)
+			repo.On("Update", mock.Anything, &models.Category{Code: "accessories", Name: "Accessories", Version: 1}).Run(updated).Return(nil)
+
+			rec := put(repo, "accessories", `"1"`, body)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, `{"code":"accessories","name":"Accessories","markup_percent":null,"version":2}`, rec.Body.String())
+			repo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("version in the body", func(t *testing.T) {
+		for _, ifMatch := range []string{"", `"5"`} {
+			repo := new(mockRepo)
+			repo.On("Update", mock.Anything, &models.Category{Code: "accessories", Name: "Accessories", Version: 5}).Run(updated).Return(nil)
+
+			rec := put(repo, "accessories", ifMatch, `{"name":"Accessories","version":5}`)
+
+			assert.Equal(t, http.StatusOK, rec.Code, ifMatch)
+			assert.Equal(t, `"6"`, rec.Header().Get("ETag"), ifMatch)
+			repo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("outdated version", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Update", mock.Anything, mock.Anything).Return(&category.VersionMismatchError{Current: 3})
+
+		rec := put(repo, "accessories", `"2"`, `{"name":"Accessories"}`)
+
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"error":"category was modified meanwhile, its current version is 3","current_version":3}`, rec.Body.String())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Update", mock.Anything, mock.Anything).Return(category.ErrCategoryNotFound)
+
+		rec := put(repo, "bags", `"1"`, `{"name":"Bags","markup_percent":10}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("rejects missing or malformed versions", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			ifMatch    string
+			body       string
+			wantStatus int
+			want       string
+		}{
+			{"missing", "", `{"name":"Bags"}`, http.StatusPreconditionRequired, "If-Match header or version is required"},
+			{"malformed If-Match", "2", `{"name":"Bags"}`, http.StatusBadRequest, `If-Match must be a single version entity tag such as \"1\"`},
+			{"zero version", "", `{"name":"Bags","version":0}`, http.StatusBadRequest, "version must be a positive integer"},
+			{"disagreeing versions", `"2"`, `{"name":"Bags","version":3}`, http.StatusBadRequest, "version and If-Match must match"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := put(repo, "bags", tt.ifMatch, tt.body)
+
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				assert.JSONEq(t, `{"error":"`+tt.want+`"}`, rec.Body.String())
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+
+	t.Run("rejects invalid payloads", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			body  string
+			field string
+			want  string
+		}{
+			{"missing name", `{"markup_percent":10}`, "name", "name is required"},
+			{"name too long", `{"name":"` + strings.Repeat("a", 257) + `"}`, "name", "name must be at most 256 characters"},
+			{"negative markup", `{"name":"Bags","markup_percent":-0.01}`, "markup_percent", "markup_percent must be between 0 and 100"},
+			{"markup over 100", `{"name":"Bags","markup_percent":101}`, "markup_percent", "markup_percent must be between 0 and 100"},
+			{"markup with 3 decimals", `{"name":"Bags","markup_percent":"1.005"}`, "markup_percent", "markup_percent must have at most 2 decimal places"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(mockRepo)
+
+				rec := put(repo, "bags", `"1"`, tt.body)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.JSONEq(t, `{"error":"`+tt.want+`","errors":[{"field":"`+tt.field+`","message":"`+tt.want+`"}]}`, rec.Body.String())
+				assert.Empty(t, repo.Calls)
+			})
+		}
+	})
+
+	t.Run("reports every invalid field", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := put(repo, "bags", `"1"`, `{"markup_percent":150}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{
+			"error":"name is required; markup_percent must be between 0 and 100",
+			"errors":[
+				{"field":"name","message":"name is required"},
+				{"field":"markup_percent","message":"markup_percent must be between 0 and 100"}
+			]
+		}`, rec.Body.String())
+		assert.Empty(t, repo.Calls)
+	})
+}
+
+func TestHandlePatch(t *testing.T) {
+	patch := func(repo *mockRepo, code, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/categories/"+code, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return serve(repo, req)
+	}
+	stored := models.Category{
+		ID: 3, Code: "accessories", Name: "Accessories", MarkupPercent: valid("10"), Version: 2,
+		Translations: []models.CategoryTranslation{{CategoryID: 3, Locale: "de", Name: "Accessoires"}},
+	}
+	updated := func(args mock.Arguments) {
+		args.Get(1).(*models.Category).Version++
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want models.Category
+	}{
+		{"name only", `{"name":"Jewellery"}`, models.Category{Name: "Jewellery", MarkupPercent: valid("10")}},
+		{"markup only", `{"markup_percent":"12.5"}`, models.Category{Name: "Accessories", MarkupPercent: valid("12.5")}},
+		{"markup removed", `{"markup_percent":null}`, models.Category{Name: "Accessories"}},
+		{"nothing", `{}`, models.Category{Name: "Accessories", MarkupPercent: valid("10")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockRepo)
+			repo.On("Get", mock.Anything, "accessories").Return(stored, nil)
+			want := stored
+			want.Name, want.MarkupPercent = tt.want.Name, tt.want.MarkupPercent
+			repo.On("Update", mock.Anything, &want).Run(updated).Return(nil)
+
+			rec := patch(repo, "accessories", `"2"`, tt.body)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+			repo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("answers with the whole category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Get", mock.Anything, "accessories").Return(stored, nil)
+		repo.On("Update", mock.Anything, mock.Anything).Run(updated).Return(nil)
+
+		rec := patch(repo, "accessories", "", `{"name":"Jewellery","version":2}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"code":"accessories","name":"Jewellery","markup_percent":"10","version":3,"translations":{"de":"Accessoires"}}`, rec.Body.String())
+	})
+
+	t.Run("second writer of the same version", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Get", mock.Anything, "accessories").Return(stored, nil)
+		repo.On("Update", mock.Anything, mock.Anything).Run(updated).Return(nil).Once()
+		repo.On("Update", mock.Anything, mock.Anything).Return(&category.VersionMismatchError{Current: 3}).Once()
+
+		first := patch(repo, "accessories", `"2"`, `{"name":"Jewellery"}`)
+		second := patch(repo, "accessories", `"2"`, `{"markup_percent":20}`)
+
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, http.StatusPreconditionFailed, second.Code)
+		assert.JSONEq(t, `{"error":"category was modified meanwhile, its current version is 3","current_version":3}`, second.Body.String())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Get", mock.Anything, "bags").Return(models.Category{}, category.ErrCategoryNotFound)
+
+		rec := patch(repo, "bags", `"1"`, `{"name":"Bags"}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires a version", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := patch(repo, "accessories", "", `{"name":"Jewellery"}`)
+
+		assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+		assert.Empty(t, repo.Calls)
+	})
+
+	t.Run("rejects invalid fields", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := patch(repo, "accessories", `"2"`, `{"name":"","markup_percent":150}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{
+			"error":"name is required; markup_percent must be between 0 and 100",
+			"errors":[
+				{"field":"name","message":"name is required"},
+				{"field":"markup_percent","message":"markup_percent must be between 0 and 100"}
+			]
+		}`, rec.Body.String())
+		assert.Empty(t, repo.Calls)
+	})
+}
+
+func TestCategoryHandler_ReadOnly(t *testing.T) {
+	repo := new(mockRepo)
+	repo.On("ListAllWithCounts", mock.Anything).Return(nil, nil)
+	h := NewCategoryHandler(repo, nil, nil, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /categories", h.HandleGet)
+	mux.HandleFunc("POST /categories", h.HandlePost)
+	mux.HandleFunc("PUT /categories/{code}", h.HandlePut)
+	mux.HandleFunc("PATCH /categories/{code}", h.HandlePatch)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(`{"code":"bags","name":"Bags"}`)),
+		httptest.NewRequest(http.MethodPut, "/categories/bags", strings.NewReader(`{"name":"Bags"}`)),
+		httptest.NewRequest(http.MethodPatch, "/categories/bags", strings.NewReader(`{"name":"Bags"}`)),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code, req.Method)
+		assert.JSONEq(t, `{"error":"category writes are not available"}`, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/categories", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
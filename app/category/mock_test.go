@@ -0,0 +1,71 @@
+package category
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/category"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type mockRepo struct {
+	mock.Mock
+}
+
+func (m *mockRepo) PriceRange(ctx context.Context, code string, includeVariants bool) (category.PriceRange, error) {
+	args := m.Called(ctx, code, includeVariants)
+	return args.Get(0).(category.PriceRange), args.Error(1)
+}
+
+func (m *mockRepo) CountProductsInCategory(ctx context.Context, code string) (int, error) {
+	args := m.Called(ctx, code)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockRepo) Get(ctx context.Context, code string) (models.Category, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(models.Category), args.Error(1)
+}
+
+func (m *mockRepo) ListAll(ctx context.Context) ([]models.Category, error) {
+	args := m.Called(ctx)
+	categories, _ := args.Get(0).([]models.Category)
+	return categories, args.Error(1)
+}
+
+func (m *mockRepo) ListNonEmpty(ctx context.Context) ([]models.Category, error) {
+	args := m.Called(ctx)
+	categories, _ := args.Get(0).([]models.Category)
+	return categories, args.Error(1)
+}
+
+func (m *mockRepo) ListAllWithCounts(ctx context.Context) ([]category.CategoryCount, error) {
+	args := m.Called(ctx)
+	categories, _ := args.Get(0).([]category.CategoryCount)
+	return categories, args.Error(1)
+}
+
+func (m *mockRepo) Create(ctx context.Context, c models.Category) (models.Category, error) {
+	args := m.Called(ctx, c)
+	return args.Get(0).(models.Category), args.Error(1)
+}
+
+func (m *mockRepo) Update(ctx context.Context, c *models.Category) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *mockRepo) Upsert(ctx context.Context, c *models.Category) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+type mockNotifier struct {
+	mock.Mock
+}
+
+func (m *mockNotifier) CategoryCreated(ctx context.Context, c models.Category) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
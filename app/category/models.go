@@ -0,0 +1,103 @@
+package category
+
+import (
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type Response struct {
+	Categories []Category `json:"categories"`
+}
+
+type Category struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	// ProductCount is omitted when the counts were not asked for.
+	ProductCount *int64 `json:"product_count,omitempty"`
+}
+
+// CreateCategoryRequest optionally carries the category name in other
+// locales, keyed by locale, and the markup percentage added to the price
+// inherited by variants.
+type CreateCategoryRequest struct {
+	Code          string            `json:"code"`
+	Name          string            `json:"name"`
+	MarkupPercent *decimal.Decimal  `json:"markup_percent,omitempty"`
+	Translations  map[string]string `json:"translations,omitempty"`
+}
+
+// UpdateCategoryRequest replaces the name and the markup of a category. A
+// missing or null markup_percent removes the markup. Version is the version
+// the update was made from, unless the If-Match header tells it.
+type UpdateCategoryRequest struct {
+	Name          string           `json:"name"`
+	MarkupPercent *decimal.Decimal `json:"markup_percent"`
+	Version       *int             `json:"version,omitempty"`
+}
+
+// PatchCategoryRequest changes only the fields it carries: a missing
+// markup_percent keeps the markup while a null one removes it. Version is
+// as for UpdateCategoryRequest.
+type PatchCategoryRequest struct {
+	Name          *string       `json:"name"`
+	MarkupPercent PatchedMarkup `json:"markup_percent"`
+	Version       *int          `json:"version,omitempty"`
+}
+
+// PatchedMarkup tells a null markup_percent, Set with a nil Value, from a
+// missing one.
+type PatchedMarkup struct {
+	Set   bool
+	Value *decimal.Decimal
+}
+
+func (m *PatchedMarkup) UnmarshalJSON(data []byte) error {
+	m.Set = true
+	return json.Unmarshal(data, &m.Value)
+}
+
+// CategoryResponse is a category as created or updated. Translations is
+// keyed by locale.
+type CategoryResponse struct {
+	Code          string            `json:"code"`
+	Name          string            `json:"name"`
+	MarkupPercent *decimal.Decimal  `json:"markup_percent"`
+	Version       int               `json:"version"`
+	Translations  map[string]string `json:"translations,omitempty"`
+}
+
+func newCategoryResponse(c models.Category) CategoryResponse {
+	res := CategoryResponse{Code: c.Code, Name: c.Name, Version: c.Version}
+	if c.MarkupPercent.Valid {
+		res.MarkupPercent = &c.MarkupPercent.Decimal
+	}
+	if len(c.Translations) > 0 {
+		res.Translations = make(map[string]string, len(c.Translations))
+		for _, t := range c.Translations {
+			res.Translations[t.Locale] = t.Name
+		}
+	}
+	return res
+}
+
+// ProductCountResponse holds the number of products of a category.
+type ProductCountResponse struct {
+	Count int `json:"count"`
+}
+
+// PriceRangeResponse holds the price aggregates of a category. The prices are
+// null and Empty is set when the category has no products. Currency is set
+// when the prices are in minor units.
+type PriceRangeResponse struct {
+	Category string          `json:"category"`
+	Min      *currency.Money `json:"min"`
+	Max      *currency.Money `json:"max"`
+	Avg      *currency.Money `json:"avg"`
+	Currency string          `json:"currency,omitempty"`
+	Empty    bool            `json:"empty"`
+}
@@ -0,0 +1,108 @@
+package category
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// Notifier lets downstream systems react to category changes.
+type Notifier interface {
+	CategoryCreated(ctx context.Context, c models.Category) error
+}
+
+// NopNotifier is the Notifier used when no webhook is configured.
+type NopNotifier struct{}
+
+func (NopNotifier) CategoryCreated(context.Context, models.Category) error {
+	return nil
+}
+
+// Notifiers notifies every notifier in turn, joining their errors.
+type Notifiers []Notifier
+
+func (ns Notifiers) CategoryCreated(ctx context.Context, c models.Category) error {
+	var errs []error
+	for _, n := range ns {
+		errs = append(errs, n.CategoryCreated(ctx, c))
+	}
+	return errors.Join(errs...)
+}
+
+// EventNotifier publishes category.created events.
+type EventNotifier struct {
+	Events events.Publisher
+}
+
+func (n EventNotifier) CategoryCreated(_ context.Context, c models.Category) error {
+	n.Events.Publish(events.New(events.CategoryCreated, Category{Code: c.Code, Name: c.Name}))
+	return nil
+}
+
+// WebhookNotifier POSTs the created category as JSON to a configured URL,
+// retrying failed deliveries with a linear backoff.
+type WebhookNotifier struct {
+	url      string
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+func NewWebhookNotifier(url string, timeout time.Duration, attempts int) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:      url,
+		client:   &http.Client{Timeout: timeout},
+		attempts: max(attempts, 1),
+		backoff:  200 * time.Millisecond,
+	}
+}
+
+func (n *WebhookNotifier) CategoryCreated(ctx context.Context, c models.Category) error {
+	body, err := json.Marshal(Category{Code: c.Code, Name: c.Name})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = n.post(ctx, body)
+		if err == nil || attempt == n.attempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * n.backoff):
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("notifying category %s after %d attempts: %w", c.Code, n.attempts, err)
+	}
+	return nil
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package category
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	bags := models.Category{ID: 4, Code: "bags", Name: "Bags"}
+
+	t.Run("posts the category", func(t *testing.T) {
+		var received Category
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		err := NewWebhookNotifier(srv.URL, time.Second, 3).CategoryCreated(context.Background(), bags)
+
+		require.NoError(t, err)
+		assert.Equal(t, Category{Code: "bags", Name: "Bags"}, received)
+	})
+
+	t.Run("retries failed deliveries", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer srv.Close()
+
+		n := NewWebhookNotifier(srv.URL, time.Second, 3)
+		n.backoff = time.Millisecond
+
+		require.NoError(t, n.CategoryCreated(context.Background(), bags))
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		n := NewWebhookNotifier(srv.URL, time.Second, 2)
+		n.backoff = time.Millisecond
+
+		err := n.CategoryCreated(context.Background(), bags)
+
+		assert.ErrorContains(t, err, "after 2 attempts: webhook answered 500 Internal Server Error")
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("times out slow webhooks", func(t *testing.T) {
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer srv.Close()
+		defer close(release)
+
+		err := NewWebhookNotifier(srv.URL, 20*time.Millisecond, 1).CategoryCreated(context.Background(), bags)
+
+		assert.Error(t, err)
+	})
+}
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(e events.Event) {
+	p.events = append(p.events, e)
+}
+
+func TestEventNotifier(t *testing.T) {
+	publisher := &recordingPublisher{}
+
+	err := EventNotifier{Events: publisher}.CategoryCreated(context.Background(), models.Category{ID: 4, Code: "bags", Name: "Bags"})
+
+	require.NoError(t, err)
+	require.Len(t, publisher.events, 1)
+	assert.Equal(t, events.CategoryCreated, publisher.events[0].Type)
+	assert.Equal(t, Category{Code: "bags", Name: "Bags"}, publisher.events[0].Data)
+}
+
+func TestNotifiers(t *testing.T) {
+	bags := models.Category{Code: "bags", Name: "Bags"}
+	first, second := new(mockNotifier), new(mockNotifier)
+	first.On("CategoryCreated", mock.Anything, bags).Return(errors.New("webhook down"))
+	second.On("CategoryCreated", mock.Anything, bags).Return(nil)
+
+	err := Notifiers{first, second}.CategoryCreated(context.Background(), bags)
+
+	assert.EqualError(t, err, "webhook down")
+	first.AssertExpectations(t)
+	second.AssertExpectations(t)
+}
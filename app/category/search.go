@@ -0,0 +1,38 @@
+package category
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// foldName normalizes a name for searching, ignoring case and accents: the
+// name is decomposed and stripped of its combining marks, so that "Café"
+// and "CAFE" both become "cafe".
+func foldName(name string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)))
+	folded, _, err := transform.String(t, name)
+	if err != nil {
+		folded = name
+	}
+	return strings.ToLower(folded)
+}
+
+// matchesName reports whether the name of the category, in any locale,
+// contains the folded query.
+func matchesName(c models.Category, query string) bool {
+	if strings.Contains(foldName(c.Name), query) {
+		return true
+	}
+	for _, t := range c.Translations {
+		if strings.Contains(foldName(t.Name), query) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package currency
+
+import "errors"
+
+// Format is how the amounts of a response are rendered, as requested with
+// the priceFormat=string|number and amountFormat=major|minor parameters.
+type Format struct {
+	// AsNumber renders amounts as JSON numbers, as they were rendered before
+	// being strings.
+	AsNumber bool
+	// Currency is set when amounts are rendered as integers of minor units
+	// having Exponent decimal places.
+	Currency string
+	Exponent int32
+}
+
+// ParseFormat reads the priceFormat and amountFormat parameters, empty when
+// not given. Minor units are counted in currency, whose minor unit units
+// tells.
+func ParseFormat(priceFormat, amountFormat, currency string, units MinorUnits) (Format, error) {
+	var format Format
+	switch priceFormat {
+	case "", "string":
+	case "number":
+		format.AsNumber = true
+	default:
+		return Format{}, errors.New("priceFormat must be string or number")
+	}
+
+	switch amountFormat {
+	case "", "major":
+	case "minor":
+		format.Currency = currency
+		format.Exponent = units.Exponent(currency)
+	default:
+		return Format{}, errors.New("amountFormat must be major or minor")
+	}
+	return format, nil
+}
+
+// Apply renders m in the format.
+func (f Format) Apply(m *Money) {
+	m.AsNumber = f.AsNumber
+	if f.Currency != "" {
+		m.InMinorUnits, m.MinorExponent = true, f.Exponent
+	}
+}
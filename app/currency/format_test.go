@@ -0,0 +1,64 @@
+package currency
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+)
+
+func TestParseFormat(t *testing.T) {
+	units := MinorUnits{"JPY": 0}
+	tests := []struct {
+		priceFormat, amountFormat, currency string
+		want                                Format
+	}{
+		{"", "", "EUR", Format{}},
+		{"string", "major", "EUR", Format{}},
+		{"number", "", "EUR", Format{AsNumber: true}},
+		{"", "minor", "EUR", Format{Currency: "EUR", Exponent: 2}},
+		{"number", "minor", "JPY", Format{AsNumber: true, Currency: "JPY", Exponent: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.priceFormat+"/"+tt.amountFormat+"/"+tt.currency, func(t *testing.T) {
+			got, err := ParseFormat(tt.priceFormat, tt.amountFormat, tt.currency, units)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("unknown formats", func(t *testing.T) {
+		_, err := ParseFormat("float", "", "EUR", units)
+		assert.EqualError(t, err, "priceFormat must be string or number")
+
+		_, err = ParseFormat("", "cents", "EUR", units)
+		assert.EqualError(t, err, "amountFormat must be major or minor")
+	})
+}
+
+func TestFormat_Apply(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{Format{}, `"10.50"`},
+		{Format{AsNumber: true}, `10.50`},
+		{Format{Currency: "EUR", Exponent: 2}, `1050`},
+		{Format{AsNumber: true, Currency: "JPY", Exponent: 0}, `10`},
+	}
+
+	for _, tt := range tests {
+		m := NewMoney(decimal.RequireFromString("10.50"), pricing.HalfEven)
+		tt.format.Apply(&m)
+
+		got, err := json.Marshal(m)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, string(got))
+	}
+}
@@ -0,0 +1,46 @@
+package currency
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultMinorUnitExponent is the number of decimal places of the minor unit
+// of the currencies not configured otherwise, as cents are to euros.
+const DefaultMinorUnitExponent = 2
+
+// MinorUnits holds the minor unit exponents of the currencies differing from
+// DefaultMinorUnitExponent, keyed by currency code.
+type MinorUnits map[string]int32
+
+// ParseMinorUnits parses exponents in the "JPY:0,KWD:3" format.
+func ParseMinorUnits(s string) (MinorUnits, error) {
+	units := MinorUnits{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		code, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid minor unit %q, expected CODE:exponent", pair)
+		}
+		exponent, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || exponent < 0 || exponent > 4 {
+			return nil, fmt.Errorf("invalid minor unit exponent for %s: %q, expected 0 to 4", code, value)
+		}
+		units[strings.ToUpper(code)] = int32(exponent)
+	}
+	return units, nil
+}
+
+// Exponent returns the number of decimal places of the minor unit of the
+// currency.
+func (m MinorUnits) Exponent(currency string) int32 {
+	if exponent, ok := m[currency]; ok {
+		return exponent
+	}
+	return DefaultMinorUnitExponent
+}
@@ -0,0 +1,69 @@
+package currency
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMinorUnits(t *testing.T) {
+	t.Run("valid exponents", func(t *testing.T) {
+		units, err := ParseMinorUnits("jpy:0, KWD:3,")
+
+		require.NoError(t, err)
+		assert.Equal(t, MinorUnits{"JPY": 0, "KWD": 3}, units)
+	})
+
+	t.Run("unlisted currencies default to cents", func(t *testing.T) {
+		units, err := ParseMinorUnits("JPY:0")
+
+		require.NoError(t, err)
+		assert.Equal(t, int32(0), units.Exponent("JPY"))
+		assert.Equal(t, int32(2), units.Exponent("EUR"))
+		assert.Equal(t, int32(2), MinorUnits(nil).Exponent("EUR"))
+	})
+
+	for _, s := range []string{"JPY", "JPY:x", "JPY:-1", "JPY:5", "JPY:1.5"} {
+		t.Run("rejects "+s, func(t *testing.T) {
+			_, err := ParseMinorUnits(s)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMoney_MarshalJSON_MinorUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		exponent int32
+		want     string
+	}{
+		{"cents", "10.50", 2, `1050`},
+		{"whole amount", "100", 2, `10000`},
+		{"zero", "0", 2, `0`},
+		{"no minor unit", "1299", 0, `1299`},
+		{"no minor unit rounds to the even unit", "1298.50", 0, `1298`},
+		{"three decimal places", "12.34", 3, `12340`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			money := Money{Decimal: decimal.RequireFromString(tt.amount), InMinorUnits: true, MinorExponent: tt.exponent}
+
+			got, err := json.Marshal(money)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+
+	t.Run("takes precedence over numbers", func(t *testing.T) {
+		got, err := json.Marshal(Money{Decimal: decimal.RequireFromString("10.50"), AsNumber: true, InMinorUnits: true, MinorExponent: 2})
+
+		require.NoError(t, err)
+		assert.Equal(t, `1050`, string(got))
+	})
+}
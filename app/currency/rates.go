@@ -0,0 +1,137 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+)
+
+// Base is the currency prices are stored in.
+const Base = "EUR"
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// RatesProvider returns exchange rates from the base currency.
+type RatesProvider interface {
+	// Rate returns how many units of the currency one unit of Base is worth.
+	Rate(ctx context.Context, currency string) (decimal.Decimal, error)
+	// Supported lists the currency codes Rate knows about, sorted.
+	Supported() []string
+}
+
+// StaticRates is a RatesProvider backed by fixed rates, keyed by currency code.
+// The base currency is always supported with a rate of 1.
+type StaticRates map[string]decimal.Decimal
+
+// ParseStaticRates parses rates in the "GBP:0.85,USD:1.08" format.
+func ParseStaticRates(s string) (StaticRates, error) {
+	rates := StaticRates{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		code, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate %q, expected CODE:rate", pair)
+		}
+		rate, err := decimal.NewFromString(value)
+		if err != nil || !rate.IsPositive() {
+			return nil, fmt.Errorf("invalid rate for %s: %q", code, value)
+		}
+		rates[strings.ToUpper(code)] = rate
+	}
+	return rates, nil
+}
+
+func (s StaticRates) Rate(_ context.Context, currency string) (decimal.Decimal, error) {
+	if currency == Base {
+		return decimal.NewFromInt(1), nil
+	}
+	rate, ok := s[currency]
+	if !ok {
+		return decimal.Decimal{}, ErrUnsupportedCurrency
+	}
+	return rate, nil
+}
+
+func (s StaticRates) Supported() []string {
+	codes := []string{Base}
+	for code := range s {
+		if code != Base {
+			codes = append(codes, code)
+		}
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// Convert converts an amount in the base currency using rate, rounding to
+// cents with mode. Amounts already in cents are not rounded and, with a rate
+// of exactly 1, not multiplied either, sparing the allocations of listing
+// prices in the base currency.
+func Convert(amount, rate decimal.Decimal, mode pricing.Mode) decimal.Decimal {
+	if rate.Exponent() != 0 || rate.CoefficientInt64() != 1 {
+		amount = amount.Mul(rate)
+	}
+	if amount.Exponent() >= -2 {
+		return amount
+	}
+	return mode.Round(amount, 2)
+}
+
+// Conversion is how the prices of a response are converted from the base
+// currency: at Rate, rounded with Rounding.
+type Conversion struct {
+	Rate     decimal.Decimal
+	Rounding pricing.Mode
+}
+
+// Convert converts amount as the Convert function does.
+func (c Conversion) Convert(amount decimal.Decimal) decimal.Decimal {
+	return Convert(amount, c.Rate, c.Rounding)
+}
+
+// Money converts amount, rendered with the rounding of the conversion.
+func (c Conversion) Money(amount decimal.Decimal) Money {
+	return Money{Decimal: c.Convert(amount), Rounding: c.Rounding}
+}
+
+// Money is an amount rendered in JSON as a string with exactly two decimal
+// places, e.g. "10.50", so prices never pick up float rounding errors.
+type Money struct {
+	decimal.Decimal
+	// AsNumber renders the amount as a JSON number instead, still with two
+	// decimal places, for clients predating string prices.
+	AsNumber bool
+	// InMinorUnits renders the amount as a JSON integer counting minor units
+	// of MinorExponent decimal places, e.g. 1050 cents for 10.50. It takes
+	// precedence over AsNumber.
+	InMinorUnits  bool
+	MinorExponent int32
+	// Rounding rounds the amount to the places rendered.
+	Rounding pricing.Mode
+}
+
+// NewMoney wraps an amount as Money, rounded with mode when rendered.
+func NewMoney(d decimal.Decimal, mode pricing.Mode) Money {
+	return Money{Decimal: d, Rounding: mode}
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m.InMinorUnits {
+		return []byte(m.Rounding.Round(m.Shift(m.MinorExponent), 0).String()), nil
+	}
+	amount := m.Rounding.Round(m.Decimal, 2).StringFixed(2)
+	if m.AsNumber {
+		return []byte(amount), nil
+	}
+	return []byte(`"` + amount + `"`), nil
+}
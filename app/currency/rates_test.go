@@ -0,0 +1,131 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+)
+
+func TestParseStaticRates(t *testing.T) {
+	t.Run("valid rates", func(t *testing.T) {
+		rates, err := ParseStaticRates("gbp:0.85, USD:1.08,")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"EUR", "GBP", "USD"}, rates.Supported())
+
+		rate, err := rates.Rate(context.Background(), "GBP")
+		require.NoError(t, err)
+		assert.Equal(t, "0.85", rate.String())
+	})
+
+	t.Run("empty config only supports the base currency", func(t *testing.T) {
+		rates, err := ParseStaticRates("")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"EUR"}, rates.Supported())
+	})
+
+	for _, s := range []string{"GBP", "GBP:abc", "GBP:-1", "GBP:0"} {
+		t.Run("rejects "+s, func(t *testing.T) {
+			_, err := ParseStaticRates(s)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStaticRates_Rate(t *testing.T) {
+	rates := StaticRates{"GBP": decimal.RequireFromString("0.85")}
+
+	rate, err := rates.Rate(context.Background(), "EUR")
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(1)))
+
+	_, err = rates.Rate(context.Background(), "JPY")
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount, rate, want string
+	}{
+		{"10.99", "0.85", "9.34"}, // 9.3415
+		{"20.01", "0.5", "10"},    // 10.005 rounds to the even cent
+		{"20.03", "0.5", "10.02"}, // 10.015 rounds to the even cent
+		{"12.49", "1", "12.49"},
+		{"12.485", "1", "12.48"},   // rounded without a conversion too
+		{"12.49", "1.00", "12.49"}, // a rate of 1 in cents
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+"x"+tt.rate, func(t *testing.T) {
+			got := Convert(decimal.RequireFromString(tt.amount), decimal.RequireFromString(tt.rate), pricing.HalfEven)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestRoundingModes(t *testing.T) {
+	amount := decimal.RequireFromString("4.25")
+	half := decimal.RequireFromString("0.5")
+
+	tests := []struct {
+		mode                 pricing.Mode
+		converted, formatted string
+		minor                string
+	}{
+		{pricing.HalfEven, "2.12", `"2.12"`, "212"},
+		{pricing.HalfUp, "2.13", `"2.13"`, "213"},
+		{pricing.Down, "2.12", `"2.12"`, "212"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			assert.Equal(t, tt.converted, Convert(amount, half, tt.mode).String())
+			assert.Equal(t, NewMoney(decimal.RequireFromString(tt.converted), tt.mode), Conversion{Rate: half, Rounding: tt.mode}.Money(amount))
+			// Unrounded amounts are rounded alike when rendered
+			formatted, err := json.Marshal(NewMoney(decimal.RequireFromString("2.125"), tt.mode))
+			require.NoError(t, err)
+			assert.Equal(t, tt.formatted, string(formatted))
+			minor, err := json.Marshal(Money{Decimal: decimal.RequireFromString("2.125"), InMinorUnits: true, MinorExponent: 2, Rounding: tt.mode})
+			require.NoError(t, err)
+			assert.Equal(t, tt.minor, string(minor))
+		})
+	}
+}
+
+func TestMoney_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount decimal.Decimal
+		want   string
+	}{
+		{"sum of fractions", decimal.RequireFromString("0.1").Add(decimal.RequireFromString("0.2")), `"0.30"`},
+		{"whole amount", decimal.NewFromInt(100), `"100.00"`},
+		{"one decimal place", decimal.RequireFromString("100.5"), `"100.50"`},
+		{"trailing zeros", decimal.RequireFromString("100.50000"), `"100.50"`},
+		{"zero", decimal.Zero, `"0.00"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(NewMoney(tt.amount, pricing.HalfEven))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+
+	t.Run("as number", func(t *testing.T) {
+		sum := decimal.RequireFromString("0.1").Add(decimal.RequireFromString("0.2"))
+
+		got, err := json.Marshal(Money{Decimal: sum, AsNumber: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, `0.30`, string(got))
+	})
+}
@@ -2,25 +2,51 @@ package database
 
 import (
 	"fmt"
-	"log"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/lib/pq"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func New(user, password, dbname, port string) (db *gorm.DB, close func() error) {
+// cancelDeadlineDelay is how long the server is given to cancel a query
+// before its connection is dropped.
+const cancelDeadlineDelay = time.Second
+
+// New connects to the local database, returning the function closing the
+// connection.
+func New(user, password, dbname, port string) (db *gorm.DB, close func() error, err error) {
 	dsn := fmt.Sprintf("postgres://%s:%s@localhost:%s/%s?sslmode=disable", user, password, port, dbname)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err = Open(dsn, &gorm.Config{
+		TranslateError: true,
+	})
 	if err != nil {
-		log.Fatalf("failed to connect database: %s", err)
+		return nil, nil, fmt.Errorf("connecting to the database: %w", err)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to get database connection: %s", err)
+		return nil, nil, fmt.Errorf("getting the database connection: %w", err)
 	}
 
-	return db, sqlDB.Close
+	return db, sqlDB.Close, nil
+}
+
+// Open connects to the database of dsn. The queries whose context is
+// cancelled are cancelled on the server too: by default pgx only drops the
+// connection, which leaves the server running the query to completion.
+func Open(dsn string, config *gorm.Config) (*gorm.DB, error) {
+	pgxConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	pgxConfig.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: cancelDeadlineDelay}
+	}
+	return gorm.Open(postgres.New(postgres.Config{Conn: stdlib.OpenDB(*pgxConfig)}), config)
 }
@@ -0,0 +1,16 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_Unreachable(t *testing.T) {
+	// Nothing listens on port 1
+	db, close, err := New("postgres", "password", "challenge", "1")
+
+	assert.ErrorContains(t, err, "connecting to the database")
+	assert.Nil(t, db)
+	assert.Nil(t, close)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// PoolLimits are the connection limits of a pool. Zero idle connections
+// closes every connection once released.
+type PoolLimits struct {
+	MaxOpenConns int
+	MaxIdleConns int
+}
+
+// PoolLimitsError tells why pool limits are refused.
+type PoolLimitsError struct {
+	Reason string
+}
+
+func (e *PoolLimitsError) Error() string {
+	return "invalid pool limits: " + e.Reason
+}
+
+// Pool exposes the statistics and limits of the connection pool of a
+// database, without giving access to the connections themselves. The limits
+// can be changed at runtime, up to maxConns open connections.
+type Pool struct {
+	db       *sql.DB
+	maxConns int
+
+	mu     sync.Mutex
+	limits PoolLimits
+}
+
+// NewPool applies limits to the pool of db, which can later be raised up to
+// maxConns open connections.
+func NewPool(db *gorm.DB, limits PoolLimits, maxConns int) (*Pool, error) {
+	if maxConns < 1 {
+		return nil, fmt.Errorf("max connections must be positive, got %d", maxConns)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pool{db: sqlDB, maxConns: maxConns}
+	if err := p.SetLimits(limits); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Stats returns a snapshot of the statistics of the pool.
+func (p *Pool) Stats() sql.DBStats {
+	return p.db.Stats()
+}
+
+// Limits returns the limits currently applied.
+func (p *Pool) Limits() PoolLimits {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.limits
+}
+
+// MaxConns is the highest number of open connections SetLimits accepts.
+func (p *Pool) MaxConns() int {
+	return p.maxConns
+}
+
+// SetLimits applies limits to the pool, which must allow between 1 and
+// MaxConns open connections and no more idle connections than open ones.
+// Lowering the limits closes the connections over them once released.
+func (p *Pool) SetLimits(limits PoolLimits) error {
+	switch {
+	case limits.MaxOpenConns < 1 || limits.MaxOpenConns > p.maxConns:
+		return &PoolLimitsError{Reason: fmt.Sprintf("max open connections must be between 1 and %d", p.maxConns)}
+	case limits.MaxIdleConns < 0 || limits.MaxIdleConns > limits.MaxOpenConns:
+		return &PoolLimitsError{Reason: "max idle connections must be between 0 and max open connections"}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.db.SetMaxOpenConns(limits.MaxOpenConns)
+	p.db.SetMaxIdleConns(limits.MaxIdleConns)
+	p.limits = limits
+	return nil
+}
@@ -0,0 +1,70 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/database"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+)
+
+func TestPool(t *testing.T) {
+	t.Run("applies the initial limits", func(t *testing.T) {
+		db, _ := testsupport.MockDB(t)
+
+		pool, err := database.NewPool(db, database.PoolLimits{MaxOpenConns: 10, MaxIdleConns: 2}, 20)
+
+		require.NoError(t, err)
+		assert.Equal(t, database.PoolLimits{MaxOpenConns: 10, MaxIdleConns: 2}, pool.Limits())
+		assert.Equal(t, 10, pool.Stats().MaxOpenConnections)
+		assert.Equal(t, 20, pool.MaxConns())
+	})
+
+	t.Run("changes the limits within bounds", func(t *testing.T) {
+		db, _ := testsupport.MockDB(t)
+		pool, err := database.NewPool(db, database.PoolLimits{MaxOpenConns: 10, MaxIdleConns: 2}, 20)
+		require.NoError(t, err)
+
+		require.NoError(t, pool.SetLimits(database.PoolLimits{MaxOpenConns: 20, MaxIdleConns: 0}))
+
+		assert.Equal(t, database.PoolLimits{MaxOpenConns: 20}, pool.Limits())
+		assert.Equal(t, 20, pool.Stats().MaxOpenConnections)
+	})
+
+	t.Run("refuses limits out of bounds", func(t *testing.T) {
+		db, _ := testsupport.MockDB(t)
+		pool, err := database.NewPool(db, database.PoolLimits{MaxOpenConns: 10, MaxIdleConns: 2}, 20)
+		require.NoError(t, err)
+
+		tests := []struct {
+			limits  database.PoolLimits
+			wantErr string
+		}{
+			{database.PoolLimits{MaxOpenConns: 0}, "invalid pool limits: max open connections must be between 1 and 20"},
+			{database.PoolLimits{MaxOpenConns: 21}, "invalid pool limits: max open connections must be between 1 and 20"},
+			{database.PoolLimits{MaxOpenConns: 5, MaxIdleConns: 6}, "invalid pool limits: max idle connections must be between 0 and max open connections"},
+			{database.PoolLimits{MaxOpenConns: 5, MaxIdleConns: -1}, "invalid pool limits: max idle connections must be between 0 and max open connections"},
+		}
+		for _, tt := range tests {
+			err := pool.SetLimits(tt.limits)
+
+			var limitsErr *database.PoolLimitsError
+			assert.ErrorAs(t, err, &limitsErr)
+			assert.EqualError(t, err, tt.wantErr)
+		}
+		assert.Equal(t, database.PoolLimits{MaxOpenConns: 10, MaxIdleConns: 2}, pool.Limits())
+		assert.Equal(t, 10, pool.Stats().MaxOpenConnections)
+	})
+
+	t.Run("refuses invalid configurations", func(t *testing.T) {
+		db, _ := testsupport.MockDB(t)
+
+		_, err := database.NewPool(db, database.PoolLimits{MaxOpenConns: 10, MaxIdleConns: 2}, 0)
+		assert.EqualError(t, err, "max connections must be positive, got 0")
+
+		_, err = database.NewPool(db, database.PoolLimits{MaxOpenConns: 30, MaxIdleConns: 2}, 20)
+		assert.EqualError(t, err, "invalid pool limits: max open connections must be between 1 and 20")
+	})
+}
@@ -0,0 +1,37 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/database"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+)
+
+func TestPostgres_VerifySchema(t *testing.T) {
+	t.Run("migrated schema", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.Postgres(t)
+
+		assert.NoError(t, database.VerifySchema(context.Background(), db, database.CheckedModels...))
+	})
+
+	t.Run("incomplete schema", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.Postgres(t)
+		require.NoError(t, db.Exec(`ALTER TABLE product_variants DROP COLUMN price`).Error)
+		require.NoError(t, db.Exec(`DROP INDEX idx_products_code`).Error)
+
+		err := database.VerifySchema(context.Background(), db, database.CheckedModels...)
+
+		var schemaErr *database.SchemaError
+		require.ErrorAs(t, err, &schemaErr)
+		assert.Equal(t, []string{
+			"unique index on products (code)",
+			"column product_variants.price",
+		}, schemaErr.Missing)
+	})
+}
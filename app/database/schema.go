@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// CheckedModels are the models whose tables are verified at startup, as the
+// catalog cannot serve a single request without them.
+var CheckedModels = []any{&models.Product{}, &models.Category{}, &models.Variant{}}
+
+// SchemaError lists what the database lacks for the models, as in
+// "column product_variants.price".
+type SchemaError struct {
+	Missing []string
+}
+
+func (e *SchemaError) Error() string {
+	return "database schema is missing " + strings.Join(e.Missing, ", ")
+}
+
+// indexCount counts the indexes of a table of the current schema on exactly
+// the columns, in order. Indexes backing unique constraints count too.
+const indexCount = `SELECT COUNT(*) FROM pg_index i
+	JOIN pg_class t ON t.oid = i.indrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	WHERE n.nspname = CURRENT_SCHEMA() AND t.relname = @table AND (i.indisunique OR NOT @unique)
+	AND array_to_string(ARRAY(
+		SELECT a.attname FROM unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		ORDER BY k.ord
+	), ',') = @columns`
+
+// VerifySchema checks that the database has the tables of the models, with a
+// column for each of their fields and the indexes they declare. Indexes
+// match on their columns and uniqueness whatever their name, as those of the
+// sql directory are not named after gorm's conventions. What is missing is
+// returned as a *SchemaError.
+func VerifySchema(ctx context.Context, db *gorm.DB, models ...any) error {
+	db = db.WithContext(ctx)
+	migrator := db.Migrator()
+
+	var missing []string
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("parsing %T: %w", model, err)
+		}
+		table := stmt.Schema.Table
+
+		if !migrator.HasTable(model) {
+			missing = append(missing, "table "+table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			// Relationships have no column of their own
+			if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+				missing = append(missing, fmt.Sprintf("column %s.%s", table, field.DBName))
+			}
+		}
+		for _, idx := range stmt.Schema.ParseIndexes() {
+			columns := make([]string, len(idx.Fields))
+			for i, f := range idx.Fields {
+				columns[i] = f.DBName
+			}
+			unique := idx.Class == "UNIQUE"
+
+			var count int64
+			err := db.Raw(indexCount, map[string]any{
+				"table":   table,
+				"unique":  unique,
+				"columns": strings.Join(columns, ","),
+			}).Scan(&count).Error
+			if err != nil {
+				return fmt.Errorf("looking up the indexes of %s: %w", table, err)
+			}
+			if count == 0 {
+				kind := "index"
+				if unique {
+					kind = "unique index"
+				}
+				missing = append(missing, fmt.Sprintf("%s on %s (%s)", kind, table, strings.Join(columns, ", ")))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return &SchemaError{Missing: missing}
+	}
+	return nil
+}
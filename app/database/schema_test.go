@@ -0,0 +1,67 @@
+package database_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/database"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+var (
+	hasTable  = regexp.QuoteMeta(`SELECT count(*) FROM information_schema.tables WHERE table_schema = CURRENT_SCHEMA() AND table_name = $1 AND table_type = $2`)
+	hasColumn = regexp.QuoteMeta(`SELECT count(*) FROM INFORMATION_SCHEMA.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = $1 AND column_name = $2`)
+	hasIndex  = regexp.QuoteMeta(`SELECT COUNT(*) FROM pg_index i`)
+)
+
+func count(n int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"count"}).AddRow(n)
+}
+
+func TestVerifySchema(t *testing.T) {
+	t.Run("matching schema", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(hasTable).WithArgs("categories", "BASE TABLE").WillReturnRows(count(1))
+		for _, column := range []string{"id", "code", "name", "markup_percent", "version"} {
+			mock.ExpectQuery(hasColumn).WithArgs("categories", column).WillReturnRows(count(1))
+		}
+		mock.ExpectQuery(hasIndex).WithArgs("categories", true, "code").WillReturnRows(count(1))
+
+		err := database.VerifySchema(context.Background(), db, &models.Category{})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lists what is missing", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(hasTable).WithArgs("product_variants", "BASE TABLE").WillReturnRows(count(1))
+		for _, column := range []string{"id", "product_id", "name", "sku", "price", "created_at", "updated_at"} {
+			n := 1
+			if column == "price" {
+				n = 0
+			}
+			mock.ExpectQuery(hasColumn).WithArgs("product_variants", column).WillReturnRows(count(n))
+		}
+		mock.ExpectQuery(hasIndex).WithArgs("product_variants", true, "sku").WillReturnRows(count(0))
+		mock.ExpectQuery(hasTable).WithArgs("categories", "BASE TABLE").WillReturnRows(count(0))
+
+		err := database.VerifySchema(context.Background(), db, &models.Variant{}, &models.Category{})
+
+		var schemaErr *database.SchemaError
+		require.ErrorAs(t, err, &schemaErr)
+		assert.Equal(t, []string{
+			"column product_variants.price",
+			"unique index on product_variants (sku)",
+			"table categories",
+		}, schemaErr.Missing)
+		assert.EqualError(t, err, "database schema is missing column product_variants.price, unique index on product_variants (sku), table categories")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,47 @@
+// Package dto holds the product representations sent to the clients and the
+// mapping from the stored models to them, so that no internal field such as
+// ids or foreign keys reaches the responses.
+package dto
+
+import (
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+)
+
+type Product struct {
+	Code     string         `json:"code"`
+	Price    currency.Money `json:"price"`
+	Category *Category      `json:"category,omitempty"`
+	// Currency is only set when prices are in minor units, for them to be
+	// read without the request.
+	Currency string `json:"currency,omitempty"`
+	// Variants are only included in the product details, where they are an
+	// empty array rather than omitted when there are none.
+	Variants []Variant `json:"variants,omitzero"`
+	// Images holds the first image only, unless all of them are asked for
+	// on the product details.
+	Images []Image `json:"images,omitempty"`
+	// AvailableFrom and AvailableTo bound the availability window of the
+	// product, left out when open-ended.
+	AvailableFrom *time.Time `json:"available_from,omitempty"`
+	AvailableTo   *time.Time `json:"available_to,omitempty"`
+}
+
+type Category struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type Variant struct {
+	Name  string         `json:"name"`
+	SKU   string         `json:"sku"`
+	Price currency.Money `json:"price"`
+}
+
+type Image struct {
+	ID       uint   `json:"id"`
+	URL      string `json:"url"`
+	Position int    `json:"position"`
+	AltText  string `json:"alt_text,omitempty"`
+}
@@ -0,0 +1,131 @@
+package dto
+
+import (
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// ToProductResponse maps a product as listed, with its first image and
+// without variants. Prices are converted from the base currency with conv
+// and the category is named in locale.
+func ToProductResponse(p models.Product, conv currency.Conversion, locale string) Product {
+	product := toListedProduct(&p, conv)
+	if len(p.Images) > 0 {
+		product.Images = []Image{ToImageResponse(p.Images[0])}
+	}
+	if p.Category != nil {
+		product.Category = toCategoryResponse(p.Category, locale)
+	}
+	return product
+}
+
+// ToProductsResponse maps a page of products as ToProductResponse does, with
+// the allocations shared across the page: the first images are backed by a
+// single array and the products of a category share its mapping, named once.
+func ToProductsResponse(ps []models.Product, conv currency.Conversion, locale string) []Product {
+	products := make([]Product, len(ps))
+	images := make([]Image, len(ps))
+	var categories map[string]*Category
+	for i := range ps {
+		p := &ps[i]
+		products[i] = toListedProduct(p, conv)
+		if len(p.Images) > 0 {
+			images[i] = ToImageResponse(p.Images[0])
+			products[i].Images = images[i : i+1 : i+1]
+		}
+		if p.Category == nil {
+			continue
+		}
+		category, ok := categories[p.Category.Code]
+		if !ok {
+			if categories == nil {
+				categories = make(map[string]*Category)
+			}
+			category = toCategoryResponse(p.Category, locale)
+			categories[p.Category.Code] = category
+		}
+		products[i].Category = category
+	}
+	return products
+}
+
+// toListedProduct maps the fields of a listed product but its images and
+// category.
+func toListedProduct(p *models.Product, conv currency.Conversion) Product {
+	return Product{
+		Code:  p.Code,
+		Price: conv.Money(p.Price),
+
+		AvailableFrom: p.AvailableFrom,
+		AvailableTo:   p.AvailableTo,
+	}
+}
+
+func toCategoryResponse(c *models.Category, locale string) *Category {
+	return &Category{
+		Code: c.Code,
+		Name: c.LocalizedName(locale),
+	}
+}
+
+// ToProductDetailsResponse maps a product with all its variants and images.
+// Variants is never nil, so the details always list them.
+func ToProductDetailsResponse(p models.Product, conv currency.Conversion, locale string) Product {
+	product := ToProductResponse(p, conv, locale)
+	product.Images = ToImagesResponse(p.Images)
+	product.Variants = make([]Variant, len(p.Variants))
+	for i, v := range p.Variants {
+		product.Variants[i] = ToVariantResponse(&p, v, conv)
+	}
+	return product
+}
+
+// ToVariantResponse maps a variant of product p, priced by
+// resolveVariantPrice. The inherited price is converted, and so rounded,
+// only once inherited.
+func ToVariantResponse(p *models.Product, v models.Variant, conv currency.Conversion) Variant {
+	return Variant{
+		Name:  v.Name,
+		SKU:   v.SKU,
+		Price: conv.Money(resolveVariantPrice(p, v)),
+	}
+}
+
+// resolveVariantPrice is the price variant v of product p sells at: its own
+// price, or when it has none the price p currently has, see
+// Product.InheritedVariantPrice. Inherited prices are never stored with the
+// variants, so they follow the changes of the product price.
+func resolveVariantPrice(p *models.Product, v models.Variant) decimal.Decimal {
+	if v.Price.IsZero() {
+		return p.InheritedVariantPrice()
+	}
+	return v.Price
+}
+
+// ToImagesResponse maps the images, nil when there are none.
+func ToImagesResponse(images []models.Image) []Image {
+	if len(images) == 0 {
+		return nil
+	}
+	res := make([]Image, len(images))
+	for i, img := range images {
+		res[i] = ToImageResponse(img)
+	}
+	return res
+}
+
+func ToImageResponse(img models.Image) Image {
+	return Image{
+		ID:       img.ID,
+		URL:      img.URL,
+		Position: img.Position,
+		AltText:  img.AltText,
+	}
+}
+
+// FirstImage keeps the first of the ordered images, if any.
+func FirstImage(images []Image) []Image {
+	return images[:min(len(images), 1)]
+}
@@ -0,0 +1,215 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+var one = currency.Conversion{Rate: decimal.NewFromInt(1)}
+
+func TestToProductResponse(t *testing.T) {
+	product := models.Product{
+		ID:         1,
+		Code:       "PROD001",
+		Price:      decimal.RequireFromString("10.99"),
+		CategoryID: new(uint),
+		Category: &models.Category{ID: 2, Code: "clothing", Name: "Clothing", Translations: []models.CategoryTranslation{
+			{Locale: "de", Name: "Kleidung"},
+		}},
+		Variants: []models.Variant{{ID: 3, ProductID: 1, Name: "Variant A", SKU: "SKU001A"}},
+		Images: []models.Image{
+			{ID: 4, ProductID: 1, URL: "https://cdn.example.com/front.jpg", Position: 1},
+			{ID: 5, ProductID: 1, URL: "https://cdn.example.com/back.jpg", Position: 2},
+		},
+	}
+
+	t.Run("listed with the first image and without variants", func(t *testing.T) {
+		got := ToProductResponse(product, one, "de")
+
+		assert.Equal(t, "PROD001", got.Code)
+		assert.Equal(t, "10.99", got.Price.StringFixed(2))
+		assert.Equal(t, &Category{Code: "clothing", Name: "Kleidung"}, got.Category)
+		assert.Nil(t, got.Variants)
+		assert.Equal(t, []Image{{ID: 4, URL: "https://cdn.example.com/front.jpg", Position: 1}}, got.Images)
+	})
+
+	t.Run("converted with the rate", func(t *testing.T) {
+		got := ToProductResponse(product, currency.Conversion{Rate: decimal.RequireFromString("0.5")}, "")
+
+		assert.Equal(t, "5.50", got.Price.StringFixed(2))
+	})
+
+	t.Run("internal fields are left out", func(t *testing.T) {
+		body, err := json.Marshal(ToProductDetailsResponse(product, one, ""))
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"code":"PROD001","price":"10.99",
+			"category":{"code":"clothing","name":"Clothing"},
+			"variants":[{"name":"Variant A","sku":"SKU001A","price":"10.99"}],
+			"images":[
+				{"id":4,"url":"https://cdn.example.com/front.jpg","position":1},
+				{"id":5,"url":"https://cdn.example.com/back.jpg","position":2}
+			]
+		}`, string(body))
+	})
+
+	t.Run("with an availability window", func(t *testing.T) {
+		from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		seasonal := product
+		seasonal.AvailableFrom = &from
+
+		body, err := json.Marshal(ToProductResponse(seasonal, one, ""))
+
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `"available_from":"2025-06-01T00:00:00Z"`)
+		assert.NotContains(t, string(body), "available_to")
+	})
+
+	t.Run("empty product", func(t *testing.T) {
+		got := ToProductDetailsResponse(models.Product{}, one, "")
+
+		assert.Nil(t, got.Category)
+		assert.Nil(t, got.Images)
+		assert.Equal(t, []Variant{}, got.Variants)
+	})
+}
+
+func TestToProductsResponse(t *testing.T) {
+	clothing := &models.Category{Code: "clothing", Name: "Clothing", Translations: []models.CategoryTranslation{
+		{Locale: "de", Name: "Kleidung"},
+	}}
+	ps := []models.Product{
+		{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Category: clothing, Images: []models.Image{
+			{ID: 1, URL: "https://cdn.example.com/front.jpg", Position: 1},
+			{ID: 2, URL: "https://cdn.example.com/back.jpg", Position: 2},
+		}},
+		{Code: "PROD002", Price: decimal.RequireFromString("20"), Category: &models.Category{Code: "clothing", Name: "Clothing"}},
+		{Code: "PROD003", Price: decimal.RequireFromString("5.5")},
+	}
+
+	got := ToProductsResponse(ps, currency.Conversion{Rate: decimal.RequireFromString("0.5")}, "de")
+
+	require.Len(t, got, len(ps))
+	for i, p := range ps {
+		assert.Equal(t, ToProductResponse(p, currency.Conversion{Rate: decimal.RequireFromString("0.5")}, "de").Price.String(), got[i].Price.String())
+	}
+	assert.Equal(t, &Category{Code: "clothing", Name: "Kleidung"}, got[0].Category)
+	assert.Same(t, got[0].Category, got[1].Category, "named once per category")
+	assert.Nil(t, got[2].Category)
+	assert.Equal(t, []Image{{ID: 1, URL: "https://cdn.example.com/front.jpg", Position: 1}}, got[0].Images)
+	assert.Nil(t, got[1].Images)
+	assert.Empty(t, ToProductsResponse(nil, one, ""))
+}
+
+func TestToVariantResponse(t *testing.T) {
+	product := models.Product{Code: "PROD001", Price: decimal.RequireFromString("10.99")}
+
+	tests := []struct {
+		name    string
+		variant models.Variant
+		rate    string
+		want    string
+	}{
+		{"own price", models.Variant{Price: decimal.RequireFromString("11.99")}, "1", "11.99"},
+		{"inherits the product price", models.Variant{}, "1", "10.99"},
+		{"own price converted", models.Variant{Price: decimal.RequireFromString("11.99")}, "0.5", "6.00"},
+		{"inherited price converted", models.Variant{}, "0.5", "5.50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.variant.ID, tt.variant.ProductID, tt.variant.Name, tt.variant.SKU = 3, 1, "Variant A", "SKU001A"
+
+			got := ToVariantResponse(&product, tt.variant, currency.Conversion{Rate: decimal.RequireFromString(tt.rate)})
+
+			assert.Equal(t, "Variant A", got.Name)
+			assert.Equal(t, "SKU001A", got.SKU)
+			assert.Equal(t, tt.want, got.Price.StringFixed(2))
+		})
+	}
+}
+
+func TestToProductDetailsResponse_CategoryMarkup(t *testing.T) {
+	variants := []models.Variant{
+		{Name: "Inherited", SKU: "SKU001A"},
+		{Name: "Own price", SKU: "SKU001B", Price: decimal.RequireFromString("11.99")},
+	}
+	markup := func(percent string) *models.Category {
+		return &models.Category{Code: "accessories", MarkupPercent: decimal.NullDecimal{Decimal: decimal.RequireFromString(percent), Valid: true}}
+	}
+
+	tests := []struct {
+		name     string
+		category *models.Category
+		rate     string
+		product  string
+		variants []string
+	}{
+		{"without category", nil, "1", "10.99", []string{"10.99", "11.99"}},
+		{"category without markup", &models.Category{Code: "clothing"}, "1", "10.99", []string{"10.99", "11.99"}},
+		{"category with markup", markup("15"), "1", "10.99", []string{"12.64", "11.99"}},
+		{"zero markup", markup("0"), "1", "10.99", []string{"10.99", "11.99"}},
+		{"fractional markup", markup("12.5"), "1", "10.99", []string{"12.36", "11.99"}},
+		{"marked up before conversion and rounded once", markup("15"), "0.5", "5.50", []string{"6.32", "6.00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := models.Product{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Category: tt.category, Variants: variants}
+
+			got := ToProductDetailsResponse(product, currency.Conversion{Rate: decimal.RequireFromString(tt.rate)}, "")
+
+			assert.Equal(t, tt.product, got.Price.StringFixed(2))
+			assert.Equal(t, tt.variants, []string{got.Variants[0].Price.StringFixed(2), got.Variants[1].Price.StringFixed(2)})
+		})
+	}
+}
+
+func TestToProductDetailsResponse_ProductPriceChange(t *testing.T) {
+	// Partial overrides: only SKU001B has a price of its own
+	product := models.Product{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Variants: []models.Variant{
+		{Name: "Variant A", SKU: "SKU001A"},
+		{Name: "Variant B", SKU: "SKU001B", Price: decimal.RequireFromString("11.99")},
+		{Name: "Variant C", SKU: "SKU001C"},
+	}}
+	prices := func(p Product) []string {
+		var res []string
+		for _, v := range p.Variants {
+			res = append(res, v.Price.StringFixed(2))
+		}
+		return res
+	}
+
+	assert.Equal(t, []string{"10.99", "11.99", "10.99"}, prices(ToProductDetailsResponse(product, one, "")))
+
+	product.Price = decimal.RequireFromString("8.49")
+	assert.Equal(t, []string{"8.49", "11.99", "8.49"}, prices(ToProductDetailsResponse(product, one, "")),
+		"inheriting variants follow the product price, overriding ones keep theirs")
+
+	product.Category = &models.Category{Code: "clothing", MarkupPercent: decimal.NullDecimal{Decimal: decimal.NewFromInt(10), Valid: true}}
+	assert.Equal(t, []string{"9.34", "11.99", "9.34"}, prices(ToProductDetailsResponse(product, one, "")))
+}
+
+func TestResolveVariantPrice(t *testing.T) {
+	product := &models.Product{Price: decimal.RequireFromString("10.99")}
+
+	assert.Equal(t, "10.99", resolveVariantPrice(product, models.Variant{}).String())
+	assert.Equal(t, "11.99", resolveVariantPrice(product, models.Variant{Price: decimal.RequireFromString("11.99")}).String())
+	// A zero price is no override
+	assert.Equal(t, "10.99", resolveVariantPrice(product, models.Variant{Price: decimal.RequireFromString("0.00")}).String())
+}
+
+func TestToImagesResponse(t *testing.T) {
+	assert.Nil(t, ToImagesResponse(nil))
+	assert.Empty(t, FirstImage(nil))
+	assert.Equal(t, []Image{{ID: 1, URL: "a"}}, FirstImage([]Image{{ID: 1, URL: "a"}, {ID: 2, URL: "b"}}))
+}
@@ -0,0 +1,87 @@
+// Package eventlog serves the changes recorded in the outbox to the platform
+// consumers polling them, behind the API key of the machine to machine
+// endpoints.
+package eventlog
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/outbox"
+)
+
+// Bounds of a page of events.
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type Handler struct {
+	repo outbox.Repository
+}
+
+func NewHandler(r outbox.Repository) *Handler {
+	return &Handler{
+		repo: r,
+	}
+}
+
+// HandleList returns the events with an ID greater than the after parameter,
+// oldest first. Event IDs only ever increase in commit order, so consumers
+// keep the ID of the last event they processed and poll with it: nothing is
+// marked consumed on the server, and any number of consumers can read the log
+// at their own pace.
+func (h *Handler) HandleList(w http.ResponseWriter, r *http.Request) {
+	after, limit, err := validateListQuery(r)
+	if err != nil {
+		api.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.repo.List(r.Context(), after, limit)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	response := EventsResponse{Events: make([]Event, len(res)), NextAfter: after}
+	for i, e := range res {
+		response.Events[i] = Event{
+			ID:            e.ID,
+			Type:          e.Type,
+			AggregateCode: e.AggregateCode,
+			Payload:       e.Payload,
+			CreatedAt:     e.CreatedAt.UTC(),
+		}
+		response.NextAfter = e.ID
+	}
+	api.OKResponse(w, response)
+}
+
+// validateListQuery reads the after parameter, 0 to start from the first
+// event, and the clamped limit.
+func validateListQuery(r *http.Request) (uint64, int, error) {
+	q := r.URL.Query()
+	var after uint64
+	if v := q.Get("after"); v != "" {
+		var err error
+		if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return 0, 0, errors.New("after must be a non-negative integer")
+		}
+	}
+
+	limit := defaultLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, errors.New("limit must be an integer")
+		}
+		limit = min(max(n, 1), maxLimit)
+	}
+	return after, limit, nil
+}
@@ -0,0 +1,100 @@
+package eventlog
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func get(repo *mockRepo, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	NewHandler(repo).HandleList(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestHandleList(t *testing.T) {
+	createdAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("events after the given ID", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, uint64(41), 2).Return([]models.OutboxEvent{
+			{ID: 42, Type: "product.created", AggregateCode: "PROD009", Payload: []byte(`{"code":"PROD009"}`), CreatedAt: createdAt},
+			{ID: 44, Type: "catalog.snapshot_imported", Payload: []byte(`{"categories":{"created":1,"updated":0}}`), CreatedAt: createdAt},
+		}, nil)
+
+		rec := get(repo, "/events?after=41&limit=2")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"events":[
+			{"id":42,"type":"product.created","aggregate_code":"PROD009","payload":{"code":"PROD009"},"created_at":"2026-10-01T12:00:00Z"},
+			{"id":44,"type":"catalog.snapshot_imported","payload":{"categories":{"created":1,"updated":0}},"created_at":"2026-10-01T12:00:00Z"}
+		],"next_after":44}`, rec.Body.String())
+	})
+
+	t.Run("from the first event by default", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, uint64(0), defaultLimit).Return([]models.OutboxEvent{}, nil)
+
+		rec := get(repo, "/events")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"events":[],"next_after":0}`, rec.Body.String())
+		testsupport.AssertJSONArrays(t, rec.Body.String(), "events")
+	})
+
+	t.Run("no new event keeps the position", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, uint64(44), defaultLimit).Return([]models.OutboxEvent{}, nil)
+
+		rec := get(repo, "/events?after=44")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"events":[],"next_after":44}`, rec.Body.String())
+	})
+
+	t.Run("limit is clamped", func(t *testing.T) {
+		for target, limit := range map[string]int{"/events?limit=5000": maxLimit, "/events?limit=0": 1} {
+			repo := new(mockRepo)
+			repo.On("List", mock.Anything, uint64(0), limit).Return([]models.OutboxEvent{}, nil)
+
+			rec := get(repo, target)
+
+			assert.Equal(t, http.StatusOK, rec.Code, target)
+			repo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("malformed parameters", func(t *testing.T) {
+		tests := map[string]string{
+			"/events?after=-1":   "after must be a non-negative integer",
+			"/events?after=abc":  "after must be a non-negative integer",
+			"/events?limit=many": "limit must be an integer",
+		}
+		for target, msg := range tests {
+			repo := new(mockRepo)
+
+			rec := get(repo, target)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+			assert.JSONEq(t, `{"error":"`+msg+`"}`, rec.Body.String(), target)
+			repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, uint64(0), defaultLimit).Return(nil, errors.New("connection reset"))
+
+		rec := get(repo, "/events")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
@@ -0,0 +1,19 @@
+package eventlog
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type mockRepo struct {
+	mock.Mock
+}
+
+func (m *mockRepo) List(ctx context.Context, after uint64, limit int) ([]models.OutboxEvent, error) {
+	args := m.Called(ctx, after, limit)
+	events, _ := args.Get(0).([]models.OutboxEvent)
+	return events, args.Error(1)
+}
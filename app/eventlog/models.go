@@ -0,0 +1,24 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventsResponse is a page of the event log. NextAfter is the after
+// parameter of the next page: the ID of the last event of the page, or the
+// after parameter of this one when there is no event yet.
+type EventsResponse struct {
+	Events    []Event `json:"events"`
+	NextAfter uint64  `json:"next_after"`
+}
+
+type Event struct {
+	ID   uint64 `json:"id"`
+	Type string `json:"type"`
+	// AggregateCode is the code of the product or the category changed,
+	// omitted for the changes spanning the catalog.
+	AggregateCode string          `json:"aggregate_code,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
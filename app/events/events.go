@@ -0,0 +1,61 @@
+package events
+
+import (
+	"time"
+)
+
+// Event types published on catalog mutations.
+const (
+	ProductCreated         = "product.created"
+	ProductUpdated         = "product.updated"
+	CategoryCreated        = "category.created"
+	CategoryUpdated        = "category.updated"
+	CategoryPricesAdjusted = "category.prices_adjusted"
+	SnapshotImported       = "catalog.snapshot_imported"
+)
+
+// Event is the JSON document delivered to the webhook endpoints.
+type Event struct {
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// New stamps an event of the given type with the current time.
+func New(typ string, data any) Event {
+	return Event{
+		Type:       typ,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// Publisher hands events over for delivery. Publishing never blocks on the
+// delivery itself, only on room to queue it, so handlers can publish on the
+// request path.
+type Publisher interface {
+	Publish(e Event)
+}
+
+// NopPublisher discards every event.
+type NopPublisher struct{}
+
+func (NopPublisher) Publish(Event) {}
+
+// Buffer holds the events published to it until they are flushed, so that
+// events of changes which may still be rolled back are not delivered.
+type Buffer struct {
+	events []Event
+}
+
+func (b *Buffer) Publish(e Event) {
+	b.events = append(b.events, e)
+}
+
+// Flush publishes the buffered events to p in order and empties the buffer.
+func (b *Buffer) Flush(p Publisher) {
+	for _, e := range b.events {
+		p.Publish(e)
+	}
+	b.events = nil
+}
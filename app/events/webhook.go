@@ -0,0 +1,241 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body,
+// keyed with the endpoint secret and prefixed with "sha256=".
+const SignatureHeader = "X-Signature"
+
+// Endpoint is a webhook receiver and the secret its deliveries are signed with.
+type Endpoint struct {
+	URL    string
+	Secret string
+}
+
+// ParseEndpoints parses a comma separated list of url|secret pairs, as in
+// "https://search.example.com/hooks|s3cret". An empty string yields no endpoints.
+func ParseEndpoints(s string) ([]Endpoint, error) {
+	var endpoints []Endpoint
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		rawURL, secret, ok := strings.Cut(pair, "|")
+		if !ok || secret == "" {
+			return nil, fmt.Errorf("invalid webhook endpoint %q, expected url|secret", pair)
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook url %q", rawURL)
+		}
+		endpoints = append(endpoints, Endpoint{URL: rawURL, Secret: secret})
+	}
+	return endpoints, nil
+}
+
+// Sign computes the X-Signature value of body for secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DispatcherOptions tunes the delivery of events. Zero values use defaults.
+type DispatcherOptions struct {
+	// Workers is the number of concurrent deliveries.
+	Workers int
+	// QueueSize bounds the deliveries waiting for a worker. Publishing while
+	// the queue is full waits for the workers to make room.
+	QueueSize int
+	// MaxAttempts is the number of tries per delivery.
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubled after
+	// every further failure.
+	Backoff time.Duration
+	// Timeout bounds each delivery attempt.
+	Timeout time.Duration
+	// Logger receives the failed deliveries and the events published once
+	// stopped, slog.Default() when nil.
+	Logger *slog.Logger
+}
+
+type delivery struct {
+	endpoint  Endpoint
+	eventType string
+	body      []byte
+}
+
+// Dispatcher delivers events to every endpoint asynchronously through a
+// bounded pool of workers, retrying failed deliveries with an exponential
+// backoff. Deliveries that still fail are logged and dropped.
+type Dispatcher struct {
+	endpoints   []Endpoint
+	client      *http.Client
+	workers     int
+	maxAttempts int
+	backoff     time.Duration
+	logger      *slog.Logger
+
+	queue  chan delivery
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu is held for reading while queueing and for writing while closing
+	// the queue, so that publishers waiting for room are not cut off.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewDispatcher(endpoints []Endpoint, opts DispatcherOptions) *Dispatcher {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		endpoints:   endpoints,
+		client:      &http.Client{Timeout: opts.Timeout},
+		workers:     opts.Workers,
+		maxAttempts: opts.MaxAttempts,
+		backoff:     opts.Backoff,
+		logger:      opts.Logger,
+		queue:       make(chan delivery, opts.QueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start launches the workers. Call Stop to release them.
+func (d *Dispatcher) Start() {
+	for range d.workers {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for dl := range d.queue {
+				d.deliver(dl)
+			}
+		}()
+	}
+}
+
+// Stop refuses new events and waits for the queued ones to be delivered. When
+// ctx expires first, pending retries are abandoned and ctx's error returned.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Publish queues e for every endpoint. When the queue is full it waits for
+// the workers to make room rather than dropping the event, slowing the
+// publishers down to the pace of the deliveries.
+func (d *Dispatcher) Publish(e Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		d.logger.Error("Failed to encode event", "event", e.Type, "error", err)
+		return
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.logger.Warn("Dropping event, dispatcher stopped", "event", e.Type)
+		return
+	}
+	for _, endpoint := range d.endpoints {
+		d.queue <- delivery{endpoint: endpoint, eventType: e.Type, body: body}
+	}
+}
+
+func (d *Dispatcher) deliver(dl delivery) {
+	var err error
+	wait := d.backoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		if err = d.post(dl); err == nil {
+			return
+		}
+		if attempt == d.maxAttempts {
+			break
+		}
+
+		select {
+		case <-d.ctx.Done():
+			d.logger.Warn("Abandoning event delivery", "event", dl.eventType, "endpoint", dl.endpoint.URL, "attempts", attempt, "error", err)
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+	d.logger.Error("Failed to deliver event", "event", dl.eventType, "endpoint", dl.endpoint.URL, "attempts", d.maxAttempts, "error", err)
+}
+
+func (d *Dispatcher) post(dl delivery) error {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, dl.endpoint.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(dl.endpoint.Secret, dl.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
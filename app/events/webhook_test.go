@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		endpoints, err := ParseEndpoints("https://search.example.com/hooks|s3cret, http://localhost:9000/events?v=1|other")
+
+		require.NoError(t, err)
+		assert.Equal(t, []Endpoint{
+			{URL: "https://search.example.com/hooks", Secret: "s3cret"},
+			{URL: "http://localhost:9000/events?v=1", Secret: "other"},
+		}, endpoints)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		endpoints, err := ParseEndpoints("")
+
+		require.NoError(t, err)
+		assert.Empty(t, endpoints)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, s := range []string{"https://search.example.com", "https://search.example.com|", "ftp://example.com|s", "not a url|s"} {
+			_, err := ParseEndpoints(s)
+
+			assert.Error(t, err, s)
+		}
+	})
+}
+
+func TestSign(t *testing.T) {
+	// Reference value from: printf '{"a":1}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=aa9e2e3575f5d7098b6caccd790888c36d5fdb63342a73bada2d6a51747a8494", Sign("secret", []byte(`{"a":1}`)))
+}
+
+// receiver records the deliveries it accepts after answering the first
+// failures calls with an error.
+type receiver struct {
+	mu       sync.Mutex
+	calls    atomic.Int32
+	failures int32
+	bodies   [][]byte
+	sigs     []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rc.calls.Add(1) <= rc.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	rc.bodies = append(rc.bodies, body)
+	rc.sigs = append(rc.sigs, r.Header.Get(SignatureHeader))
+	rc.mu.Unlock()
+}
+
+func newDispatcher(t *testing.T, rc *receiver, opts DispatcherOptions) *Dispatcher {
+	t.Helper()
+
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	if opts.Backoff == 0 {
+		opts.Backoff = time.Millisecond
+	}
+	d := NewDispatcher([]Endpoint{{URL: srv.URL, Secret: "s3cret"}}, opts)
+	d.Start()
+	return d
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("delivers signed events", func(t *testing.T) {
+		rc := &receiver{}
+		d := newDispatcher(t, rc, DispatcherOptions{})
+
+		d.Publish(New(ProductCreated, map[string]string{"code": "PROD009"}))
+		require.NoError(t, d.Stop(context.Background()))
+
+		require.Len(t, rc.bodies, 1)
+		assert.Equal(t, Sign("s3cret", rc.bodies[0]), rc.sigs[0])
+		var e struct {
+			Type string            `json:"type"`
+			Data map[string]string `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rc.bodies[0], &e))
+		assert.Equal(t, ProductCreated, e.Type)
+		assert.Equal(t, map[string]string{"code": "PROD009"}, e.Data)
+	})
+
+	t.Run("retries failed deliveries", func(t *testing.T) {
+		rc := &receiver{failures: 2}
+		d := newDispatcher(t, rc, DispatcherOptions{MaxAttempts: 3})
+
+		d.Publish(New(CategoryCreated, nil))
+		require.NoError(t, d.Stop(context.Background()))
+
+		assert.Equal(t, int32(3), rc.calls.Load())
+		assert.Len(t, rc.bodies, 1)
+	})
+
+	t.Run("gives up after the max attempts", func(t *testing.T) {
+		rc := &receiver{failures: 10}
+		d := newDispatcher(t, rc, DispatcherOptions{MaxAttempts: 2})
+
+		d.Publish(New(CategoryCreated, nil))
+		require.NoError(t, d.Stop(context.Background()))
+
+		assert.Equal(t, int32(2), rc.calls.Load())
+		assert.Empty(t, rc.bodies)
+	})
+
+	t.Run("stop abandons retries after the deadline", func(t *testing.T) {
+		rc := &receiver{failures: 10}
+		d := newDispatcher(t, rc, DispatcherOptions{MaxAttempts: 5, Backoff: time.Hour})
+
+		d.Publish(New(CategoryCreated, nil))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, d.Stop(ctx), context.DeadlineExceeded)
+		assert.Equal(t, int32(1), rc.calls.Load())
+	})
+
+	t.Run("drops events once stopped", func(t *testing.T) {
+		rc := &receiver{}
+		d := newDispatcher(t, rc, DispatcherOptions{})
+		require.NoError(t, d.Stop(context.Background()))
+
+		d.Publish(New(ProductUpdated, nil))
+
+		assert.Zero(t, rc.calls.Load())
+	})
+
+	t.Run("waits for room when the queue is full", func(t *testing.T) {
+		rc := &receiver{}
+		srv := httptest.NewServer(rc)
+		defer srv.Close()
+		// Not started, so nothing drains the queue
+		d := NewDispatcher([]Endpoint{{URL: srv.URL, Secret: "s3cret"}}, DispatcherOptions{QueueSize: 1})
+
+		d.Publish(New(ProductUpdated, nil))
+		published := make(chan struct{})
+		go func() {
+			d.Publish(New(ProductUpdated, nil))
+			close(published)
+		}()
+		select {
+		case <-published:
+			t.Fatal("published to a full queue")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		d.Start()
+		<-published
+		require.NoError(t, d.Stop(context.Background()))
+
+		assert.Equal(t, int32(2), rc.calls.Load())
+	})
+}
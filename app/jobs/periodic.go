@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+)
+
+// Task is a maintenance routine run periodically. ctx is canceled when the
+// task is stopped, and carries the logger of the task.
+type Task func(ctx context.Context) error
+
+// Periodic runs a task at a fixed interval in the background, starting one
+// interval after Start. Runs never overlap: a run taking longer than the
+// interval delays the next one.
+type Periodic struct {
+	interval time.Duration
+	task     Task
+	logger   *slog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPeriodic prepares the task named name to run every interval. logger
+// receives the failures, slog.Default() when nil.
+func NewPeriodic(name string, interval time.Duration, task Task, logger *slog.Logger) *Periodic {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("task", name)
+
+	ctx, cancel := context.WithCancel(logging.NewContext(context.Background(), logger))
+	return &Periodic{
+		interval: interval,
+		task:     task,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the timer running the task. Call Stop to release it.
+func (p *Periodic) Start() {
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+				p.run()
+			}
+		}
+	}()
+}
+
+// run keeps the timer alive when the task fails or panics.
+func (p *Periodic) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Error("Task panicked", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	if err := p.task(p.ctx); err != nil && p.ctx.Err() == nil {
+		p.logger.Error("Task failed", "error", err)
+	}
+}
+
+// Stop cancels the running task, if any, and waits for it to return. When
+// ctx expires first, ctx's error is returned without waiting any longer.
+// Stop must only be called once the task is started.
+func (p *Periodic) Stop(ctx context.Context) error {
+	p.cancel()
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriodic(t *testing.T) {
+	t.Run("runs the task at every interval", func(t *testing.T) {
+		runs := make(chan struct{}, 3)
+		p := NewPeriodic("test", time.Millisecond, func(ctx context.Context) error {
+			select {
+			case runs <- struct{}{}:
+			default:
+			}
+			return nil
+		}, nil)
+		p.Start()
+
+		for range 3 {
+			<-runs
+		}
+		require.NoError(t, p.Stop(context.Background()))
+	})
+
+	t.Run("keeps running after the task fails or panics", func(t *testing.T) {
+		var calls atomic.Int32
+		done := make(chan struct{})
+		p := NewPeriodic("test", time.Millisecond, func(ctx context.Context) error {
+			switch calls.Add(1) {
+			case 1:
+				return errors.New("boom")
+			case 2:
+				panic("boom")
+			case 3:
+				close(done)
+			}
+			return nil
+		}, nil)
+		p.Start()
+
+		<-done
+		require.NoError(t, p.Stop(context.Background()))
+	})
+
+	t.Run("stop cancels the running task and no other run starts", func(t *testing.T) {
+		started := make(chan struct{})
+		var calls atomic.Int32
+		var canceled bool
+		p := NewPeriodic("test", time.Millisecond, func(ctx context.Context) error {
+			if calls.Add(1) == 1 {
+				close(started)
+			}
+			<-ctx.Done()
+			canceled = true
+			return ctx.Err()
+		}, nil)
+		p.Start()
+
+		<-started
+		require.NoError(t, p.Stop(context.Background()))
+		time.Sleep(5 * time.Millisecond)
+
+		assert.True(t, canceled)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("stop gives up waiting after the deadline", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		defer close(release)
+		p := NewPeriodic("test", time.Millisecond, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		}, nil)
+		p.Start()
+
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, p.Stop(ctx), context.DeadlineExceeded)
+	})
+}
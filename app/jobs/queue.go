@@ -0,0 +1,140 @@
+// Package jobs runs background jobs, identified by the id of their database
+// row, on a bounded pool of workers, and maintenance tasks at a fixed
+// interval.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+)
+
+var (
+	ErrQueueFull    = errors.New("job queue is full")
+	ErrQueueStopped = errors.New("job queue is stopped")
+)
+
+// Runner runs the job with the given id. ctx is canceled when the queue
+// stops, and carries the logger of the queue.
+type Runner func(ctx context.Context, id uint)
+
+// QueueOptions tunes the queue. Zero values use defaults.
+type QueueOptions struct {
+	// Workers is the number of jobs run concurrently.
+	Workers int
+	// QueueSize bounds the jobs waiting for a worker. Enqueue fails with
+	// ErrQueueFull beyond it.
+	QueueSize int
+	// Logger receives the panicking jobs, slog.Default() when nil.
+	Logger *slog.Logger
+}
+
+// Queue runs the enqueued jobs on a bounded pool of workers. Jobs are only
+// kept in memory, so those still queued when the queue stops are not run.
+type Queue struct {
+	workers int
+	logger  *slog.Logger
+
+	queue  chan uint
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewQueue(opts QueueOptions) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	ctx, cancel := context.WithCancel(logging.NewContext(context.Background(), opts.Logger))
+	return &Queue{
+		workers: opts.Workers,
+		logger:  opts.Logger,
+		queue:   make(chan uint, opts.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start launches the workers running the jobs with run. Call Stop to
+// release them.
+func (q *Queue) Start(run Runner) {
+	for range q.workers {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for {
+				select {
+				case <-q.ctx.Done():
+					return
+				case id := <-q.queue:
+					if q.ctx.Err() != nil {
+						return
+					}
+					q.run(run, id)
+				}
+			}
+		}()
+	}
+}
+
+// run keeps the worker alive when a job panics.
+func (q *Queue) run(run Runner, id uint) {
+	defer func() {
+		if p := recover(); p != nil {
+			q.logger.Error("Job panicked", "job", id, "panic", p, "stack", string(debug.Stack()))
+		}
+	}()
+	run(q.ctx, id)
+}
+
+// Enqueue hands the job over to the workers without waiting for it to run.
+func (q *Queue) Enqueue(id uint) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueStopped
+	}
+	select {
+	case q.queue <- id:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop refuses new jobs, cancels the running ones and waits for them to
+// return. When ctx expires first, ctx's error is returned without waiting
+// any longer.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	t.Run("runs the enqueued jobs", func(t *testing.T) {
+		var mu sync.Mutex
+		var ran []uint
+		var wg sync.WaitGroup
+		q := NewQueue(QueueOptions{Workers: 1})
+		q.Start(func(ctx context.Context, id uint) {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, id)
+		})
+
+		wg.Add(2)
+		require.NoError(t, q.Enqueue(1))
+		require.NoError(t, q.Enqueue(2))
+		wg.Wait()
+		require.NoError(t, q.Stop(context.Background()))
+
+		assert.Equal(t, []uint{1, 2}, ran)
+	})
+
+	t.Run("keeps running after a job panics", func(t *testing.T) {
+		done := make(chan uint, 1)
+		q := NewQueue(QueueOptions{Workers: 1})
+		q.Start(func(ctx context.Context, id uint) {
+			if id == 1 {
+				panic("boom")
+			}
+			done <- id
+		})
+
+		require.NoError(t, q.Enqueue(1))
+		require.NoError(t, q.Enqueue(2))
+
+		assert.Equal(t, uint(2), <-done)
+		require.NoError(t, q.Stop(context.Background()))
+	})
+
+	t.Run("stop cancels the running jobs", func(t *testing.T) {
+		started := make(chan struct{})
+		var canceled bool
+		q := NewQueue(QueueOptions{Workers: 1})
+		q.Start(func(ctx context.Context, id uint) {
+			close(started)
+			<-ctx.Done()
+			canceled = true
+		})
+
+		require.NoError(t, q.Enqueue(1))
+		<-started
+		require.NoError(t, q.Stop(context.Background()))
+
+		assert.True(t, canceled)
+		assert.ErrorIs(t, q.Enqueue(2), ErrQueueStopped)
+	})
+
+	t.Run("stop gives up waiting after the deadline", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		defer close(release)
+		q := NewQueue(QueueOptions{Workers: 1})
+		q.Start(func(ctx context.Context, id uint) {
+			close(started)
+			<-release
+		})
+
+		require.NoError(t, q.Enqueue(1))
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, q.Stop(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("refuses jobs when the queue is full", func(t *testing.T) {
+		// Not started, so nothing drains the queue
+		q := NewQueue(QueueOptions{QueueSize: 1})
+
+		require.NoError(t, q.Enqueue(1))
+		assert.ErrorIs(t, q.Enqueue(2), ErrQueueFull)
+	})
+}
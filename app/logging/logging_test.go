@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		logger, err := New(&out, "json", "info")
+		require.NoError(t, err)
+
+		logger.Info("Starting server", "addr", "localhost:8484")
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entry), out.String())
+		assert.Equal(t, "INFO", entry["level"])
+		assert.Equal(t, "Starting server", entry["msg"])
+		assert.Equal(t, "localhost:8484", entry["addr"])
+		assert.Contains(t, entry, "time")
+	})
+
+	t.Run("text by default", func(t *testing.T) {
+		var out bytes.Buffer
+		logger, err := New(&out, "", "")
+		require.NoError(t, err)
+
+		logger.Info("Starting server", "addr", "localhost:8484")
+
+		assert.Contains(t, out.String(), `level=INFO msg="Starting server" addr=localhost:8484`)
+	})
+
+	t.Run("entries below the level are dropped", func(t *testing.T) {
+		var out bytes.Buffer
+		logger, err := New(&out, "json", "warn")
+		require.NoError(t, err)
+
+		logger.Info("ignored")
+		logger.Warn("kept")
+
+		assert.NotContains(t, out.String(), "ignored")
+		assert.Contains(t, out.String(), "kept")
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		_, err := New(&bytes.Buffer{}, "xml", "")
+		assert.EqualError(t, err, `unsupported log format "xml", expected text or json`)
+
+		_, err = New(&bytes.Buffer{}, "json", "verbose")
+		assert.EqualError(t, err, `unsupported log level "verbose", expected debug, info, warn or error`)
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+
+	assert.Same(t, logger, FromContext(NewContext(context.Background(), logger)))
+	assert.Same(t, slog.Default(), FromContext(context.Background()))
+}
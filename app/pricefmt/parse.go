@@ -0,0 +1,129 @@
+// Package pricefmt parses the prices sent by clients, which are formatted for
+// display as often as not.
+package pricefmt
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Accepted describes the formats Parse accepts, to complete error messages
+// such as "priceLessThan must be a price " + Accepted.
+const Accepted = "such as 1299.50, 1,299.50, 1.299,50 or €1299"
+
+var ErrMalformed = errors.New("malformed price")
+
+// symbols are the currency symbols allowed before or after the amount.
+var symbols = []string{"€", "$", "£", "¥"}
+
+// Parse reads a price such as "1299.50", "-5", "€1,299.50", "1.299,50 €" or
+// "1299,50". A single currency symbol is allowed before or after the amount,
+// and a minus sign first.
+//
+// Both the point and the comma can be the decimal separator, the other one
+// being the thousands separator:
+//   - when both appear, the last one is the decimal separator;
+//   - when one appears several times, it separates thousands;
+//   - a single point is a decimal separator, as in "1.299";
+//   - a single comma followed by exactly three digits separates thousands,
+//     so "1,299" is 1299, while any other single comma is a decimal
+//     separator, as in "1299,5".
+//
+// Thousands must be grouped by three digits. Anything else is ErrMalformed.
+func Parse(s string) (decimal.Decimal, error) {
+	s = strings.TrimSpace(s)
+	s, negative := strings.CutPrefix(s, "-")
+	s = strings.TrimSpace(trimSymbol(s))
+
+	decimalSep, thousandsSep := separators(s)
+	integer, fraction, hasFraction := s, "", false
+	if decimalSep != 0 {
+		i := strings.LastIndexByte(s, decimalSep)
+		integer, fraction, hasFraction = s[:i], s[i+1:], true
+	}
+
+	if hasFraction && !isDigits(fraction) {
+		return decimal.Decimal{}, ErrMalformed
+	}
+	if thousandsSep != 0 {
+		groups := strings.Split(integer, string(thousandsSep))
+		if len(groups[0]) > 3 {
+			return decimal.Decimal{}, ErrMalformed
+		}
+		for i, g := range groups {
+			if !isDigits(g) || (i > 0 && len(g) != 3) {
+				return decimal.Decimal{}, ErrMalformed
+			}
+		}
+		integer = strings.Join(groups, "")
+	} else if !isDigits(integer) && !(integer == "" && hasFraction) {
+		// The integer part can only be left out before a fraction, as in ".5"
+		return decimal.Decimal{}, ErrMalformed
+	}
+
+	normalized := integer
+	if hasFraction {
+		normalized += "." + fraction
+	}
+	price, err := decimal.NewFromString(normalized)
+	if err != nil {
+		return decimal.Decimal{}, ErrMalformed
+	}
+	if negative {
+		price = price.Neg()
+	}
+	return price, nil
+}
+
+// trimSymbol removes a currency symbol from either end of s.
+func trimSymbol(s string) string {
+	for _, symbol := range symbols {
+		if rest, ok := strings.CutPrefix(s, symbol); ok {
+			return rest
+		}
+		if rest, ok := strings.CutSuffix(s, symbol); ok {
+			return rest
+		}
+	}
+	return s
+}
+
+// separators tells which of the point and the comma are the decimal and the
+// thousands separators of s, zero when there is none.
+func separators(s string) (decimalSep, thousandsSep byte) {
+	points, commas := strings.Count(s, "."), strings.Count(s, ",")
+	switch {
+	case points > 0 && commas > 0:
+		if strings.LastIndexByte(s, '.') > strings.LastIndexByte(s, ',') {
+			return '.', ','
+		}
+		return ',', '.'
+	case points > 1:
+		return 0, '.'
+	case commas > 1:
+		return 0, ','
+	case points == 1:
+		return '.', 0
+	case commas == 1:
+		if len(s)-strings.IndexByte(s, ',')-1 == 3 {
+			return 0, ','
+		}
+		return ',', 0
+	default:
+		return 0, 0
+	}
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package pricefmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"integer", "1299", "1299"},
+		{"decimal point", "1299.50", "1299.5"},
+		{"more than two decimals", "1.299", "1.299"},
+		{"fraction only", ".5", "0.5"},
+		{"zero", "0", "0"},
+		{"negative", "-5", "-5"},
+		{"surrounding spaces", " 12.5 ", "12.5"},
+
+		{"leading euro", "€1299", "1299"},
+		{"leading dollar", "$12.50", "12.5"},
+		{"leading pound", "£12.50", "12.5"},
+		{"leading yen", "¥1299", "1299"},
+		{"trailing euro", "1299€", "1299"},
+		{"trailing euro after a space", "1.299,50 €", "1299.5"},
+		{"symbol after the minus sign", "-€5", "-5"},
+
+		{"comma thousands with decimal point", "1,299.00", "1299"},
+		{"comma thousands with symbol", "€1,299.50", "1299.5"},
+		{"several comma thousands", "1,299,000", "1299000"},
+		{"several comma thousands with decimal point", "1,299,000.25", "1299000.25"},
+		{"point thousands with decimal comma", "1.299,50", "1299.5"},
+		{"several point thousands", "1.299.000", "1299000"},
+		{"several point thousands with decimal comma", "1.299.000,25", "1299000.25"},
+
+		{"decimal comma", "1299,50", "1299.5"},
+		{"decimal comma with one digit", "12,5", "12.5"},
+		{"decimal comma with four digits", "1,2999", "1.2999"},
+		{"single comma before three digits separates thousands", "1,299", "1299"},
+		{"single point before three digits is decimal", "1.299", "1.299"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"spaces", "  "},
+		{"symbol only", "€"},
+		{"minus only", "-"},
+		{"word", "cheap"},
+		{"unknown symbol", "₿12"},
+		{"currency code", "EUR 12"},
+		{"two symbols", "€12€"},
+		{"symbol in the middle", "12€50"},
+		{"two minus signs", "--5"},
+		{"minus sign after the amount", "5-"},
+		{"plus sign", "+5"},
+		{"exponent", "1e3"},
+		{"trailing decimal point", "12."},
+		{"trailing decimal comma", "12,"},
+		{"two decimal points around a comma", "1.299,50.3"},
+		{"short thousands group", "1,29.50"},
+		{"long thousands group", "1,2999.50"},
+		{"long leading group", "1299,000.50"},
+		{"several comma thousands with a short group", "1,299,00"},
+		{"empty leading group", ",299"},
+		{"point thousands with a short group", "1.29,50"},
+		{"space thousands", "1 299"},
+		{"letters in the fraction", "12.5a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+
+			assert.ErrorIs(t, err, ErrMalformed)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Package pricing rounds the prices displayed to clients. The mode is
+// configured once and handed to everything rounding prices, so that a
+// converted price, its minor units and its string never disagree.
+package pricing
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Mode is a way of rounding prices.
+type Mode int32
+
+const (
+	// HalfEven rounds halves to the even digit, the banker's rounding, so
+	// that rounding many prices is not biased upwards: 2.125 is 2.12.
+	HalfEven Mode = iota
+	// HalfUp rounds halves away from zero: 2.125 is 2.13.
+	HalfUp
+	// Down drops the digits past the rounding, towards zero: 2.129 is 2.12.
+	Down
+)
+
+var modeNames = map[Mode]string{HalfEven: "half-even", HalfUp: "half-up", Down: "down"}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int32(m))
+}
+
+// ParseMode reads a mode by its name: half-even, half-up or down. The empty
+// name stands for HalfEven.
+func ParseMode(name string) (Mode, error) {
+	if name == "" {
+		return HalfEven, nil
+	}
+	for m, n := range modeNames {
+		if n == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported rounding mode %q, expected half-even, half-up or down", name)
+}
+
+// Round rounds d to places decimal places with m.
+func (m Mode) Round(d decimal.Decimal, places int32) decimal.Decimal {
+	switch m {
+	case HalfUp:
+		return d.Round(places)
+	case Down:
+		return d.Truncate(places)
+	default:
+		return d.RoundBank(places)
+	}
+}
@@ -0,0 +1,63 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMode_Round(t *testing.T) {
+	tests := []struct {
+		amount                 string
+		halfEven, halfUp, down string
+	}{
+		{"2.125", "2.12", "2.13", "2.12"},
+		{"2.135", "2.14", "2.14", "2.13"},
+		{"2.129", "2.13", "2.13", "2.12"},
+		{"2.121", "2.12", "2.12", "2.12"},
+		{"-2.125", "-2.12", "-2.13", "-2.12"},
+		{"2.12", "2.12", "2.12", "2.12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			d := decimal.RequireFromString(tt.amount)
+
+			assert.Equal(t, tt.halfEven, HalfEven.Round(d, 2).String(), "half-even")
+			assert.Equal(t, tt.halfUp, HalfUp.Round(d, 2).String(), "half-up")
+			assert.Equal(t, tt.down, Down.Round(d, 2).String(), "down")
+		})
+	}
+
+	t.Run("to whole minor units", func(t *testing.T) {
+		d := decimal.RequireFromString("212.5")
+
+		assert.Equal(t, "212", HalfEven.Round(d, 0).String())
+		assert.Equal(t, "213", HalfUp.Round(d, 0).String())
+		assert.Equal(t, "212", Down.Round(d, 0).String())
+	})
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		name string
+		want Mode
+	}{
+		{"", HalfEven},
+		{"half-even", HalfEven},
+		{"half-up", HalfUp},
+		{"down", Down},
+	}
+	for _, tt := range tests {
+		got, err := ParseMode(tt.name)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+
+	for _, name := range []string{"bankers", "HALF-UP", "up"} {
+		_, err := ParseMode(name)
+		assert.EqualError(t, err, `unsupported rounding mode "`+name+`", expected half-even, half-up or down`)
+	}
+}
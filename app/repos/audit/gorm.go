@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type GormRepo struct {
+	db *gorm.DB
+}
+
+func NewGormRepo(db *gorm.DB) *GormRepo {
+	return &GormRepo{
+		db: db,
+	}
+}
+
+func (r *GormRepo) Record(ctx context.Context, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *GormRepo) List(ctx context.Context, filters Filters) ([]models.AuditEntry, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.AuditEntry{})
+	if filters.EntityType != "" {
+		db = db.Where("entity_type = ?", filters.EntityType)
+	}
+	if filters.EntityCode != "" {
+		db = db.Where("entity_code = ?", filters.EntityCode)
+	}
+	if !filters.Since.IsZero() {
+		db = db.Where("created_at >= ?", filters.Since)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	entries := []models.AuditEntry{}
+	err := db.Order("id").Offset(filters.Offset).Limit(filters.Limit).Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestGormRepo_Record(t *testing.T) {
+	t.Run("both versions", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "audit_log" ("actor","action","entity_type","entity_code","before","after","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+			WithArgs("api-key:2bb80d537b1da3e3", "update", "category", "shoes",
+				json.RawMessage(`{"name":"Shoes"}`), json.RawMessage(`{"name":"Footwear"}`), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectCommit()
+
+		entry := models.AuditEntry{
+			Actor:      "api-key:2bb80d537b1da3e3",
+			Action:     "update",
+			EntityType: "category",
+			EntityCode: "shoes",
+			Before:     json.RawMessage(`{"name":"Shoes"}`),
+			After:      json.RawMessage(`{"name":"Footwear"}`),
+		}
+		err := NewGormRepo(db).Record(context.Background(), &entry)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(4), entry.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no version before a creation", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		// The missing version is stored as NULL
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "audit_log" ("actor","action","entity_type","entity_code","before","after","created_at") VALUES ($1,$2,$3,$4,(NULL),$5,$6) RETURNING "id"`)).
+			WithArgs("api-key:2bb80d537b1da3e3", "create", "category", "shoes",
+				json.RawMessage(`{"name":"Shoes"}`), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectCommit()
+
+		err := NewGormRepo(db).Record(context.Background(), &models.AuditEntry{
+			Actor:      "api-key:2bb80d537b1da3e3",
+			Action:     "create",
+			EntityType: "category",
+			EntityCode: "shoes",
+			After:      json.RawMessage(`{"name":"Shoes"}`),
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_List(t *testing.T) {
+	t.Run("filtered page", func(t *testing.T) {
+		since := time.Date(2025, 6, 1, 8, 30, 0, 0, time.UTC)
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "audit_log" WHERE entity_type = $1 AND entity_code = $2 AND created_at >= $3`)).
+			WithArgs("product", "PROD001", since).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "audit_log" WHERE entity_type = $1 AND entity_code = $2 AND created_at >= $3 ORDER BY id LIMIT $4 OFFSET $5`)).
+			WithArgs("product", "PROD001", since, 1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "actor", "action", "entity_type", "entity_code", "before", "after"}).
+				AddRow(9, "admin:2bb80d537b1da3e3", "delete", "variant", "SKU001A", []byte(`{"sku":"SKU001A"}`), nil))
+
+		entries, total, err := NewGormRepo(db).List(context.Background(), Filters{
+			EntityType: "product", EntityCode: "PROD001", Since: since, Offset: 2, Limit: 1,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		require.Len(t, entries, 1)
+		assert.Equal(t, uint64(9), entries[0].ID)
+		assert.JSONEq(t, `{"sku":"SKU001A"}`, string(entries[0].Before))
+		assert.Nil(t, entries[0].After)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("none", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "audit_log"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "audit_log" ORDER BY id LIMIT $1`)).
+			WithArgs(50).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		entries, total, err := NewGormRepo(db).List(context.Background(), Filters{Limit: 50})
+
+		require.NoError(t, err)
+		assert.Zero(t, total)
+		assert.NotNil(t, entries)
+		assert.Empty(t, entries)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestPostgres_RecordAndList(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	ctx := context.Background()
+	repo := NewGormRepo(db)
+	start := time.Now().Add(-time.Minute)
+
+	for _, entry := range []models.AuditEntry{
+		{Actor: "api-key:a", Action: "create", EntityType: "category", EntityCode: "shoes", After: json.RawMessage(`{"name":"Shoes"}`)},
+		{Actor: "api-key:a", Action: "update", EntityType: "category", EntityCode: "shoes", Before: json.RawMessage(`{"name":"Shoes"}`), After: json.RawMessage(`{"name":"Footwear"}`)},
+		{Actor: "admin:b", Action: "update", EntityType: "product", EntityCode: "PROD001", Before: json.RawMessage(`{}`), After: json.RawMessage(`{}`)},
+	} {
+		require.NoError(t, repo.Record(ctx, &entry))
+		assert.NotZero(t, entry.ID)
+	}
+
+	entries, total, err := repo.List(ctx, Filters{EntityType: "category", EntityCode: "shoes", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "create", entries[0].Action)
+	assert.Nil(t, entries[0].Before)
+	assert.Equal(t, "update", entries[1].Action)
+	assert.JSONEq(t, `{"name":"Shoes"}`, string(entries[1].Before))
+	assert.JSONEq(t, `{"name":"Footwear"}`, string(entries[1].After))
+	assert.False(t, entries[1].CreatedAt.IsZero())
+
+	page, total, err := repo.List(ctx, Filters{Since: start, Offset: 2, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, page, 1)
+	assert.Equal(t, "PROD001", page[0].EntityCode)
+
+	later, total, err := repo.List(ctx, Filters{Since: time.Now().Add(time.Hour), Limit: 10})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, later)
+}
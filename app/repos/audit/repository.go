@@ -0,0 +1,29 @@
+// Package audit stores the audit log of the changes made through the write
+// endpoints.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// Filters narrows down the listed entries. Zero values filter nothing.
+type Filters struct {
+	EntityType string
+	EntityCode string
+	// Since keeps the entries recorded at or after it.
+	Since  time.Time
+	Offset int
+	Limit  int
+}
+
+// Repository describes the audit log storage operations.
+type Repository interface {
+	// Record appends an entry to the log.
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	// List returns a page of the entries matching the filters, oldest
+	// first, along with how many match.
+	List(ctx context.Context, filters Filters) ([]models.AuditEntry, int64, error)
+}
@@ -0,0 +1,246 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/outbox"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// availableProducts joins the categories to their available products. The
+// products of every count are those within their availability window, as
+// listed by the catalog.
+const availableProducts = `LEFT JOIN products ON products.category_id = categories.id AND ` + products.AvailableNow
+
+type GormRepo struct {
+	db       *gorm.DB
+	rounding pricing.Mode
+}
+
+func NewGormRepo(db *gorm.DB) *GormRepo {
+	return &GormRepo{
+		db: db,
+	}
+}
+
+// SetRounding changes the mode the average prices are rounded to cents with,
+// HalfEven until set.
+func (r *GormRepo) SetRounding(mode pricing.Mode) {
+	r.rounding = mode
+}
+
+// Get loads the translations along with the category.
+func (r *GormRepo) Get(ctx context.Context, code string) (models.Category, error) {
+	return firstByCode(r.db.WithContext(ctx).Preload("Translations"), code)
+}
+
+// ListAll returns the categories sorted by name, with the id breaking ties so
+// the order is stable across calls.
+func (r *GormRepo) ListAll(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.WithContext(ctx).
+		Preload("Translations").
+		Order("name, id").
+		Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// ListNonEmpty is ListAll without the categories lacking products, which
+// EXISTS tells without repeating the categories as a join would.
+func (r *GormRepo) ListNonEmpty(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.WithContext(ctx).
+		Preload("Translations").
+		Where("EXISTS (SELECT 1 FROM products WHERE products.category_id = categories.id AND " + products.AvailableNow + ")").
+		Order("name, id").
+		Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// ListAllWithCounts counts the products in the same query as the categories
+// are listed, the left join counting zero for the empty ones.
+func (r *GormRepo) ListAllWithCounts(ctx context.Context) ([]CategoryCount, error) {
+	var categories []CategoryCount
+	err := r.db.WithContext(ctx).
+		Model(&models.Category{}).
+		Select("categories.*, COUNT(products.id) AS product_count").
+		Joins(availableProducts).
+		Group("categories.id").
+		Order("categories.name, categories.id").
+		Preload("Translations").
+		Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// CountProductsInCategory counts in a single query, the left join telling
+// an empty category, counted zero, from an unknown one, without any row.
+func (r *GormRepo) CountProductsInCategory(ctx context.Context, code string) (int, error) {
+	var counts []int
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT COUNT(products.id) FROM categories `+availableProducts+`
+			WHERE categories.code = ? GROUP BY categories.id`, code).
+		Scan(&counts).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(counts) == 0 {
+		return 0, ErrCategoryNotFound
+	}
+	return counts[0], nil
+}
+
+// Create inserts the category together with its translations.
+func (r *GormRepo) Create(ctx context.Context, category models.Category) (models.Category, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&category).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrCategoryExists
+		}
+		if err != nil {
+			return err
+		}
+		return outbox.Record(tx, events.CategoryCreated, category.Code, categoryEvent{Code: category.Code})
+	})
+	if err != nil {
+		return models.Category{}, err
+	}
+	return category, nil
+}
+
+// Update writes both columns even when the markup is removed. The version
+// is checked by the UPDATE itself, so that two writers of the same version
+// cannot both succeed; when no row is updated, the current version tells an
+// outdated version from an unknown category. A change of the markup changes
+// the inherited variant prices of the products of the category, which are
+// marked as updated for their Last-Modified and the changes feed to tell.
+func (r *GormRepo) Update(ctx context.Context, category *models.Category) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Product{}).
+			Where("category_id IN (SELECT id FROM categories WHERE code = ? AND version = ? AND markup_percent IS DISTINCT FROM ?)",
+				category.Code, category.Version, category.MarkupPercent).
+			Update("updated_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		res := tx.Model(&models.Category{}).
+			Where("code = ? AND version = ?", category.Code, category.Version).
+			Select("name", "markup_percent", "version").
+			Updates(map[string]any{
+				"name":           category.Name,
+				"markup_percent": category.MarkupPercent,
+				"version":        gorm.Expr("version + 1"),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			var current []int
+			err := tx.Model(&models.Category{}).Where("code = ?", category.Code).Pluck("version", &current).Error
+			if err != nil {
+				return err
+			}
+			if len(current) == 0 {
+				return ErrCategoryNotFound
+			}
+			return &VersionMismatchError{Current: current[0]}
+		}
+		return outbox.Record(tx, events.CategoryUpdated, category.Code, categoryEvent{Code: category.Code})
+	})
+	if err != nil {
+		return err
+	}
+	category.Version++
+	return nil
+}
+
+// Upsert inserts the category, or renames the one with its code in the same
+// statement, so that concurrent upserts of a new code cannot both insert it.
+// An existing category keeps its markup and translations and has its
+// version incremented even when the name is unchanged, which tells an update
+// from a creation, always at version 1.
+func (r *GormRepo) Upsert(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		upserted := models.Category{Code: category.Code, Name: category.Name, MarkupPercent: category.MarkupPercent}
+		err := tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "code"}},
+				DoUpdates: clause.Set{
+					{Column: clause.Column{Name: "name"}, Value: gorm.Expr("excluded.name")},
+					{Column: clause.Column{Name: "version"}, Value: gorm.Expr("categories.version + 1")},
+				},
+			}, clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "markup_percent"}, {Name: "version"}}}).
+			Create(&upserted).Error
+		if err != nil {
+			return err
+		}
+
+		kind := events.CategoryUpdated
+		if upserted.Version == 1 {
+			kind = events.CategoryCreated
+			translations := category.Translations
+			for i := range translations {
+				translations[i].CategoryID = upserted.ID
+			}
+			if len(translations) > 0 {
+				if err := tx.Create(&translations).Error; err != nil {
+					return err
+				}
+			}
+			upserted.Translations = translations
+		}
+		if err := outbox.Record(tx, kind, upserted.Code, categoryEvent{Code: upserted.Code}); err != nil {
+			return err
+		}
+		*category = upserted
+		return nil
+	})
+}
+
+// categoryEvent is the payload of the events of a category.
+type categoryEvent struct {
+	Code string `json:"code"`
+}
+
+// PriceRange aggregates the prices as products.CategoryPriceStats, the
+// average being rounded with the mode of SetRounding.
+func (r *GormRepo) PriceRange(ctx context.Context, code string, includeVariants bool) (PriceRange, error) {
+	category, err := r.getByCode(ctx, code)
+	if err != nil {
+		return PriceRange{}, err
+	}
+	return products.CategoryPriceStats(r.db.WithContext(ctx), category.ID, includeVariants, r.rounding)
+}
+
+func (r *GormRepo) getByCode(ctx context.Context, code string) (models.Category, error) {
+	return firstByCode(r.db.WithContext(ctx), code)
+}
+
+func firstByCode(db *gorm.DB, code string) (models.Category, error) {
+	var category models.Category
+	err := db.Where("code = ?", code).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Category{}, ErrCategoryNotFound
+	}
+	if err != nil {
+		return models.Category{}, err
+	}
+	return category, nil
+}
@@ -0,0 +1,504 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func expectCategory(mock sqlmock.Sqlmock, code string, id int) {
+	rows := sqlmock.NewRows([]string{"id", "code", "name"})
+	if id > 0 {
+		rows.AddRow(id, code, code)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "categories" WHERE code = $1 ORDER BY "categories"."id" LIMIT $2`)).
+		WithArgs(code, 1).
+		WillReturnRows(rows)
+}
+
+func TestGormRepo_PriceRange(t *testing.T) {
+	aggregate := regexp.QuoteMeta(`SELECT MIN(price) AS min, MAX(price) AS max, AVG(price) AS avg, COUNT(*) AS count FROM (SELECT price FROM products WHERE category_id = $1 AND ` + products.AvailableNow)
+	variants := regexp.QuoteMeta(`UNION ALL SELECT `+products.VariantPrice+` FROM product_variants`) +
+		`\s+JOIN products ON products.id = product_variants.product_id\s+` +
+		`JOIN categories ON categories.id = products.category_id\s+` +
+		regexp.QuoteMeta(`WHERE products.category_id = $2 AND `+products.AvailableNow+`) AS prices`)
+
+	t.Run("product prices only", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectCategory(mock, "shoes", 2)
+		mock.ExpectQuery(aggregate + regexp.QuoteMeta(`) AS prices`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max", "avg", "count"}).AddRow("5.50", "12.49", "8.985", 2))
+
+		res, err := NewGormRepo(db).PriceRange(context.Background(), "shoes", false)
+
+		require.NoError(t, err)
+		assert.Equal(t, "5.5", res.Min.Decimal.String())
+		assert.Equal(t, "12.49", res.Max.Decimal.String())
+		// ROUND would take 8.985 up, it goes to the even cent
+		assert.Equal(t, "8.98", res.Avg.Decimal.String())
+		assert.Equal(t, int64(2), res.Count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("average rounded with the configured mode", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectCategory(mock, "shoes", 2)
+		mock.ExpectQuery(aggregate + regexp.QuoteMeta(`) AS prices`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max", "avg", "count"}).AddRow("5.50", "12.49", "8.985", 2))
+
+		repo := NewGormRepo(db)
+		repo.SetRounding(pricing.HalfUp)
+		res, err := repo.PriceRange(context.Background(), "shoes", false)
+
+		require.NoError(t, err)
+		assert.Equal(t, "8.99", res.Avg.Decimal.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("variant prices include the inherited ones", func(t *testing.T) {
+		// PROD001 10.99 with variants 11.99, 10.99 and 10.99; PROD004 15.00
+		// with variants 15.50 and 15.00
+		db, mock := testsupport.MockDB(t)
+		expectCategory(mock, "clothing", 1)
+		mock.ExpectQuery(aggregate+` `+variants).
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max", "avg", "count"}).AddRow("10.99", "15.50", "12.92", 7))
+
+		res, err := NewGormRepo(db).PriceRange(context.Background(), "clothing", true)
+
+		require.NoError(t, err)
+		assert.Equal(t, "10.99", res.Min.Decimal.String())
+		assert.Equal(t, "15.5", res.Max.Decimal.String())
+		assert.Equal(t, int64(7), res.Count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectCategory(mock, "bags", 4)
+		mock.ExpectQuery(aggregate).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max", "avg", "count"}).AddRow(nil, nil, nil, 0))
+
+		res, err := NewGormRepo(db).PriceRange(context.Background(), "bags", false)
+
+		require.NoError(t, err)
+		assert.False(t, res.Min.Valid)
+		assert.False(t, res.Avg.Valid)
+		assert.Zero(t, res.Count)
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectCategory(mock, "unknown", 0)
+
+		_, err := NewGormRepo(db).PriceRange(context.Background(), "unknown", false)
+
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+		assert.Equal(t, errs.NotFound, errs.KindOf(err))
+	})
+}
+
+func TestGormRepo_ListAll(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "categories" ORDER BY name, id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).
+			AddRow(1, "clothing", "Clothing").
+			AddRow(2, "shoes", "Shoes"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "category_translations" WHERE "category_translations"."category_id" IN ($1,$2)`)).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}).
+			AddRow(2, "de", "Schuhe"))
+
+	res, err := NewGormRepo(db).ListAll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []models.Category{
+		{ID: 1, Code: "clothing", Name: "Clothing", Translations: []models.CategoryTranslation{}},
+		{ID: 2, Code: "shoes", Name: "Shoes", Translations: []models.CategoryTranslation{
+			{CategoryID: 2, Locale: "de", Name: "Schuhe"},
+		}},
+	}, res)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_ListNonEmpty(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "categories" WHERE EXISTS (SELECT 1 FROM products WHERE products.category_id = categories.id AND ` + products.AvailableNow + `) ORDER BY name, id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).
+			AddRow(2, "shoes", "Shoes"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "category_translations" WHERE "category_translations"."category_id" = $1`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}).
+			AddRow(2, "de", "Schuhe"))
+
+	res, err := NewGormRepo(db).ListNonEmpty(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []models.Category{
+		{ID: 2, Code: "shoes", Name: "Shoes", Translations: []models.CategoryTranslation{
+			{CategoryID: 2, Locale: "de", Name: "Schuhe"},
+		}},
+	}, res)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_ListAllSortedByName(t *testing.T) {
+	// Categories inserted out of name order come back sorted by the
+	// database, ties broken by id
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "categories" ORDER BY name, id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).
+			AddRow(3, "accessories", "Accessories").
+			AddRow(1, "clothing", "Clothing").
+			AddRow(4, "outlet-clothing", "Clothing"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "category_translations" WHERE "category_translations"."category_id" IN ($1,$2,$3)`)).
+		WithArgs(3, 1, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}))
+
+	res, err := NewGormRepo(db).ListAll(context.Background())
+
+	require.NoError(t, err)
+	codes := make([]string, len(res))
+	for i, c := range res {
+		codes[i] = c.Code
+	}
+	assert.Equal(t, []string{"accessories", "clothing", "outlet-clothing"}, codes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_Create(t *testing.T) {
+	insert := regexp.QuoteMeta(`INSERT INTO "categories" ("code","name","markup_percent","version") VALUES ($1,$2,$3,$4) RETURNING "id"`)
+
+	t.Run("inserts the category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(insert).
+			WithArgs("bags", "Bags", nil, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		testsupport.ExpectEvent(mock, events.CategoryCreated, "bags")
+		mock.ExpectCommit()
+
+		bags, err := NewGormRepo(db).Create(context.Background(), models.Category{Code: "bags", Name: "Bags"})
+
+		require.NoError(t, err)
+		assert.Equal(t, models.Category{ID: 4, Code: "bags", Name: "Bags", Version: 1}, bags)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("inserts the translations with the category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(insert).
+			WithArgs("bags", "Bags", nil, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "category_translations" ("category_id","locale","name") VALUES ($1,$2,$3) ON CONFLICT ("category_id","locale") DO UPDATE SET "category_id"="excluded"."category_id"`)).
+			WithArgs(4, "de", "Taschen").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		testsupport.ExpectEvent(mock, events.CategoryCreated, "bags")
+		mock.ExpectCommit()
+
+		_, err := NewGormRepo(db).Create(context.Background(), models.Category{
+			Code:         "bags",
+			Name:         "Bags",
+			Translations: []models.CategoryTranslation{{Locale: "de", Name: "Taschen"}},
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate code", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(insert).
+			WithArgs("shoes", "Shoes", nil, 1).
+			WillReturnError(&pgconn.PgError{Code: "23505"})
+		mock.ExpectRollback()
+
+		_, err := NewGormRepo(db).Create(context.Background(), models.Category{Code: "shoes", Name: "Shoes"})
+
+		assert.ErrorIs(t, err, ErrCategoryExists)
+		assert.Equal(t, errs.Conflict, errs.KindOf(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed commit fails the creation", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(insert).
+			WithArgs("bags", "Bags", nil, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		testsupport.ExpectEvent(mock, events.CategoryCreated, "bags")
+		mock.ExpectCommit().WillReturnError(errors.New("connection reset"))
+
+		_, err := NewGormRepo(db).Create(context.Background(), models.Category{Code: "bags", Name: "Bags"})
+
+		assert.EqualError(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_Get(t *testing.T) {
+	get := regexp.QuoteMeta(`SELECT * FROM "categories" WHERE code = $1 ORDER BY "categories"."id" LIMIT $2`)
+
+	t.Run("with its translations", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(get).
+			WithArgs("shoes", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name", "version"}).AddRow(2, "shoes", "Shoes", 3))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "category_translations" WHERE "category_translations"."category_id" = $1`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}).AddRow(2, "de", "Schuhe"))
+
+		shoes, err := NewGormRepo(db).Get(context.Background(), "shoes")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.Category{
+			ID: 2, Code: "shoes", Name: "Shoes", Version: 3,
+			Translations: []models.CategoryTranslation{{CategoryID: 2, Locale: "de", Name: "Schuhe"}},
+		}, shoes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(get).
+			WithArgs("bags", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name", "version"}))
+
+		_, err := NewGormRepo(db).Get(context.Background(), "bags")
+
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_Update(t *testing.T) {
+	update := regexp.QuoteMeta(`UPDATE "categories" SET "markup_percent"=$1,"name"=$2,"version"=version + 1 WHERE code = $3 AND version = $4`)
+	currentVersion := regexp.QuoteMeta(`SELECT "version" FROM "categories" WHERE code = $1`)
+	touch := regexp.QuoteMeta(`UPDATE "products" SET "updated_at"=$1 WHERE category_id IN ` +
+		`(SELECT id FROM categories WHERE code = $2 AND version = $3 AND markup_percent IS DISTINCT FROM $4)`)
+
+	t.Run("updates the name and the markup", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(touch).
+			WithArgs(sqlmock.AnyArg(), "accessories", 2, "15").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(update).
+			WithArgs("15", "Accessories", "accessories", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		testsupport.ExpectEvent(mock, events.CategoryUpdated, "accessories")
+		mock.ExpectCommit()
+
+		accessories := &models.Category{
+			Code:          "accessories",
+			Name:          "Accessories",
+			MarkupPercent: decimal.NullDecimal{Decimal: decimal.NewFromInt(15), Valid: true},
+			Version:       2,
+		}
+		err := NewGormRepo(db).Update(context.Background(), accessories)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, accessories.Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("removes the markup", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(touch).
+			WithArgs(sqlmock.AnyArg(), "accessories", 1, nil).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(update).
+			WithArgs(nil, "Accessories", "accessories", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		testsupport.ExpectEvent(mock, events.CategoryUpdated, "accessories")
+		mock.ExpectCommit()
+
+		err := NewGormRepo(db).Update(context.Background(), &models.Category{Code: "accessories", Name: "Accessories", Version: 1})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("outdated version", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(touch).
+			WithArgs(sqlmock.AnyArg(), "accessories", 1, nil).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(update).
+			WithArgs(nil, "Accessories", "accessories", 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(currentVersion).
+			WithArgs("accessories").
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+		mock.ExpectRollback()
+
+		accessories := &models.Category{Code: "accessories", Name: "Accessories", Version: 1}
+		err := NewGormRepo(db).Update(context.Background(), accessories)
+
+		var mismatch *VersionMismatchError
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, 2, mismatch.Current)
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+		assert.Equal(t, errs.Precondition, errs.KindOf(err))
+		assert.Equal(t, 1, accessories.Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(touch).
+			WithArgs(sqlmock.AnyArg(), "bags", 1, nil).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(update).
+			WithArgs(nil, "Bags", "bags", 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(currentVersion).
+			WithArgs("bags").
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectRollback()
+
+		err := NewGormRepo(db).Update(context.Background(), &models.Category{Code: "bags", Name: "Bags", Version: 1})
+
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_CountProductsInCategory(t *testing.T) {
+	count := regexp.QuoteMeta(`SELECT COUNT(products.id) FROM categories`) +
+		`\s+` + regexp.QuoteMeta(`LEFT JOIN products ON products.category_id = categories.id AND `+products.AvailableNow) +
+		`\s+` + regexp.QuoteMeta(`WHERE categories.code = $1 GROUP BY categories.id`)
+
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+		want int
+		err  error
+	}{
+		{"populated category", sqlmock.NewRows([]string{"count"}).AddRow(3), 3, nil},
+		{"empty category", sqlmock.NewRows([]string{"count"}).AddRow(0), 0, nil},
+		{"unknown category", sqlmock.NewRows([]string{"count"}), 0, ErrCategoryNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := testsupport.MockDB(t)
+			mock.ExpectQuery(count).WithArgs("clothing").WillReturnRows(tt.rows)
+
+			got, err := NewGormRepo(db).CountProductsInCategory(context.Background(), "clothing")
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGormRepo_ListAllWithCounts(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT categories.*, COUNT(products.id) AS product_count FROM "categories" LEFT JOIN products ON products.category_id = categories.id AND ` + products.AvailableNow + ` GROUP BY "categories"."id" ORDER BY categories.name, categories.id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name", "product_count"}).
+			AddRow(3, "bags", "Bags", 0).
+			AddRow(1, "clothing", "Clothing", 3))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "category_translations" WHERE "category_translations"."category_id" IN ($1,$2)`)).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}).
+			AddRow(1, "de", "Kleidung"))
+
+	res, err := NewGormRepo(db).ListAllWithCounts(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []CategoryCount{
+		{Category: models.Category{ID: 3, Code: "bags", Name: "Bags", Translations: []models.CategoryTranslation{}}, ProductCount: 0},
+		{Category: models.Category{ID: 1, Code: "clothing", Name: "Clothing", Translations: []models.CategoryTranslation{
+			{CategoryID: 1, Locale: "de", Name: "Kleidung"},
+		}}, ProductCount: 3},
+	}, res)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_Upsert(t *testing.T) {
+	upsert := regexp.QuoteMeta(`INSERT INTO "categories" ("code","name","markup_percent","version") VALUES ($1,$2,$3,$4) ON CONFLICT ("code") DO UPDATE SET "name"=excluded.name,"version"=categories.version + 1 RETURNING "id","markup_percent","version"`)
+
+	t.Run("creates a new code", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(upsert).
+			WithArgs("bags", "Bags", nil, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "markup_percent", "version"}).AddRow(4, nil, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "category_translations" ("category_id","locale","name") VALUES ($1,$2,$3)`)).
+			WithArgs(4, "de", "Taschen").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		testsupport.ExpectEvent(mock, events.CategoryCreated, "bags")
+		mock.ExpectCommit()
+
+		bags := &models.Category{Code: "bags", Name: "Bags", Translations: []models.CategoryTranslation{{Locale: "de", Name: "Taschen"}}}
+		err := NewGormRepo(db).Upsert(context.Background(), bags)
+
+		require.NoError(t, err)
+		assert.Equal(t, &models.Category{
+			ID: 4, Code: "bags", Name: "Bags", Version: 1,
+			Translations: []models.CategoryTranslation{{CategoryID: 4, Locale: "de", Name: "Taschen"}},
+		}, bags)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("renames an existing code", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(upsert).
+			WithArgs("shoes", "Footwear", nil, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "markup_percent", "version"}).AddRow(2, "10", 3))
+		testsupport.ExpectEvent(mock, events.CategoryUpdated, "shoes")
+		mock.ExpectCommit()
+
+		// The translations of an existing category are left alone
+		shoes := &models.Category{Code: "shoes", Name: "Footwear", Translations: []models.CategoryTranslation{{Locale: "de", Name: "Schuhwerk"}}}
+		err := NewGormRepo(db).Upsert(context.Background(), shoes)
+
+		require.NoError(t, err)
+		assert.Equal(t, &models.Category{
+			ID: 2, Code: "shoes", Name: "Footwear", Version: 3,
+			MarkupPercent: decimal.NullDecimal{Decimal: decimal.NewFromInt(10), Valid: true},
+		}, shoes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed upsert records no event", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(upsert).
+			WithArgs("bags", "Bags", nil, 1).
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		bags := &models.Category{Code: "bags", Name: "Bags"}
+		err := NewGormRepo(db).Upsert(context.Background(), bags)
+
+		assert.EqualError(t, err, "connection reset")
+		assert.Equal(t, &models.Category{Code: "bags", Name: "Bags"}, bags)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
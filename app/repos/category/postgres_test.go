@@ -0,0 +1,271 @@
+package category
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestPostgres_ListAll(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+
+	categories, err := repo.ListAll(context.Background())
+
+	require.NoError(t, err)
+	names := make([]string, len(categories))
+	for i, c := range categories {
+		names[i] = c.LocalizedName("de")
+	}
+	assert.Equal(t, []string{"Accessoires", "Kleidung", "Schuhe"}, names)
+}
+
+func TestPostgres_ListAllWithCounts(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+	_, err := repo.Create(ctx, models.Category{Code: "bags", Name: "Bags"})
+	require.NoError(t, err)
+
+	categories, err := repo.ListAllWithCounts(ctx)
+
+	require.NoError(t, err)
+	counts := make(map[string]int64, len(categories))
+	for _, c := range categories {
+		counts[c.Code] = c.ProductCount
+	}
+	assert.Equal(t, map[string]int64{"accessories": 3, "bags": 0, "clothing": 3, "shoes": 2}, counts)
+}
+
+func TestPostgres_ListNonEmpty(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+	_, err := repo.Create(ctx, models.Category{Code: "bags", Name: "Bags"})
+	require.NoError(t, err)
+
+	categories, err := repo.ListNonEmpty(ctx)
+
+	require.NoError(t, err)
+	codes := make([]string, len(categories))
+	for i, c := range categories {
+		codes[i] = c.Code
+	}
+	assert.Equal(t, []string{"accessories", "clothing", "shoes"}, codes)
+	assert.Equal(t, "Schuhe", categories[2].LocalizedName("de"))
+}
+
+func TestPostgres_CountProductsInCategory(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+	_, err := repo.Create(ctx, models.Category{Code: "bags", Name: "Bags"})
+	require.NoError(t, err)
+
+	count, err := repo.CountProductsInCategory(ctx, "clothing")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = repo.CountProductsInCategory(ctx, "bags")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	_, err = repo.CountProductsInCategory(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrCategoryNotFound)
+}
+
+func TestPostgres_Create(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+
+	bags, err := repo.Create(ctx, models.Category{Code: "bags", Name: "Bags"})
+	require.NoError(t, err)
+	assert.NotZero(t, bags.ID)
+
+	_, err = repo.Create(ctx, models.Category{Code: "bags", Name: "Other bags"})
+	assert.ErrorIs(t, err, ErrCategoryExists)
+}
+
+func TestPostgres_Upsert(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+
+	bags := &models.Category{Code: "bags", Name: "Bags", Translations: []models.CategoryTranslation{{Locale: "de", Name: "Taschen"}}}
+	require.NoError(t, repo.Upsert(ctx, bags))
+	assert.NotZero(t, bags.ID)
+	assert.Equal(t, 1, bags.Version)
+
+	shoes := &models.Category{Code: "shoes", Name: "Footwear"}
+	require.NoError(t, repo.Upsert(ctx, shoes))
+	assert.Equal(t, 2, shoes.Version)
+
+	stored, err := repo.Get(ctx, "shoes")
+	require.NoError(t, err)
+	assert.Equal(t, "Footwear", stored.Name)
+	stored, err = repo.Get(ctx, "bags")
+	require.NoError(t, err)
+	assert.Equal(t, "Taschen", stored.LocalizedName("de"))
+}
+
+func TestPostgres_PriceRange(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+
+	res, err := repo.PriceRange(ctx, "clothing", false)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), res.Count)
+	assert.Equal(t, "10.99", res.Min.Decimal.StringFixed(2))
+	assert.Equal(t, "18.20", res.Max.Decimal.StringFixed(2))
+	assert.Equal(t, "14.73", res.Avg.Decimal.StringFixed(2))
+
+	_, err = repo.PriceRange(ctx, "bags", false)
+	assert.ErrorIs(t, err, ErrCategoryNotFound)
+}
+
+// The inherited variant prices are marked up by the category, as the catalog
+// shows them.
+func TestPostgres_PriceRange_Variants(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`UPDATE categories SET markup_percent = 10 WHERE code = 'clothing'`).Error)
+
+	res, err := repo.PriceRange(ctx, "clothing", true)
+
+	require.NoError(t, err)
+	// The 3 products and their 12 variants, four of those of PROD007
+	// inheriting its 18.20 marked up to 20.02
+	assert.Equal(t, int64(15), res.Count)
+	assert.Equal(t, "10.99", res.Min.Decimal.StringFixed(2))
+	assert.Equal(t, "20.02", res.Max.Decimal.StringFixed(2))
+}
+
+// The counts and prices of the categories leave out the products outside of
+// their availability window, as the catalog listing does.
+func TestPostgres_Availability(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	catalog := products.NewGormRepo(db)
+	ctx := context.Background()
+
+	// PROD001 of the clothing category opens tomorrow, and so does PROD009,
+	// the only product of the bags category
+	tomorrow := time.Now().Add(24 * time.Hour)
+	require.NoError(t, db.Exec(`UPDATE products SET available_from = ? WHERE code = 'PROD001'`, tomorrow).Error)
+	_, err := repo.Create(ctx, models.Category{Code: "bags", Name: "Bags"})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`INSERT INTO products (code, price, category_id, available_from)
+		VALUES ('PROD009', 30, (SELECT id FROM categories WHERE code = 'bags'), ?)`, tomorrow).Error)
+
+	listed := map[string]int64{}
+	for _, code := range []string{"accessories", "bags", "clothing", "shoes"} {
+		_, total, err := catalog.List(ctx, products.SearchFilters{Category: code, Limit: 1})
+		require.NoError(t, err)
+		listed[code] = total
+	}
+	assert.Equal(t, map[string]int64{"accessories": 3, "bags": 0, "clothing": 2, "shoes": 2}, listed)
+
+	withCounts, err := repo.ListAllWithCounts(ctx)
+	require.NoError(t, err)
+	for _, c := range withCounts {
+		assert.Equal(t, listed[c.Code], c.ProductCount, c.Code)
+	}
+
+	for code, total := range listed {
+		count, err := repo.CountProductsInCategory(ctx, code)
+		require.NoError(t, err)
+		assert.Equal(t, total, int64(count), code)
+
+		prices, err := repo.PriceRange(ctx, code, false)
+		require.NoError(t, err)
+		assert.Equal(t, total, prices.Count, code)
+
+		stats, err := catalog.PriceStats(ctx, code)
+		require.NoError(t, err)
+		assert.Equal(t, total, stats.Count, code)
+	}
+
+	nonEmpty, err := repo.ListNonEmpty(ctx)
+	require.NoError(t, err)
+	codes := make([]string, len(nonEmpty))
+	for i, c := range nonEmpty {
+		codes[i] = c.Code
+	}
+	assert.Equal(t, []string{"accessories", "clothing", "shoes"}, codes)
+
+	clothing, err := repo.PriceRange(ctx, "clothing", true)
+	require.NoError(t, err)
+	assert.Equal(t, "15.00", clothing.Min.Decimal.StringFixed(2), "PROD001 and its variants are left out")
+}
+
+func TestPostgres_Update(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+	markups := func() map[string]string {
+		categories, err := repo.ListAll(ctx)
+		require.NoError(t, err)
+		res := make(map[string]string, len(categories))
+		for _, c := range categories {
+			res[c.Code] = c.MarkupPercent.Decimal.String()
+			if !c.MarkupPercent.Valid {
+				res[c.Code] = "none"
+			}
+		}
+		return res
+	}
+
+	markup := decimal.NullDecimal{Decimal: decimal.RequireFromString("12.5"), Valid: true}
+	require.NoError(t, repo.Update(ctx, &models.Category{Code: "accessories", Name: "Accessories", MarkupPercent: markup, Version: 1}))
+	assert.Equal(t, map[string]string{"accessories": "12.5", "clothing": "none", "shoes": "none"}, markups())
+
+	require.NoError(t, repo.Update(ctx, &models.Category{Code: "accessories", Name: "Accessories", Version: 2}))
+	assert.Equal(t, map[string]string{"accessories": "none", "clothing": "none", "shoes": "none"}, markups())
+
+	err := repo.Update(ctx, &models.Category{Code: "bags", Name: "Bags", Version: 1})
+	assert.ErrorIs(t, err, ErrCategoryNotFound)
+}
+
+// Two admins read the same version of a category, the update of the second
+// must not overwrite the first one.
+func TestPostgres_Update_LostUpdate(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+
+	first, err := repo.Get(ctx, "shoes")
+	require.NoError(t, err)
+	second, err := repo.Get(ctx, "shoes")
+	require.NoError(t, err)
+	require.Equal(t, 1, first.Version)
+
+	first.Name = "Footwear"
+	require.NoError(t, repo.Update(ctx, &first))
+	assert.Equal(t, 2, first.Version)
+
+	second.Name = "Sneakers"
+	err = repo.Update(ctx, &second)
+	var mismatch *VersionMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, 2, mismatch.Current)
+
+	shoes, err := repo.Get(ctx, "shoes")
+	require.NoError(t, err)
+	assert.Equal(t, "Footwear", shoes.Name)
+	assert.Equal(t, 2, shoes.Version)
+	assert.Equal(t, "Schuhe", shoes.LocalizedName("de"))
+}
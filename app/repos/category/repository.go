@@ -0,0 +1,81 @@
+package category
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+var (
+	ErrCategoryNotFound = errs.New(errs.NotFound, "category not found")
+	ErrCategoryExists   = errs.New(errs.Conflict, "category code already exists")
+	ErrVersionMismatch  = errs.New(errs.Precondition, "category was modified meanwhile")
+)
+
+// VersionMismatchError refuses an update made from an outdated version of a
+// category, telling the current one. It matches ErrVersionMismatch.
+type VersionMismatchError struct {
+	Current int
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("category was modified meanwhile, its current version is %d", e.Current)
+}
+
+func (e *VersionMismatchError) Unwrap() error {
+	return ErrVersionMismatch
+}
+
+// PriceRange aggregates the prices of a category, as the catalog does.
+type PriceRange = products.PriceStats
+
+// CategoryCount is a category along with its number of products.
+type CategoryCount struct {
+	models.Category
+	ProductCount int64
+}
+
+// CategoryReader describes the category storage reads used by the handlers.
+type CategoryReader interface {
+	// Get returns the category with its translations.
+	Get(ctx context.Context, code string) (models.Category, error)
+	ListAll(ctx context.Context) ([]models.Category, error)
+	// ListNonEmpty is ListAll restricted to the categories with at least one
+	// product.
+	ListNonEmpty(ctx context.Context) ([]models.Category, error)
+	// ListAllWithCounts is ListAll along with the number of products of each
+	// category, zero for the empty ones.
+	ListAllWithCounts(ctx context.Context) ([]CategoryCount, error)
+	// CountProductsInCategory counts the products of the category, zero for
+	// an empty one.
+	CountProductsInCategory(ctx context.Context, code string) (int, error)
+	// PriceRange aggregates the product prices of the category, adding the
+	// variant prices, inherited ones included, when includeVariants is set.
+	PriceRange(ctx context.Context, code string, includeVariants bool) (PriceRange, error)
+}
+
+// CategoryWriter describes the category storage writes used by the handlers.
+type CategoryWriter interface {
+	// Create inserts the category and returns it as persisted, with its
+	// generated ID.
+	Create(ctx context.Context, category models.Category) (models.Category, error)
+	// Update replaces the name and the markup of the category with the code
+	// of category, provided it still has the version of category, which is
+	// then incremented. An outdated version fails with a
+	// *VersionMismatchError.
+	Update(ctx context.Context, category *models.Category) error
+	// Upsert creates the category, with its translations, when its code is
+	// new and otherwise only replaces the name of the stored one. category
+	// then holds the category as persisted, whose version is 1 only when it
+	// was created.
+	Upsert(ctx context.Context, category *models.Category) error
+}
+
+// Repository is the whole category storage, as implemented by GormRepo.
+type Repository interface {
+	CategoryReader
+	CategoryWriter
+}
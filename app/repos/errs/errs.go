@@ -0,0 +1,83 @@
+// Package errs classifies repository errors, so handlers can pick a response
+// without knowing about the storage underneath.
+package errs
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Kind is the class of a repository error.
+type Kind int
+
+const (
+	// Internal is the kind of every error that is not classified otherwise.
+	Internal Kind = iota
+	NotFound
+	Conflict
+	Invalid
+	// Precondition is the kind of the writes refused because the data
+	// changed since the client read it.
+	Precondition
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NotFound:
+		return "not found"
+	case Conflict:
+		return "conflict"
+	case Invalid:
+		return "invalid"
+	case Precondition:
+		return "precondition failed"
+	default:
+		return "internal"
+	}
+}
+
+// Error is an error of a given kind.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error of the kind, for declaring sentinel errors.
+func New(kind Kind, message string) error {
+	return &Error{Kind: kind, Err: errors.New(message)}
+}
+
+// WithKind classifies err as kind, overriding the kind it already has.
+// errors.Is still matches err itself.
+func WithKind(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of the outermost classified error in the chain of
+// err. gorm's missing record and duplicated key errors that were not mapped
+// to a sentinel are NotFound and Conflict respectively.
+func KindOf(err error) Kind {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e.Kind
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return Conflict
+	default:
+		return Internal
+	}
+}
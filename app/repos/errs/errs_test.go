@@ -0,0 +1,43 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestKindOf(t *testing.T) {
+	notFound := New(NotFound, "thing not found")
+
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"sentinel", notFound, NotFound},
+		{"wrapped sentinel", fmt.Errorf("loading: %w", notFound), NotFound},
+		{"reclassified sentinel", WithKind(Invalid, notFound), Invalid},
+		{"record not found", gorm.ErrRecordNotFound, NotFound},
+		{"duplicated key", fmt.Errorf("insert: %w", gorm.ErrDuplicatedKey), Conflict},
+		{"unclassified", errors.New("connection refused"), Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, KindOf(tt.err))
+		})
+	}
+}
+
+func TestWithKind(t *testing.T) {
+	notFound := New(NotFound, "thing not found")
+
+	err := WithKind(Invalid, notFound)
+
+	assert.ErrorIs(t, err, notFound)
+	assert.EqualError(t, err, "thing not found")
+	assert.NoError(t, WithKind(Invalid, nil))
+}
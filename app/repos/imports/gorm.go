@@ -0,0 +1,74 @@
+package imports
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type GormRepo struct {
+	db *gorm.DB
+}
+
+func NewGormRepo(db *gorm.DB) *GormRepo {
+	return &GormRepo{
+		db: db,
+	}
+}
+
+func (r *GormRepo) Create(ctx context.Context, job *models.ImportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *GormRepo) Get(ctx context.Context, id uint) (models.ImportJob, error) {
+	var job models.ImportJob
+	err := r.db.WithContext(ctx).Omit("data").First(&job, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return job, ErrJobNotFound
+	}
+	return job, err
+}
+
+// Claim updates the status only when the job is still queued, returning the
+// row in the same statement.
+func (r *GormRepo) Claim(ctx context.Context, id uint) (models.ImportJob, error) {
+	var job models.ImportJob
+	res := r.db.WithContext(ctx).
+		Model(&job).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ?", id, models.ImportQueued).
+		Update("status", models.ImportRunning)
+	if res.Error != nil {
+		return job, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return job, ErrJobNotQueued
+	}
+	return job, nil
+}
+
+func (r *GormRepo) Update(ctx context.Context, job *models.ImportJob) error {
+	if job.Finished() {
+		job.Data = nil
+	}
+	return r.db.WithContext(ctx).
+		Model(job).
+		Select("status", "data", "total", "processed", "created", "failed", "errors", "error").
+		Updates(job).Error
+}
+
+func (r *GormRepo) FailInterrupted(ctx context.Context) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.ImportJob{}).
+		Where("status IN ?", []string{models.ImportQueued, models.ImportRunning}).
+		Updates(map[string]any{
+			"status": models.ImportFailed,
+			"data":   nil,
+			"error":  InterruptedError,
+		})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,120 @@
+package imports
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestGormRepo_Get(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT "import_jobs"."id","import_jobs"."status","import_jobs"."total","import_jobs"."processed","import_jobs"."created","import_jobs"."failed","import_jobs"."errors","import_jobs"."error","import_jobs"."created_at","import_jobs"."updated_at" FROM "import_jobs" WHERE "import_jobs"."id" = $1 ORDER BY "import_jobs"."id" LIMIT $2`)
+
+	t.Run("job without its upload", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(query).
+			WithArgs(7, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "total", "processed", "failed", "errors"}).
+				AddRow(7, "done", 2, 2, 1, `[{"row":2,"code":"PROD002","error":"price must not be negative"}]`))
+
+		job, err := NewGormRepo(db).Get(context.Background(), 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.ImportDone, job.Status)
+		assert.Equal(t, []models.ImportError{{Row: 2, Code: "PROD002", Error: "price must not be negative"}}, job.Errors)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(query).WithArgs(8, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := NewGormRepo(db).Get(context.Background(), 8)
+
+		assert.ErrorIs(t, err, ErrJobNotFound)
+		assert.Equal(t, errs.NotFound, errs.KindOf(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_Claim(t *testing.T) {
+	update := regexp.QuoteMeta(`UPDATE "import_jobs" SET "status"=$1,"updated_at"=$2 WHERE id = $3 AND status = $4 RETURNING *`)
+
+	t.Run("queued job", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(update).
+			WithArgs(models.ImportRunning, sqlmock.AnyArg(), 7, models.ImportQueued).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "data"}).AddRow(7, "running", []byte("code,price\n")))
+		mock.ExpectCommit()
+
+		job, err := NewGormRepo(db).Claim(context.Background(), 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint(7), job.ID)
+		assert.Equal(t, models.ImportRunning, job.Status)
+		assert.Equal(t, []byte("code,price\n"), job.Data)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("job already claimed", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(update).
+			WithArgs(models.ImportRunning, sqlmock.AnyArg(), 7, models.ImportQueued).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		_, err := NewGormRepo(db).Claim(context.Background(), 7)
+
+		assert.ErrorIs(t, err, ErrJobNotQueued)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_Update(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "import_jobs" SET "status"=$1,"data"=$2,"total"=$3,"processed"=$4,"created"=$5,"failed"=$6,"errors"=$7,"error"=$8,"updated_at"=$9 WHERE "id" = $10`)).
+		WithArgs(models.ImportDone, []byte(nil), 2, 2, 1, 1, `[{"row":2,"error":"code is required"}]`, "", sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	job := models.ImportJob{
+		ID:        7,
+		Status:    models.ImportDone,
+		Data:      []byte("code,price\n"),
+		Total:     2,
+		Processed: 2,
+		Created:   1,
+		Failed:    1,
+		Errors:    []models.ImportError{{Row: 2, Error: "code is required"}},
+	}
+	err := NewGormRepo(db).Update(context.Background(), &job)
+
+	require.NoError(t, err)
+	assert.Nil(t, job.Data, "the upload is dropped once the job is finished")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_FailInterrupted(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "import_jobs" SET "data"=$1,"error"=$2,"status"=$3,"updated_at"=$4 WHERE status IN ($5,$6)`)).
+		WithArgs(nil, InterruptedError, models.ImportFailed, sqlmock.AnyArg(), models.ImportQueued, models.ImportRunning).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	n, err := NewGormRepo(db).FailInterrupted(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,69 @@
+package imports
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestPostgres_JobLifecycle(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+
+	job := models.ImportJob{Status: models.ImportQueued, Data: []byte("code,price\nPROD100,10\n")}
+	require.NoError(t, repo.Create(ctx, &job))
+
+	claimed, err := repo.Claim(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportRunning, claimed.Status)
+	assert.Equal(t, job.Data, claimed.Data)
+
+	_, err = repo.Claim(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrJobNotQueued)
+
+	claimed.Status = models.ImportDone
+	claimed.Total, claimed.Processed, claimed.Failed = 1, 1, 1
+	claimed.Errors = []models.ImportError{{Row: 1, Code: "PROD100", Error: "product code or variant sku already exists"}}
+	require.NoError(t, repo.Update(ctx, &claimed))
+
+	got, err := repo.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportDone, got.Status)
+	assert.Equal(t, claimed.Errors, got.Errors)
+	assert.Nil(t, got.Data)
+}
+
+func TestPostgres_FailInterrupted(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+
+	queued := models.ImportJob{Status: models.ImportQueued}
+	running := models.ImportJob{Status: models.ImportQueued}
+	done := models.ImportJob{Status: models.ImportDone}
+	for _, job := range []*models.ImportJob{&queued, &running, &done} {
+		require.NoError(t, repo.Create(ctx, job))
+	}
+	_, err := repo.Claim(ctx, running.ID)
+	require.NoError(t, err)
+
+	n, err := repo.FailInterrupted(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	for _, id := range []uint{queued.ID, running.ID} {
+		job, err := repo.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, models.ImportFailed, job.Status)
+		assert.Equal(t, InterruptedError, job.Error)
+	}
+	job, err := repo.Get(ctx, done.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportDone, job.Status)
+}
@@ -0,0 +1,33 @@
+package imports
+
+import (
+	"context"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+var (
+	ErrJobNotFound  = errs.New(errs.NotFound, "import job not found")
+	ErrJobNotQueued = errs.New(errs.Conflict, "import job is not queued")
+)
+
+// InterruptedError is the Error of the jobs failed by FailInterrupted.
+const InterruptedError = "interrupted by a server restart"
+
+// Repository describes the import job storage operations.
+type Repository interface {
+	// Create stores a new job along with its upload.
+	Create(ctx context.Context, job *models.ImportJob) error
+	// Get returns the job without its upload.
+	Get(ctx context.Context, id uint) (models.ImportJob, error)
+	// Claim marks a queued job running and returns it with its upload, so
+	// that a job is run only once.
+	Claim(ctx context.Context, id uint) (models.ImportJob, error)
+	// Update saves the status, progress and errors of the job. The upload is
+	// dropped once the job is finished.
+	Update(ctx context.Context, job *models.ImportJob) error
+	// FailInterrupted marks failed the jobs left queued or running by a
+	// previous run of the server, returning how many there were.
+	FailInterrupted(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// lockKey is the transaction-level advisory lock serializing the recording
+// of the events.
+const lockKey = 0x6f7574626f78
+
+type GormRepo struct {
+	db *gorm.DB
+}
+
+func NewGormRepo(db *gorm.DB) *GormRepo {
+	return &GormRepo{
+		db: db,
+	}
+}
+
+func (r *GormRepo) List(ctx context.Context, after uint64, limit int) ([]models.OutboxEvent, error) {
+	events := []models.OutboxEvent{}
+	err := r.db.WithContext(ctx).
+		Where("id > ?", after).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Record inserts the event of a change in tx, the transaction of the change,
+// so that the event is committed if and only if the change is. It should be
+// the last statement of the transaction: the advisory lock it takes is held
+// until the commit, so that the transactions recording events commit in the
+// order of their event IDs.
+func Record(tx *gorm.DB, typ, aggregateCode string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.OutboxEvent{
+		Type:          typ,
+		AggregateCode: aggregateCode,
+		Payload:       data,
+	}).Error
+}
@@ -0,0 +1,105 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+)
+
+func TestGormRepo_List(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT * FROM "events" WHERE id > $1 ORDER BY id LIMIT $2`)
+
+	t.Run("events after the given ID", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(query).
+			WithArgs(3, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "type", "aggregate_code", "payload"}).
+				AddRow(4, "product.created", "PROD009", []byte(`{"code":"PROD009"}`)).
+				AddRow(5, "category.updated", "shoes", []byte(`{"code":"shoes"}`)))
+
+		events, err := NewGormRepo(db).List(context.Background(), 3, 2)
+
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, uint64(4), events[0].ID)
+		assert.Equal(t, "product.created", events[0].Type)
+		assert.Equal(t, "PROD009", events[0].AggregateCode)
+		assert.JSONEq(t, `{"code":"PROD009"}`, string(events[0].Payload))
+		assert.Equal(t, uint64(5), events[1].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("none", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(query).
+			WithArgs(9, 100).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		events, err := NewGormRepo(db).List(context.Background(), 9, 100)
+
+		require.NoError(t, err)
+		assert.NotNil(t, events)
+		assert.Empty(t, events)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRecord(t *testing.T) {
+	lock := regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)
+	insert := regexp.QuoteMeta(`INSERT INTO "events" ("type","aggregate_code","payload","created_at") VALUES ($1,$2,$3,$4) RETURNING "id"`)
+
+	t.Run("inserts the event after taking the lock", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(lock).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insert).
+			WithArgs("product.updated", "PROD001", []byte(`{"code":"PROD001"}`), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectCommit()
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return Record(tx, "product.updated", "PROD001", map[string]string{"code": "PROD001"})
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failure after the insert rolls the event back", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(lock).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insert).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectRollback()
+		boom := errors.New("boom")
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := Record(tx, "product.updated", "PROD001", map[string]string{"code": "PROD001"}); err != nil {
+				return err
+			}
+			return boom
+		})
+
+		assert.ErrorIs(t, err, boom)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("payloads that cannot be encoded are refused", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+
+		err := Record(db, "product.updated", "PROD001", func() {})
+
+		var jsonErr *json.UnsupportedTypeError
+		assert.ErrorAs(t, err, &jsonErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
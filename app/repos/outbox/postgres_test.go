@@ -0,0 +1,46 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+)
+
+func TestPostgres_RecordAndList(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	ctx := context.Background()
+
+	for _, code := range []string{"PROD001", "PROD002", "PROD003"} {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return Record(tx, "product.updated", code, map[string]string{"code": code})
+		})
+		require.NoError(t, err)
+	}
+
+	repo := NewGormRepo(db)
+	all, err := repo.List(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	for i, code := range []string{"PROD001", "PROD002", "PROD003"} {
+		assert.Equal(t, code, all[i].AggregateCode)
+		assert.JSONEq(t, `{"code":"`+code+`"}`, string(all[i].Payload))
+		assert.False(t, all[i].CreatedAt.IsZero())
+	}
+	assert.Less(t, all[0].ID, all[1].ID)
+	assert.Less(t, all[1].ID, all[2].ID)
+
+	page, err := repo.List(ctx, all[0].ID, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, all[1].ID, page[0].ID)
+
+	rest, err := repo.List(ctx, all[2].ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+}
@@ -0,0 +1,18 @@
+// Package outbox records the changes of the catalog in the transaction of
+// the change, for consumers to read them in order.
+package outbox
+
+import (
+	"context"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// Repository describes the reading of the recorded events.
+type Repository interface {
+	// List returns up to limit events with an ID greater than after, in
+	// increasing ID order. The IDs of the events increase in the order their
+	// transactions committed, so that consumers never miss an event by
+	// asking for those after the last one they read.
+	List(ctx context.Context, after uint64, limit int) ([]models.OutboxEvent, error)
+}
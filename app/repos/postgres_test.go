@@ -0,0 +1,136 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/category"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/outbox"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestPostgres_WithTransaction_RollsBack(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	productRepo := products.NewGormRepo(db)
+	ctx := context.Background()
+
+	err := NewTransactor(db, productRepo).WithTransaction(ctx, func(tx TxRepos) error {
+		if _, err := tx.Categories.Create(ctx, models.Category{Code: "bags", Name: "Bags"}); err != nil {
+			return err
+		}
+		bag := models.Product{Code: "BAG001", Price: decimal.RequireFromString("120"), Category: &models.Category{Code: "bags"}}
+		if err := tx.Products.Create(ctx, &bag); err != nil {
+			return err
+		}
+		// Already seeded
+		return tx.Products.Create(ctx, &models.Product{Code: "PROD001", Price: decimal.RequireFromString("10")})
+	})
+	require.ErrorIs(t, err, products.ErrProductExists)
+
+	exists, err := productRepo.Exists(ctx, "BAG001")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	categories, err := category.NewGormRepo(db).ListAll(ctx)
+	require.NoError(t, err)
+	for _, c := range categories {
+		assert.NotEqual(t, "bags", c.Code)
+	}
+}
+
+func TestPostgres_WithTransaction_Commits(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	productRepo := products.NewGormRepo(db)
+	ctx := context.Background()
+
+	err := NewTransactor(db, productRepo).WithTransaction(ctx, func(tx TxRepos) error {
+		if _, err := tx.Categories.Create(ctx, models.Category{Code: "bags", Name: "Bags"}); err != nil {
+			return err
+		}
+		return tx.Products.Create(ctx, &models.Product{Code: "BAG001", Price: decimal.RequireFromString("120"), Category: &models.Category{Code: "bags"}})
+	})
+	require.NoError(t, err)
+
+	bag, err := productRepo.GetByCode(ctx, "BAG001")
+	require.NoError(t, err)
+	assert.Equal(t, "bags", bag.Category.Code)
+}
+
+func TestPostgres_Events_FailedMutationsLeaveNone(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	productRepo := products.NewGormRepo(db)
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	// The function fails once the events of its writes were inserted
+	err := NewTransactor(db, productRepo).WithTransaction(ctx, func(tx TxRepos) error {
+		if _, err := tx.Categories.Create(ctx, models.Category{Code: "bags", Name: "Bags"}); err != nil {
+			return err
+		}
+		if err := tx.Products.Create(ctx, &models.Product{Code: "BAG001", Price: decimal.RequireFromString("120")}); err != nil {
+			return err
+		}
+		return boom
+	})
+	require.ErrorIs(t, err, boom)
+
+	_, err = productRepo.UpdateVariantPrices(ctx, "PROD999", map[string]decimal.Decimal{"SKU999A": decimal.NewFromInt(1)})
+	require.ErrorIs(t, err, products.ErrProductNotFound)
+	_, err = productRepo.AdjustPrices(ctx, "shoes", products.Adjustment{Type: products.AdjustAbsolute, Value: decimal.NewFromInt(-1000)})
+	var negErr *products.NegativePriceError
+	require.ErrorAs(t, err, &negErr)
+
+	events, err := outbox.NewGormRepo(db).List(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestPostgres_Events_OnePerMutation(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	productRepo := products.NewGormRepo(db)
+	categoryRepo := category.NewGormRepo(db)
+	ctx := context.Background()
+
+	_, err := categoryRepo.Create(ctx, models.Category{Code: "bags", Name: "Bags"})
+	require.NoError(t, err)
+	require.NoError(t, productRepo.Create(ctx, &models.Product{
+		Code:     "BAG001",
+		Price:    decimal.RequireFromString("120"),
+		Category: &models.Category{Code: "bags"},
+		Variants: []models.Variant{{Name: "Small", SKU: "SKUBAG1"}},
+	}))
+	_, err = productRepo.UpdateVariantPrices(ctx, "BAG001", map[string]decimal.Decimal{"SKUBAG1": decimal.NewFromInt(110)})
+	require.NoError(t, err)
+	require.NoError(t, productRepo.DeleteVariant(ctx, "BAG001", "SKUBAG1"))
+	require.NoError(t, categoryRepo.Update(ctx, &models.Category{Code: "bags", Name: "Handbags", Version: 1}))
+	_, err = productRepo.AdjustPrices(ctx, "bags", products.Adjustment{Type: products.AdjustPercentage, Value: decimal.NewFromInt(10)})
+	require.NoError(t, err)
+
+	recorded, err := outbox.NewGormRepo(db).List(ctx, 0, 10)
+	require.NoError(t, err)
+	var got []string
+	for _, e := range recorded {
+		got = append(got, e.Type+" "+e.AggregateCode)
+	}
+	assert.Equal(t, []string{
+		events.CategoryCreated + " bags",
+		events.ProductCreated + " BAG001",
+		events.ProductUpdated + " BAG001",
+		events.ProductUpdated + " BAG001",
+		events.CategoryUpdated + " bags",
+		events.CategoryPricesAdjusted + " bags",
+	}, got)
+	assert.JSONEq(t, `{"code":"BAG001","change":"variant_deleted","sku":"SKUBAG1"}`, string(recorded[3].Payload))
+}
@@ -0,0 +1,1058 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/outbox"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+const (
+	defaultBatchSize = 500
+	maxListAll       = 1000
+)
+
+// Postgres error codes of queries referring to missing tables and columns.
+const (
+	pgUndefinedTable  = "42P01"
+	pgUndefinedColumn = "42703"
+)
+
+var errListAllCapped = errors.New("list all capped")
+
+// tiebreaker ends every ORDER BY of List, whatever the sort, so that the
+// order is total and pages neither repeat nor skip products sharing the
+// sorted values. The batches of ListAllFunc, keyed on the id, follow the same
+// order.
+const tiebreaker = "products.id"
+
+// sortOrders maps the sort keys to their ORDER BY clause, before the
+// tiebreaker.
+var sortOrders = map[string]string{
+	SortFeatured:  "",
+	SortNewest:    "products.created_at DESC",
+	SortPriceAsc:  "products.price",
+	SortPriceDesc: "products.price DESC",
+}
+
+// relevanceOrder scores the matches of a query: 3 for the exact code, 2 for a
+// code prefix and 1 for a category name containing it.
+const relevanceOrder = `CASE WHEN LOWER(products.code) = ? THEN 3 ` +
+	`WHEN LOWER(products.code) LIKE ? THEN 2 ` +
+	`WHEN products.category_id IN (SELECT id FROM categories WHERE LOWER(name) LIKE ?) THEN 1 ` +
+	`ELSE 0 END DESC`
+
+// modifiedOrder lists products by their last update, for clients syncing the
+// changes since a given time.
+const modifiedOrder = "products.updated_at"
+
+type GormRepo struct {
+	db          *gorm.DB
+	batchSize   int
+	defaultSort string
+	rounding    pricing.Mode
+}
+
+func NewGormRepo(db *gorm.DB) *GormRepo {
+	return &GormRepo{
+		db:          db,
+		batchSize:   defaultBatchSize,
+		defaultSort: SortFeatured,
+	}
+}
+
+// WithTx returns a copy of the repository running its queries in tx, with
+// the same settings.
+func (r *GormRepo) WithTx(tx *gorm.DB) *GormRepo {
+	c := *r
+	c.db = tx
+	return &c
+}
+
+// IsValidSort reports whether List accepts the sort key on its own.
+// SortRelevance is not, as it only applies together with a query.
+func IsValidSort(sort string) bool {
+	_, ok := sortOrders[sort]
+	return ok
+}
+
+// SetDefaultSort changes the order List uses when the filters do not ask for
+// one. An empty sort keeps the current default.
+func (r *GormRepo) SetDefaultSort(sort string) error {
+	if sort == "" {
+		return nil
+	}
+	if !IsValidSort(sort) {
+		return fmt.Errorf("unsupported sort %q", sort)
+	}
+	r.defaultSort = sort
+	return nil
+}
+
+// SetRounding changes the mode the prices computed by the repository are
+// rounded to cents with, HalfEven until set.
+func (r *GormRepo) SetRounding(mode pricing.Mode) {
+	r.rounding = mode
+}
+
+// ListAll returns every product with its category and variants, up to
+// maxListAll products. A warning is logged when the catalog is truncated.
+//
+// Deprecated: use List to paginate or ListAllFunc to process every product.
+func (r *GormRepo) ListAll(ctx context.Context) ([]models.Product, error) {
+	var products []models.Product
+	err := r.ListAllFunc(ctx, "", func(batch []models.Product) error {
+		products = append(products, batch...)
+		if len(products) >= maxListAll {
+			return errListAllCapped
+		}
+		return nil
+	})
+	if errors.Is(err, errListAllCapped) {
+		logging.FromContext(ctx).Warn("ListAll truncated the catalog, use List or ListAllFunc instead", "max", maxListAll)
+		return products[:maxListAll], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// ListAllFunc walks through every product of the category, or of the whole
+// catalog when it is empty, within its availability window as List, in
+// batches, with their category and variants preloaded, calling fn once per
+// batch. Returning an error from fn stops the
+// iteration and is returned as is. The batches are keyed on the id, the
+// tiebreaker of List, so that the walk is a total order as well.
+func (r *GormRepo) ListAllFunc(ctx context.Context, category string, fn func([]models.Product) error) error {
+	var batch []models.Product
+	return r.db.WithContext(ctx).
+		Scopes(applyFilters(SearchFilters{Category: category}), availability(SearchFilters{})).
+		Preload("Category.Translations").
+		Preload("Variants").
+		FindInBatches(&batch, r.batchSize, func(*gorm.DB, int) error {
+			return fn(batch)
+		}).Error
+}
+
+// List returns a page of products matching the filters, with their category,
+// variants and first image preloaded, together with the total number of
+// matching products. Products outside of their availability window are left
+// out unless the filters include them. When the variants cannot be read and
+// the filters allow it, the page is loaded again without them and comes with
+// ErrVariantsUnavailable.
+func (r *GormRepo) List(ctx context.Context, filters SearchFilters) ([]models.Product, int64, error) {
+	total, err := r.Count(ctx, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	products, err := r.ListPage(ctx, filters)
+	if err != nil && !errors.Is(err, ErrVariantsUnavailable) {
+		return nil, 0, err
+	}
+	return products, total, err
+}
+
+func (r *GormRepo) Count(ctx context.Context, filters SearchFilters) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.Product{}).Scopes(applyFilters(filters), availability(filters)).Count(&total).Error
+	return total, err
+}
+
+func (r *GormRepo) ListPage(ctx context.Context, filters SearchFilters) ([]models.Product, error) {
+	var order any = withTiebreaker(sortOrders[r.defaultSort])
+	switch {
+	case filters.Sort == SortRelevance, filters.Sort == "" && filters.Query != "":
+		q := strings.ToLower(filters.Query)
+		order = clause.OrderBy{Expression: clause.Expr{
+			SQL:                withTiebreaker(relevanceOrder),
+			Vars:               []any{q, escapeLike(q) + "%", "%" + escapeLike(q) + "%"},
+			WithoutParentheses: true,
+		}}
+	case filters.Sort != "":
+		order = withTiebreaker(sortOrders[filters.Sort])
+	case filters.ModifiedSince != nil:
+		order = withTiebreaker(modifiedOrder)
+	}
+
+	products, err := r.findPage(ctx, filters, order, true)
+	if err != nil && filters.AllowMissingVariants && isMissingRelation(err) {
+		logging.FromContext(ctx).Warn("Listing products without their variants", "error", err)
+		if products, err = r.findPage(ctx, filters, order, false); err == nil {
+			return products, ErrVariantsUnavailable
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// findPage loads a page of List, preloading the variants or not.
+func (r *GormRepo) findPage(ctx context.Context, filters SearchFilters, order any, withVariants bool) ([]models.Product, error) {
+	db := r.db.WithContext(ctx).
+		Scopes(applyFilters(filters), availability(filters)).
+		Preload("Category.Translations")
+	if withVariants {
+		db = db.Preload("Variants")
+	}
+
+	var products []models.Product
+	err := db.
+		Preload("Images", FirstImage).
+		Order(order).
+		Offset(filters.Offset).
+		Limit(filters.Limit).
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// ListVariants joins the variants to their product, so that orphan variants,
+// which cannot be sold, are left out, and to its category for the inherited
+// prices. The price filter applies to the resolved price, for variants
+// inheriting a price under it to match as well.
+func (r *GormRepo) ListVariants(ctx context.Context, filters VariantFilters) ([]ResolvedVariant, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Scopes(variantFilters(filters)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	variants := []ResolvedVariant{}
+	err := r.db.WithContext(ctx).
+		Scopes(variantFilters(filters)).
+		Select("product_variants.sku, product_variants.name, " + VariantPrice + " AS price, " +
+			"products.code AS product_code, COALESCE(categories.code, '') AS category_code").
+		Order("product_variants.id").
+		Offset(filters.Offset).
+		Limit(filters.Limit).
+		Scan(&variants).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return variants, total, nil
+}
+
+// variantFilters selects the variants of ListVariants, leaving out those of
+// products outside of their availability window.
+func variantFilters(filters VariantFilters) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Table("product_variants").
+			Joins("JOIN products ON products.id = product_variants.product_id").
+			Joins("LEFT JOIN categories ON categories.id = products.category_id").
+			Where(AvailableNow)
+		if filters.Category != "" {
+			db = db.Where("categories.code = ?", filters.Category)
+		}
+		if filters.PriceLessThan != nil {
+			db = db.Where(VariantPrice+" < ?", *filters.PriceLessThan)
+		}
+		return db
+	}
+}
+
+// isMissingRelation tells the errors of a query reading a table or a column
+// that does not exist (anymore).
+func isMissingRelation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == pgUndefinedTable || pgErr.Code == pgUndefinedColumn)
+}
+
+// withTiebreaker appends the tiebreaker to the order.
+func withTiebreaker(order string) string {
+	if order == "" {
+		return tiebreaker
+	}
+	return order + ", " + tiebreaker
+}
+
+// ListByCategories runs one limited List per category, so each category gets
+// its own page with the same preloads and order as the catalog listing.
+func (r *GormRepo) ListByCategories(ctx context.Context, categories []string, perCategory int) (map[string]CategoryProducts, error) {
+	pages := make(map[string]CategoryProducts, len(categories))
+	for _, code := range categories {
+		products, total, err := r.List(ctx, SearchFilters{Category: code, Limit: perCategory})
+		if err != nil {
+			return nil, err
+		}
+		pages[code] = CategoryProducts{Products: products, Total: total}
+	}
+	return pages, nil
+}
+
+// applyFilters narrows the products query down to the given filters. Variant
+// filters use EXISTS subqueries so products are never duplicated by a join and
+// their preloaded variants stay complete.
+func applyFilters(filters SearchFilters) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filters.Category != "" {
+			db = db.Where("products.category_id IN (SELECT id FROM categories WHERE code = ?)", filters.Category)
+		}
+		if filters.Query != "" {
+			q := escapeLike(strings.ToLower(filters.Query))
+			db = db.Where("(LOWER(products.code) LIKE ? OR products.category_id IN (SELECT id FROM categories WHERE LOWER(name) LIKE ?))", q+"%", "%"+q+"%")
+		}
+		if filters.PriceLessThan != nil {
+			db = db.Where(priceCondition(filters, "<"), *filters.PriceLessThan)
+		}
+		if filters.PriceEquals != nil {
+			db = db.Where(priceCondition(filters, "="), *filters.PriceEquals)
+		}
+		if filters.Variant != "" {
+			db = db.Where("EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND LOWER(product_variants.name) = LOWER(?))", filters.Variant)
+		}
+		if filters.SKUPrefix != "" {
+			db = db.Where("EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.sku LIKE ?)", filters.SKUPrefix+"%")
+		}
+		if filters.ModifiedSince != nil {
+			db = db.Where("products.updated_at > ?", *filters.ModifiedSince)
+		}
+		if filters.HasVariants != nil {
+			exists := "EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id)"
+			if !*filters.HasVariants {
+				exists = "NOT " + exists
+			}
+			db = db.Where(exists)
+		}
+		return db
+	}
+}
+
+// AvailableNow keeps the products within their availability window at the
+// time of the query. The other repositories reading products apply it too.
+const AvailableNow = `(products.available_from IS NULL OR products.available_from <= NOW()) AND ` +
+	`(products.available_to IS NULL OR products.available_to >= NOW())`
+
+// availability leaves the products outside of their availability window out
+// of List, unless the filters include them.
+func availability(filters SearchFilters) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filters.IncludeUnavailable {
+			return db
+		}
+		return db.Where(AvailableNow)
+	}
+}
+
+// VariantPrice is the price of a variant before conversion: its own one, or
+// the product price marked up by the category for the variants inheriting it.
+// It needs the products and the categories joined to the variants.
+const VariantPrice = `COALESCE(NULLIF(product_variants.price, 0), products.price * (100 + COALESCE(categories.markup_percent, 0)) / 100)`
+
+// priceCondition compares the product price, or the variant prices when
+// filters asks for them, with a placeholder using op.
+func priceCondition(filters SearchFilters, op string) string {
+	if !filters.VariantPrices {
+		return "products.price " + op + " ?"
+	}
+	return "EXISTS (SELECT 1 FROM product_variants LEFT JOIN categories ON categories.id = products.category_id " +
+		"WHERE product_variants.product_id = products.id AND " + VariantPrice + " " + op + " ?)"
+}
+
+// escapeLike escapes the wildcards of LIKE patterns so s matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// GetByCode returns the product with the given code, with its category,
+// variants and ordered images preloaded. Products outside of their
+// availability window are not found, as List leaves them out.
+func (r *GormRepo) GetByCode(ctx context.Context, code string) (models.Product, error) {
+	return firstProduct(r.db.WithContext(ctx).Where("code = ?", code).Where(AvailableNow))
+}
+
+// GetByCodeIncludingUnavailable is GetByCode finding the products outside of
+// their availability window too.
+func (r *GormRepo) GetByCodeIncludingUnavailable(ctx context.Context, code string) (models.Product, error) {
+	return firstProduct(r.db.WithContext(ctx).Where("code = ?", code))
+}
+
+// firstProduct loads the product matching the conditions of db with the
+// preloads of GetByCode.
+func firstProduct(db *gorm.DB) (models.Product, error) {
+	var product models.Product
+	err := db.
+		Preload("Category.Translations").
+		Preload("Variants").
+		Preload("Images", orderImages).
+		First(&product).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Product{}, ErrProductNotFound
+	}
+	if err != nil {
+		return models.Product{}, err
+	}
+	return product, nil
+}
+
+// Exists runs a single SELECT EXISTS, with none of the preloads of GetByCode
+// but the same availability window.
+func (r *GormRepo) Exists(ctx context.Context, code string) (bool, error) {
+	var exists bool
+	err := r.db.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM products WHERE code = ? AND "+AvailableNow+")", code).
+		Scan(&exists).Error
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// GetByCodes returns the products with the given codes, with their category,
+// variants and first image preloaded, leaving out those outside of their
+// availability window as List does.
+func (r *GormRepo) GetByCodes(ctx context.Context, codes []string) ([]models.Product, error) {
+	var products []models.Product
+	err := r.db.WithContext(ctx).
+		Preload("Category.Translations").
+		Preload("Variants").
+		Preload("Images", FirstImage).
+		Where("code IN ?", codes).
+		Where(AvailableNow).
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// ListChangedSince pages by the (updated_at, id) row value rather than by
+// offset, so that products updated at the same time are neither skipped nor
+// repeated across pages. Products outside of their availability window are
+// listed too, for the consumers of the changes to remove them.
+func (r *GormRepo) ListChangedSince(ctx context.Context, after ChangePosition, limit int) ([]models.Product, error) {
+	var products []models.Product
+	err := r.db.WithContext(ctx).
+		Preload("Category.Translations").
+		Preload("Variants").
+		Preload("Images", orderImages).
+		Where("(products.updated_at, products.id) > (?, ?)", after.UpdatedAt, after.ID).
+		Order("products.updated_at, products.id").
+		Limit(limit).
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// FindExisting runs one plain SELECT per kind of key, skipping the kinds
+// without keys to look up.
+func (r *GormRepo) FindExisting(ctx context.Context, keys ExistingKeys) (ExistingKeys, error) {
+	return findExisting(r.db.WithContext(ctx), keys)
+}
+
+func findExisting(db *gorm.DB, keys ExistingKeys) (ExistingKeys, error) {
+	var found ExistingKeys
+	lookups := []struct {
+		model  any
+		column string
+		keys   []string
+		found  *[]string
+	}{
+		{&models.Product{}, "code", keys.Codes, &found.Codes},
+		{&models.Variant{}, "sku", keys.SKUs, &found.SKUs},
+		{&models.Category{}, "code", keys.Categories, &found.Categories},
+	}
+	for _, l := range lookups {
+		if len(l.keys) == 0 {
+			continue
+		}
+		err := db.Model(l.model).Where(l.column+" IN ?", l.keys).Pluck(l.column, l.found).Error
+		if err != nil {
+			return ExistingKeys{}, err
+		}
+	}
+	return found, nil
+}
+
+// GetRelated lists the other products of the category by id, with their
+// category, variants and first image preloaded, leaving out those outside of
+// their availability window as List does.
+func (r *GormRepo) GetRelated(ctx context.Context, code string, limit int) ([]models.Product, error) {
+	var product models.Product
+	err := r.db.WithContext(ctx).Select("id", "category_id").Where("code = ?", code).First(&product).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if product.CategoryID == nil {
+		return []models.Product{}, nil
+	}
+
+	var related []models.Product
+	err = r.db.WithContext(ctx).
+		Preload("Category.Translations").
+		Preload("Variants").
+		Preload("Images", FirstImage).
+		Where("products.category_id = ? AND products.id <> ?", *product.CategoryID, product.ID).
+		Where(AvailableNow).
+		Order("products.id").
+		Limit(limit).
+		Find(&related).Error
+	if err != nil {
+		return nil, err
+	}
+	return related, nil
+}
+
+// GetByID returns the product with the given id, with its category,
+// variants and ordered images preloaded. Ids that are not positive integers are refused with
+// ErrInvalidProductID before reaching the database.
+func (r *GormRepo) GetByID(ctx context.Context, id string) (models.Product, error) {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || n == 0 {
+		return models.Product{}, ErrInvalidProductID
+	}
+
+	var product models.Product
+	err = r.db.WithContext(ctx).
+		Preload("Category.Translations").
+		Preload("Variants").
+		Preload("Images", orderImages).
+		First(&product, n).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Product{}, ErrProductNotFound
+	}
+	if err != nil {
+		return models.Product{}, err
+	}
+	return product, nil
+}
+
+// Create inserts the product together with its variants in a single
+// transaction, so a failing variant leaves nothing behind. The category is
+// resolved from the code of product.Category when one is given.
+func (r *GormRepo) Create(ctx context.Context, product *models.Product) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if product.Category != nil {
+			var category models.Category
+			err := tx.Where("code = ?", product.Category.Code).First(&category).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// The category is part of the product being created
+				return errs.WithKind(errs.Invalid, ErrCategoryNotFound)
+			}
+			if err != nil {
+				return err
+			}
+			product.CategoryID = &category.ID
+			product.Category = &category
+		}
+
+		err := tx.Omit("Category").Create(product).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrProductExists
+		}
+		if err != nil {
+			return err
+		}
+		return outbox.Record(tx, events.ProductCreated, product.Code, productEvent{Code: product.Code})
+	})
+}
+
+// UpsertSnapshot looks up the existing keys first to tell creations from
+// updates, then upserts each kind in batches with INSERT ... ON CONFLICT.
+// The updated_at of existing rows only moves when a value changes, so that
+// upserting the same snapshot again is a no-op.
+func (r *GormRepo) UpsertSnapshot(ctx context.Context, snapshot Snapshot) (SnapshotSummary, error) {
+	var keys ExistingKeys
+	for _, c := range snapshot.Categories {
+		keys.Categories = append(keys.Categories, c.Code)
+	}
+	for _, p := range snapshot.Products {
+		keys.Codes = append(keys.Codes, p.Code)
+		for _, v := range p.Variants {
+			keys.SKUs = append(keys.SKUs, v.SKU)
+		}
+	}
+
+	var summary SnapshotSummary
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := findExisting(tx, keys)
+		if err != nil {
+			return err
+		}
+		summary = SnapshotSummary{
+			Categories: upsertCounts(keys.Categories, existing.Categories),
+			Products:   upsertCounts(keys.Codes, existing.Codes),
+			Variants:   upsertCounts(keys.SKUs, existing.SKUs),
+		}
+
+		if len(snapshot.Categories) > 0 {
+			// Snapshots carry no markup, which is managed per category. Renames
+			// are updates of the category, which bump its version.
+			upsert := upsertOn("categories", "code", "name")
+			upsert.DoUpdates = append(upsert.DoUpdates, clause.Assignment{
+				Column: clause.Column{Name: "version"},
+				Value:  gorm.Expr("CASE WHEN categories.name IS DISTINCT FROM excluded.name THEN categories.version + 1 ELSE categories.version END"),
+			})
+			err := tx.Omit("Translations", "MarkupPercent").
+				Clauses(upsert).
+				CreateInBatches(&snapshot.Categories, r.batchSize).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(snapshot.Products) == 0 {
+			// Snapshots span the catalog, their event has no code
+			return outbox.Record(tx, events.SnapshotImported, "", summary)
+		}
+
+		categoryIDs, err := snapshotCategoryIDs(tx, snapshot.Products)
+		if err != nil {
+			return err
+		}
+		for i := range snapshot.Products {
+			p := &snapshot.Products[i]
+			if p.Category != nil {
+				id := categoryIDs[p.Category.Code]
+				p.CategoryID = &id
+			}
+		}
+		// Snapshots carry no availability window, which is managed per product
+		err = tx.Omit("Category", "Variants", "Images", "AvailableFrom", "AvailableTo").
+			Clauses(upsertOn("products", "code", "price", "category_id")).
+			CreateInBatches(&snapshot.Products, r.batchSize).Error
+		if err != nil {
+			return err
+		}
+
+		var variants []models.Variant
+		for _, p := range snapshot.Products {
+			for _, v := range p.Variants {
+				v.ProductID = p.ID
+				variants = append(variants, v)
+			}
+		}
+		if len(variants) > 0 {
+			err := tx.Clauses(upsertOn("product_variants", "sku", "product_id", "name", "price")).
+				CreateInBatches(&variants, r.batchSize).Error
+			if err != nil {
+				return err
+			}
+		}
+		return outbox.Record(tx, events.SnapshotImported, "", summary)
+	})
+	if err != nil {
+		return SnapshotSummary{}, err
+	}
+	return summary, nil
+}
+
+// snapshotCategoryIDs looks up the categories of the products by code. An
+// unknown category makes the whole snapshot invalid.
+func snapshotCategoryIDs(tx *gorm.DB, products []models.Product) (map[string]uint, error) {
+	var codes []string
+	for _, p := range products {
+		if p.Category != nil {
+			codes = append(codes, p.Category.Code)
+		}
+	}
+	ids := make(map[string]uint, len(codes))
+	if len(codes) == 0 {
+		return ids, nil
+	}
+
+	var categories []models.Category
+	if err := tx.Select("id", "code").Where("code IN ?", codes).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		ids[c.Code] = c.ID
+	}
+	for _, code := range codes {
+		if _, ok := ids[code]; !ok {
+			return nil, errs.WithKind(errs.Invalid, fmt.Errorf("%w: %s", ErrCategoryNotFound, code))
+		}
+	}
+	return ids, nil
+}
+
+// upsertOn updates the columns of the rows conflicting on key with the
+// inserted values, only bumping updated_at when one of them changes.
+func upsertOn(table, key string, columns ...string) clause.OnConflict {
+	set := make(clause.Set, 0, len(columns)+1)
+	changed := make([]string, len(columns))
+	for i, c := range columns {
+		set = append(set, clause.Assignment{Column: clause.Column{Name: c}, Value: gorm.Expr("excluded." + c)})
+		changed[i] = fmt.Sprintf("%s.%s IS DISTINCT FROM excluded.%s", table, c, c)
+	}
+	set = append(set, clause.Assignment{
+		Column: clause.Column{Name: "updated_at"},
+		Value: gorm.Expr(fmt.Sprintf("CASE WHEN %s THEN excluded.updated_at ELSE %s.updated_at END",
+			strings.Join(changed, " OR "), table)),
+	})
+	return clause.OnConflict{
+		Columns:   []clause.Column{{Name: key}},
+		DoUpdates: set,
+	}
+}
+
+// upsertCounts counts the keys not found among the existing ones as created
+// and the others as updated. The keys must be unique.
+func upsertCounts(keys, existing []string) UpsertCounts {
+	counts := UpsertCounts{Updated: len(existing)}
+	counts.Created = len(keys) - counts.Updated
+	return counts
+}
+
+// DeleteVariant removes a single variant of the product by its SKU, leaving
+// the product and its other variants untouched. A SKU of another product
+// matches no variant. The product is marked as updated.
+func (r *GormRepo) DeleteVariant(ctx context.Context, code, sku string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var variant models.Variant
+		res := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "product_id"}}}).
+			Where("product_id = (SELECT id FROM products WHERE code = ?) AND sku = ?", code, sku).
+			Delete(&variant)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVariantNotFound
+		}
+		if err := touchProduct(tx, variant.ProductID); err != nil {
+			return err
+		}
+		return outbox.Record(tx, events.ProductUpdated, code, productEvent{Code: code, Change: changeVariantDeleted, SKU: sku})
+	})
+}
+
+// orphanVariants matches the variants whose product does not exist.
+const orphanVariants = `NOT EXISTS (SELECT 1 FROM products WHERE products.id = product_variants.product_id)`
+
+func (r *GormRepo) FindOrphanVariants(ctx context.Context) ([]models.Variant, error) {
+	var variants []models.Variant
+	err := r.db.WithContext(ctx).Where(orphanVariants).Order("id").Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// DeleteOrphanVariants deletes the orphans in batches of the repository
+// batch size, each in its own statement so that no lock is held for long.
+// The variants deleted by the batches that succeeded are counted even when a
+// later batch fails.
+func (r *GormRepo) DeleteOrphanVariants(ctx context.Context) (int64, error) {
+	var deleted int64
+	for {
+		res := r.db.WithContext(ctx).
+			Exec(`DELETE FROM product_variants WHERE id IN (SELECT id FROM product_variants WHERE `+orphanVariants+` LIMIT ?)`, r.batchSize)
+		if res.Error != nil {
+			return deleted, res.Error
+		}
+		deleted += res.RowsAffected
+		if res.RowsAffected < int64(r.batchSize) {
+			return deleted, nil
+		}
+	}
+}
+
+// AddImage inserts the image at its position, shifting the images from there
+// on by one. Positions out of range append the image. The product is marked as
+// updated.
+func (r *GormRepo) AddImage(ctx context.Context, code string, image *models.Image) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		productID, err := productID(tx, code)
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.Image{}).Where("product_id = ?", productID).Count(&count).Error; err != nil {
+			return err
+		}
+		if image.Position <= 0 || image.Position > int(count) {
+			image.Position = int(count) + 1
+		} else {
+			err := tx.Model(&models.Image{}).
+				Where("product_id = ? AND position >= ?", productID, image.Position).
+				Update("position", gorm.Expr("position + 1")).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		image.ProductID = productID
+		if err := tx.Create(image).Error; err != nil {
+			return err
+		}
+		if err := touchProduct(tx, productID); err != nil {
+			return err
+		}
+		return outbox.Record(tx, events.ProductUpdated, code, productEvent{Code: code, Change: changeImageAdded})
+	})
+}
+
+// DeleteImage removes an image of the product, moving the following images
+// up by one. The product is marked as updated.
+func (r *GormRepo) DeleteImage(ctx context.Context, code string, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		productID, err := productID(tx, code)
+		if err != nil {
+			return err
+		}
+
+		var image models.Image
+		err = tx.Where("id = ? AND product_id = ?", id, productID).First(&image).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrImageNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&models.Image{}).
+			Where("product_id = ? AND position > ?", productID, image.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return err
+		}
+		if err := touchProduct(tx, productID); err != nil {
+			return err
+		}
+		return outbox.Record(tx, events.ProductUpdated, code, productEvent{Code: code, Change: changeImageDeleted})
+	})
+}
+
+// UpdateVariantPrices updates the variants one SKU at a time in a single
+// transaction. The product is marked as updated, and its change recorded,
+// when any variant matched.
+func (r *GormRepo) UpdateVariantPrices(ctx context.Context, code string, prices map[string]decimal.Decimal) ([]string, error) {
+	unmatched := []string{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		productID, err := productID(tx, code)
+		if err != nil {
+			return err
+		}
+
+		for _, sku := range slices.Sorted(maps.Keys(prices)) {
+			res := tx.Model(&models.Variant{}).
+				Where("product_id = ? AND sku = ?", productID, sku).
+				Update("price", prices[sku])
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				unmatched = append(unmatched, sku)
+			}
+		}
+		if len(unmatched) == len(prices) {
+			return nil
+		}
+		if err := touchProduct(tx, productID); err != nil {
+			return err
+		}
+		return outbox.Record(tx, events.ProductUpdated, code, productEvent{Code: code, Change: changeVariantPrices})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return unmatched, nil
+}
+
+// The prices aggregated by CategoryPriceStats, of the category @category.
+const (
+	categoryProductPrices = `SELECT price FROM products WHERE category_id = @category AND ` + AvailableNow
+	categoryVariantPrices = `SELECT ` + VariantPrice + ` FROM product_variants
+		JOIN products ON products.id = product_variants.product_id
+		JOIN categories ON categories.id = products.category_id
+		WHERE products.category_id = @category AND ` + AvailableNow
+)
+
+// PriceStats looks the category up by code, then aggregates its product
+// prices as CategoryPriceStats.
+func (r *GormRepo) PriceStats(ctx context.Context, categoryCode string) (PriceStats, error) {
+	db := r.db.WithContext(ctx)
+	id, err := categoryID(db, categoryCode)
+	if err != nil {
+		return PriceStats{}, err
+	}
+	return CategoryPriceStats(db, id, false, r.rounding)
+}
+
+// CategoryPriceStats aggregates in the database, rather than loading the
+// products, the prices of the available products of the category, and with
+// includeVariants those of their variants at the price the catalog shows for
+// them: their own one, or the product price marked up by the category for
+// those inheriting it. The average is rounded to cents with mode, as the
+// prices shown, rather than by ROUND.
+func CategoryPriceStats(db *gorm.DB, categoryID uint, includeVariants bool, mode pricing.Mode) (PriceStats, error) {
+	prices := categoryProductPrices
+	if includeVariants {
+		prices += " UNION ALL " + categoryVariantPrices
+	}
+
+	var stats PriceStats
+	err := db.Raw(`SELECT MIN(price) AS min, MAX(price) AS max, AVG(price) AS avg, COUNT(*) AS count FROM (`+prices+`) AS prices`,
+		map[string]any{"category": categoryID}).
+		Scan(&stats).Error
+	if err != nil {
+		return PriceStats{}, err
+	}
+	if stats.Avg.Valid {
+		stats.Avg.Decimal = mode.Round(stats.Avg.Decimal, 2)
+	}
+	return stats, nil
+}
+
+// AdjustPrices updates every product of the category with a single UPDATE,
+// rounding the new prices to cents with the mode of SetRounding.
+// Adjustments that would turn any price negative, or raise it past the
+// precision of the column, are refused as a whole with a *NegativePriceError
+// or a *PriceOverflowError.
+func (r *GormRepo) AdjustPrices(ctx context.Context, categoryCode string, adj Adjustment) (int64, error) {
+	var newPrice clause.Expr
+	switch adj.Type {
+	case AdjustPercentage:
+		factor := decimal.NewFromInt(100).Add(adj.Value).Div(decimal.NewFromInt(100))
+		newPrice = roundedPrice(r.rounding, "price * ?", factor)
+	case AdjustFactor:
+		newPrice = roundedPrice(r.rounding, "price * ?", adj.Value)
+	case AdjustAbsolute:
+		newPrice = roundedPrice(r.rounding, "price + ?", adj.Value)
+	default:
+		return 0, fmt.Errorf("unsupported adjustment type %q", adj.Type)
+	}
+
+	var updated int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		categoryID, err := categoryID(tx, categoryCode)
+		if err != nil {
+			return err
+		}
+
+		var negative int64
+		err = tx.Model(&models.Product{}).
+			Where("category_id = ? AND ? < 0", categoryID, newPrice).
+			Count(&negative).Error
+		if err != nil {
+			return err
+		}
+		if negative > 0 {
+			return &NegativePriceError{Count: negative}
+		}
+		var overflowing int64
+		err = tx.Model(&models.Product{}).
+			Where("category_id = ? AND ? > ?", categoryID, newPrice, MaxStoredPrice).
+			Count(&overflowing).Error
+		if err != nil {
+			return err
+		}
+		if overflowing > 0 {
+			return &PriceOverflowError{Count: overflowing}
+		}
+
+		res := tx.Model(&models.Product{}).
+			Where("category_id = ?", categoryID).
+			Update("price", newPrice)
+		if res.Error != nil {
+			return res.Error
+		}
+		updated = res.RowsAffected
+		return outbox.Record(tx, events.CategoryPricesAdjusted, categoryCode,
+			categoryPricesEvent{Code: categoryCode, Updated: updated})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// roundedPrice is the SQL of the price computed by expr, whose placeholder
+// is arg, rounded to cents as mode.Round would.
+func roundedPrice(mode pricing.Mode, expr string, arg any) clause.Expr {
+	switch mode {
+	case pricing.HalfUp:
+		return gorm.Expr("ROUND("+expr+", 2)", arg)
+	case pricing.Down:
+		return gorm.Expr("TRUNC("+expr+", 2)", arg)
+	default:
+		// ROUND takes the halves away from zero, they go to the even cent
+		return gorm.Expr("CASE WHEN ABS(MOD(("+expr+") * 100, 2)) = 0.5 THEN TRUNC("+expr+", 2) ELSE ROUND("+expr+", 2) END", arg, arg, arg)
+	}
+}
+
+// categoryID resolves a category code to its primary key.
+func categoryID(tx *gorm.DB, code string) (uint, error) {
+	var category models.Category
+	err := tx.Select("id").Where("code = ?", code).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrCategoryNotFound
+	}
+	return category.ID, err
+}
+
+// orderImages preloads the images of a product in their display order.
+func orderImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// FirstImage preloads only the first image of every product, keeping listings
+// small. The wishlists are listed with it too.
+func FirstImage(db *gorm.DB) *gorm.DB {
+	return db.Where(`NOT EXISTS (SELECT 1 FROM product_images AS earlier
+		WHERE earlier.product_id = product_images.product_id
+		AND (earlier.position, earlier.id) < (product_images.position, product_images.id))`)
+}
+
+// productID resolves a product code to its primary key.
+func productID(tx *gorm.DB, code string) (uint, error) {
+	var product models.Product
+	err := tx.Select("id").Where("code = ?", code).First(&product).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrProductNotFound
+	}
+	return product.ID, err
+}
+
+// Changes of the product.updated events.
+const (
+	changeImageAdded     = "image_added"
+	changeImageDeleted   = "image_deleted"
+	changeVariantPrices  = "variant_prices"
+	changeVariantDeleted = "variant_deleted"
+)
+
+// productEvent is the payload of the events of a product.
+type productEvent struct {
+	Code   string `json:"code"`
+	Change string `json:"change,omitempty"`
+	SKU    string `json:"sku,omitempty"`
+}
+
+// categoryPricesEvent is the payload of the price adjustments of a category.
+type categoryPricesEvent struct {
+	Code    string `json:"code"`
+	Updated int64  `json:"updated"`
+}
+
+// touchProduct bumps the update time of the product, for changes made to its
+// associations.
+func touchProduct(tx *gorm.DB, id uint) error {
+	return tx.Model(&models.Product{}).Where("id = ?", id).Update("updated_at", time.Now()).Error
+}
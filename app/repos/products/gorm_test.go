@@ -0,0 +1,1660 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestGormRepo_UpsertSnapshot(t *testing.T) {
+	snapshot := func() Snapshot {
+		return Snapshot{
+			Categories: []models.Category{{Code: "bags", Name: "Bags"}},
+			Products: []models.Product{{
+				Code:     "PROD009",
+				Price:    decimal.RequireFromString("49.90"),
+				Category: &models.Category{Code: "bags"},
+				Variants: []models.Variant{{Name: "Small", SKU: "SKU009A"}},
+			}},
+		}
+	}
+	expectUpsert := func(mock sqlmock.Sqlmock, existing bool) {
+		codes := sqlmock.NewRows([]string{"code"})
+		skus := sqlmock.NewRows([]string{"sku"})
+		categories := sqlmock.NewRows([]string{"code"})
+		if existing {
+			codes.AddRow("PROD009")
+			skus.AddRow("SKU009A")
+			categories.AddRow("bags")
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "code" FROM "products" WHERE code IN ($1)`)).
+			WithArgs("PROD009").
+			WillReturnRows(codes)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "sku" FROM "product_variants" WHERE sku IN ($1)`)).
+			WithArgs("SKU009A").
+			WillReturnRows(skus)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "code" FROM "categories" WHERE code IN ($1)`)).
+			WithArgs("bags").
+			WillReturnRows(categories)
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "categories" ("code","name","version") VALUES ($1,$2,$3) `+
+			`ON CONFLICT ("code") DO UPDATE SET "name"=excluded.name,`+
+			`"updated_at"=CASE WHEN categories.name IS DISTINCT FROM excluded.name THEN excluded.updated_at ELSE categories.updated_at END,`+
+			`"version"=CASE WHEN categories.name IS DISTINCT FROM excluded.name THEN categories.version + 1 ELSE categories.version END `+
+			`RETURNING "id"`)).
+			WithArgs("bags", "Bags", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","code" FROM "categories" WHERE code IN ($1)`)).
+			WithArgs("bags").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(4, "bags"))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "products" ("code","price","category_id","created_at","updated_at") VALUES ($1,$2,$3,$4,$5) `+
+			`ON CONFLICT ("code") DO UPDATE SET "price"=excluded.price,"category_id"=excluded.category_id,`+
+			`"updated_at"=CASE WHEN products.price IS DISTINCT FROM excluded.price OR products.category_id IS DISTINCT FROM excluded.category_id `+
+			`THEN excluded.updated_at ELSE products.updated_at END RETURNING "id"`)).
+			WithArgs("PROD009", decimal.RequireFromString("49.90"), 4, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "product_variants" ("product_id","name","sku","price","created_at","updated_at") VALUES ($1,$2,$3,$4,$5,$6) `+
+			`ON CONFLICT ("sku") DO UPDATE SET "product_id"=excluded.product_id,"name"=excluded.name,"price"=excluded.price,`)).
+			WithArgs(9, "Small", "SKU009A", decimal.Zero, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+		testsupport.ExpectEvent(mock, events.SnapshotImported, "")
+		mock.ExpectCommit()
+	}
+
+	t.Run("first import creates everything", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectUpsert(mock, false)
+
+		summary, err := NewGormRepo(db).UpsertSnapshot(context.Background(), snapshot())
+
+		require.NoError(t, err)
+		created := UpsertCounts{Created: 1}
+		assert.Equal(t, SnapshotSummary{Categories: created, Products: created, Variants: created}, summary)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("re-import updates everything", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectUpsert(mock, true)
+
+		summary, err := NewGormRepo(db).UpsertSnapshot(context.Background(), snapshot())
+
+		require.NoError(t, err)
+		updated := UpsertCounts{Updated: 1}
+		assert.Equal(t, SnapshotSummary{Categories: updated, Products: updated, Variants: updated}, summary)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "code" FROM "products" WHERE code IN ($1)`)).
+			WillReturnRows(sqlmock.NewRows([]string{"code"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "sku" FROM "product_variants" WHERE sku IN ($1)`)).
+			WillReturnRows(sqlmock.NewRows([]string{"sku"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","code" FROM "categories" WHERE code IN ($1)`)).
+			WithArgs("bags").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code"}))
+		mock.ExpectRollback()
+
+		snapshot := snapshot()
+		snapshot.Categories = nil
+		_, err := NewGormRepo(db).UpsertSnapshot(context.Background(), snapshot)
+
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+		assert.Equal(t, errs.Invalid, errs.KindOf(err))
+		assert.EqualError(t, err, "category not found: bags")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_DeleteVariant(t *testing.T) {
+	query := regexp.QuoteMeta(`DELETE FROM "product_variants" WHERE product_id = (SELECT id FROM products WHERE code = $1) AND sku = $2 RETURNING "product_id"`)
+	touch := regexp.QuoteMeta(`UPDATE "products" SET "updated_at"=$1 WHERE id = $2`)
+
+	t.Run("deletes only the matching variant and touches the product", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(query).WithArgs("PROD001", "SKU001A").
+			WillReturnRows(sqlmock.NewRows([]string{"product_id"}).AddRow(1))
+		mock.ExpectExec(touch).WithArgs(sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		testsupport.ExpectEvent(mock, events.ProductUpdated, "PROD001")
+		mock.ExpectCommit()
+
+		err := NewGormRepo(db).DeleteVariant(context.Background(), "PROD001", "SKU001A")
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found when no variant of the product matches", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(query).WithArgs("PROD001", "SKU002A").
+			WillReturnRows(sqlmock.NewRows([]string{"product_id"}))
+		mock.ExpectRollback()
+
+		err := NewGormRepo(db).DeleteVariant(context.Background(), "PROD001", "SKU002A")
+
+		assert.ErrorIs(t, err, ErrVariantNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// available matches the availability window List adds to the conditions,
+// alone or, in parentheses, after the others.
+var (
+	available    = regexp.QuoteMeta(AvailableNow)
+	andAvailable = regexp.QuoteMeta(" AND (" + AvailableNow + ")")
+)
+
+func TestGormRepo_List(t *testing.T) {
+	variantExists := regexp.QuoteMeta(`EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND LOWER(product_variants.name) = LOWER($1))`)
+	skuExists := regexp.QuoteMeta(`EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.sku LIKE $1)`)
+
+	t.Run("variant name keeps all preloaded variants", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" WHERE \(` + variantExists + `\)` + andAvailable).
+			WithArgs("medium").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE \(`+variantExists+`\)`+andAvailable+` ORDER BY products.id LIMIT \$2`).
+			WithArgs("medium", 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}).AddRow(1, "PROD001", "10.99", nil))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" = $1 AND NOT EXISTS`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" = $1`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku", "price"}).
+				AddRow(1, 1, "Small", "SKU001A", nil).
+				AddRow(2, 1, "Medium", "SKU001B", nil))
+
+		res, total, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, Variant: "medium"})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, res, 1)
+		assert.Equal(t, "PROD001", res[0].Code)
+		assert.Len(t, res[0].Variants, 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("sku prefix without matches", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" WHERE \(` + skuExists + `\)` + andAvailable).
+			WithArgs("SKU9%").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE \(`+skuExists+`\)`+andAvailable).
+			WithArgs("SKU9%", 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}))
+
+		res, total, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, SKUPrefix: "SKU9"})
+
+		require.NoError(t, err)
+		assert.Zero(t, total)
+		assert.Empty(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("exact price", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" WHERE products.price = \$1` + andAvailable).
+			WithArgs(decimal.RequireFromString("99")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE products.price = \$1`+andAvailable+` ORDER BY products.id LIMIT \$2`).
+			WithArgs(decimal.RequireFromString("99"), 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}))
+
+		price := decimal.RequireFromString("99")
+		res, total, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, PriceEquals: &price})
+
+		require.NoError(t, err)
+		assert.Zero(t, total)
+		assert.Empty(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("price applied to variants", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		where := regexp.QuoteMeta(`WHERE (EXISTS (SELECT 1 FROM product_variants LEFT JOIN categories ON categories.id = products.category_id `+
+			`WHERE product_variants.product_id = products.id AND `+
+			`COALESCE(NULLIF(product_variants.price, 0), products.price * (100 + COALESCE(categories.markup_percent, 0)) / 100) < $1))`) + andAvailable
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" ` + where).
+			WithArgs(decimal.RequireFromString("10")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT \* FROM "products" `+where+` ORDER BY products.id LIMIT \$2`).
+			WithArgs(decimal.RequireFromString("10"), 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}))
+
+		price := decimal.RequireFromString("10")
+		_, _, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, PriceLessThan: &price, VariantPrices: true})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("variant combined with category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		where := regexp.QuoteMeta(`WHERE products.category_id IN (SELECT id FROM categories WHERE code = $1) AND (EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND LOWER(product_variants.name) = LOWER($2)))`) + andAvailable
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" `+where).
+			WithArgs("shoes", "variant a").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`SELECT \* FROM "products" `+where+` ORDER BY products.id LIMIT \$3 OFFSET \$4`).
+			WithArgs("shoes", "variant a", 5, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}).AddRow(2, "PROD002", "12.49", 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "categories" WHERE "categories"."id" = $1`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(2, "shoes", "Shoes"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "category_translations" WHERE "category_translations"."category_id" = $1`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" = $1 AND NOT EXISTS`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" = $1`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku", "price"}).AddRow(4, 2, "Variant A", "SKU002A", nil))
+
+		res, total, err := NewGormRepo(db).List(context.Background(), SearchFilters{Offset: 5, Limit: 5, Category: "shoes", Variant: "variant a"})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, res, 1)
+		assert.Equal(t, "shoes", res[0].Category.Code)
+		assert.Len(t, res[0].Variants, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_GetByCode(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT * FROM "products" WHERE code = $1`) + andAvailable + regexp.QuoteMeta(` ORDER BY "products"."id" LIMIT $2`)
+
+	t.Run("preloads category with translations, ordered images and variants with timestamps", func(t *testing.T) {
+		updatedAt := time.Date(2025, 6, 1, 8, 30, 0, 0, time.UTC)
+
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(query).
+			WithArgs("PROD001", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id", "updated_at"}).AddRow(1, "PROD001", "10.99", 1, updatedAt))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "categories" WHERE "categories"."id" = $1`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(1, "clothing", "Clothing"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "category_translations" WHERE "category_translations"."category_id" = $1`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}).AddRow(1, "de", "Kleidung"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" = $1 ORDER BY position, id`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}).
+				AddRow(7, 1, "https://cdn.example.com/prod001-back.jpg", 1).
+				AddRow(3, 1, "https://cdn.example.com/prod001-front.jpg", 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" = $1`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku", "price", "updated_at"}).
+				AddRow(1, 1, "Variant A", "SKU001A", "11.99", updatedAt.Add(time.Hour)))
+
+		product, err := NewGormRepo(db).GetByCode(context.Background(), "PROD001")
+
+		require.NoError(t, err)
+		assert.Equal(t, "PROD001", product.Code)
+		assert.Equal(t, updatedAt, product.UpdatedAt)
+		assert.Equal(t, "clothing", product.Category.Code)
+		assert.Equal(t, "Kleidung", product.Category.LocalizedName("de"))
+		require.Len(t, product.Images, 2)
+		assert.Equal(t, uint(7), product.Images[0].ID)
+		assert.Equal(t, uint(3), product.Images[1].ID)
+		require.Len(t, product.Variants, 1)
+		assert.Equal(t, updatedAt.Add(time.Hour), product.Variants[0].UpdatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(query).
+			WithArgs("PROD999", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+		_, err := NewGormRepo(db).GetByCode(context.Background(), "PROD999")
+
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.Equal(t, errs.NotFound, errs.KindOf(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("including the unavailable products", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "products" WHERE code = $1 ORDER BY "products"."id" LIMIT $2`)).
+			WithArgs("PROD001", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}).AddRow(1, "PROD001", "10.99"))
+		mock.ExpectQuery(`FROM "product_images"`).WillReturnRows(sqlmock.NewRows([]string{"id", "product_id"}))
+		mock.ExpectQuery(`FROM "product_variants"`).WillReturnRows(sqlmock.NewRows([]string{"id", "product_id"}))
+
+		product, err := NewGormRepo(db).GetByCodeIncludingUnavailable(context.Background(), "PROD001")
+
+		require.NoError(t, err)
+		assert.Equal(t, "PROD001", product.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_Create(t *testing.T) {
+	categoryQuery := regexp.QuoteMeta(`SELECT * FROM "categories" WHERE code = $1 ORDER BY "categories"."id" LIMIT $2`)
+	productInsert := regexp.QuoteMeta(`INSERT INTO "products"`)
+	variantInsert := regexp.QuoteMeta(`INSERT INTO "product_variants"`)
+
+	newProduct := func() *models.Product {
+		return &models.Product{
+			Code:     "PROD009",
+			Price:    decimal.RequireFromString("19.99"),
+			Category: &models.Category{Code: "shoes"},
+			Variants: []models.Variant{
+				{Name: "Variant A", SKU: "SKU009A"},
+				{Name: "Variant B", SKU: "SKU009B"},
+			},
+		}
+	}
+
+	t.Run("inserts product and variants in one transaction", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(categoryQuery).
+			WithArgs("shoes", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(2, "shoes", "Shoes"))
+		mock.ExpectQuery(productInsert).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(variantInsert).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30).AddRow(31))
+		testsupport.ExpectEvent(mock, events.ProductCreated, "PROD009")
+		mock.ExpectCommit()
+
+		product := newProduct()
+		err := NewGormRepo(db).Create(context.Background(), product)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint(9), product.ID)
+		assert.Equal(t, uint(2), *product.CategoryID)
+		assert.Equal(t, "Shoes", product.Category.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate sku rolls back the product", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(categoryQuery).
+			WithArgs("shoes", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(2, "shoes", "Shoes"))
+		mock.ExpectQuery(productInsert).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(variantInsert).
+			WillReturnError(&pgconn.PgError{Code: "23505"})
+		mock.ExpectRollback()
+
+		err := NewGormRepo(db).Create(context.Background(), newProduct())
+
+		assert.ErrorIs(t, err, ErrProductExists)
+		assert.Equal(t, errs.Conflict, errs.KindOf(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(categoryQuery).
+			WithArgs("shoes", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}))
+		mock.ExpectRollback()
+
+		err := NewGormRepo(db).Create(context.Background(), newProduct())
+
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+		assert.Equal(t, errs.Invalid, errs.KindOf(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// The event is recorded last: whatever fails after it rolls it back with
+	// the product, and a failed event insert rolls back the product.
+	expectInserts := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(categoryQuery).
+			WithArgs("shoes", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(2, "shoes", "Shoes"))
+		mock.ExpectQuery(productInsert).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(variantInsert).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30).AddRow(31))
+	}
+
+	t.Run("a failed commit fails the creation", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectInserts(mock)
+		testsupport.ExpectEvent(mock, events.ProductCreated, "PROD009")
+		mock.ExpectCommit().WillReturnError(errors.New("connection reset"))
+
+		err := NewGormRepo(db).Create(context.Background(), newProduct())
+
+		assert.EqualError(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed event insert rolls back the product", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectInserts(mock)
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "events"`)).
+			WillReturnError(errors.New("disk full"))
+		mock.ExpectRollback()
+
+		err := NewGormRepo(db).Create(context.Background(), newProduct())
+
+		assert.EqualError(t, err, "disk full")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func productRows(from, to int) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "code", "price"})
+	for id := from; id <= to; id++ {
+		rows.AddRow(id, fmt.Sprintf("PROD%03d", id), "10.00")
+	}
+	return rows
+}
+
+func TestGormRepo_ListAllFunc(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "products" WHERE `) + available + regexp.QuoteMeta(` ORDER BY "products"."id" LIMIT $1`)).
+		WithArgs(2).
+		WillReturnRows(productRows(1, 2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" IN ($1,$2)`)).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}).AddRow(1, 1, "Variant A", "SKU001A"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "products" WHERE "products"."id" > $1`)+andAvailable+regexp.QuoteMeta(` ORDER BY "products"."id" LIMIT $2`)).
+		WithArgs(2, 2).
+		WillReturnRows(productRows(3, 3))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" = $1`)).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}))
+
+	repo := NewGormRepo(db)
+	repo.batchSize = 2
+
+	var batches [][]string
+	err := repo.ListAllFunc(context.Background(), "", func(products []models.Product) error {
+		var codes []string
+		for _, p := range products {
+			codes = append(codes, p.Code)
+		}
+		batches = append(batches, codes)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"PROD001", "PROD002"}, {"PROD003"}}, batches)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_ListAllFunc_Category(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "products" WHERE products.category_id IN (SELECT id FROM categories WHERE code = $1)`)+andAvailable+regexp.QuoteMeta(` ORDER BY "products"."id" LIMIT $2`)).
+		WithArgs("shoes", defaultBatchSize).
+		WillReturnRows(productRows(2, 2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" = $1`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}))
+
+	var codes []string
+	err := NewGormRepo(db).ListAllFunc(context.Background(), "shoes", func(products []models.Product) error {
+		codes = append(codes, productCodes(products)...)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD002"}, codes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_ListAll(t *testing.T) {
+	t.Run("collects every batch", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE ` + available + ` ORDER BY`).WillReturnRows(productRows(1, 2))
+		mock.ExpectQuery(`SELECT \* FROM "product_variants"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE "products"."id" > \$1`).WillReturnRows(productRows(3, 3))
+		mock.ExpectQuery(`SELECT \* FROM "product_variants"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		repo := NewGormRepo(db)
+		repo.batchSize = 2
+
+		res, err := repo.ListAll(context.Background())
+
+		require.NoError(t, err)
+		assert.Len(t, res, 3)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("caps large catalogs", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE ` + available + ` ORDER BY`).WillReturnRows(productRows(1, 600))
+		mock.ExpectQuery(`SELECT \* FROM "product_variants"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE "products"."id" > \$1`).WillReturnRows(productRows(601, 1200))
+		mock.ExpectQuery(`SELECT \* FROM "product_variants"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		repo := NewGormRepo(db)
+		repo.batchSize = 600
+
+		res, err := repo.ListAll(context.Background())
+
+		require.NoError(t, err)
+		assert.Len(t, res, maxListAll)
+		assert.Equal(t, "PROD1000", res[len(res)-1].Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_List_HasVariants(t *testing.T) {
+	exists := regexp.QuoteMeta(`EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id)`)
+	yes, no := true, false
+
+	tests := []struct {
+		name        string
+		hasVariants *bool
+		where       string
+	}{
+		{"with variants", &yes, `SELECT \* FROM "products" WHERE ` + exists + andAvailable + ` ORDER BY`},
+		{"without variants", &no, `SELECT \* FROM "products" WHERE NOT ` + exists + andAvailable + ` ORDER BY`},
+		{"absent", nil, `SELECT \* FROM "products" WHERE ` + available + ` ORDER BY`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := testsupport.MockDB(t)
+			mock.ExpectQuery(`SELECT count\(\*\) FROM "products"`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(tt.where).
+				WithArgs(10).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+			_, _, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, HasVariants: tt.hasVariants})
+
+			require.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGormRepo_List_ModifiedSince(t *testing.T) {
+	cutoff := time.Date(2025, 6, 1, 8, 30, 0, 0, time.UTC)
+	where := regexp.QuoteMeta(`WHERE products.updated_at > $1`) + andAvailable
+
+	t.Run("products updated after the cutoff, oldest first", func(t *testing.T) {
+		// PROD001 was last updated before the cutoff and is left out by the
+		// database, the others come back in update order
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" ` + where).
+			WithArgs(cutoff).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery(`SELECT \* FROM "products" `+where+regexp.QuoteMeta(` ORDER BY products.updated_at, products.id LIMIT $2`)).
+			WithArgs(cutoff, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "updated_at"}).
+				AddRow(3, "PROD003", "8.00", cutoff.Add(time.Minute)).
+				AddRow(2, "PROD002", "12.49", cutoff.Add(time.Hour)))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" IN ($1,$2) AND NOT EXISTS`)).
+			WithArgs(3, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" IN ($1,$2)`)).
+			WithArgs(3, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}))
+
+		res, total, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, ModifiedSince: &cutoff})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		require.Len(t, res, 2)
+		assert.Equal(t, "PROD003", res[0].Code)
+		assert.Equal(t, "PROD002", res[1].Code)
+		for _, p := range res {
+			assert.True(t, p.UpdatedAt.After(cutoff))
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("explicit sort wins", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" ` + where).
+			WithArgs(cutoff).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT \* FROM "products" `+where+regexp.QuoteMeta(` ORDER BY products.price, products.id LIMIT $2`)).
+			WithArgs(cutoff, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+		_, _, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, ModifiedSince: &cutoff, Sort: SortPriceAsc})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_ListByCategories(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	where := regexp.QuoteMeta(`WHERE products.category_id IN (SELECT id FROM categories WHERE code = $1)`) + andAvailable
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "products" ` + where).
+		WithArgs("shoes").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT \* FROM "products" `+where+` ORDER BY products.id LIMIT \$2`).
+		WithArgs("shoes", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}).AddRow(2, "PROD002", "12.49", nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" = $1 AND NOT EXISTS`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" = $1`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}).AddRow(3, 2, "Variant A", "SKU002A"))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "products" ` + where).
+		WithArgs("bags").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT \* FROM "products" `+where+` ORDER BY products.id LIMIT \$2`).
+		WithArgs("bags", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}))
+
+	pages, err := NewGormRepo(db).ListByCategories(context.Background(), []string{"shoes", "bags"}, 1)
+
+	require.NoError(t, err)
+	require.Len(t, pages["shoes"].Products, 1)
+	assert.Equal(t, "PROD002", pages["shoes"].Products[0].Code)
+	assert.Len(t, pages["shoes"].Products[0].Variants, 1)
+	assert.Equal(t, int64(2), pages["shoes"].Total)
+	assert.Empty(t, pages["bags"].Products)
+	assert.Zero(t, pages["bags"].Total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_FindExisting(t *testing.T) {
+	t.Run("reads each kind of key without a transaction", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "code" FROM "products" WHERE code IN ($1,$2)`)).
+			WithArgs("PROD001", "PROD009").
+			WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("PROD001"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "sku" FROM "product_variants" WHERE sku IN ($1)`)).
+			WithArgs("SKU009A").
+			WillReturnRows(sqlmock.NewRows([]string{"sku"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "code" FROM "categories" WHERE code IN ($1)`)).
+			WithArgs("shoes").
+			WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("shoes"))
+
+		found, err := NewGormRepo(db).FindExisting(context.Background(), ExistingKeys{
+			Codes:      []string{"PROD001", "PROD009"},
+			SKUs:       []string{"SKU009A"},
+			Categories: []string{"shoes"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"PROD001"}, found.Codes)
+		assert.Empty(t, found.SKUs)
+		assert.Equal(t, []string{"shoes"}, found.Categories)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("skips the kinds without keys", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "code" FROM "products" WHERE code IN ($1)`)).
+			WithArgs("PROD009").
+			WillReturnRows(sqlmock.NewRows([]string{"code"}))
+
+		found, err := NewGormRepo(db).FindExisting(context.Background(), ExistingKeys{Codes: []string{"PROD009"}})
+
+		require.NoError(t, err)
+		assert.Empty(t, found.Codes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_Exists(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM products WHERE code = $1 AND ` + AvailableNow + `)`)
+
+	for _, want := range []bool{true, false} {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(query).
+			WithArgs("PROD001").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(want))
+
+		exists, err := NewGormRepo(db).Exists(context.Background(), "PROD001")
+
+		require.NoError(t, err)
+		assert.Equal(t, want, exists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+// BenchmarkGormRepo_Exists compares the existence check to loading the
+// product with GetByCode, which takes one query per preload on top.
+func BenchmarkGormRepo_Exists(b *testing.B) {
+	ctx := context.Background()
+
+	b.Run("Exists", func(b *testing.B) {
+		db, mock := testsupport.MockDB(b)
+		repo := NewGormRepo(db)
+		for b.Loop() {
+			mock.ExpectQuery(`SELECT EXISTS`).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			if _, err := repo.Exists(ctx, "PROD001"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("GetByCode", func(b *testing.B) {
+		db, mock := testsupport.MockDB(b)
+		repo := NewGormRepo(db)
+		for b.Loop() {
+			mock.ExpectQuery(`FROM "products"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}).AddRow(1, "PROD001", "10.99", 1))
+			mock.ExpectQuery(`FROM "categories"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(1, "clothing", "Clothing"))
+			mock.ExpectQuery(`FROM "category_translations"`).
+				WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}))
+			mock.ExpectQuery(`FROM "product_images"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}))
+			mock.ExpectQuery(`FROM "product_variants"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku", "price"}).
+					AddRow(1, 1, "Variant A", "SKU001A", "11.99").
+					AddRow(2, 1, "Variant B", "SKU001B", "12.99"))
+			if _, err := repo.GetByCode(ctx, "PROD001"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestGormRepo_GetByCodes(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "products" WHERE code IN ($1,$2,$3)`)+andAvailable).
+		WithArgs("PROD003", "PROD009", "PROD001").
+		WillReturnRows(productRows(1, 1).AddRow(3, "PROD003", "8.75"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" IN ($1,$2) AND NOT EXISTS`)).
+		WithArgs(1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" IN ($1,$2)`)).
+		WithArgs(1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}).AddRow(1, 3, "Variant A", "SKU003A"))
+
+	res, err := NewGormRepo(db).GetByCodes(context.Background(), []string{"PROD003", "PROD009", "PROD001"})
+
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "PROD001", res[0].Code)
+	assert.Equal(t, "PROD003", res[1].Code)
+	assert.Len(t, res[1].Variants, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_ListChangedSince(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	since := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "products" WHERE (products.updated_at, products.id) > ($1, $2) ORDER BY products.updated_at, products.id LIMIT $3`)).
+		WithArgs(since, 7, 2).
+		WillReturnRows(productRows(8, 9))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" IN ($1,$2) ORDER BY position, id`)).
+		WithArgs(8, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" IN ($1,$2)`)).
+		WithArgs(8, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}))
+
+	res, err := NewGormRepo(db).ListChangedSince(context.Background(), ChangePosition{UpdatedAt: since, ID: 7}, 2)
+
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "PROD008", res[0].Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_ListVariants(t *testing.T) {
+	from := regexp.QuoteMeta(`FROM "product_variants" JOIN products ON products.id = product_variants.product_id ` +
+		`LEFT JOIN categories ON categories.id = products.category_id WHERE `)
+	columns := regexp.QuoteMeta(`SELECT product_variants.sku, product_variants.name, ` + VariantPrice + ` AS price, ` +
+		`products.code AS product_code, COALESCE(categories.code, '') AS category_code `)
+
+	t.Run("filters on the resolved price", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		price := decimal.NewFromInt(15)
+		where := regexp.QuoteMeta(`(` + AvailableNow + `) AND categories.code = $1 AND ` + VariantPrice + ` < $2`)
+		mock.ExpectQuery(`SELECT count\(\*\) `+from+where).
+			WithArgs("shoes", price).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+		mock.ExpectQuery(columns+from+where+regexp.QuoteMeta(` ORDER BY product_variants.id LIMIT $3 OFFSET $4`)).
+			WithArgs("shoes", price, 2, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"sku", "name", "price", "product_code", "category_code"}).
+				AddRow("SKU002A", "Variant A", "12.49", "PROD002", "shoes").
+				AddRow("SKU006A", "Variant A", "14.3", "PROD006", "shoes"))
+
+		res, total, err := NewGormRepo(db).ListVariants(context.Background(), VariantFilters{
+			Offset: 10, Limit: 2, Category: "shoes", PriceLessThan: &price,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), total)
+		assert.Equal(t, []ResolvedVariant{
+			{SKU: "SKU002A", Name: "Variant A", Price: decimal.RequireFromString("12.49"), ProductCode: "PROD002", CategoryCode: "shoes"},
+			{SKU: "SKU006A", Name: "Variant A", Price: decimal.RequireFromString("14.3"), ProductCode: "PROD006", CategoryCode: "shoes"},
+		}, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no variants", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		where := regexp.QuoteMeta(AvailableNow)
+		mock.ExpectQuery(`SELECT count\(\*\) ` + from + where + `$`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(columns + from + where + regexp.QuoteMeta(` ORDER BY product_variants.id LIMIT $1`)).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"sku", "name", "price", "product_code", "category_code"}))
+
+		res, total, err := NewGormRepo(db).ListVariants(context.Background(), VariantFilters{Limit: 10})
+
+		require.NoError(t, err)
+		assert.Zero(t, total)
+		assert.NotNil(t, res)
+		assert.Empty(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_GetRelated(t *testing.T) {
+	lookup := regexp.QuoteMeta(`SELECT "id","category_id" FROM "products" WHERE code = $1 ORDER BY "products"."id" LIMIT $2`)
+
+	t.Run("other products of the category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(lookup).
+			WithArgs("PROD001", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "category_id"}).AddRow(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "products" WHERE (products.category_id = $1 AND products.id <> $2)`)+andAvailable+regexp.QuoteMeta(` ORDER BY products.id LIMIT $3`)).
+			WithArgs(1, 1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}).
+				AddRow(4, "PROD004", "15.00", 1).
+				AddRow(7, "PROD007", "21.00", 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "categories" WHERE "categories"."id" = $1`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(1, "clothing", "Clothing"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "category_translations" WHERE "category_translations"."category_id" = $1`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" IN ($1,$2)`)).
+			WithArgs(4, 7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" IN ($1,$2)`)).
+			WithArgs(4, 7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}))
+
+		related, err := NewGormRepo(db).GetRelated(context.Background(), "PROD001", 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"PROD004", "PROD007"}, productCodes(related))
+		assert.Equal(t, "clothing", related[0].Category.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("product without category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(lookup).
+			WithArgs("PROD009", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "category_id"}).AddRow(9, nil))
+
+		related, err := NewGormRepo(db).GetRelated(context.Background(), "PROD009", 4)
+
+		require.NoError(t, err)
+		assert.NotNil(t, related)
+		assert.Empty(t, related)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(lookup).
+			WithArgs("PROD999", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "category_id"}))
+
+		_, err := NewGormRepo(db).GetRelated(context.Background(), "PROD999", 4)
+
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_GetByID(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT * FROM "products" WHERE "products"."id" = $1 ORDER BY "products"."id" LIMIT $2`)
+
+	t.Run("numeric id found", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(query).
+			WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}).AddRow(2, "PROD002", "12.49"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" = $1 ORDER BY position, id`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE "product_variants"."product_id" = $1`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}).AddRow(4, 2, "Variant A", "SKU002A"))
+
+		product, err := NewGormRepo(db).GetByID(context.Background(), "2")
+
+		require.NoError(t, err)
+		assert.Equal(t, "PROD002", product.Code)
+		assert.Len(t, product.Variants, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("numeric id missing", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(query).
+			WithArgs(999, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+		_, err := NewGormRepo(db).GetByID(context.Background(), "999")
+
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("non numeric id", func(t *testing.T) {
+		for _, id := range []string{"PROD001", "", "-1", "0", "1.5", "1 OR 1=1"} {
+			db, mock := testsupport.MockDB(t)
+
+			_, err := NewGormRepo(db).GetByID(context.Background(), id)
+
+			assert.ErrorIs(t, err, ErrInvalidProductID, id)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+}
+
+func TestGormRepo_List_Sort(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultSort string
+		sort        string
+		order       string
+	}{
+		{"featured by default", "", "", "products.id"},
+		{"newest default", SortNewest, "", "products.created_at DESC, products.id"},
+		{"cheapest default", SortPriceAsc, "", "products.price, products.id"},
+		{"most expensive default", SortPriceDesc, "", "products.price DESC, products.id"},
+		{"request sort overrides the default", SortNewest, SortPriceAsc, "products.price, products.id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := testsupport.MockDB(t)
+			mock.ExpectQuery(`SELECT count\(\*\) FROM "products"`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(`SELECT \* FROM "products" WHERE ` + available + regexp.QuoteMeta(` ORDER BY `+tt.order+` LIMIT $1`)).
+				WithArgs(10).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+			repo := NewGormRepo(db)
+			require.NoError(t, repo.SetDefaultSort(tt.defaultSort))
+
+			_, _, err := repo.List(context.Background(), SearchFilters{Limit: 10, Sort: tt.sort})
+
+			require.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGormRepo_List_MissingVariants(t *testing.T) {
+	expectPage := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products"`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE ` + available + ` ORDER BY products.id LIMIT \$1`).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}).AddRow(1, "PROD001", "10.99", nil))
+	}
+	expectImages := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT \* FROM "product_images"`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id"}))
+	}
+	missingTable := &pgconn.PgError{Code: "42P01", Message: `relation "product_variants" does not exist`}
+
+	t.Run("lists again without variants when allowed", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectPage(mock)
+		expectImages(mock)
+		mock.ExpectQuery(`SELECT \* FROM "product_variants"`).WithArgs(1).WillReturnError(missingTable)
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE ` + available + ` ORDER BY products.id LIMIT \$1`).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}).AddRow(1, "PROD001", "10.99", nil))
+		expectImages(mock)
+
+		res, total, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, AllowMissingVariants: true})
+
+		assert.ErrorIs(t, err, ErrVariantsUnavailable)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, res, 1)
+		assert.Equal(t, "PROD001", res[0].Code)
+		assert.Nil(t, res[0].Variants)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails unless allowed", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectPage(mock)
+		expectImages(mock)
+		mock.ExpectQuery(`SELECT \* FROM "product_variants"`).WithArgs(1).WillReturnError(missingTable)
+
+		_, _, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10})
+
+		assert.ErrorAs(t, err, new(*pgconn.PgError))
+		assert.NotErrorIs(t, err, ErrVariantsUnavailable)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails on other errors", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectPage(mock)
+		expectImages(mock)
+		mock.ExpectQuery(`SELECT \* FROM "product_variants"`).WithArgs(1).WillReturnError(errors.New("connection reset"))
+
+		_, _, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, AllowMissingVariants: true})
+
+		assert.EqualError(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_List_Tiebreaker(t *testing.T) {
+	since := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	filters := map[string]SearchFilters{
+		"relevance": {Query: "prod", Sort: SortRelevance},
+		"modified":  {ModifiedSince: &since},
+	}
+	// Every sort List accepts, including those added later
+	for sort := range sortOrders {
+		filters[sort] = SearchFilters{Sort: sort}
+	}
+
+	for name, f := range filters {
+		t.Run(name, func(t *testing.T) {
+			db, mock := testsupport.MockDB(t)
+			mock.ExpectQuery(`SELECT count\(\*\) FROM "products"`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(`SELECT \* FROM "products" .*ORDER BY (.+, )?products\.id LIMIT \$\d+$`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+			f.Limit = 10
+			_, _, err := NewGormRepo(db).List(context.Background(), f)
+
+			require.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGormRepo_List_Query(t *testing.T) {
+	where := regexp.QuoteMeta(`WHERE ((LOWER(products.code) LIKE $1 OR products.category_id IN (SELECT id FROM categories WHERE LOWER(name) LIKE $2)))`) + andAvailable
+	relevance := regexp.QuoteMeta(`ORDER BY CASE WHEN LOWER(products.code) = $3 THEN 3 WHEN LOWER(products.code) LIKE $4 THEN 2 ` +
+		`WHEN products.category_id IN (SELECT id FROM categories WHERE LOWER(name) LIKE $5) THEN 1 ELSE 0 END DESC, products.id LIMIT $6`)
+
+	t.Run("ranks the matches by relevance", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" `+where).
+			WithArgs("prod\\_1%", "%prod\\_1%").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT \* FROM "products" `+where+` `+relevance).
+			WithArgs("prod\\_1%", "%prod\\_1%", "prod_1", "prod\\_1%", "%prod\\_1%", 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+		_, _, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, Query: "PROD_1"})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("explicit sort overrides the relevance", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" ` + where).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT \* FROM "products" ` + where + regexp.QuoteMeta(` ORDER BY products.price, products.id LIMIT $3`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+		_, _, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, Query: "shoes", Sort: SortPriceAsc})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_List_Availability(t *testing.T) {
+	t.Run("only products within their window by default", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products" WHERE ` + available + `$`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT \* FROM "products" WHERE ` + available + ` ORDER BY products.id LIMIT \$1`).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+		_, _, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("every product when including the unavailable ones", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "products"$`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT \* FROM "products" ORDER BY products.id LIMIT \$1`).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}))
+
+		_, _, err := NewGormRepo(db).List(context.Background(), SearchFilters{Limit: 10, IncludeUnavailable: true})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_SetDefaultSort(t *testing.T) {
+	repo := NewGormRepo(nil)
+
+	err := repo.SetDefaultSort("popularity")
+
+	assert.EqualError(t, err, `unsupported sort "popularity"`)
+	assert.Equal(t, SortFeatured, repo.defaultSort)
+}
+
+func TestGormRepo_FindOrphanVariants(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants" WHERE NOT EXISTS (SELECT 1 FROM products WHERE products.id = product_variants.product_id) ORDER BY id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "sku"}).AddRow(4, 99, "Variant A", "SKU099A"))
+
+	variants, err := NewGormRepo(db).FindOrphanVariants(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	assert.Equal(t, "SKU099A", variants[0].SKU)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_DeleteOrphanVariants(t *testing.T) {
+	query := regexp.QuoteMeta(`DELETE FROM product_variants WHERE id IN (SELECT id FROM product_variants WHERE NOT EXISTS (SELECT 1 FROM products WHERE products.id = product_variants.product_id) LIMIT $1)`)
+
+	t.Run("deletes in batches until one is not full", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		for _, n := range []int64{2, 2, 1} {
+			mock.ExpectExec(query).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, n))
+		}
+		repo := NewGormRepo(db)
+		repo.batchSize = 2
+
+		deleted, err := repo.DeleteOrphanVariants(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no orphans", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectExec(query).WithArgs(defaultBatchSize).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		deleted, err := NewGormRepo(db).DeleteOrphanVariants(context.Background())
+
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("counts the batches deleted before a failure", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectExec(query).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(query).WithArgs(2).WillReturnError(fmt.Errorf("lock timeout"))
+		repo := NewGormRepo(db)
+		repo.batchSize = 2
+
+		deleted, err := repo.DeleteOrphanVariants(context.Background())
+
+		assert.EqualError(t, err, "lock timeout")
+		assert.Equal(t, int64(2), deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_PriceStats(t *testing.T) {
+	lookup := regexp.QuoteMeta(`SELECT "id" FROM "categories" WHERE code = $1 ORDER BY "categories"."id" LIMIT $2`)
+	aggregate := regexp.QuoteMeta(`SELECT MIN(price) AS min, MAX(price) AS max, AVG(price) AS avg, COUNT(*) AS count FROM (SELECT price FROM products WHERE category_id = $1 AND ` + AvailableNow + `) AS prices`)
+	columns := []string{"min", "max", "avg", "count"}
+
+	t.Run("populated category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(lookup).
+			WithArgs("clothing", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(aggregate).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("10.99", "18.20", "14.7266666666666667", 3))
+
+		stats, err := NewGormRepo(db).PriceStats(context.Background(), "clothing")
+
+		require.NoError(t, err)
+		assert.Equal(t, "10.99", stats.Min.Decimal.String())
+		assert.Equal(t, "18.2", stats.Max.Decimal.String())
+		assert.Equal(t, "14.73", stats.Avg.Decimal.String())
+		assert.Equal(t, int64(3), stats.Count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(lookup).
+			WithArgs("bags", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectQuery(aggregate).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(nil, nil, nil, 0))
+
+		stats, err := NewGormRepo(db).PriceStats(context.Background(), "bags")
+
+		require.NoError(t, err)
+		assert.False(t, stats.Avg.Valid)
+		assert.Zero(t, stats.Count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectQuery(lookup).
+			WithArgs("unknown", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := NewGormRepo(db).PriceStats(context.Background(), "unknown")
+
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_AdjustPrices(t *testing.T) {
+	lookup := regexp.QuoteMeta(`SELECT "id" FROM "categories" WHERE code = $1 ORDER BY "categories"."id" LIMIT $2`)
+	// The new prices rounded half to even, the default pricing mode, with
+	// their argument from the placeholder n
+	halfEven := func(n int) string {
+		return fmt.Sprintf(`CASE WHEN ABS(MOD((price * $%d) * 100, 2)) = 0.5 THEN TRUNC(price * $%d, 2) ELSE ROUND(price * $%d, 2) END`, n, n+1, n+2)
+	}
+	halfEvenSum := func(n int) string {
+		return fmt.Sprintf(`CASE WHEN ABS(MOD((price + $%d) * 100, 2)) = 0.5 THEN TRUNC(price + $%d, 2) ELSE ROUND(price + $%d, 2) END`, n, n+1, n+2)
+	}
+
+	t.Run("percentage", func(t *testing.T) {
+		factor := decimal.RequireFromString("0.9")
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).
+			WithArgs("shoes", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "products" WHERE category_id = $1 AND `+halfEven(2)+` < 0`)).
+			WithArgs(2, factor, factor, factor).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "products" WHERE category_id = $1 AND `+halfEven(2)+` > $5`)).
+			WithArgs(2, factor, factor, factor, MaxStoredPrice).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "products" SET "price"=`+halfEven(1)+`,"updated_at"=$4 WHERE category_id = $5`)).
+			WithArgs(factor, factor, factor, sqlmock.AnyArg(), 2).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		testsupport.ExpectEvent(mock, events.CategoryPricesAdjusted, "shoes")
+		mock.ExpectCommit()
+
+		updated, err := NewGormRepo(db).AdjustPrices(context.Background(), "shoes", Adjustment{
+			Type:  AdjustPercentage,
+			Value: decimal.NewFromInt(-10),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("factor rounded with the pricing mode", func(t *testing.T) {
+		factor := decimal.RequireFromString("0.9")
+		for mode, newPrice := range map[pricing.Mode]string{pricing.HalfUp: "ROUND(price * $%d, 2)", pricing.Down: "TRUNC(price * $%d, 2)"} {
+			db, mock := testsupport.MockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(lookup).
+				WithArgs("shoes", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "products" WHERE category_id = $1 AND `+fmt.Sprintf(newPrice, 2)+` < 0`)).
+				WithArgs(2, factor).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "products" WHERE category_id = $1 AND `+fmt.Sprintf(newPrice, 2)+` > $3`)).
+				WithArgs(2, factor, MaxStoredPrice).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "products" SET "price"=`+fmt.Sprintf(newPrice, 1)+`,"updated_at"=$2 WHERE category_id = $3`)).
+				WithArgs(factor, sqlmock.AnyArg(), 2).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			testsupport.ExpectEvent(mock, events.CategoryPricesAdjusted, "shoes")
+			mock.ExpectCommit()
+
+			repo := NewGormRepo(db)
+			repo.SetRounding(mode)
+			updated, err := repo.AdjustPrices(context.Background(), "shoes", Adjustment{Type: AdjustFactor, Value: factor})
+
+			require.NoError(t, err, mode)
+			assert.Equal(t, int64(2), updated, mode)
+			assert.NoError(t, mock.ExpectationsWereMet(), mode)
+		}
+	})
+
+	t.Run("absolute", func(t *testing.T) {
+		amount := decimal.RequireFromString("2.50")
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).
+			WithArgs("shoes", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "products" WHERE category_id = $1 AND `+halfEvenSum(2)+` < 0`)).
+			WithArgs(2, amount, amount, amount).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "products" WHERE category_id = $1 AND `+halfEvenSum(2)+` > $5`)).
+			WithArgs(2, amount, amount, amount, MaxStoredPrice).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "products" SET "price"=`+halfEvenSum(1)+`,"updated_at"=$4 WHERE category_id = $5`)).
+			WithArgs(amount, amount, amount, sqlmock.AnyArg(), 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		testsupport.ExpectEvent(mock, events.CategoryPricesAdjusted, "shoes")
+		mock.ExpectCommit()
+
+		updated, err := NewGormRepo(db).AdjustPrices(context.Background(), "shoes", Adjustment{Type: AdjustAbsolute, Value: amount})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("negative prices roll back", func(t *testing.T) {
+		amount := decimal.NewFromInt(-50)
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).
+			WithArgs("shoes", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "products" WHERE category_id = $1 AND `+halfEvenSum(2)+` < 0`)).
+			WithArgs(2, amount, amount, amount).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+		mock.ExpectRollback()
+
+		updated, err := NewGormRepo(db).AdjustPrices(context.Background(), "shoes", Adjustment{Type: AdjustAbsolute, Value: amount})
+
+		var negErr *NegativePriceError
+		require.ErrorAs(t, err, &negErr)
+		assert.Equal(t, int64(4), negErr.Count)
+		assert.Zero(t, updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("prices over the column precision roll back", func(t *testing.T) {
+		factor := decimal.NewFromInt(1000000)
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).
+			WithArgs("shoes", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "products" WHERE category_id = $1 AND `+halfEven(2)+` < 0`)).
+			WithArgs(2, factor, factor, factor).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "products" WHERE category_id = $1 AND `+halfEven(2)+` > $5`)).
+			WithArgs(2, factor, factor, factor, MaxStoredPrice).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectRollback()
+
+		updated, err := NewGormRepo(db).AdjustPrices(context.Background(), "shoes", Adjustment{Type: AdjustFactor, Value: factor})
+
+		var overflowErr *PriceOverflowError
+		require.ErrorAs(t, err, &overflowErr)
+		assert.Equal(t, int64(1), overflowErr.Count)
+		assert.Zero(t, updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).
+			WithArgs("bags", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		_, err := NewGormRepo(db).AdjustPrices(context.Background(), "bags", Adjustment{Type: AdjustAbsolute, Value: decimal.NewFromInt(1)})
+
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+
+		_, err := NewGormRepo(db).AdjustPrices(context.Background(), "shoes", Adjustment{Type: "ratio", Value: decimal.NewFromInt(1)})
+
+		assert.EqualError(t, err, `unsupported adjustment type "ratio"`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_AddImage(t *testing.T) {
+	lookup := regexp.QuoteMeta(`SELECT "id" FROM "products" WHERE code = $1 ORDER BY "products"."id" LIMIT $2`)
+	count := regexp.QuoteMeta(`SELECT count(*) FROM "product_images" WHERE product_id = $1`)
+	insert := regexp.QuoteMeta(`INSERT INTO "product_images" ("product_id","url","position","alt_text","created_at") VALUES ($1,$2,$3,$4,$5) RETURNING "id"`)
+	touch := regexp.QuoteMeta(`UPDATE "products" SET "updated_at"=$1 WHERE id = $2`)
+
+	t.Run("inserting at a position shifts the following images", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD001", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(count).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "product_images" SET "position"=position + 1 WHERE product_id = $1 AND position >= $2`)).
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(insert).
+			WithArgs(1, "https://cdn.example.com/prod001.jpg", 2, "Front", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectExec(touch).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		testsupport.ExpectEvent(mock, events.ProductUpdated, "PROD001")
+		mock.ExpectCommit()
+
+		image := models.Image{URL: "https://cdn.example.com/prod001.jpg", Position: 2, AltText: "Front"}
+		err := NewGormRepo(db).AddImage(context.Background(), "PROD001", &image)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint(9), image.ID)
+		assert.Equal(t, 2, image.Position)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("positions out of range append the image", func(t *testing.T) {
+		for _, position := range []int{0, 4, 50} {
+			db, mock := testsupport.MockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(lookup).WithArgs("PROD001", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectQuery(count).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+			mock.ExpectQuery(insert).
+				WithArgs(1, "https://cdn.example.com/prod001.jpg", 4, "", sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+			mock.ExpectExec(touch).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			testsupport.ExpectEvent(mock, events.ProductUpdated, "PROD001")
+			mock.ExpectCommit()
+
+			image := models.Image{URL: "https://cdn.example.com/prod001.jpg", Position: position}
+			err := NewGormRepo(db).AddImage(context.Background(), "PROD001", &image)
+
+			require.NoError(t, err)
+			assert.Equal(t, 4, image.Position)
+			assert.NoError(t, mock.ExpectationsWereMet(), position)
+		}
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD999", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		err := NewGormRepo(db).AddImage(context.Background(), "PROD999", &models.Image{URL: "https://cdn.example.com/x.jpg"})
+
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_DeleteImage(t *testing.T) {
+	lookup := regexp.QuoteMeta(`SELECT "id" FROM "products" WHERE code = $1 ORDER BY "products"."id" LIMIT $2`)
+	image := regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE id = $1 AND product_id = $2 ORDER BY "product_images"."id" LIMIT $3`)
+
+	t.Run("moves the following images up", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD001", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(image).
+			WithArgs(9, 1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "url", "position"}).AddRow(9, 1, "https://cdn.example.com/prod001.jpg", 2))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "product_images" WHERE "product_images"."id" = $1`)).
+			WithArgs(9).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "product_images" SET "position"=position - 1 WHERE product_id = $1 AND position > $2`)).
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "products" SET "updated_at"=$1 WHERE id = $2`)).
+			WithArgs(sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		testsupport.ExpectEvent(mock, events.ProductUpdated, "PROD001")
+		mock.ExpectCommit()
+
+		err := NewGormRepo(db).DeleteImage(context.Background(), "PROD001", 9)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("image of another product", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD002", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(image).WithArgs(9, 2, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		err := NewGormRepo(db).DeleteImage(context.Background(), "PROD002", 9)
+
+		assert.ErrorIs(t, err, ErrImageNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD999", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		err := NewGormRepo(db).DeleteImage(context.Background(), "PROD999", 9)
+
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_UpdateVariantPrices(t *testing.T) {
+	lookup := regexp.QuoteMeta(`SELECT "id" FROM "products" WHERE code = $1 ORDER BY "products"."id" LIMIT $2`)
+	update := regexp.QuoteMeta(`UPDATE "product_variants" SET "price"=$1,"updated_at"=$2 WHERE product_id = $3 AND sku = $4`)
+	touch := regexp.QuoteMeta(`UPDATE "products" SET "updated_at"=$1 WHERE id = $2`)
+	prices := map[string]decimal.Decimal{
+		"SKU001B": decimal.RequireFromString("9.50"),
+		"SKU001A": decimal.RequireFromString("8.99"),
+	}
+
+	t.Run("all variants matched", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD001", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(update).WithArgs(prices["SKU001A"], sqlmock.AnyArg(), 1, "SKU001A").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(update).WithArgs(prices["SKU001B"], sqlmock.AnyArg(), 1, "SKU001B").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(touch).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		testsupport.ExpectEvent(mock, events.ProductUpdated, "PROD001")
+		mock.ExpectCommit()
+
+		unmatched, err := NewGormRepo(db).UpdateVariantPrices(context.Background(), "PROD001", prices)
+
+		require.NoError(t, err)
+		assert.Empty(t, unmatched)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unmatched SKUs are reported", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD001", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(update).WithArgs(prices["SKU001A"], sqlmock.AnyArg(), 1, "SKU001A").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(update).WithArgs(prices["SKU001B"], sqlmock.AnyArg(), 1, "SKU001B").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(touch).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		testsupport.ExpectEvent(mock, events.ProductUpdated, "PROD001")
+		mock.ExpectCommit()
+
+		unmatched, err := NewGormRepo(db).UpdateVariantPrices(context.Background(), "PROD001", prices)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"SKU001B"}, unmatched)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("product left untouched when nothing matched", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD001", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(update).WithArgs(prices["SKU001A"], sqlmock.AnyArg(), 1, "SKU001A").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(update).WithArgs(prices["SKU001B"], sqlmock.AnyArg(), 1, "SKU001B").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		unmatched, err := NewGormRepo(db).UpdateVariantPrices(context.Background(), "PROD001", prices)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"SKU001A", "SKU001B"}, unmatched)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD999", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		_, err := NewGormRepo(db).UpdateVariantPrices(context.Background(), "PROD999", prices)
+
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failing update rolls back", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(lookup).WithArgs("PROD001", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(update).WithArgs(prices["SKU001A"], sqlmock.AnyArg(), 1, "SKU001A").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(update).WithArgs(prices["SKU001B"], sqlmock.AnyArg(), 1, "SKU001B").WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err := NewGormRepo(db).UpdateVariantPrices(context.Background(), "PROD001", prices)
+
+		assert.EqualError(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,838 @@
+package products
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func productCodes(products []models.Product) []string {
+	codes := make([]string, len(products))
+	for i, p := range products {
+		codes[i] = p.Code
+	}
+	return codes
+}
+
+func TestPostgres_List(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+
+	price := func(s string) *decimal.Decimal {
+		d := decimal.RequireFromString(s)
+		return &d
+	}
+	yes, no := true, false
+	past := time.Now().Add(-24 * time.Hour)
+	future := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		filters SearchFilters
+		want    []string
+		total   int64
+	}{
+		{"no filters", SearchFilters{Limit: 10},
+			[]string{"PROD001", "PROD002", "PROD003", "PROD004", "PROD005", "PROD006", "PROD007", "PROD008"}, 8},
+		{"category", SearchFilters{Limit: 10, Category: "clothing"},
+			[]string{"PROD001", "PROD004", "PROD007"}, 3},
+		{"price less than", SearchFilters{Limit: 10, PriceLessThan: price("10")},
+			[]string{"PROD003", "PROD006", "PROD008"}, 3},
+		{"price less than applied to variants", SearchFilters{Limit: 10, PriceLessThan: price("10"), VariantPrices: true},
+			[]string{"PROD003"}, 1},
+		{"exact price", SearchFilters{Limit: 10, PriceEquals: price("15")},
+			[]string{"PROD004"}, 1},
+		{"exact price applied to variants", SearchFilters{Limit: 10, PriceEquals: price("15"), VariantPrices: true},
+			[]string{"PROD004"}, 1},
+		{"exact price without match", SearchFilters{Limit: 10, PriceEquals: price("99")},
+			[]string{}, 0},
+		{"category and price", SearchFilters{Limit: 10, Category: "accessories", PriceLessThan: price("10")},
+			[]string{"PROD003", "PROD008"}, 2},
+		{"variant name ignoring case", SearchFilters{Limit: 10, Variant: "variant e"},
+			[]string{"PROD005", "PROD007"}, 2},
+		{"variant and price", SearchFilters{Limit: 10, Variant: "Variant D", PriceLessThan: price("20")},
+			[]string{"PROD004", "PROD007"}, 2},
+		{"sku prefix", SearchFilters{Limit: 10, SKUPrefix: "SKU004"},
+			[]string{"PROD004"}, 1},
+		{"without variants", SearchFilters{Limit: 10, HasVariants: &no},
+			[]string{"PROD006"}, 1},
+		{"with variants in a category", SearchFilters{Limit: 10, Category: "shoes", HasVariants: &yes},
+			[]string{"PROD002"}, 1},
+		{"modified since the past", SearchFilters{Limit: 10, ModifiedSince: &past},
+			[]string{"PROD001", "PROD002", "PROD003", "PROD004", "PROD005", "PROD006", "PROD007", "PROD008"}, 8},
+		{"modified since the future", SearchFilters{Limit: 10, ModifiedSince: &future},
+			[]string{}, 0},
+		{"sorted page", SearchFilters{Limit: 3, Sort: SortPriceAsc},
+			[]string{"PROD006", "PROD003", "PROD008"}, 8},
+		{"last page", SearchFilters{Offset: 6, Limit: 10},
+			[]string{"PROD007", "PROD008"}, 8},
+		{"unknown category", SearchFilters{Limit: 10, Category: "bags"},
+			[]string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			products, total, err := repo.List(context.Background(), tt.filters)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, productCodes(products))
+			assert.Equal(t, tt.total, total)
+		})
+	}
+}
+
+func TestPostgres_List_HasVariants(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+
+	bags := models.Category{Code: "bags", Name: "Bags"}
+	require.NoError(t, db.Create(&bags).Error)
+	// A mix of products with several variants, none and one
+	require.NoError(t, db.Create([]*models.Product{
+		{Code: "BAG001", Price: decimal.NewFromInt(30), CategoryID: &bags.ID, Variants: []models.Variant{
+			{Name: "Small", SKU: "BAG001S"},
+			{Name: "Medium", SKU: "BAG001M"},
+			{Name: "Large", SKU: "BAG001L"},
+		}},
+		{Code: "BAG002", Price: decimal.NewFromInt(20), CategoryID: &bags.ID},
+		{Code: "BAG003", Price: decimal.NewFromInt(10), CategoryID: &bags.ID, Variants: []models.Variant{
+			{Name: "One size", SKU: "BAG003U"},
+		}},
+	}).Error)
+	yes, no := true, false
+
+	withVariants, total, err := repo.List(context.Background(), SearchFilters{Limit: 10, Category: "bags", HasVariants: &yes})
+	require.NoError(t, err)
+	// One row per product, however many variants it has
+	assert.Equal(t, []string{"BAG001", "BAG003"}, productCodes(withVariants))
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, withVariants[0].Variants, 3)
+	assert.Len(t, withVariants[1].Variants, 1)
+
+	withoutVariants, total, err := repo.List(context.Background(), SearchFilters{Limit: 10, Category: "bags", HasVariants: &no})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BAG002"}, productCodes(withoutVariants))
+	assert.Equal(t, int64(1), total)
+
+	all, total, err := repo.List(context.Background(), SearchFilters{Limit: 10, Category: "bags"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BAG001", "BAG002", "BAG003"}, productCodes(all))
+	assert.Equal(t, int64(3), total)
+}
+
+func TestPostgres_List_VariantPrices(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+
+	bags := models.Category{Code: "bags", Name: "Bags", MarkupPercent: decimal.NullDecimal{Decimal: decimal.NewFromInt(50), Valid: true}}
+	require.NoError(t, db.Create(&bags).Error)
+	require.NoError(t, db.Create([]*models.Product{
+		// Priced over the threshold, with a cheaper variant
+		{Code: "BAG001", Price: decimal.NewFromInt(30), CategoryID: &bags.ID, Variants: []models.Variant{
+			{Name: "Small", SKU: "BAG001S", Price: decimal.NewFromInt(20)},
+			{Name: "Large", SKU: "BAG001L"},
+		}},
+		// Priced under the threshold, but inheriting 30 with the markup
+		{Code: "BAG002", Price: decimal.NewFromInt(20), CategoryID: &bags.ID, Variants: []models.Variant{
+			{Name: "Small", SKU: "BAG002S"},
+		}},
+		// Priced under the threshold, without variants
+		{Code: "BAG003", Price: decimal.NewFromInt(20), CategoryID: &bags.ID},
+	}).Error)
+	threshold := decimal.NewFromInt(25)
+
+	products, _, err := repo.List(context.Background(), SearchFilters{Limit: 10, Category: "bags", PriceLessThan: &threshold})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BAG002", "BAG003"}, productCodes(products))
+
+	products, _, err = repo.List(context.Background(), SearchFilters{Limit: 10, Category: "bags", PriceLessThan: &threshold, VariantPrices: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BAG001"}, productCodes(products))
+	assert.Len(t, products[0].Variants, 2, "all the variants are preloaded")
+}
+
+func TestPostgres_ListVariants(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+	skus := func(variants []ResolvedVariant) []string {
+		res := make([]string, len(variants))
+		for i, v := range variants {
+			res[i] = v.SKU
+		}
+		return res
+	}
+
+	// SKU004C has no price of its own and qualifies through the 15.00 of
+	// PROD004 only, unlike its siblings
+	threshold := decimal.RequireFromString("15.01")
+	variants, total, err := repo.ListVariants(ctx, VariantFilters{Limit: 10, Category: "clothing", PriceLessThan: &threshold})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Equal(t, []string{"SKU001A", "SKU001B", "SKU001C", "SKU004C"}, skus(variants))
+	assert.Equal(t, ResolvedVariant{
+		SKU: "SKU004C", Name: "Variant C", Price: variants[3].Price, ProductCode: "PROD004", CategoryCode: "clothing",
+	}, variants[3])
+	assert.Equal(t, "15.00", variants[3].Price.StringFixed(2))
+
+	// Inherited prices are marked up by the category
+	bags := models.Category{Code: "bags", Name: "Bags", MarkupPercent: decimal.NullDecimal{Decimal: decimal.NewFromInt(50), Valid: true}}
+	require.NoError(t, db.Create(&bags).Error)
+	require.NoError(t, db.Create(&models.Product{Code: "BAG001", Price: decimal.NewFromInt(20), CategoryID: &bags.ID, Variants: []models.Variant{
+		{Name: "Small", SKU: "BAG001S", Price: decimal.NewFromInt(24)},
+		{Name: "Large", SKU: "BAG001L"},
+	}}).Error)
+	threshold = decimal.NewFromInt(25)
+	variants, total, err = repo.ListVariants(ctx, VariantFilters{Limit: 10, Category: "bags", PriceLessThan: &threshold})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, []string{"BAG001S"}, skus(variants))
+
+	// Pages of the whole catalog
+	variants, total, err = repo.ListVariants(ctx, VariantFilters{Offset: 20, Limit: 5})
+	require.NoError(t, err)
+	assert.Equal(t, int64(24), total)
+	assert.Len(t, variants, 4)
+}
+
+func TestPostgres_List_Relevance(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+
+	// Created in reverse order of relevance, so that ordering by id alone
+	// would list the category match first
+	require.NoError(t, db.Create(&models.Category{Code: "picks", Name: "Prod009 picks"}).Error)
+	for _, p := range []models.Product{
+		{Code: "PROD100", Price: decimal.NewFromInt(10), Category: &models.Category{Code: "picks"}},
+		{Code: "PROD0091", Price: decimal.NewFromInt(10)},
+		{Code: "PROD009", Price: decimal.NewFromInt(10)},
+	} {
+		require.NoError(t, repo.Create(ctx, &p))
+	}
+
+	products, total, err := repo.List(ctx, SearchFilters{Limit: 10, Query: "prod009"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD009", "PROD0091", "PROD100"}, productCodes(products))
+	assert.Equal(t, int64(3), total)
+}
+
+func TestPostgres_GetByCode(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+
+	require.NoError(t, repo.AddImage(ctx, "PROD001", &models.Image{URL: "https://cdn.example.com/back.jpg"}))
+	require.NoError(t, repo.AddImage(ctx, "PROD001", &models.Image{URL: "https://cdn.example.com/front.jpg", Position: 1}))
+
+	product, err := repo.GetByCode(ctx, "PROD001")
+
+	require.NoError(t, err)
+	assert.Equal(t, "10.99", product.Price.StringFixed(2))
+	require.NotNil(t, product.Category)
+	assert.Equal(t, "clothing", product.Category.Code)
+	assert.Equal(t, "Kleidung", product.Category.LocalizedName("de"))
+
+	skus := make([]string, len(product.Variants))
+	for i, v := range product.Variants {
+		skus[i] = v.SKU
+	}
+	assert.ElementsMatch(t, []string{"SKU001A", "SKU001B", "SKU001C"}, skus)
+
+	require.Len(t, product.Images, 2)
+	assert.Equal(t, "https://cdn.example.com/front.jpg", product.Images[0].URL)
+	assert.Equal(t, 1, product.Images[0].Position)
+	assert.Equal(t, "https://cdn.example.com/back.jpg", product.Images[1].URL)
+	assert.Equal(t, 2, product.Images[1].Position)
+
+	_, err = repo.GetByCode(ctx, "PROD999")
+	assert.ErrorIs(t, err, ErrProductNotFound)
+}
+
+func TestPostgres_GetByCode_CategoryMarkup(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+	require.NoError(t, db.Model(&models.Category{}).Where("code = ?", "accessories").Update("markup_percent", 10).Error)
+
+	marked, err := repo.GetByCode(ctx, "PROD003")
+	require.NoError(t, err)
+	assert.Equal(t, "9.625", marked.InheritedVariantPrice().String())
+	require.Len(t, marked.Variants, 1)
+	assert.Equal(t, "8.99", marked.Variants[0].Price.StringFixed(2), "explicit prices are stored as is")
+
+	plain, err := repo.GetByCode(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, "10.99", plain.InheritedVariantPrice().StringFixed(2))
+}
+
+func TestPostgres_ListByCategories(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+
+	pages, err := repo.ListByCategories(context.Background(), []string{"clothing", "shoes", "bags"}, 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD001", "PROD004"}, productCodes(pages["clothing"].Products))
+	assert.Equal(t, int64(3), pages["clothing"].Total)
+	assert.NotEmpty(t, pages["clothing"].Products[0].Variants)
+	assert.Equal(t, []string{"PROD002", "PROD006"}, productCodes(pages["shoes"].Products))
+	assert.Equal(t, int64(2), pages["shoes"].Total)
+	assert.Empty(t, pages["bags"].Products)
+}
+
+func TestPostgres_GetRelated(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+
+	related, err := repo.GetRelated(ctx, "PROD004", 4)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD001", "PROD007"}, productCodes(related))
+
+	require.NoError(t, repo.Create(ctx, &models.Product{Code: "PROD009", Price: decimal.NewFromInt(10)}))
+	related, err = repo.GetRelated(ctx, "PROD009", 4)
+	require.NoError(t, err)
+	assert.Empty(t, related)
+
+	_, err = repo.GetRelated(ctx, "PROD999", 4)
+	assert.ErrorIs(t, err, ErrProductNotFound)
+}
+
+func TestPostgres_GetByCodes(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+
+	products, err := repo.GetByCodes(context.Background(), []string{"PROD008", "PROD999", "PROD002"})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"PROD002", "PROD008"}, productCodes(products))
+}
+
+func TestPostgres_Create(t *testing.T) {
+	t.Parallel()
+
+	t.Run("inserts the product in its category", func(t *testing.T) {
+		t.Parallel()
+		repo := NewGormRepo(testsupport.Postgres(t))
+		ctx := context.Background()
+
+		product := &models.Product{
+			Code:     "PROD009",
+			Price:    decimal.RequireFromString("19.99"),
+			Category: &models.Category{Code: "shoes"},
+			Variants: []models.Variant{{Name: "Variant A", SKU: "SKU009A"}},
+		}
+		require.NoError(t, repo.Create(ctx, product))
+		assert.NotZero(t, product.ID)
+
+		created, err := repo.GetByCode(ctx, "PROD009")
+		require.NoError(t, err)
+		assert.Equal(t, "shoes", created.Category.Code)
+		require.Len(t, created.Variants, 1)
+		assert.Equal(t, "SKU009A", created.Variants[0].SKU)
+	})
+
+	t.Run("duplicate sku leaves nothing behind", func(t *testing.T) {
+		t.Parallel()
+		repo := NewGormRepo(testsupport.Postgres(t))
+		ctx := context.Background()
+
+		err := repo.Create(ctx, &models.Product{
+			Code:     "PROD009",
+			Price:    decimal.RequireFromString("19.99"),
+			Variants: []models.Variant{{Name: "Variant A", SKU: "SKU001A"}},
+		})
+
+		assert.ErrorIs(t, err, ErrProductExists)
+		_, err = repo.GetByCode(ctx, "PROD009")
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		t.Parallel()
+		repo := NewGormRepo(testsupport.Postgres(t))
+
+		err := repo.Create(context.Background(), &models.Product{
+			Code:     "PROD009",
+			Price:    decimal.RequireFromString("19.99"),
+			Category: &models.Category{Code: "bags"},
+		})
+
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+	})
+}
+
+func TestPostgres_PriceStats(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+
+	stats, err := repo.PriceStats(ctx, "clothing")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Count)
+	assert.Equal(t, "10.99", stats.Min.Decimal.StringFixed(2))
+	assert.Equal(t, "18.20", stats.Max.Decimal.StringFixed(2))
+	assert.Equal(t, "14.73", stats.Avg.Decimal.String())
+
+	require.NoError(t, db.Create(&models.Category{Code: "bags", Name: "Bags"}).Error)
+	stats, err = repo.PriceStats(ctx, "bags")
+	require.NoError(t, err)
+	assert.False(t, stats.Min.Valid || stats.Max.Valid || stats.Avg.Valid)
+	assert.Zero(t, stats.Count)
+
+	_, err = repo.PriceStats(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrCategoryNotFound)
+}
+
+func TestPostgres_Exists(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+
+	exists, err := repo.Exists(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = repo.Exists(ctx, "PROD999")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestPostgres_UpsertSnapshot(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+	snapshot := func() Snapshot {
+		return Snapshot{
+			Categories: []models.Category{{Code: "bags", Name: "Bags"}, {Code: "shoes", Name: "Shoes"}},
+			Products: []models.Product{
+				{
+					Code:     "PROD009",
+					Price:    decimal.RequireFromString("49.90"),
+					Category: &models.Category{Code: "bags"},
+					Variants: []models.Variant{{Name: "Small", SKU: "SKU009A"}},
+				},
+				{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Category: &models.Category{Code: "clothing"}},
+			},
+		}
+	}
+
+	summary, err := repo.UpsertSnapshot(ctx, snapshot())
+	require.NoError(t, err)
+	assert.Equal(t, SnapshotSummary{
+		Categories: UpsertCounts{Created: 1, Updated: 1},
+		Products:   UpsertCounts{Created: 1, Updated: 1},
+		Variants:   UpsertCounts{Created: 1},
+	}, summary)
+	first, err := repo.GetByCode(ctx, "PROD009")
+	require.NoError(t, err)
+	assert.Equal(t, "bags", first.Category.Code)
+	require.Len(t, first.Variants, 1)
+
+	summary, err = repo.UpsertSnapshot(ctx, snapshot())
+	require.NoError(t, err)
+	assert.Equal(t, SnapshotSummary{
+		Categories: UpsertCounts{Updated: 2},
+		Products:   UpsertCounts{Updated: 2},
+		Variants:   UpsertCounts{Updated: 1},
+	}, summary)
+	again, err := repo.GetByCode(ctx, "PROD009")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Equal(t, first.UpdatedAt, again.UpdatedAt)
+	assert.Equal(t, first.Variants[0].UpdatedAt, again.Variants[0].UpdatedAt)
+}
+
+func TestPostgres_DeleteVariant(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+	skus := func(code string) []string {
+		product, err := repo.GetByCode(ctx, code)
+		require.NoError(t, err)
+		res := make([]string, len(product.Variants))
+		for i, v := range product.Variants {
+			res[i] = v.SKU
+		}
+		return res
+	}
+
+	// A SKU of PROD002 is not a variant of PROD001
+	err := repo.DeleteVariant(ctx, "PROD001", "SKU002A")
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+	assert.ElementsMatch(t, []string{"SKU002A", "SKU002B"}, skus("PROD002"))
+
+	require.NoError(t, repo.DeleteVariant(ctx, "PROD002", "SKU002A"))
+	assert.Equal(t, []string{"SKU002B"}, skus("PROD002"))
+	assert.ElementsMatch(t, []string{"SKU001A", "SKU001B", "SKU001C"}, skus("PROD001"))
+
+	err = repo.DeleteVariant(ctx, "PROD999", "SKU002B")
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+}
+
+func TestPostgres_DeleteOrphanVariants(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	repo.batchSize = 2
+	ctx := context.Background()
+
+	// The foreign key prevents orphans nowadays, but the imports made without
+	// it left some behind
+	require.NoError(t, db.Exec(`ALTER TABLE product_variants DROP CONSTRAINT product_variants_product_id_fkey`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO product_variants (product_id, name, sku) VALUES
+		(900, 'Orphan A', 'SKU900A'), (900, 'Orphan B', 'SKU900B'), (901, 'Orphan C', 'SKU901A')`).Error)
+	var total int64
+	require.NoError(t, db.Model(&models.Variant{}).Count(&total).Error)
+
+	orphans, err := repo.FindOrphanVariants(ctx)
+	require.NoError(t, err)
+	assert.Len(t, orphans, 3)
+
+	deleted, err := repo.DeleteOrphanVariants(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	orphans, err = repo.FindOrphanVariants(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+	var remaining int64
+	require.NoError(t, db.Model(&models.Variant{}).Count(&remaining).Error)
+	assert.Equal(t, total-3, remaining)
+}
+
+func TestPostgres_List_StablePages(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+
+	price := decimal.RequireFromString("5.00")
+	products := make([]models.Product, 50)
+	for i := range products {
+		products[i] = models.Product{Code: fmt.Sprintf("TIE%03d", i), Price: price}
+	}
+	require.NoError(t, db.Create(&products).Error)
+
+	for _, sort := range []string{SortPriceAsc, SortPriceDesc} {
+		seen := map[string]int{}
+		for offset := 0; offset < 50; offset += 10 {
+			page, total, err := repo.List(ctx, SearchFilters{PriceEquals: &price, Sort: sort, Offset: offset, Limit: 10})
+			require.NoError(t, err)
+			require.Equal(t, int64(50), total)
+			require.Len(t, page, 10)
+			for _, p := range page {
+				seen[p.Code]++
+			}
+		}
+
+		assert.Len(t, seen, 50, sort)
+		for code, n := range seen {
+			assert.Equal(t, 1, n, "%s listed %d times sorted by %s", code, n, sort)
+		}
+	}
+}
+
+func TestPostgres_List_CategoryPages(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+
+	var shoes models.Category
+	require.NoError(t, db.Where("code = ?", "shoes").First(&shoes).Error)
+	products := make([]models.Product, 120)
+	for i := range products {
+		products[i] = models.Product{Code: fmt.Sprintf("SHOE%03d", i), Price: decimal.New(int64(1000+i%7), -2), CategoryID: &shoes.ID}
+	}
+	require.NoError(t, db.Create(&products).Error)
+	// PROD002 and PROD006 are seeded in shoes
+	const inCategory, limit = 122, 25
+
+	seen := map[string]int{}
+	for offset := 0; offset < inCategory; offset += limit {
+		page, total, err := repo.List(ctx, SearchFilters{Category: "shoes", Offset: offset, Limit: limit})
+		require.NoError(t, err)
+		require.Equal(t, int64(inCategory), total)
+		require.Len(t, page, min(limit, inCategory-offset), "offset %d", offset)
+		for _, p := range page {
+			require.NotNil(t, p.Category, p.Code)
+			assert.Equal(t, "shoes", p.Category.Code, p.Code)
+			seen[p.Code]++
+		}
+	}
+
+	assert.Len(t, seen, inCategory)
+	for code, n := range seen {
+		assert.Equal(t, 1, n, "%s listed on %d pages", code, n)
+	}
+
+	page, total, err := repo.List(ctx, SearchFilters{Category: "shoes", Offset: inCategory, Limit: limit})
+	require.NoError(t, err)
+	assert.Empty(t, page)
+	assert.Equal(t, int64(inCategory), total)
+}
+
+func TestPostgres_List_MissingVariants(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+	// As during a migration of the variants table
+	require.NoError(t, db.Exec("DROP TABLE product_variants").Error)
+
+	products, total, err := repo.List(ctx, SearchFilters{Limit: 3, AllowMissingVariants: true})
+	assert.ErrorIs(t, err, ErrVariantsUnavailable)
+	assert.Equal(t, int64(8), total)
+	assert.Equal(t, []string{"PROD001", "PROD002", "PROD003"}, productCodes(products))
+
+	_, _, err = repo.List(ctx, SearchFilters{Limit: 3})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrVariantsUnavailable)
+
+	// The details are about the variants, so they never degrade
+	_, err = repo.GetByCode(ctx, "PROD001")
+	assert.Error(t, err)
+}
+
+func TestPostgres_ListChangedSince_Replay(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+
+	// replay applies the changes following the position to prices, a few at
+	// a time, and returns the position reached
+	prices := map[string]string{}
+	replay := func(after ChangePosition) ChangePosition {
+		for {
+			changes, err := repo.ListChangedSince(ctx, after, 3)
+			require.NoError(t, err)
+			if len(changes) == 0 {
+				return after
+			}
+			for _, p := range changes {
+				prices[p.Code] = p.Price.StringFixed(2)
+				after = ChangePosition{UpdatedAt: p.UpdatedAt, ID: p.ID}
+			}
+		}
+	}
+	assertReplayed := func() {
+		t.Helper()
+		stored, err := repo.ListAll(ctx)
+		require.NoError(t, err)
+		want := make(map[string]string, len(stored))
+		for _, p := range stored {
+			want[p.Code] = p.Price.StringFixed(2)
+		}
+		assert.Equal(t, want, prices)
+	}
+
+	position := replay(ChangePosition{})
+	assertReplayed()
+
+	require.NoError(t, repo.Create(ctx, &models.Product{Code: "PROD100", Price: decimal.RequireFromString("5")}))
+	_, err := repo.AdjustPrices(ctx, "clothing", Adjustment{Type: AdjustFactor, Value: decimal.RequireFromString("2")})
+	require.NoError(t, err)
+	// Changes sharing a timestamp across the boundary of the pages
+	sameTime := time.Now().Add(time.Minute).Truncate(time.Microsecond)
+	require.NoError(t, db.Exec("UPDATE products SET price = price + 1, updated_at = ? WHERE code IN ?",
+		sameTime, []string{"PROD001", "PROD002", "PROD004", "PROD005", "PROD100"}).Error)
+
+	position = replay(position)
+	assertReplayed()
+	assert.True(t, position.UpdatedAt.Equal(sameTime))
+
+	changes, err := repo.ListChangedSince(ctx, position, 3)
+	require.NoError(t, err)
+	assert.Empty(t, changes, "nothing changed since the last replay")
+}
+
+func TestPostgres_List_Availability(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+
+	past, future := time.Now().Add(-24*time.Hour), time.Now().Add(24*time.Hour)
+	// PROD001 opens tomorrow, PROD002 is within its window, PROD003's window
+	// closed yesterday and PROD004 only has an end date. The others have no
+	// window and are always available.
+	require.NoError(t, db.Exec(`UPDATE products SET available_from = ? WHERE code = 'PROD001'`, future).Error)
+	require.NoError(t, db.Exec(`UPDATE products SET available_from = ?, available_to = ? WHERE code = 'PROD002'`, past, future).Error)
+	require.NoError(t, db.Exec(`UPDATE products SET available_from = ?, available_to = ? WHERE code = 'PROD003'`, past.Add(-24*time.Hour), past).Error)
+	require.NoError(t, db.Exec(`UPDATE products SET available_to = ? WHERE code = 'PROD004'`, future).Error)
+
+	res, total, err := repo.List(ctx, SearchFilters{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD002", "PROD004", "PROD005", "PROD006", "PROD007", "PROD008"}, productCodes(res))
+	assert.Equal(t, int64(6), total)
+
+	res, total, err = repo.List(ctx, SearchFilters{Limit: 10, IncludeUnavailable: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD001", "PROD002", "PROD003", "PROD004", "PROD005", "PROD006", "PROD007", "PROD008"}, productCodes(res))
+	assert.Equal(t, int64(8), total)
+}
+
+// The other reads of the public endpoints leave the unavailable products out
+// as List does.
+func TestPostgres_Availability_OtherReads(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+
+	// PROD001 of the clothing category opens tomorrow
+	require.NoError(t, db.Exec(`UPDATE products SET available_from = ? WHERE code = 'PROD001'`, time.Now().Add(24*time.Hour)).Error)
+
+	_, err := repo.GetByCode(ctx, "PROD001")
+	assert.ErrorIs(t, err, ErrProductNotFound)
+	exists, err := repo.Exists(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	// The admin reads still find it
+	product, err := repo.GetByCodeIncludingUnavailable(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, "PROD001", product.Code)
+
+	related, err := repo.GetRelated(ctx, "PROD004", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD007"}, productCodes(related))
+
+	found, err := repo.GetByCodes(ctx, []string{"PROD001", "PROD004"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD004"}, productCodes(found))
+
+	var walked []models.Product
+	require.NoError(t, repo.ListAllFunc(ctx, "clothing", func(batch []models.Product) error {
+		walked = append(walked, batch...)
+		return nil
+	}))
+	assert.Equal(t, []string{"PROD004", "PROD007"}, productCodes(walked))
+
+	// The changes list it for its consumers to remove it
+	changed, err := repo.ListChangedSince(ctx, ChangePosition{}, 100)
+	require.NoError(t, err)
+	assert.Contains(t, productCodes(changed), "PROD001")
+	assert.Len(t, changed, 8)
+}
+
+func TestPostgres_AdjustPrices(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+	ctx := context.Background()
+
+	prices := func() map[string]string {
+		t.Helper()
+		var stored []models.Product
+		require.NoError(t, db.Where("code IN ?", []string{"PROD001", "PROD004", "PROD007"}).Find(&stored).Error)
+		got := make(map[string]string, len(stored))
+		for _, p := range stored {
+			got[p.Code] = p.Price.StringFixed(2)
+		}
+		return got
+	}
+	require.NoError(t, db.Exec(`UPDATE products SET price = CASE code WHEN 'PROD001' THEN 10.05 WHEN 'PROD004' THEN 10.07 ELSE 10.10 END
+		WHERE code IN ('PROD001', 'PROD004', 'PROD007')`).Error)
+
+	// Halves are rounded to the even cent
+	updated, err := repo.AdjustPrices(ctx, "clothing", Adjustment{Type: AdjustFactor, Value: decimal.RequireFromString("0.5")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), updated)
+	assert.Equal(t, map[string]string{"PROD001": "5.02", "PROD004": "5.04", "PROD007": "5.05"}, prices())
+
+	_, err = repo.AdjustPrices(ctx, "clothing", Adjustment{Type: AdjustFactor, Value: decimal.NewFromInt(20000000)})
+	var overflowErr *PriceOverflowError
+	require.ErrorAs(t, err, &overflowErr)
+	assert.Equal(t, int64(3), overflowErr.Count)
+	assert.Equal(t, map[string]string{"PROD001": "5.02", "PROD004": "5.04", "PROD007": "5.05"}, prices())
+}
+
+func TestPostgres_UpdateVariantPrices(t *testing.T) {
+	t.Parallel()
+	repo := NewGormRepo(testsupport.Postgres(t))
+	ctx := context.Background()
+
+	before, err := repo.GetByCode(ctx, "PROD001")
+	require.NoError(t, err)
+	require.NotEmpty(t, before.Variants)
+	sku := before.Variants[0].SKU
+
+	unmatched, err := repo.UpdateVariantPrices(ctx, "PROD001", map[string]decimal.Decimal{
+		sku:       decimal.RequireFromString("7.77"),
+		"SKU002A": decimal.RequireFromString("1"),
+	})
+	require.NoError(t, err)
+	// SKU002A belongs to another product and is left alone
+	assert.Equal(t, []string{"SKU002A"}, unmatched)
+
+	after, err := repo.GetByCode(ctx, "PROD001")
+	require.NoError(t, err)
+	for _, v := range after.Variants {
+		if v.SKU == sku {
+			assert.Equal(t, "7.77", v.Price.StringFixed(2))
+		}
+	}
+	other, err := repo.GetByCode(ctx, "PROD002")
+	require.NoError(t, err)
+	for _, v := range other.Variants {
+		assert.NotEqual(t, "1.00", v.Price.StringFixed(2), v.SKU)
+	}
+
+	_, err = repo.UpdateVariantPrices(ctx, "PROD999", map[string]decimal.Decimal{sku: decimal.RequireFromString("1")})
+	assert.ErrorIs(t, err, ErrProductNotFound)
+}
+
+// sleep is a query lasting d, standing for a slow one. The comment tells it
+// apart in pg_stat_activity.
+func (r *GormRepo) sleep(ctx context.Context, d time.Duration) error {
+	return r.db.WithContext(ctx).Exec("SELECT pg_sleep(?) /* GormRepo.sleep */", d.Seconds()).Error
+}
+
+func TestPostgres_CancelledQuery(t *testing.T) {
+	t.Parallel()
+	db := testsupport.Postgres(t)
+	repo := NewGormRepo(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := repo.sleep(ctx, time.Minute)
+
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "the query returns once cancelled")
+	// The server stopped running the query, rather than only the client
+	// waiting for it
+	assert.Eventually(t, func() bool {
+		var running int64
+		err := db.Raw(`SELECT count(*) FROM pg_stat_activity
+			WHERE state = 'active' AND query LIKE '%GormRepo.sleep%' AND pid <> pg_backend_pid()`).Scan(&running).Error
+		return err == nil && running == 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// The connection is usable again
+	assert.NoError(t, repo.sleep(context.Background(), 0))
+}
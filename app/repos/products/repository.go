@@ -0,0 +1,269 @@
+package products
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+var (
+	ErrProductNotFound  = errs.New(errs.NotFound, "product not found")
+	ErrVariantNotFound  = errs.New(errs.NotFound, "variant not found")
+	ErrCategoryNotFound = errs.New(errs.NotFound, "category not found")
+	ErrProductExists    = errs.New(errs.Conflict, "product code or variant sku already exists")
+	ErrInvalidProductID = errs.New(errs.Invalid, "product id must be a positive integer")
+	ErrImageNotFound    = errs.New(errs.NotFound, "image not found")
+	// ErrVariantsUnavailable comes with the products List returned without
+	// their variants, see SearchFilters.AllowMissingVariants.
+	ErrVariantsUnavailable = errs.New(errs.Internal, "variants unavailable")
+)
+
+// NegativePriceError is returned by AdjustPrices when the adjustment would
+// leave some products with a negative price. Nothing is updated in that case.
+type NegativePriceError struct {
+	Count int64
+}
+
+func (e *NegativePriceError) Error() string {
+	return fmt.Sprintf("adjustment would make the price of %d products negative", e.Count)
+}
+
+// PriceOverflowError is returned by AdjustPrices when the adjustment would
+// raise some prices past MaxStoredPrice. Nothing is updated in that case.
+type PriceOverflowError struct {
+	Count int64
+}
+
+func (e *PriceOverflowError) Error() string {
+	return fmt.Sprintf("adjustment would raise the price of %d products over %s", e.Count, MaxStoredPrice.StringFixed(2))
+}
+
+// MaxStoredPrice is the largest price the decimal(10,2) price columns hold.
+var MaxStoredPrice = decimal.RequireFromString("99999999.99")
+
+// Adjustment types accepted by AdjustPrices.
+const (
+	AdjustPercentage = "percentage"
+	AdjustAbsolute   = "absolute"
+	AdjustFactor     = "factor"
+)
+
+// Adjustment describes a price change: a percentage of the current price
+// (-10 is a 10% discount), an absolute amount added to it or a factor it is
+// multiplied by (0.9 is a 10% discount).
+type Adjustment struct {
+	Type  string
+	Value decimal.Decimal
+}
+
+// Sort keys accepted by List.
+const (
+	SortFeatured  = "featured"
+	SortNewest    = "newest"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	// SortRelevance ranks the matches of SearchFilters.Query: exact code
+	// first, then code prefix, then category name. It requires a Query.
+	SortRelevance = "relevance"
+)
+
+// SearchFilters narrows down and paginates the products returned by List.
+// Zero values mean the corresponding filter is not applied.
+type SearchFilters struct {
+	Offset   int
+	Limit    int
+	Category string
+	// Query matches products whose code starts with it or whose category
+	// name contains it, ignoring case. Unless Sort is set, the matches come
+	// by relevance.
+	Query         string
+	PriceLessThan *decimal.Decimal
+	// PriceEquals matches the product price exactly.
+	PriceEquals *decimal.Decimal
+	// VariantPrices applies PriceLessThan and PriceEquals to the prices of
+	// the variants instead, matching products having any variant priced so,
+	// inherited prices included. Products without variants never match.
+	VariantPrices bool
+	// Variant matches products having a variant with this name, ignoring case.
+	Variant string
+	// SKUPrefix matches products having a variant whose SKU starts with it.
+	SKUPrefix string
+	// HasVariants keeps only products with (true) or without (false) variants.
+	HasVariants *bool
+	// ModifiedSince keeps only products updated after it. Unless Sort is set,
+	// the products then come oldest update first.
+	ModifiedSince *time.Time
+	// Sort is one of the Sort* keys, empty to use the repository default.
+	Sort string
+	// IncludeUnavailable lists the products outside of their availability
+	// window too.
+	IncludeUnavailable bool
+	// AllowMissingVariants lists the products without their variants when
+	// the variants cannot be read, e.g. while their table is migrated. The
+	// products then come with ErrVariantsUnavailable.
+	AllowMissingVariants bool
+}
+
+// VariantFilters narrows down and paginates the variants returned by
+// ListVariants. Zero values mean the corresponding filter is not applied.
+type VariantFilters struct {
+	Offset   int
+	Limit    int
+	Category string
+	// PriceLessThan applies to the resolved price of the variants, see
+	// ResolvedVariant.
+	PriceLessThan *decimal.Decimal
+}
+
+// ResolvedVariant is a variant with its resolved price: its own one, or the
+// product price marked up by the category when it inherits it.
+// CategoryCode is empty for products without a category.
+type ResolvedVariant struct {
+	SKU          string
+	Name         string
+	Price        decimal.Decimal
+	ProductCode  string
+	CategoryCode string
+}
+
+// ChangePosition locates a product in the order of its changes, the id
+// breaking ties between products updated at the same time.
+type ChangePosition struct {
+	UpdatedAt time.Time
+	ID        uint
+}
+
+// CategoryProducts is a page of the products of a category along with the
+// total number of products in it.
+type CategoryProducts struct {
+	Products []models.Product
+	Total    int64
+}
+
+// PriceStats aggregates the prices of a category, Avg being rounded to
+// cents. Min, Max and Avg are not valid when the category has no prices at
+// all.
+type PriceStats struct {
+	Min   decimal.NullDecimal
+	Max   decimal.NullDecimal
+	Avg   decimal.NullDecimal
+	Count int64
+}
+
+// Snapshot is a set of categories and products to upsert. Products refer to
+// their category by code, either one of the snapshot or an existing one.
+type Snapshot struct {
+	Categories []models.Category
+	Products   []models.Product
+}
+
+// UpsertCounts tells how many rows of a kind were created, and how many
+// existing ones were written over, whether or not their values changed.
+type UpsertCounts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// SnapshotSummary counts the rows upserted by UpsertSnapshot by kind.
+type SnapshotSummary struct {
+	Categories UpsertCounts `json:"categories"`
+	Products   UpsertCounts `json:"products"`
+	Variants   UpsertCounts `json:"variants"`
+}
+
+// ExistingKeys holds product codes, variant SKUs and category codes, either
+// looked up or found by FindExisting.
+type ExistingKeys struct {
+	Codes      []string
+	SKUs       []string
+	Categories []string
+}
+
+// ProductReader describes the product storage reads used by the handlers.
+type ProductReader interface {
+	ListAll(ctx context.Context) ([]models.Product, error)
+	// ListAllFunc walks through the products of the category, or of the
+	// whole catalog when it is empty, one batch at a time.
+	ListAllFunc(ctx context.Context, category string, fn func([]models.Product) error) error
+	// List returns a page of the products matching the filters along with
+	// their total. The page comes with ErrVariantsUnavailable when it was
+	// listed without variants.
+	List(ctx context.Context, filters SearchFilters) ([]models.Product, int64, error)
+	// Count is the total of List, and ListPage its page, for the callers
+	// running them concurrently.
+	Count(ctx context.Context, filters SearchFilters) (int64, error)
+	ListPage(ctx context.Context, filters SearchFilters) ([]models.Product, error)
+	// GetByCode returns the product with the given code, unless it is
+	// outside of its availability window.
+	GetByCode(ctx context.Context, code string) (models.Product, error)
+	// GetByCodeIncludingUnavailable returns the product with the given code
+	// whatever its availability window, for the admin reads such as the
+	// audit log.
+	GetByCodeIncludingUnavailable(ctx context.Context, code string) (models.Product, error)
+	// Exists reports whether a product with the given code is stored and
+	// within its availability window, without loading it.
+	Exists(ctx context.Context, code string) (bool, error)
+	// ListByCategories returns the first perCategory products of each
+	// category, keyed by category code. Unknown categories have no products.
+	ListByCategories(ctx context.Context, categories []string, perCategory int) (map[string]CategoryProducts, error)
+	GetByID(ctx context.Context, id string) (models.Product, error)
+	// GetRelated returns up to limit other products of the category of the
+	// product with the given code, none when it has no category.
+	GetRelated(ctx context.Context, code string, limit int) ([]models.Product, error)
+	// GetByCodes returns the products with the given codes, in no particular
+	// order. Unknown codes are left out.
+	GetByCodes(ctx context.Context, codes []string) ([]models.Product, error)
+	// FindExisting returns which of the keys are already stored. It only
+	// reads and never opens a transaction.
+	FindExisting(ctx context.Context, keys ExistingKeys) (ExistingKeys, error)
+	// FindOrphanVariants lists the variants whose product does not exist.
+	FindOrphanVariants(ctx context.Context) ([]models.Variant, error)
+	// PriceStats aggregates the prices of the available products of the
+	// category.
+	PriceStats(ctx context.Context, categoryCode string) (PriceStats, error)
+	// ListChangedSince returns up to limit products changed after the
+	// position, oldest change first, with their variants and images,
+	// whatever their availability window.
+	ListChangedSince(ctx context.Context, after ChangePosition, limit int) ([]models.Product, error)
+	// ListVariants returns a page of the variants of the available products
+	// matching the filters, with their resolved price, along with their
+	// total.
+	ListVariants(ctx context.Context, filters VariantFilters) ([]ResolvedVariant, int64, error)
+}
+
+// ProductWriter describes the product storage writes used by the handlers.
+type ProductWriter interface {
+	Create(ctx context.Context, product *models.Product) error
+	// UpsertSnapshot upserts the categories by code, the products by code and
+	// their variants by SKU, all or nothing. Rows missing from the snapshot
+	// are left untouched.
+	UpsertSnapshot(ctx context.Context, snapshot Snapshot) (SnapshotSummary, error)
+	// DeleteVariant deletes the variant with the SKU among those of the
+	// product with the code.
+	DeleteVariant(ctx context.Context, code, sku string) error
+	// DeleteOrphanVariants deletes the variants whose product does not
+	// exist and returns how many were deleted.
+	DeleteOrphanVariants(ctx context.Context) (int64, error)
+	// AddImage inserts the image among the images of the product with the
+	// given code, at image.Position or last when it is zero.
+	AddImage(ctx context.Context, code string, image *models.Image) error
+	DeleteImage(ctx context.Context, code string, id uint) error
+	// AdjustPrices applies adj to every product in the category and returns
+	// the number of products updated.
+	AdjustPrices(ctx context.Context, categoryCode string, adj Adjustment) (int64, error)
+	// UpdateVariantPrices sets the prices of the variants of the product with
+	// the given code, keyed by SKU, all or nothing. It returns the SKUs
+	// matching none of its variants, sorted.
+	UpdateVariantPrices(ctx context.Context, code string, prices map[string]decimal.Decimal) ([]string, error)
+}
+
+// Repository is the whole product storage, as implemented by GormRepo.
+type Repository interface {
+	ProductReader
+	ProductWriter
+}
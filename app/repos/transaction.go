@@ -0,0 +1,46 @@
+// Package repos runs the work of several repositories in a single database
+// transaction.
+package repos
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/category"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+)
+
+// TxRepos are the repositories of a transaction. They must not be used once
+// the function they are given to returns.
+type TxRepos struct {
+	Products   products.Repository
+	Categories category.Repository
+}
+
+// Transactor opens the transactions of WithTransaction.
+type Transactor struct {
+	db       *gorm.DB
+	products *products.GormRepo
+}
+
+// NewTransactor runs the transactions on db. The product repository of a
+// transaction has the settings of productsRepo.
+func NewTransactor(db *gorm.DB, productsRepo *products.GormRepo) *Transactor {
+	return &Transactor{
+		db:       db,
+		products: productsRepo,
+	}
+}
+
+// WithTransaction calls fn with repositories sharing a single transaction,
+// committed when fn returns nil and rolled back otherwise, fn's error being
+// returned. The writes of fn are only visible to others once committed.
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(TxRepos) error) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(TxRepos{
+			Products:   t.products.WithTx(tx),
+			Categories: category.NewGormRepo(tx),
+		})
+	})
+}
@@ -0,0 +1,98 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func TestTransactor_WithTransaction(t *testing.T) {
+	categoryInsert := regexp.QuoteMeta(`INSERT INTO "categories"`)
+	categoryQuery := regexp.QuoteMeta(`SELECT * FROM "categories" WHERE code = $1`)
+	productInsert := regexp.QuoteMeta(`INSERT INTO "products"`)
+
+	createBoth := func(ctx context.Context) func(TxRepos) error {
+		return func(tx TxRepos) error {
+			if _, err := tx.Categories.Create(ctx, models.Category{Code: "bags", Name: "Bags"}); err != nil {
+				return err
+			}
+			return tx.Products.Create(ctx, &models.Product{
+				Code:     "PROD009",
+				Price:    decimal.RequireFromString("19.99"),
+				Category: &models.Category{Code: "bags"},
+			})
+		}
+	}
+
+	t.Run("commits the writes of every repository", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(categoryInsert).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		testsupport.ExpectEvent(mock, events.CategoryCreated, "bags")
+		mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(categoryQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(4, "bags", "Bags"))
+		mock.ExpectQuery(productInsert).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		testsupport.ExpectEvent(mock, events.ProductCreated, "PROD009")
+		mock.ExpectCommit()
+
+		err := NewTransactor(db, products.NewGormRepo(db)).WithTransaction(context.Background(), createBoth(context.Background()))
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failing write rolls back the previous ones", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(categoryInsert).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		testsupport.ExpectEvent(mock, events.CategoryCreated, "bags")
+		mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(categoryQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(4, "bags", "Bags"))
+		mock.ExpectQuery(productInsert).WillReturnError(errors.New("connection reset"))
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := NewTransactor(db, products.NewGormRepo(db)).WithTransaction(context.Background(), createBoth(context.Background()))
+
+		assert.EqualError(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("an error of the function rolls back its writes and their events", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(categoryInsert).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		testsupport.ExpectEvent(mock, events.CategoryCreated, "bags")
+		mock.ExpectRollback()
+		boom := errors.New("boom")
+
+		err := NewTransactor(db, products.NewGormRepo(db)).WithTransaction(context.Background(), func(tx TxRepos) error {
+			_, err := tx.Categories.Create(context.Background(), models.Category{Code: "bags", Name: "Bags"})
+			require.NoError(t, err)
+			return boom
+		})
+
+		assert.ErrorIs(t, err, boom)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,93 @@
+package wishlist
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type GormRepo struct {
+	db *gorm.DB
+}
+
+func NewGormRepo(db *gorm.DB) *GormRepo {
+	return &GormRepo{
+		db: db,
+	}
+}
+
+// List leaves out the products outside of their availability window, as the
+// catalog does, but keeps them in the wishlist for when they are back. The
+// products are loaded as the catalog lists them, with their first image and
+// their category translations.
+func (r *GormRepo) List(ctx context.Context, token string) ([]models.Product, error) {
+	var res []models.Product
+	err := r.db.WithContext(ctx).
+		Select("products.*").
+		Joins("JOIN wishlist_items ON wishlist_items.product_id = products.id").
+		Where("wishlist_items.token = ?", token).
+		Where(products.AvailableNow).
+		Order("wishlist_items.id").
+		Preload("Category.Translations").
+		Preload("Images", products.FirstImage).
+		Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Add relies on the (token, product_id) unique constraint to make adding the
+// same product twice a no-op.
+func (r *GormRepo) Add(ctx context.Context, token, code string) (bool, error) {
+	productID, err := r.productID(ctx, code)
+	if err != nil {
+		return false, err
+	}
+
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.WishlistItem{Token: token, ProductID: productID})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *GormRepo) Remove(ctx context.Context, token, code string) error {
+	productID, err := r.productID(ctx, code)
+	if errors.Is(err, ErrProductNotFound) {
+		return ErrItemNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	res := r.db.WithContext(ctx).
+		Where("token = ? AND product_id = ?", token, productID).
+		Delete(&models.WishlistItem{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+func (r *GormRepo) productID(ctx context.Context, code string) (uint, error) {
+	var product models.Product
+	err := r.db.WithContext(ctx).Select("id").Where("code = ?", code).First(&product).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrProductNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return product.ID, nil
+}
@@ -0,0 +1,126 @@
+package wishlist
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+)
+
+func expectProductLookup(mock sqlmock.Sqlmock, code string, id int) {
+	rows := sqlmock.NewRows([]string{"id"})
+	if id > 0 {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "products" WHERE code = $1 ORDER BY "products"."id" LIMIT $2`)).
+		WithArgs(code, 1).
+		WillReturnRows(rows)
+}
+
+func TestGormRepo_Add(t *testing.T) {
+	insert := regexp.QuoteMeta(`INSERT INTO "wishlist_items" ("token","product_id","created_at") VALUES ($1,$2,$3) ON CONFLICT DO NOTHING RETURNING "id"`)
+
+	t.Run("new item", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectProductLookup(mock, "PROD001", 1)
+		mock.ExpectBegin()
+		mock.ExpectQuery(insert).
+			WithArgs("abc", 1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectCommit()
+
+		created, err := NewGormRepo(db).Add(context.Background(), "abc", "PROD001")
+
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unique constraint makes re-adding a no-op", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectProductLookup(mock, "PROD001", 1)
+		mock.ExpectBegin()
+		mock.ExpectQuery(insert).
+			WithArgs("abc", 1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		created, err := NewGormRepo(db).Add(context.Background(), "abc", "PROD001")
+
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectProductLookup(mock, "PROD999", 0)
+
+		_, err := NewGormRepo(db).Add(context.Background(), "abc", "PROD999")
+
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.Equal(t, errs.NotFound, errs.KindOf(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormRepo_List(t *testing.T) {
+	db, mock := testsupport.MockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT products.* FROM "products" JOIN wishlist_items ON wishlist_items.product_id = products.id ` +
+		`WHERE wishlist_items.token = $1 AND (` + products.AvailableNow + `) ORDER BY wishlist_items.id`)).
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price", "category_id"}).AddRow(2, "PROD002", "12.49", 2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "categories" WHERE "categories"."id" = $1`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name"}).AddRow(2, "shoes", "Shoes"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "category_translations" WHERE "category_translations"."category_id" = $1`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"category_id", "locale", "name"}).AddRow(2, "de", "Schuhe"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images" WHERE "product_images"."product_id" = $1 AND NOT EXISTS`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "url", "position"}).AddRow(2, "https://example.com/prod002.jpg", 0))
+
+	res, err := NewGormRepo(db).List(context.Background(), "abc")
+
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "PROD002", res[0].Code)
+	assert.Equal(t, "Schuhe", res[0].Category.LocalizedName("de"))
+	require.Len(t, res[0].Images, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepo_Remove(t *testing.T) {
+	del := regexp.QuoteMeta(`DELETE FROM "wishlist_items" WHERE token = $1 AND product_id = $2`)
+
+	t.Run("removes the item", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectProductLookup(mock, "PROD001", 1)
+		mock.ExpectBegin()
+		mock.ExpectExec(del).WithArgs("abc", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, NewGormRepo(db).Remove(context.Background(), "abc", "PROD001"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("item not in the wishlist", func(t *testing.T) {
+		db, mock := testsupport.MockDB(t)
+		expectProductLookup(mock, "PROD001", 1)
+		mock.ExpectBegin()
+		mock.ExpectExec(del).WithArgs("other", 1).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := NewGormRepo(db).Remove(context.Background(), "other", "PROD001")
+
+		assert.ErrorIs(t, err, ErrItemNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
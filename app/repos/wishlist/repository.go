@@ -0,0 +1,23 @@
+package wishlist
+
+import (
+	"context"
+
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+var (
+	ErrProductNotFound = errs.New(errs.NotFound, "product not found")
+	ErrItemNotFound    = errs.New(errs.NotFound, "product not in wishlist")
+)
+
+// Repository describes the wishlist storage operations used by the handlers.
+type Repository interface {
+	// List returns the wishlist products in the order they were added.
+	List(ctx context.Context, token string) ([]models.Product, error)
+	// Add puts the product in the wishlist, reporting whether it was not
+	// there yet.
+	Add(ctx context.Context, token, code string) (bool, error)
+	Remove(ctx context.Context, token, code string) error
+}
@@ -0,0 +1,54 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// AssertJSONArrays checks that each path of body is a JSON array, which
+// clients can iterate without a null check, rather than null or missing.
+// Paths are dot separated object keys and array indexes, as in
+// "products.0.variants".
+func AssertJSONArrays(t *testing.T, body string, paths ...string) {
+	t.Helper()
+
+	var doc any
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatalf("decoding %s: %s", body, err)
+	}
+
+	for _, path := range paths {
+		v, ok := lookupJSON(doc, path)
+		if !ok {
+			t.Errorf("%s is missing from %s", path, body)
+			continue
+		}
+		if _, ok := v.([]any); !ok {
+			t.Errorf("%s is %v rather than an array in %s", path, v, body)
+		}
+	}
+}
+
+func lookupJSON(doc any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := doc.(type) {
+		case map[string]any:
+			v, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			doc = v
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			doc = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
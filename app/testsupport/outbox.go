@@ -0,0 +1,18 @@
+package testsupport
+
+import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// ExpectEvent expects the statements recording an event of type typ for
+// aggregateCode in the outbox, which repositories run last in the
+// transaction of a change.
+func ExpectEvent(mock sqlmock.Sqlmock, typ, aggregateCode string) {
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "events" ("type","aggregate_code","payload","created_at") VALUES ($1,$2,$3,$4) RETURNING "id"`)).
+		WithArgs(typ, aggregateCode, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
@@ -0,0 +1,116 @@
+package wishlist
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
+	"github.com/mytheresa/go-hiring-challenge/app/dto"
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/wishlist"
+)
+
+var tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+type Response struct {
+	Products []dto.Product `json:"products"`
+}
+
+type AddItemRequest struct {
+	Code string `json:"code"`
+}
+
+type WishlistHandler struct {
+	repo wishlist.Repository
+	conv currency.Conversion
+}
+
+// NewWishlistHandler lists the products of the wishlists in the base
+// currency, rounded with rounding.
+func NewWishlistHandler(r wishlist.Repository, rounding pricing.Mode) *WishlistHandler {
+	return &WishlistHandler{
+		repo: r,
+		conv: currency.Conversion{Rate: decimal.NewFromInt(1), Rounding: rounding},
+	}
+}
+
+func (h *WishlistHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	token, ok := validateToken(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.repo.List(r.Context(), token)
+	if api.Abandoned(r) {
+		return
+	}
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	products := make([]dto.Product, len(res))
+	for i, p := range res {
+		products[i] = dto.ToProductResponse(p, h.conv, "")
+	}
+
+	api.OKResponse(w, Response{
+		Products: products,
+	})
+}
+
+// HandleAdd is idempotent: adding a product already in the wishlist answers
+// 200 instead of 201.
+func (h *WishlistHandler) HandleAdd(w http.ResponseWriter, r *http.Request) {
+	token, ok := validateToken(w, r)
+	if !ok {
+		return
+	}
+
+	var req AddItemRequest
+	if !api.DecodeJSON(w, r, &req) {
+		return
+	}
+	if req.Code == "" {
+		api.ErrorResponse(w, http.StatusBadRequest, "product code is required")
+		return
+	}
+
+	created, err := h.repo.Add(r.Context(), token, req.Code)
+	if err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	if created {
+		api.CreatedResponse(w, req)
+		return
+	}
+	api.OKResponse(w, req)
+}
+
+func (h *WishlistHandler) HandleRemove(w http.ResponseWriter, r *http.Request) {
+	token, ok := validateToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Remove(r.Context(), token, r.PathValue("code")); err != nil {
+		api.RepositoryErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validateToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := r.PathValue("token")
+	if !tokenPattern.MatchString(token) {
+		api.ErrorResponse(w, http.StatusBadRequest, "invalid wishlist token")
+		return "", false
+	}
+	return token, true
+}
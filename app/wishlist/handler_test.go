@@ -0,0 +1,170 @@
+package wishlist
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/errs"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/wishlist"
+	"github.com/mytheresa/go-hiring-challenge/app/testsupport"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+func serve(repo *mockRepo, req *http.Request) *httptest.ResponseRecorder {
+	h := NewWishlistHandler(repo, pricing.HalfEven)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /wishlist/{token}", h.HandleGet)
+	mux.HandleFunc("POST /wishlist/{token}/items", h.HandleAdd)
+	mux.HandleFunc("DELETE /wishlist/{token}/items/{code}", h.HandleRemove)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleGet(t *testing.T) {
+	t.Run("lists products of the token only", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, "abc-123").Return([]models.Product{
+			{Code: "PROD002", Price: decimal.RequireFromString("12.49"), Category: &models.Category{Code: "shoes", Name: "Shoes"}},
+		}, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/wishlist/abc-123", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[{"code":"PROD002","price":"12.49","category":{"code":"shoes","name":"Shoes"}}]}`, rec.Body.String())
+	})
+
+	t.Run("empty wishlist", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("List", mock.Anything, "abc-123").Return(nil, nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/wishlist/abc-123", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"products":[]}`, rec.Body.String())
+		testsupport.AssertJSONArrays(t, rec.Body.String(), "products")
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodGet, "/wishlist/"+strings.Repeat("a", 129), nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, repo.Calls)
+	})
+
+	t.Run("repository errors", func(t *testing.T) {
+		tests := []struct {
+			err    error
+			status int
+		}{
+			{errors.New("boom"), http.StatusInternalServerError},
+			{errs.WithKind(errs.Invalid, errors.New("malformed token")), http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			repo := new(mockRepo)
+			repo.On("List", mock.Anything, "abc-123").Return(nil, tt.err)
+
+			rec := serve(repo, httptest.NewRequest(http.MethodGet, "/wishlist/abc-123", nil))
+
+			assert.Equal(t, tt.status, rec.Code, tt.err)
+		}
+	})
+}
+
+func TestHandleAdd(t *testing.T) {
+	add := func(repo *mockRepo, body string) *httptest.ResponseRecorder {
+		return serve(repo, httptest.NewRequest(http.MethodPost, "/wishlist/abc-123/items", strings.NewReader(body)))
+	}
+
+	t.Run("adds a new product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Add", mock.Anything, "abc-123", "PROD001").Return(true, nil)
+
+		rec := add(repo, `{"code":"PROD001"}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.JSONEq(t, `{"code":"PROD001"}`, rec.Body.String())
+	})
+
+	t.Run("re-adding is idempotent", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Add", mock.Anything, "abc-123", "PROD001").Return(false, nil)
+
+		rec := add(repo, `{"code":"PROD001"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Add", mock.Anything, "abc-123", "PROD999").Return(false, wishlist.ErrProductNotFound)
+
+		rec := add(repo, `{"code":"PROD999"}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":"product not found"}`, rec.Body.String())
+	})
+
+	t.Run("missing code", func(t *testing.T) {
+		repo := new(mockRepo)
+
+		rec := add(repo, `{}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, repo.Calls)
+	})
+}
+
+func TestHandleRemove(t *testing.T) {
+	t.Run("removes the product", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Remove", mock.Anything, "abc-123", "PROD001").Return(nil)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodDelete, "/wishlist/abc-123/items/PROD001", nil))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("product not in wishlist", func(t *testing.T) {
+		repo := new(mockRepo)
+		repo.On("Remove", mock.Anything, "abc-123", "PROD001").Return(wishlist.ErrItemNotFound)
+
+		rec := serve(repo, httptest.NewRequest(http.MethodDelete, "/wishlist/abc-123/items/PROD001", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("maps repository error kinds", func(t *testing.T) {
+		tests := []struct {
+			err    error
+			status int
+		}{
+			{errs.New(errs.NotFound, "gone"), http.StatusNotFound},
+			{errs.New(errs.Conflict, "taken"), http.StatusConflict},
+			{errs.New(errs.Invalid, "bad"), http.StatusBadRequest},
+			{errors.New("boom"), http.StatusInternalServerError},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.err.Error(), func(t *testing.T) {
+				repo := new(mockRepo)
+				repo.On("Remove", mock.Anything, "abc-123", "PROD001").Return(tt.err)
+
+				rec := serve(repo, httptest.NewRequest(http.MethodDelete, "/wishlist/abc-123/items/PROD001", nil))
+
+				assert.Equal(t, tt.status, rec.Code)
+			})
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package wishlist
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type mockRepo struct {
+	mock.Mock
+}
+
+func (m *mockRepo) List(ctx context.Context, token string) ([]models.Product, error) {
+	args := m.Called(ctx, token)
+	products, _ := args.Get(0).([]models.Product)
+	return products, args.Error(1)
+}
+
+func (m *mockRepo) Add(ctx context.Context, token, code string) (bool, error) {
+	args := m.Called(ctx, token, code)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRepo) Remove(ctx context.Context, token, code string) error {
+	args := m.Called(ctx, token, code)
+	return args.Error(0)
+}
@@ -19,12 +19,15 @@ func main() {
 	}
 
 	// Initialize database connection
-	db, close := database.New(
+	db, close, err := database.New(
 		os.Getenv("POSTGRES_USER"),
 		os.Getenv("POSTGRES_PASSWORD"),
 		os.Getenv("POSTGRES_DB"),
 		os.Getenv("POSTGRES_PORT"),
 	)
+	if err != nil {
+		log.Fatalf("Failed to open the database: %s", err)
+	}
 	defer close()
 
 	dir := os.Getenv("POSTGRES_SQL_DIR")
@@ -2,64 +2,370 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/mytheresa/go-hiring-challenge/app/admin"
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
 	"github.com/mytheresa/go-hiring-challenge/app/catalog"
+	"github.com/mytheresa/go-hiring-challenge/app/category"
+	"github.com/mytheresa/go-hiring-challenge/app/currency"
 	"github.com/mytheresa/go-hiring-challenge/app/database"
-	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/mytheresa/go-hiring-challenge/app/eventlog"
+	"github.com/mytheresa/go-hiring-challenge/app/events"
+	"github.com/mytheresa/go-hiring-challenge/app/jobs"
+	"github.com/mytheresa/go-hiring-challenge/app/logging"
+	"github.com/mytheresa/go-hiring-challenge/app/pricing"
+	"github.com/mytheresa/go-hiring-challenge/app/repos"
+	auditrepo "github.com/mytheresa/go-hiring-challenge/app/repos/audit"
+	categoryrepo "github.com/mytheresa/go-hiring-challenge/app/repos/category"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/imports"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/outbox"
+	"github.com/mytheresa/go-hiring-challenge/app/repos/products"
+	wishlistrepo "github.com/mytheresa/go-hiring-challenge/app/repos/wishlist"
+	"github.com/mytheresa/go-hiring-challenge/app/wishlist"
+	"github.com/shopspring/decimal"
+)
+
+// shutdownTimeout bounds how long in-flight requests may take to complete
+// once shutdown starts.
+const shutdownTimeout = 10 * time.Second
+
+// defaultRequestTimeout and maxRequestTimeout bound the requests, which answer
+// 503 past them, clients choosing a timeout up to the max with the
+// X-Timeout-Ms header.
+const (
+	defaultRequestTimeout = 5 * time.Second
+	maxRequestTimeout     = 30 * time.Second
+)
+
+// defaultMaxImportBodyBytes is the body limit of the CSV imports used when
+// none is configured.
+const defaultMaxImportBodyBytes = 32 << 20
+
+// webhookTimeout and webhookAttempts bound each category webhook delivery.
+const (
+	webhookTimeout  = 5 * time.Second
+	webhookAttempts = 3
+)
+
+// Default connection pool limits, the max open connections being raisable
+// up to defaultPoolMaxConns at runtime.
+const (
+	defaultMaxOpenConns = 25
+	defaultMaxIdleConns = 5
+	defaultPoolMaxConns = 100
 )
 
 func main() {
 	// Load environment variables from .env file
 	if err := godotenv.Load(".env"); err != nil {
-		log.Fatalf("Error loading .env file: %s", err)
+		fatal("Error loading .env file", "error", err)
 	}
 
+	// Logs are text unless LOG_FORMAT=json, from LOG_LEVEL on. The standard
+	// log package, still used by dependencies, goes through the same logger
+	logger, err := logging.New(os.Stderr, os.Getenv("LOG_FORMAT"), os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		fatal("Invalid logging configuration", "error", err)
+	}
+	slog.SetDefault(logger)
+
 	// signal handling for graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	// Initialize database connection
-	db, close := database.New(
+	db, closeDBCon, err := database.New(
 		os.Getenv("POSTGRES_USER"),
 		os.Getenv("POSTGRES_PASSWORD"),
 		os.Getenv("POSTGRES_DB"),
 		os.Getenv("POSTGRES_PORT"),
 	)
-	defer close()
+	if err != nil {
+		fatal("Failed to open the database", "error", err)
+	}
+
+	// The tables must match the models before any request comes in.
+	// SCHEMA_CHECK=warn only logs the differences
+	schemaCheck := os.Getenv("SCHEMA_CHECK")
+	if schemaCheck != "" && schemaCheck != "strict" && schemaCheck != "warn" {
+		fatal("Invalid SCHEMA_CHECK, expected strict or warn", "value", schemaCheck)
+	}
+	if err := database.VerifySchema(ctx, db, database.CheckedModels...); err != nil {
+		var schemaErr *database.SchemaError
+		if !errors.As(err, &schemaErr) || schemaCheck != "warn" {
+			fatal("Database schema does not match the models", "error", err)
+		}
+		logger.Warn("Database schema does not match the models", "missing", schemaErr.Missing)
+	}
+
+	// The pool starts with DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS, which
+	// can then be tuned at runtime up to DB_POOL_MAX_CONNS
+	pool, err := database.NewPool(db, database.PoolLimits{
+		MaxOpenConns: envInt("DB_MAX_OPEN_CONNS", defaultMaxOpenConns),
+		MaxIdleConns: envInt("DB_MAX_IDLE_CONNS", defaultMaxIdleConns),
+	}, envInt("DB_POOL_MAX_CONNS", defaultPoolMaxConns))
+	if err != nil {
+		fatal("Invalid database pool configuration", "error", err)
+	}
+
+	rates, err := currency.ParseStaticRates(os.Getenv("CURRENCY_RATES"))
+	if err != nil {
+		fatal("Invalid CURRENCY_RATES", "error", err)
+	}
+	minorUnits, err := currency.ParseMinorUnits(os.Getenv("CURRENCY_MINOR_UNITS"))
+	if err != nil {
+		fatal("Invalid CURRENCY_MINOR_UNITS", "error", err)
+	}
+	// Displayed prices are rounded half-even, unless PRICE_ROUNDING is
+	// half-up or down
+	rounding, err := pricing.ParseMode(os.Getenv("PRICE_ROUNDING"))
+	if err != nil {
+		fatal("Invalid PRICE_ROUNDING", "error", err)
+	}
+
+	// Catalog mutations are delivered asynchronously to WEBHOOK_ENDPOINTS
+	endpoints, err := events.ParseEndpoints(os.Getenv("WEBHOOK_ENDPOINTS"))
+	if err != nil {
+		fatal("Invalid WEBHOOK_ENDPOINTS", "error", err)
+	}
+	dispatcher := events.NewDispatcher(endpoints, events.DispatcherOptions{Logger: logger})
+	dispatcher.Start()
+
+	// Asynchronous imports run on IMPORT_WORKERS workers. The jobs of a
+	// previous run cannot be resumed, as their queue was only in memory
+	importJobs := imports.NewGormRepo(db)
+	if n, err := importJobs.FailInterrupted(ctx); err != nil {
+		fatal("Failed to fail the interrupted import jobs", "error", err)
+	} else if n > 0 {
+		logger.Warn("Failed the import jobs interrupted by a restart", "count", n)
+	}
+	importQueue := jobs.NewQueue(jobs.QueueOptions{
+		Workers:   envInt("IMPORT_WORKERS", 0),
+		QueueSize: envInt("IMPORT_QUEUE_SIZE", 0),
+		Logger:    logger,
+	})
 
 	// Initialize handlers
-	prodRepo := models.NewProductsRepository(db)
-	cat := catalog.NewCatalogHandler(prodRepo)
+	prodRepo := products.NewGormRepo(db)
+	if err := prodRepo.SetDefaultSort(os.Getenv("CATALOG_DEFAULT_SORT")); err != nil {
+		fatal("Invalid CATALOG_DEFAULT_SORT", "error", err)
+	}
+	prodRepo.SetRounding(rounding)
+	// Offsets over CATALOG_MAX_OFFSET are rejected, or clamped in lenient mode
+	offsetMode := os.Getenv("CATALOG_OFFSET_MODE")
+	if offsetMode != "" && offsetMode != "strict" && offsetMode != "lenient" {
+		fatal("Invalid CATALOG_OFFSET_MODE, expected strict or lenient", "value", offsetMode)
+	}
+	// Prices over MAX_PRICE or with more than two decimals are rejected
+	maxPrice := catalog.DefaultMaxPrice
+	if v := os.Getenv("MAX_PRICE"); v != "" {
+		if maxPrice, err = decimal.NewFromString(v); err != nil {
+			fatal("Invalid MAX_PRICE", "error", err)
+		}
+	}
+	// Products are created with at most MAX_VARIANTS_PER_PRODUCT variants
+	maxVariants := envInt("MAX_VARIANTS_PER_PRODUCT", catalog.DefaultMaxVariantsPerProduct)
+	if maxVariants < 0 {
+		fatal("Invalid MAX_VARIANTS_PER_PRODUCT, must not be negative", "value", maxVariants)
+	}
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	// The changes of the write endpoints are recorded in the audit log, on
+	// behalf of the key they were authenticated with
+	auditRepo := auditrepo.NewGormRepo(db)
+	auditLog := audit.NewLog(auditRepo)
+	cat, err := catalog.NewCatalogHandler(prodRepo, catalog.Options{
+		MaxVariantsPerProduct: maxVariants,
+		ProductCodePattern:    os.Getenv("PRODUCT_CODE_PATTERN"),
+		Rates:                 rates,
+		MinorUnits:            minorUnits,
+		Rounding:              rounding,
+		Events:                dispatcher,
+		MaxOffset:             envInt("CATALOG_MAX_OFFSET", catalog.DefaultMaxOffset),
+		ClampOffset:           offsetMode == "lenient",
+		MaxPrice:              maxPrice,
+		ImportJobs:            importJobs,
+		ImportQueue:           importQueue,
+		DegradeOnVariantError: os.Getenv("CATALOG_DEGRADE_ON_VARIANT_ERROR") == "true",
+		Writer:                prodRepo,
+		Transactor:            repos.NewTransactor(db, prodRepo),
+		AdminToken:            adminToken,
+		// The deprecated products_available field of the listings counts
+		// total_products unless CATALOG_PRODUCTS_AVAILABLE says otherwise
+		ProductsAvailableAlias: os.Getenv("CATALOG_PRODUCTS_AVAILABLE"),
+		Audit:                  auditLog,
+		// The page and total of the listings are queried together unless
+		// CATALOG_CONCURRENT_QUERIES is false
+		ConcurrentQueries: os.Getenv("CATALOG_CONCURRENT_QUERIES") != "false",
+		QueryConcurrency:  envInt("CATALOG_QUERY_CONCURRENCY", 0),
+	})
+	if err != nil {
+		fatal("Invalid catalog configuration", "error", err)
+	}
+	importQueue.Start(cat.RunImportJob)
+
+	// Variants left behind by deleted products are cleaned up every
+	// ORPHAN_VARIANTS_CLEANUP_INTERVAL, never when it is unset
+	var orphanCleanup *jobs.Periodic
+	if interval := envDuration("ORPHAN_VARIANTS_CLEANUP_INTERVAL", 0); interval > 0 {
+		orphanCleanup = jobs.NewPeriodic("orphan-variants-cleanup", interval, func(ctx context.Context) error {
+			n, err := prodRepo.DeleteOrphanVariants(ctx)
+			if n > 0 {
+				logging.FromContext(ctx).Info("Deleted orphan variants", "count", n)
+			}
+			return err
+		}, logger)
+		orphanCleanup.Start()
+	}
+
+	// Category creations are also announced to CATEGORY_WEBHOOK_URL when set
+	notifiers := category.Notifiers{category.EventNotifier{Events: dispatcher}}
+	if url := os.Getenv("CATEGORY_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, category.NewWebhookNotifier(url, webhookTimeout, webhookAttempts))
+	}
+	categoryRepo := categoryrepo.NewGormRepo(db)
+	categoryRepo.SetRounding(rounding)
+	cats := category.NewCategoryHandler(categoryRepo, categoryRepo, notifiers, auditLog)
+	cats.SetPricing(rounding, minorUnits)
+	wish := wishlist.NewWishlistHandler(wishlistrepo.NewGormRepo(db), rounding)
+	dbAdmin := admin.NewDBHandler(pool)
+	eventLog := eventlog.NewHandler(outbox.NewGormRepo(db))
+	auditTrail := audit.NewHandler(auditRepo)
 
 	// Set up routing
-	mux := http.NewServeMux()
-	mux.HandleFunc("GET /catalog", cat.HandleGet)
+	mux := newMux(routes(handlers{
+		catalog:     cat,
+		categories:  cats,
+		wishlists:   wish,
+		db:          dbAdmin,
+		events:      eventLog,
+		audit:       auditTrail,
+		adminToken:  adminToken,
+		writeAPIKey: os.Getenv("WRITE_API_KEY"),
+	}), logger)
 
-	// Set up the HTTP server
+	// Set up the HTTP server. Links point to PUBLIC_BASE_URL when set, or to
+	// the host of the request, forwarded by the proxy when TRUST_PROXY is on
+	publicURL := api.PublicURL{
+		BaseURL:    os.Getenv("PUBLIC_BASE_URL"),
+		TrustProxy: os.Getenv("TRUST_PROXY") == "true",
+	}
+	if publicURL.BaseURL != "" {
+		if err := api.ValidateBaseURL(publicURL.BaseURL); err != nil {
+			fatal("Invalid PUBLIC_BASE_URL", "error", err)
+		}
+	}
+	// Reads are cached by the CDN, except for the wishlists which belong to
+	// their owner and the import jobs and feeds which are polled
+	cachePolicy := api.CachePolicy{
+		ListMaxAge:   envDuration("CACHE_LIST_MAX_AGE", api.DefaultListMaxAge),
+		DetailMaxAge: envDuration("CACHE_DETAIL_MAX_AGE", api.DefaultDetailMaxAge),
+		Routes: map[string]string{
+			"GET /wishlist/{token}":         "private, no-cache",
+			"GET /catalog/import/jobs/{id}": api.NoStore,
+			"GET /catalog/changes":          api.NoStore,
+			"GET /admin/db/stats":           api.NoStore,
+			"GET /admin/audit":              api.NoStore,
+			"GET /events":                   api.NoStore,
+		},
+	}
+	handler := withLimits(mux, limits{
+		requestTimeout:     envDuration("REQUEST_TIMEOUT", defaultRequestTimeout),
+		maxRequestTimeout:  envDuration("REQUEST_TIMEOUT_MAX", maxRequestTimeout),
+		maxConcurrent:      envInt("MAX_CONCURRENT_REQUESTS", api.DefaultMaxConcurrentRequests),
+		maxBodyBytes:       int64(envInt("MAX_BODY_BYTES", api.DefaultMaxBodyBytes)),
+		maxImportBodyBytes: int64(envInt("MAX_IMPORT_BODY_BYTES", defaultMaxImportBodyBytes)),
+	}, cachePolicy)
+	// TLS is served when TLS_CERT_FILE and TLS_KEY_FILE are set, plain HTTP
+	// otherwise
+	tlsConfig, err := loadTLSConfig(os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"))
+	if err != nil {
+		fatal("Invalid TLS configuration", "error", err)
+	}
 	srv := &http.Server{
-		Addr:    fmt.Sprintf("localhost:%s", os.Getenv("HTTP_PORT")),
-		Handler: mux,
+		Addr:      fmt.Sprintf("localhost:%s", os.Getenv("HTTP_PORT")),
+		Handler:   api.LoggerMiddleware(logger, api.RecoverMiddleware(api.PublicURLMiddleware(publicURL, api.PrettyMiddleware(handler)))),
+		TLSConfig: tlsConfig,
 	}
 
-	// Start the server
-	go func() {
-		log.Printf("Starting server on http://%s", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %s", err)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		fatal("Failed to listen", "addr", srv.Addr, "error", err)
+	}
+
+	// Serve until a signal arrives, then shut down gracefully. The running
+	// imports and cleanup are interrupted before the database they write to
+	// is closed
+	closeDB := func() error {
+		stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := importQueue.Stop(stopCtx); err != nil {
+			return errors.Join(fmt.Errorf("stopping imports: %w", err), closeDBCon())
+		}
+		if orphanCleanup != nil {
+			if err := orphanCleanup.Stop(stopCtx); err != nil {
+				return errors.Join(fmt.Errorf("stopping the orphan variants cleanup: %w", err), closeDBCon())
+			}
 		}
+		return closeDBCon()
+	}
+	err = Run(ctx, srv, ln, closeDB, shutdownTimeout)
 
-		log.Println("Server stopped gracefully")
-	}()
+	// Deliver the events still queued once no more requests publish them
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if stopErr := dispatcher.Stop(stopCtx); stopErr != nil {
+		err = errors.Join(err, fmt.Errorf("delivering pending events: %w", stopErr))
+	}
+
+	if err != nil {
+		logger.Error("Shutdown failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// envInt reads an integer environment variable, falling back to def when unset.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		fatal("Invalid "+key, "error", err)
+	}
+	return n
+}
+
+// envDuration reads a duration environment variable such as "5s", falling
+// back to def when unset.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		fatal("Invalid "+key, "value", v)
+	}
+	return d
+}
 
-	<-ctx.Done()
-	log.Println("Shutting down server...")
-	srv.Shutdown(ctx)
-	stop()
+// fatal logs the error with the default logger and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/admin"
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/catalog"
+	"github.com/mytheresa/go-hiring-challenge/app/category"
+	"github.com/mytheresa/go-hiring-challenge/app/eventlog"
+	"github.com/mytheresa/go-hiring-challenge/app/wishlist"
+)
+
+// route is an endpoint of the server.
+type route struct {
+	method  string
+	pattern string
+	handler http.Handler
+}
+
+// handlers are the handlers served by the routes, along with the keys
+// guarding them.
+type handlers struct {
+	catalog    *catalog.CatalogHandler
+	categories *category.CategoryHandler
+	wishlists  *wishlist.WishlistHandler
+	db         *admin.DBHandler
+	events     *eventlog.Handler
+	audit      *audit.Handler
+	// adminToken is the bearer token of the catalog writes and admin
+	// listings, writeAPIKey the API key of the machine to machine endpoints:
+	// the price adjustments, the database administration and the events.
+	adminToken  string
+	writeAPIKey string
+}
+
+// routes lists the endpoints of the server. The catalog writes, and the jobs
+// of the imports, require the admin token, while the reads and the wishlists
+// of the shoppers stay open.
+func routes(h handlers) []route {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return api.AdminAuthMiddleware(h.adminToken, next)
+	}
+	withAPIKey := func(next http.HandlerFunc) http.Handler {
+		return api.RequireAPIKey(h.writeAPIKey, next)
+	}
+	// Listing the products outside of their availability window is for admins
+	listing := func(next http.HandlerFunc) http.Handler {
+		return api.AdminAuthWhen(h.adminToken, func(r *http.Request) bool {
+			return r.URL.Query().Get("includeUnavailable") == "true"
+		}, next)
+	}
+	cat, cats := h.catalog, h.categories
+
+	return []route{
+		{http.MethodGet, "/catalog", listing(cat.HandleGet)},
+		{http.MethodGet, "/catalog/schema", http.HandlerFunc(cat.HandleSchema)},
+		{http.MethodGet, "/catalog/lookup", http.HandlerFunc(cat.HandleLookup)},
+		{http.MethodGet, "/catalog/compare", http.HandlerFunc(cat.HandleCompare)},
+		{http.MethodGet, "/catalog/grouped", http.HandlerFunc(cat.HandleGrouped)},
+		{http.MethodGet, "/catalog/export", http.HandlerFunc(cat.HandleExport)},
+		{http.MethodGet, "/catalog/changes", http.HandlerFunc(cat.HandleChanges)},
+		{http.MethodGet, "/catalog/{code}", http.HandlerFunc(cat.HandleGetSpecific)},
+		{http.MethodGet, "/catalog/{code}/related", http.HandlerFunc(cat.HandleRelated)},
+		{http.MethodGet, "/catalog/{code}/exists", http.HandlerFunc(cat.HandleExists)},
+		{http.MethodGet, "/variants", http.HandlerFunc(cat.HandleVariants)},
+		{http.MethodPost, "/catalog", adminOnly(cat.HandleCreate)},
+		{http.MethodPost, "/catalog/validate", http.HandlerFunc(cat.HandleValidate)},
+		{http.MethodPost, "/catalog/import", adminOnly(cat.HandleImport)},
+		{http.MethodGet, "/catalog/import/jobs/{id}", adminOnly(cat.HandleImportJob)},
+		{http.MethodDelete, "/catalog/{code}/variants/{sku}", adminOnly(cat.HandleDeleteVariant)},
+		{http.MethodPatch, "/catalog/{code}/variants/prices", adminOnly(cat.HandleUpdateVariantPrices)},
+		{http.MethodPost, "/catalog/{code}/images", adminOnly(cat.HandleAddImage)},
+		{http.MethodDelete, "/catalog/{code}/images/{id}", adminOnly(cat.HandleDeleteImage)},
+		{http.MethodPost, "/catalog/price-adjustments", withAPIKey(cat.HandleAdjustPrices)},
+		{http.MethodPost, "/admin/import", adminOnly(cat.HandleSnapshotImport)},
+		{http.MethodPost, "/admin/maintenance/orphan-variants", adminOnly(cat.HandleDeleteOrphanVariants)},
+		{http.MethodGet, "/admin/db/stats", withAPIKey(h.db.HandleStats)},
+		{http.MethodPut, "/admin/db/pool", withAPIKey(h.db.HandleSetPool)},
+		{http.MethodGet, "/admin/audit", adminOnly(h.audit.HandleList)},
+		{http.MethodGet, "/categories", http.HandlerFunc(cats.HandleGet)},
+		{http.MethodPost, "/categories", adminOnly(cats.HandlePost)},
+		{http.MethodPut, "/categories/{code}", adminOnly(cats.HandlePut)},
+		{http.MethodPatch, "/categories/{code}", adminOnly(cats.HandlePatch)},
+		{http.MethodGet, "/categories/{code}/products", listing(cat.HandleCategoryProducts)},
+		{http.MethodGet, "/categories/{code}/price-range", http.HandlerFunc(cats.HandlePriceRange)},
+		{http.MethodGet, "/categories/{code}/products/count", http.HandlerFunc(cats.HandleProductCount)},
+		{http.MethodGet, "/categories/{code}/price-stats", http.HandlerFunc(cat.HandlePriceStats)},
+		{http.MethodPost, "/categories/{code}/adjust-prices", adminOnly(cat.HandleAdjustCategoryPrices)},
+		{http.MethodGet, "/wishlist/{token}", http.HandlerFunc(h.wishlists.HandleGet)},
+		{http.MethodPost, "/wishlist/{token}/items", http.HandlerFunc(h.wishlists.HandleAdd)},
+		{http.MethodDelete, "/wishlist/{token}/items/{code}", http.HandlerFunc(h.wishlists.HandleRemove)},
+		{http.MethodGet, "/events", withAPIKey(h.events.HandleList)},
+	}
+}
+
+// newMux registers the routes, logging each of them so that the log of a
+// run tells which endpoints it served.
+func newMux(routes []route, logger *slog.Logger) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, r := range routes {
+		mux.Handle(r.method+" "+r.pattern, r.handler)
+		logger.Info("Registered route", "method", r.method, "pattern", r.pattern)
+	}
+	return mux
+}
+
+// importRoutes upload whole catalogs. They get a body limit of their own and
+// no request timeout, the import running for as long as it takes.
+var importRoutes = []string{"POST /catalog/import"}
+
+// limits bound the requests served.
+type limits struct {
+	// requestTimeout is the timeout of the requests not asking for one,
+	// maxRequestTimeout the most they can ask for.
+	requestTimeout    time.Duration
+	maxRequestTimeout time.Duration
+	maxConcurrent     int
+	// maxBodyBytes caps the request bodies but those of the import routes,
+	// capped at maxImportBodyBytes.
+	maxBodyBytes       int64
+	maxImportBodyBytes int64
+}
+
+// withLimits serves mux with the cache policy within the limits. The slot of
+// a request is held until its handler returns, even past the timeout, as
+// long as its queries may run.
+func withLimits(mux *http.ServeMux, l limits, cachePolicy api.CachePolicy) http.Handler {
+	cached := api.CacheMiddleware(cachePolicy, mux)
+	bounded := api.ConcurrencyLimitMiddleware(l.maxConcurrent, api.RoutesMiddleware(mux, importRoutes,
+		api.BodyLimitMiddleware(l.maxImportBodyBytes, cached),
+		api.BodyLimitMiddleware(l.maxBodyBytes, cached)))
+	return api.RoutesMiddleware(mux, importRoutes, bounded,
+		api.TimeoutMiddleware(l.requestTimeout, l.maxRequestTimeout, bounded))
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+)
+
+func TestRoutes(t *testing.T) {
+	var got []string
+	for _, r := range routes(handlers{}) {
+		got = append(got, r.method+" "+r.pattern)
+	}
+
+	assert.ElementsMatch(t, []string{
+		"GET /catalog",
+		"GET /catalog/schema",
+		"GET /catalog/lookup",
+		"GET /catalog/compare",
+		"GET /catalog/grouped",
+		"GET /catalog/export",
+		"GET /catalog/changes",
+		"GET /catalog/{code}",
+		"GET /catalog/{code}/related",
+		"GET /catalog/{code}/exists",
+		"GET /variants",
+		"POST /catalog",
+		"POST /catalog/validate",
+		"POST /catalog/import",
+		"GET /catalog/import/jobs/{id}",
+		"DELETE /catalog/{code}/variants/{sku}",
+		"PATCH /catalog/{code}/variants/prices",
+		"POST /catalog/{code}/images",
+		"DELETE /catalog/{code}/images/{id}",
+		"POST /catalog/price-adjustments",
+		"POST /admin/import",
+		"POST /admin/maintenance/orphan-variants",
+		"GET /admin/db/stats",
+		"PUT /admin/db/pool",
+		"GET /admin/audit",
+		"GET /categories",
+		"POST /categories",
+		"PUT /categories/{code}",
+		"PATCH /categories/{code}",
+		"GET /categories/{code}/products",
+		"GET /categories/{code}/price-range",
+		"GET /categories/{code}/products/count",
+		"GET /categories/{code}/price-stats",
+		"POST /categories/{code}/adjust-prices",
+		"GET /wishlist/{token}",
+		"POST /wishlist/{token}/items",
+		"DELETE /wishlist/{token}/items/{code}",
+		"GET /events",
+	}, got)
+}
+
+func TestRoutes_Auth(t *testing.T) {
+	mux := newMux(routes(handlers{adminToken: "secret", writeAPIKey: "key"}), slog.New(slog.DiscardHandler))
+
+	for _, target := range []string{
+		"GET /catalog/import/jobs/1",
+		"GET /events",
+	} {
+		method, path, _ := strings.Cut(target, " ")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, target)
+	}
+}
+
+func TestNewMux(t *testing.T) {
+	var logs bytes.Buffer
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	mux := newMux([]route{
+		{http.MethodGet, "/catalog", ok},
+		{http.MethodPost, "/catalog/{code}/images", ok},
+	}, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	for target, want := range map[string]int{
+		"GET /catalog":                 http.StatusNoContent,
+		"POST /catalog/PROD001/images": http.StatusNoContent,
+		"POST /catalog":                http.StatusMethodNotAllowed,
+		"GET /categories":              http.StatusNotFound,
+	} {
+		method, path, _ := strings.Cut(target, " ")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		assert.Equal(t, want, rec.Code, target)
+	}
+	assert.Contains(t, logs.String(), `msg="Registered route" method=GET pattern=/catalog`)
+	assert.Contains(t, logs.String(), `msg="Registered route" method=POST pattern=/catalog/{code}/images`)
+}
+
+func TestWithLimits(t *testing.T) {
+	// Both routes read the whole body and outlast the request timeout
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := api.ReadBody(w, r)
+		if !ok {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		api.OKResponse(w, map[string]int{"bytes": len(body)})
+	})
+	mux := http.NewServeMux()
+	mux.Handle("POST /catalog/import", slow)
+	mux.Handle("POST /catalog", slow)
+
+	handler := withLimits(mux, limits{
+		requestTimeout:     10 * time.Millisecond,
+		maxRequestTimeout:  10 * time.Millisecond,
+		maxBodyBytes:       api.DefaultMaxBodyBytes,
+		maxImportBodyBytes: 4 << 20,
+	}, api.CachePolicy{})
+
+	post := func(target string, size int) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(strings.Repeat("a", size))))
+		return rec
+	}
+
+	t.Run("imports over the body limit of the other routes", func(t *testing.T) {
+		rec := post("/catalog/import?async=true", 2<<20)
+
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.JSONEq(t, `{"bytes":2097152}`, rec.Body.String())
+	})
+
+	t.Run("imports over their own body limit", func(t *testing.T) {
+		rec := post("/catalog/import", 5<<20)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.JSONEq(t, `{"error":"request body must not be larger than 4194304 bytes"}`, rec.Body.String())
+	})
+
+	t.Run("other routes", func(t *testing.T) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, post("/catalog", 2<<20).Code)
+		assert.Equal(t, http.StatusServiceUnavailable, post("/catalog", 10).Code)
+	})
+}
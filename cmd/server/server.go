@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Run serves HTTP on ln until ctx is cancelled and then shuts down in order:
+// it stops accepting connections, waits up to shutdownTimeout for in-flight
+// requests to complete, and only then closes the database. Any failure along
+// the way is returned.
+func Run(ctx context.Context, srv *http.Server, ln net.Listener, closeDB func() error, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve(srv, ln)
+	}()
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		errs = append(errs, fmt.Errorf("server failed: %w", err))
+	case <-ctx.Done():
+		slog.Info("Shutting down server")
+
+		// The parent context is already cancelled, shutdown gets its own deadline
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("waiting for in-flight requests: %w", err))
+		} else {
+			slog.Info("Server stopped gracefully")
+		}
+	}
+
+	if err := closeDB(); err != nil {
+		errs = append(errs, fmt.Errorf("closing database: %w", err))
+	} else {
+		slog.Info("Database connection closed")
+	}
+
+	return errors.Join(errs...)
+}
+
+// serve speaks TLS when the server has a TLS configuration, with the
+// certificates loaded at startup, and plain HTTP otherwise.
+func serve(srv *http.Server, ln net.Listener) error {
+	var err error
+	if srv.TLSConfig != nil {
+		slog.Info("Starting server", "url", "https://"+ln.Addr().String())
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		slog.Info("Starting server", "url", "http://"+ln.Addr().String())
+		err = srv.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder keeps track of the order in which shutdown steps happen.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// startRun runs the server with a handler that holds requests open until
+// release is closed.
+func startRun(t *testing.T, rec *recorder, closeErr error, timeout time.Duration) (addr string, started, release chan struct{}, cancel context.CancelFunc, done chan error) {
+	t.Helper()
+
+	started, release = make(chan struct{}), make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		rec.record("request completed")
+	})}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done = make(chan error, 1)
+	go func() {
+		done <- Run(ctx, srv, ln, func() error {
+			rec.record("database closed")
+			return closeErr
+		}, timeout)
+	}()
+
+	return ln.Addr().String(), started, release, cancel, done
+}
+
+func TestRun(t *testing.T) {
+	t.Run("closes the database after in-flight requests", func(t *testing.T) {
+		rec := &recorder{}
+		addr, started, release, cancel, done := startRun(t, rec, nil, 5*time.Second)
+
+		resp := make(chan int, 1)
+		go func() {
+			res, err := http.Get("http://" + addr)
+			if err != nil {
+				resp <- 0
+				return
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+			resp <- res.StatusCode
+		}()
+
+		<-started
+		cancel()
+
+		// Shutdown must keep waiting for the request
+		time.Sleep(100 * time.Millisecond)
+		assert.Empty(t, rec.list())
+
+		close(release)
+
+		assert.Equal(t, http.StatusOK, <-resp)
+		assert.NoError(t, <-done)
+		assert.Equal(t, []string{"request completed", "database closed"}, rec.list())
+	})
+
+	t.Run("reports a database close failure", func(t *testing.T) {
+		rec := &recorder{}
+		_, _, _, cancel, done := startRun(t, rec, errors.New("close failed"), time.Second)
+
+		cancel()
+
+		err := <-done
+		assert.ErrorContains(t, err, "closing database: close failed")
+	})
+
+	t.Run("reports requests outliving the shutdown timeout", func(t *testing.T) {
+		rec := &recorder{}
+		addr, started, release, cancel, done := startRun(t, rec, nil, 50*time.Millisecond)
+		defer close(release)
+
+		go http.Get("http://" + addr)
+		<-started
+		cancel()
+
+		err := <-done
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, []string{"database closed"}, rec.list())
+	})
+}
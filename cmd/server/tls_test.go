@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair writes a self-signed certificate for 127.0.0.1 and its key to
+// dir, returning their paths.
+func writeKeyPair(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir)
+	garbage := filepath.Join(dir, "garbage.pem")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a certificate"), 0o600))
+	missing := filepath.Join(dir, "missing.pem")
+
+	t.Run("plain HTTP when neither file is set", func(t *testing.T) {
+		config, err := loadTLSConfig("", "")
+
+		require.NoError(t, err)
+		assert.Nil(t, config)
+	})
+
+	t.Run("TLS 1.2 at least with the key pair", func(t *testing.T) {
+		config, err := loadTLSConfig(certFile, keyFile)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+		assert.Len(t, config.Certificates, 1)
+		assert.Equal(t, []string{"h2", "http/1.1"}, config.NextProtos)
+	})
+
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		want     string
+	}{
+		{"certificate only", certFile, "", "TLS_CERT_FILE and TLS_KEY_FILE must be set together"},
+		{"key only", "", keyFile, "TLS_CERT_FILE and TLS_KEY_FILE must be set together"},
+		{"missing certificate", missing, keyFile, "reading the TLS certificate"},
+		{"missing key", certFile, missing, "reading the TLS key"},
+		{"not a key pair", garbage, keyFile, "loading the TLS key pair"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTLSConfig(tt.certFile, tt.keyFile)
+
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestRun_TLS(t *testing.T) {
+	certFile, keyFile := writeKeyPair(t, t.TempDir())
+	config, err := loadTLSConfig(certFile, keyFile)
+	require.NoError(t, err)
+	srv := &http.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: config,
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, srv, ln, func() error { return nil }, 5*time.Second)
+	}()
+
+	get := func(t *testing.T, http2 bool) *http.Response {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: http2,
+		}}
+		defer client.CloseIdleConnections()
+		res, err := client.Get("https://" + ln.Addr().String())
+		require.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	t.Run("negotiates HTTP/2", func(t *testing.T) {
+		res := get(t, true)
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 2, res.ProtoMajor)
+		require.NotNil(t, res.TLS)
+		assert.Equal(t, "h2", res.TLS.NegotiatedProtocol)
+		assert.GreaterOrEqual(t, res.TLS.Version, uint16(tls.VersionTLS12))
+	})
+
+	t.Run("falls back to HTTP/1.1", func(t *testing.T) {
+		res := get(t, false)
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 1, res.ProtoMajor)
+		require.NotNil(t, res.TLS)
+		assert.NotEqual(t, "h2", res.TLS.NegotiatedProtocol)
+	})
+
+	cancel()
+	assert.NoError(t, <-done)
+}
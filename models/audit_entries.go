@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry is a change made through the write endpoints: who made it, to
+// what, and the state of the entity before and after as JSON, nil when there
+// is none such as before a creation.
+type AuditEntry struct {
+	ID uint64 `gorm:"primaryKey"`
+	// Actor identifies the key the change was authenticated with.
+	Actor      string `gorm:"not null"`
+	Action     string `gorm:"not null"`
+	EntityType string `gorm:"not null"`
+	// EntityCode is the code of the entity changed, empty for the changes
+	// spanning the catalog.
+	EntityCode string          `gorm:"not null"`
+	Before     json.RawMessage `gorm:"type:jsonb"`
+	After      json.RawMessage `gorm:"type:jsonb"`
+	CreatedAt  time.Time
+}
+
+func (e *AuditEntry) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,52 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// Category represents a product category in the catalog.
+// It includes a unique human-readable code and a display name.
+// MarkupPercent, when set, is added to the product price inherited by the
+// variants without a price of their own. Version starts at 1 and is
+// incremented on every update, for concurrent updates to be detected.
+type Category struct {
+	ID            uint                  `gorm:"primaryKey"`
+	Code          string                `gorm:"uniqueIndex;not null"`
+	Name          string                `gorm:"not null"`
+	MarkupPercent decimal.NullDecimal   `gorm:"type:decimal(5,2);null"`
+	Version       int                   `gorm:"not null;default:1"`
+	Translations  []CategoryTranslation `gorm:"foreignKey:CategoryID"`
+}
+
+func (c *Category) TableName() string {
+	return "categories"
+}
+
+// LocalizedName returns the name of the category in the given locale,
+// falling back to the default Name when there is no translation for it.
+func (c *Category) LocalizedName(locale string) string {
+	for _, t := range c.Translations {
+		if t.Locale == locale {
+			return t.Name
+		}
+	}
+	return c.Name
+}
+
+// MarkUp adds the markup of the category to price, unrounded so the price is
+// rounded only once rendered. price is returned as is without a markup.
+func (c *Category) MarkUp(price decimal.Decimal) decimal.Decimal {
+	if !c.MarkupPercent.Valid {
+		return price
+	}
+	return price.Add(price.Mul(c.MarkupPercent.Decimal).Div(decimal.NewFromInt(100)))
+}
+
+// CategoryTranslation holds the name of a category in one locale.
+type CategoryTranslation struct {
+	CategoryID uint   `gorm:"primaryKey"`
+	Locale     string `gorm:"primaryKey"`
+	Name       string `gorm:"not null"`
+}
+
+func (t *CategoryTranslation) TableName() string {
+	return "category_translations"
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Image is a picture of a product. The images of a product are ordered by
+// their position, starting at 1.
+type Image struct {
+	ID        uint   `gorm:"primaryKey"`
+	ProductID uint   `gorm:"index:idx_product_images_position;not null"`
+	URL       string `gorm:"not null"`
+	Position  int    `gorm:"index:idx_product_images_position;not null"`
+	AltText   string
+	CreatedAt time.Time
+}
+
+func (i *Image) TableName() string {
+	return "product_images"
+}
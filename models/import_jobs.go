@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Statuses of an import job.
+const (
+	ImportQueued  = "queued"
+	ImportRunning = "running"
+	ImportDone    = "done"
+	ImportFailed  = "failed"
+)
+
+// ImportJob is a CSV import of products run in the background. Data holds
+// the uploaded file until the job is finished.
+type ImportJob struct {
+	ID        uint   `gorm:"primaryKey"`
+	Status    string `gorm:"index;not null"`
+	Data      []byte
+	Total     int
+	Processed int
+	Created   int
+	Failed    int
+	// Errors lists the rows that could not be imported.
+	Errors []ImportError `gorm:"serializer:json"`
+	// Error is why the job as a whole failed.
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (j *ImportJob) TableName() string {
+	return "import_jobs"
+}
+
+// Finished reports whether the job is done or failed.
+func (j *ImportJob) Finished() bool {
+	return j.Status == ImportDone || j.Status == ImportFailed
+}
+
+// ImportError is a row of an import that could not be imported. Rows are
+// numbered from 1, the header excluded.
+type ImportError struct {
+	Row   int    `json:"row"`
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a change of a product or a category, recorded in the same
+// transaction as the change itself. IDs increase in commit order, so that
+// consumers can track the last event they read.
+type OutboxEvent struct {
+	ID   uint64 `gorm:"primaryKey"`
+	Type string `gorm:"not null"`
+	// AggregateCode is the code of the product or the category changed,
+	// empty for the changes spanning the catalog.
+	AggregateCode string          `gorm:"not null"`
+	Payload       json.RawMessage `gorm:"type:jsonb;not null"`
+	CreatedAt     time.Time
+}
+
+func (e *OutboxEvent) TableName() string {
+	return "events"
+}
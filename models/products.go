@@ -1,18 +1,44 @@
 package models
 
 import (
+	"time"
+
 	"github.com/shopspring/decimal"
 )
 
 // Product represents a product in the catalog.
-// It includes a unique code and a price.
+// It includes a unique code, a price and the category it belongs to.
 type Product struct {
-	ID       uint            `gorm:"primaryKey"`
-	Code     string          `gorm:"uniqueIndex;not null"`
-	Price    decimal.Decimal `gorm:"type:decimal(10,2);not null"`
-	Variants []Variant       `gorm:"foreignKey:ProductID"`
+	ID         uint            `gorm:"primaryKey"`
+	Code       string          `gorm:"uniqueIndex;not null"`
+	Price      decimal.Decimal `gorm:"type:decimal(10,2);not null"`
+	CategoryID *uint
+	Category   *Category `gorm:"foreignKey:CategoryID"`
+	Variants   []Variant `gorm:"foreignKey:ProductID"`
+	Images     []Image   `gorm:"foreignKey:ProductID"`
+	// AvailableFrom and AvailableTo bound the window during which the
+	// product is listed, nil leaving it open on that side.
+	AvailableFrom *time.Time
+	AvailableTo   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 func (p *Product) TableName() string {
 	return "products"
 }
+
+// AvailableAt reports whether t is within the availability window of the
+// product.
+func (p *Product) AvailableAt(t time.Time) bool {
+	return (p.AvailableFrom == nil || !p.AvailableFrom.After(t)) && (p.AvailableTo == nil || !p.AvailableTo.Before(t))
+}
+
+// InheritedVariantPrice is the price of the variants without a price of their
+// own: the product price, marked up by its category when loaded.
+func (p *Product) InheritedVariantPrice() decimal.Decimal {
+	if p.Category == nil {
+		return p.Price
+	}
+	return p.Category.MarkUp(p.Price)
+}
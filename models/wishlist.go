@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// WishlistItem links a product to the wishlist identified by an opaque
+// client-generated token. A product appears at most once per wishlist.
+type WishlistItem struct {
+	ID        uint    `gorm:"primaryKey"`
+	Token     string  `gorm:"uniqueIndex:idx_wishlist_token_product;not null"`
+	ProductID uint    `gorm:"uniqueIndex:idx_wishlist_token_product;not null"`
+	Product   Product `gorm:"foreignKey:ProductID"`
+	CreatedAt time.Time
+}
+
+func (w *WishlistItem) TableName() string {
+	return "wishlist_items"
+}
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS category_translations (
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    locale VARCHAR(8) NOT NULL,
+    name VARCHAR(256) NOT NULL,
+    PRIMARY KEY (category_id, locale)
+);
+
+-- German names for the seeded categories
+INSERT INTO category_translations (category_id, locale, name)
+SELECT categories.id, 'de', names.name
+FROM categories
+JOIN (VALUES
+    ('clothing', 'Kleidung'),
+    ('shoes', 'Schuhe'),
+    ('accessories', 'Accessoires')
+) AS names (code, name) ON names.code = categories.code;
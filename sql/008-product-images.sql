@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS product_images (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    url VARCHAR(2048) NOT NULL,
+    position INTEGER NOT NULL,
+    alt_text VARCHAR(256) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Positions are shifted when images are added or removed, so they are only
+-- indexed rather than unique
+CREATE INDEX IF NOT EXISTS idx_product_images_position ON product_images (product_id, position);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id SERIAL PRIMARY KEY,
+    status VARCHAR(16) NOT NULL,
+    data BYTEA,
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    errors JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- The jobs left queued or running are looked up at startup to mark them failed
+CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs (status);
@@ -0,0 +1,3 @@
+-- Product codes were declared unique by the models only, so duplicated codes
+-- were not rejected by the database
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_code ON products (code);
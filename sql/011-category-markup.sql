@@ -0,0 +1,4 @@
+-- Percentage added to the product price inherited by variants without a
+-- price of their own. NULL means no markup.
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS markup_percent DECIMAL(5,2)
+    CHECK (markup_percent BETWEEN 0 AND 100);
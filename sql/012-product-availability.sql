@@ -0,0 +1,4 @@
+-- Window during which seasonal products are listed. NULL bounds leave the
+-- window open on that side.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS available_from TIMESTAMP;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS available_to TIMESTAMP;
@@ -0,0 +1,9 @@
+-- Outbox of the product and category changes, written in the transaction of
+-- each change
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    aggregate_code VARCHAR(32) NOT NULL DEFAULT '',
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
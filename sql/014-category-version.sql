@@ -0,0 +1,3 @@
+-- Incremented on every update of the category, writers telling the version
+-- they read so that concurrent updates do not overwrite each other.
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;